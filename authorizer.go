@@ -1,9 +1,12 @@
 package biscuit
 
 import (
+	"context"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/biscuit-auth/biscuit-go/v2/datalog"
 	"github.com/biscuit-auth/biscuit-go/v2/pb"
@@ -14,16 +17,24 @@ var (
 	ErrMissingSymbols   = errors.New("biscuit: missing symbols")
 	ErrPolicyDenied     = errors.New("biscuit: denied by policy")
 	ErrNoMatchingPolicy = errors.New("biscuit: denied by no matching policies")
+	// ErrTooManyBlocks is returned when a token has more blocks than allowed by WithMaxBlocks
+	ErrTooManyBlocks = errors.New("biscuit: too many blocks")
 )
 
 type Authorizer interface {
 	AddAuthorizer(a ParsedAuthorizer)
 	AddBlock(b ParsedBlock)
 	AddFact(fact Fact)
+	AddFacts(facts []Fact)
+	AddFactsFromString(facts string) error
+	AddCurrentTime(t time.Time)
 	AddRule(rule Rule)
 	AddCheck(check Check)
 	AddPolicy(policy Policy)
+	AddPolicyFromString(policy string) error
 	Authorize() error
+	AuthorizeContext(ctx context.Context) error
+	AuthorizeWithResult() (AuthorizationResult, error)
 	Query(rule Rule) (FactSet, error)
 	Biscuit() *Biscuit
 	Reset()
@@ -32,17 +43,54 @@ type Authorizer interface {
 	SerializePolicies() ([]byte, error)
 }
 
+// AuthorizationResult describes what an authorization decision was based on.
+type AuthorizationResult struct {
+	// PolicyIndex is the index, in the order they were added, of the
+	// policy which matched, or -1 when no policy matched.
+	PolicyIndex int
+	// PolicyKind is the kind of the matched policy, or the default
+	// policy when none matched.
+	PolicyKind PolicyKind
+	// Checks lists the evaluated checks: the authorizer checks first,
+	// then the checks of each block.
+	Checks []CheckResult
+}
+
+// CheckResult is the outcome of a check evaluated during authorization.
+type CheckResult struct {
+	// BlockID is the index of the block containing the check, the
+	// authority block being 0, or -1 for the authorizer checks.
+	BlockID int
+	// Index is the position of the check in its block or in the authorizer.
+	Index   int
+	Check   Check
+	Success bool
+}
+
 type authorizer struct {
-	biscuit      *Biscuit
-	baseWorld    *datalog.World
-	world        *datalog.World
-	baseSymbols  *datalog.SymbolTable
-	symbols      *datalog.SymbolTable
-	block_worlds []*datalog.World
+	biscuit     *Biscuit
+	baseWorld   *datalog.World
+	world       *datalog.World
+	baseSymbols *datalog.SymbolTable
+	symbols     *datalog.SymbolTable
 
 	checks   []Check
 	policies []Policy
 
+	maxBlocks int
+	// applied when no policy matches
+	defaultPolicy PolicyKind
+	// when not nil, third party blocks must be signed by one of these keys
+	trustedExternalKeys []ed25519.PublicKey
+	// provides the facts of predicates with no facts when the world is run
+	factSource func(predicateName string) []Fact
+	// predicate names already requested from factSource
+	sourcedPredicates map[string]struct{}
+	// origins trusted by each block, set when the blocks are loaded
+	// in the world by the first call to authorize
+	blocksOrigins []datalog.Origin
+	loaded        bool
+
 	dirty bool
 }
 
@@ -56,14 +104,66 @@ func WithWorldOptions(opts ...datalog.WorldOption) AuthorizerOption {
 	}
 }
 
+// WithMaxBlocks limits the number of blocks, authority included, a token can
+// have. Tokens over the limit are rejected before their signatures are verified.
+func WithMaxBlocks(n int) AuthorizerOption {
+	return func(a *authorizer) {
+		a.maxBlocks = n
+	}
+}
+
+// WithDefaultPolicy sets the result of the authorization when the checks
+// pass but no policy matches. It defaults to PolicyKindDeny, which returns
+// ErrNoMatchingPolicy. PolicyKindAllow should only be used in trusted environments.
+func WithDefaultPolicy(kind PolicyKind) AuthorizerOption {
+	return func(a *authorizer) {
+		a.defaultPolicy = kind
+	}
+}
+
+// WithTrustedExternalKeys restricts the keys third party blocks can be signed with:
+// creating the authorizer fails with ErrUntrustedExternalKey when a block is signed
+// by another key, even if its signature is valid. An empty list rejects all third
+// party blocks. By default, any key is accepted.
+func WithTrustedExternalKeys(keys []ed25519.PublicKey) AuthorizerOption {
+	return func(a *authorizer) {
+		a.trustedExternalKeys = append([]ed25519.PublicKey{}, keys...)
+	}
+}
+
+// WithFactSource lazily provides authorizer facts: before running the world,
+// source is called with the name of each predicate used in the body of a rule,
+// check or policy and which has no facts yet. The returned facts are added as if
+// by AddFact. Each name is requested at most once until the authorizer is reset.
+func WithFactSource(source func(predicateName string) []Fact) AuthorizerOption {
+	return func(a *authorizer) {
+		a.factSource = source
+	}
+}
+
+func (v *authorizer) trustsExternalKey(key ed25519.PublicKey) bool {
+	for _, k := range v.trustedExternalKeys {
+		if k.Equal(key) {
+			return true
+		}
+	}
+	return false
+}
+
 func NewVerifier(b *Biscuit, opts ...AuthorizerOption) (Authorizer, error) {
+	return newAuthorizer(b, opts...), nil
+}
+
+func newAuthorizer(b *Biscuit, opts ...AuthorizerOption) *authorizer {
 	a := &authorizer{
-		biscuit:      b,
-		baseWorld:    datalog.NewWorld(),
-		baseSymbols:  defaultSymbolTable.Clone(),
-		checks:       []Check{},
-		policies:     []Policy{},
-		block_worlds: []*datalog.World{},
+		biscuit:       b,
+		baseWorld:     datalog.NewWorld(),
+		baseSymbols:   defaultSymbolTable.Clone(),
+		checks:        []Check{},
+		policies:      []Policy{},
+		defaultPolicy: PolicyKindDeny,
+
+		sourcedPredicates: make(map[string]struct{}),
 	}
 
 	for _, opt := range opts {
@@ -73,7 +173,7 @@ func NewVerifier(b *Biscuit, opts ...AuthorizerOption) (Authorizer, error) {
 	a.world = a.baseWorld.Clone()
 	a.symbols = a.baseSymbols.Clone()
 
-	return a, nil
+	return a
 }
 
 func (v *authorizer) AddAuthorizer(a ParsedAuthorizer) {
@@ -96,11 +196,47 @@ func (v *authorizer) AddBlock(block ParsedBlock) {
 }
 
 func (v *authorizer) AddFact(fact Fact) {
-	v.world.AddFact(fact.convert(v.symbols))
+	v.world.AddFactWithOrigin(fact.convert(v.symbols), datalog.NewOrigin(datalog.AuthorizerOrigin))
+}
+
+// AddFacts adds facts to the authorizer in one pass: they share their
+// origin, and the index of the world is updated once for all of them.
+func (v *authorizer) AddFacts(facts []Fact) {
+	converted := make([]datalog.Fact, 0, len(facts))
+	for _, fact := range facts {
+		converted = append(converted, fact.convert(v.symbols))
+	}
+	v.world.AddFactsWithOrigin(converted, datalog.NewOrigin(datalog.AuthorizerOrigin))
+}
+
+// AddFactsFromString parses a list of `;` separated facts and adds
+// them to the authorizer. Nothing is added when the list contains a rule
+// or a check. It requires the parser package to be imported.
+func (v *authorizer) AddFactsFromString(facts string) error {
+	if ParseFacts == nil {
+		return ErrNoDatalogParser
+	}
+	parsed, err := ParseFacts(facts)
+	if err != nil {
+		return err
+	}
+	v.AddFacts(parsed)
+	return nil
+}
+
+// AddCurrentTime adds the fact `time(t)`, the current time as checked by
+// the expiration checks such as the ones of BlockBuilder.AddTimeLimitCheck.
+// time is one of the default symbols, so it is not added to the symbol table.
+func (v *authorizer) AddCurrentTime(t time.Time) {
+	v.AddFact(Fact{Predicate: Predicate{Name: "time", IDs: []Term{Date(t)}}})
 }
 
 func (v *authorizer) AddRule(rule Rule) {
-	v.world.AddRule(rule.convert(v.symbols))
+	v.addRule(rule.convert(v.symbols))
+}
+
+func (v *authorizer) addRule(rule datalog.Rule) {
+	v.world.AddRuleWithOrigin(rule, datalog.AuthorizerOrigin, v.authorizerTrustedOrigins(rule.Scope))
 }
 
 func (v *authorizer) AddCheck(check Check) {
@@ -111,179 +247,306 @@ func (v *authorizer) AddPolicy(policy Policy) {
 	v.policies = append(v.policies, policy)
 }
 
-func (v *authorizer) Authorize() error {
-	// if we load facts from the verifier before
-	// the token's fact and rules, we might get inconsistent symbols
-	// token ements should first be converted to builder elements
-	// with the token's symbol table, then converted back
-	// with the verifier's symbol table
-	for _, fact := range *v.biscuit.authority.facts {
-		f, err := fromDatalogFact(v.biscuit.symbols, fact)
-		if err != nil {
-			return fmt.Errorf("biscuit: verification failed: %s", err)
+// AddPolicyFromString parses a single `allow if` or `deny if` policy
+// and adds it to the authorizer. It requires the parser package to be imported.
+func (v *authorizer) AddPolicyFromString(policy string) error {
+	if ParsePolicy == nil {
+		return ErrNoDatalogParser
+	}
+	parsed, err := ParsePolicy(policy)
+	if err != nil {
+		return err
+	}
+	v.AddPolicy(parsed)
+	return nil
+}
+
+// defaultOrigins is the scope of rules without a trusting annotation:
+// they only accept facts from the authority block, their own block
+// and the authorizer.
+var defaultOrigins = datalog.NewOrigin(0)
+
+// trustedOrigins returns the origins trusted by a rule of the given block.
+// Public key scopes designate the third party blocks signed by that key.
+func (v *authorizer) trustedOrigins(scopes []datalog.Scope, blockOrigins datalog.Origin, blockID uint64) datalog.Origin {
+	var publicKeyBlocks func([]byte) []uint64
+	if v.biscuit != nil {
+		publicKeyBlocks = v.biscuit.externalKeyBlocks
+	}
+	return datalog.TrustedOrigins(scopes, blockOrigins, blockID, publicKeyBlocks)
+}
+
+func (v *authorizer) authorizerTrustedOrigins(scopes []datalog.Scope) datalog.Origin {
+	return v.trustedOrigins(scopes, defaultOrigins, datalog.AuthorizerOrigin)
+}
+
+// matches returns true if one of the queries generates a fact from
+// the facts trusted by a rule of the given block.
+func (v *authorizer) matches(queries []datalog.Rule, blockOrigins datalog.Origin, blockID uint64) bool {
+	for _, query := range queries {
+		trusted := v.trustedOrigins(query.Scope, blockOrigins, blockID)
+		res := v.world.QueryRuleTrusting(query, trusted, v.symbols)
+		if len(*res) != 0 {
+			return true
 		}
-		v.world.AddFact(f.convert(v.symbols))
+	}
+	return false
+}
+
+// checkMatches returns true when the check succeeds according to its kind.
+// An error while evaluating the expressions of a `check all` fails the query.
+func (v *authorizer) checkMatches(check datalog.Check, blockOrigins datalog.Origin, blockID uint64) bool {
+	switch check.Kind {
+	case datalog.CheckKindOne:
+		return v.matches(check.Queries, blockOrigins, blockID)
+	case datalog.CheckKindReject:
+		return !v.matches(check.Queries, blockOrigins, blockID)
+	}
+	for _, query := range check.Queries {
+		trusted := v.trustedOrigins(query.Scope, blockOrigins, blockID)
+		if ok, err := v.world.QueryMatchAllTrusting(query, trusted, v.symbols); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *authorizer) Authorize() error {
+	_, err := v.authorize(context.Background())
+	return err
+}
+
+// AuthorizeContext runs the authorization like Authorize, and stops early,
+// returning the context's error, when it is canceled or its deadline is
+// exceeded. The world run limits still apply.
+func (v *authorizer) AuthorizeContext(ctx context.Context) error {
+	_, err := v.authorize(ctx)
+	return err
+}
+
+// AuthorizeWithResult runs the authorization like Authorize, and reports
+// which policy matched and the result of each check. The result is filled
+// even when the authorization fails on checks or policies.
+func (v *authorizer) AuthorizeWithResult() (AuthorizationResult, error) {
+	return v.authorize(context.Background())
+}
+
+func (v *authorizer) authorize(ctx context.Context) (AuthorizationResult, error) {
+	result := AuthorizationResult{PolicyIndex: -1}
+	if err := ctx.Err(); err != nil {
+		return result, err
 	}
 
-	for _, rule := range v.biscuit.authority.rules {
-		r, err := fromDatalogRule(v.biscuit.symbols, rule)
+	blocks := append([]*Block{v.biscuit.authority}, v.biscuit.blocks...)
+	if !v.loaded {
+		blocksOrigins, err := v.loadBlocks(blocks)
 		if err != nil {
-			return fmt.Errorf("biscuit: verification failed: %s", err)
+			return result, err
 		}
-		v.world.AddRule(r.convert(v.symbols))
+		v.blocksOrigins = blocksOrigins
+		v.loaded = true
 	}
 
-	if err := v.world.Run(v.symbols); err != nil {
-		return err
+	v.loadSourceFacts(blocks, nil)
+	if err := v.world.RunContext(ctx, v.symbols); err != nil {
+		return result, err
 	}
 	v.dirty = true
 
+	debug := datalog.SymbolDebugger{
+		SymbolTable: v.symbols,
+	}
 	var errs []error
 
 	for i, check := range v.checks {
-		c := check.convert(v.symbols)
-		successful := false
-		for _, query := range c.Queries {
-			res := v.world.QueryRule(query, v.symbols)
-			if len(*res) != 0 {
-				successful = true
-				break
-			}
+		if err := ctx.Err(); err != nil {
+			return result, err
 		}
-		if !successful {
-			debug := datalog.SymbolDebugger{
-				SymbolTable: v.symbols,
-			}
+		c := check.convert(v.symbols)
+		success := v.checkMatches(c, defaultOrigins, datalog.AuthorizerOrigin)
+		if !success {
 			errs = append(errs, fmt.Errorf("failed to verify check #%d: %s", i, debug.Check(c)))
 		}
+		result.Checks = append(result.Checks, CheckResult{BlockID: -1, Index: i, Check: check, Success: success})
 	}
 
-	for i, check := range v.biscuit.authority.checks {
-		ch, err := fromDatalogCheck(v.biscuit.symbols, check)
-		if err != nil {
-			return fmt.Errorf("biscuit: verification failed: %s", err)
-		}
-		c := ch.convert(v.symbols)
-
-		successful := false
-		for _, query := range c.Queries {
-			res := v.world.QueryRule(query, v.symbols)
-			if len(*res) != 0 {
-				successful = true
-				break
+	for i, block := range blocks {
+		for j, check := range block.checks {
+			if err := ctx.Err(); err != nil {
+				return result, err
 			}
-		}
-		if !successful {
-			debug := datalog.SymbolDebugger{
-				SymbolTable: v.symbols,
+			ch, err := fromDatalogCheck(v.biscuit.blockSymbols(block), check)
+			if err != nil {
+				return result, fmt.Errorf("biscuit: verification failed: %s", err)
+			}
+			c := ch.convert(v.symbols)
+
+			success := v.checkMatches(c, v.blocksOrigins[i], uint64(i))
+			result.Checks = append(result.Checks, CheckResult{BlockID: i, Index: j, Check: *ch, Success: success})
+			if !success {
+				if i == 0 {
+					errs = append(errs, fmt.Errorf("failed to verify block 0 check #%d: %s", j, debug.Check(c)))
+				} else {
+					errs = append(errs, fmt.Errorf("failed to verify block #%d check #%d: %s", i, j, debug.Check(c)))
+				}
 			}
-			errs = append(errs, fmt.Errorf("failed to verify block 0 check #%d: %s", i, debug.Check(c)))
 		}
 	}
 
 	policyMatched := false
 	policyResult := ErrPolicyDenied
-	for _, policy := range v.policies {
-		if policyMatched {
-			break
+	for k, policy := range v.policies {
+		if err := ctx.Err(); err != nil {
+			return result, err
 		}
-		for _, query := range policy.Queries {
-			res := v.world.QueryRule(query.convert(v.symbols), v.symbols)
-			if len(*res) != 0 {
-				switch policy.Kind {
-				case PolicyKindAllow:
-					policyResult = nil
-					policyMatched = true
-				case PolicyKindDeny:
-					policyResult = ErrPolicyDenied
-					policyMatched = true
-				}
-				break
+		queries := make([]datalog.Rule, len(policy.Queries))
+		for i, query := range policy.Queries {
+			queries[i] = query.convert(v.symbols)
+		}
+
+		if v.matches(queries, defaultOrigins, datalog.AuthorizerOrigin) {
+			result.PolicyIndex = k
+			result.PolicyKind = policy.Kind
+			switch policy.Kind {
+			case PolicyKindAllow:
+				policyResult = nil
+				policyMatched = true
+			case PolicyKindDeny:
+				policyResult = ErrPolicyDenied
+				policyMatched = true
 			}
+			break
 		}
 	}
 
-	// remove the rules from the vrifier and authority blocks
-	// so they are not affected by facts created by later blocks
-	v.world.ResetRules()
+	if len(errs) > 0 {
+		errMsg := make([]string, len(errs))
+		for i, e := range errs {
+			errMsg[i] = e.Error()
+		}
+
+		if !policyMatched {
+			result.PolicyKind = v.defaultPolicy
+		}
+		return result, fmt.Errorf("biscuit: verification failed: %s", strings.Join(errMsg, ", "))
+	}
 
-	for i, block := range v.biscuit.blocks {
-		block_world := v.world.Clone()
+	if policyMatched {
+		return result, policyResult
+	}
+	result.PolicyKind = v.defaultPolicy
+	if v.defaultPolicy == PolicyKindAllow {
+		return result, nil
+	}
+	return result, ErrNoMatchingPolicy
+}
+
+// loadBlocks adds the facts and rules of the token's blocks to the world,
+// and returns the origins trusted by each block.
+func (v *authorizer) loadBlocks(blocks []*Block) ([]datalog.Origin, error) {
+	// if we load facts from the verifier before
+	// the token's fact and rules, we might get inconsistent symbols
+	// token ements should first be converted to builder elements
+	// with the token's symbol table, then converted back
+	// with the verifier's symbol table
+	blocksOrigins := make([]datalog.Origin, len(blocks))
+	for i, block := range blocks {
+		blockID := uint64(i)
+		blocksOrigins[i] = v.trustedOrigins(block.scopes, defaultOrigins, blockID)
 
 		for _, fact := range *block.facts {
-			f, err := fromDatalogFact(v.biscuit.symbols, fact)
+			f, err := fromDatalogFact(v.biscuit.blockSymbols(block), fact)
 			if err != nil {
-				return fmt.Errorf("biscuit: verification failed: %s", err)
+				return nil, fmt.Errorf("biscuit: verification failed: %s", err)
 			}
-			block_world.AddFact(f.convert(v.symbols))
+			v.world.AddFactWithOrigin(f.convert(v.symbols), datalog.NewOrigin(blockID))
 		}
 
 		for _, rule := range block.rules {
-			r, err := fromDatalogRule(v.biscuit.symbols, rule)
+			r, err := fromDatalogRule(v.biscuit.blockSymbols(block), rule)
 			if err != nil {
-				return fmt.Errorf("biscuit: verification failed: %s", err)
+				return nil, fmt.Errorf("biscuit: verification failed: %s", err)
 			}
-			block_world.AddRule(r.convert(v.symbols))
-		}
-
-		if err := block_world.Run(v.symbols); err != nil {
-			return err
+			dlRule := r.convert(v.symbols)
+			v.world.AddRuleWithOrigin(dlRule, blockID, v.trustedOrigins(dlRule.Scope, blocksOrigins[i], blockID))
 		}
+	}
+	return blocksOrigins, nil
+}
 
-		for j, check := range block.checks {
-			ch, err := fromDatalogCheck(v.biscuit.symbols, check)
-			if err != nil {
-				return fmt.Errorf("biscuit: verification failed: %s", err)
-			}
-			c := ch.convert(v.symbols)
+// loadSourceFacts adds the facts from factSource for the predicates referenced
+// by the rules in the world, the checks of the authorizer and the blocks, the
+// policies and the query, in that order, which have no facts yet.
+func (v *authorizer) loadSourceFacts(blocks []*Block, query *Rule) {
+	if v.factSource == nil {
+		return
+	}
 
-			successful := false
-			for _, query := range c.Queries {
-				res := block_world.QueryRule(query, v.symbols)
+	present := make(map[string]struct{})
+	for _, f := range *v.world.Facts() {
+		present[v.symbols.Str(f.Name)] = struct{}{}
+	}
 
-				if len(*res) != 0 {
-					successful = true
-					break
-				}
-			}
-			if !successful {
-				debug := datalog.SymbolDebugger{
-					SymbolTable: v.symbols,
-				}
-				errs = append(errs, fmt.Errorf("failed to verify block #%d check #%d: %s", i+1, j, debug.Check(c)))
+	var names []string
+	reference := func(name string) {
+		if _, ok := present[name]; ok {
+			return
+		}
+		if _, ok := v.sourcedPredicates[name]; ok {
+			return
+		}
+		v.sourcedPredicates[name] = struct{}{}
+		names = append(names, name)
+	}
+	referenceRules := func(rules []Rule) {
+		for _, r := range rules {
+			for _, p := range r.Body {
+				reference(p.Name)
 			}
 		}
-
-		block_world.ResetRules()
-		v.block_worlds = append(v.block_worlds, block_world)
 	}
 
-	if len(errs) > 0 {
-		errMsg := make([]string, len(errs))
-		for i, e := range errs {
-			errMsg[i] = e.Error()
+	for _, r := range v.world.Rules() {
+		for _, p := range r.Body {
+			reference(v.symbols.Str(p.Name))
 		}
-
-		return fmt.Errorf("biscuit: verification failed: %s", strings.Join(errMsg, ", "))
+	}
+	for _, c := range v.checks {
+		referenceRules(c.Queries)
+	}
+	for _, block := range blocks {
+		symbols := v.biscuit.blockSymbols(block)
+		for _, c := range block.checks {
+			for _, q := range c.Queries {
+				for _, p := range q.Body {
+					reference(symbols.Str(p.Name))
+				}
+			}
+		}
+	}
+	for _, p := range v.policies {
+		referenceRules(p.Queries)
+	}
+	if query != nil {
+		referenceRules([]Rule{*query})
 	}
 
-	v.baseWorld = v.world.Clone()
-	v.baseSymbols = v.symbols.Clone()
-
-	if policyMatched {
-		return policyResult
-	} else {
-		return ErrNoMatchingPolicy
+	for _, name := range names {
+		for _, f := range v.factSource(name) {
+			v.AddFact(f)
+		}
 	}
 }
 
 func (v *authorizer) Query(rule Rule) (FactSet, error) {
+	v.loadSourceFacts(nil, &rule)
 	if err := v.world.Run(v.symbols); err != nil {
 		return nil, err
 	}
 	v.dirty = true
 
-	facts := v.world.QueryRule(rule.convert(v.symbols), v.symbols)
+	dlRule := rule.convert(v.symbols)
+	facts := v.world.QueryRuleTrusting(dlRule, v.authorizerTrustedOrigins(dlRule.Scope), v.symbols)
 
 	result := make([]Fact, 0, len(*facts))
 	for _, fact := range *facts {
@@ -310,14 +573,22 @@ func (v *authorizer) PrintWorld() string {
 		SymbolTable: v.symbols,
 	}
 
-	return debug.World(v.world)
+	return debug.World(v.world.Trusting(v.authorizerTrustedOrigins(nil)))
 }
 
+// Reset removes the facts, rules, checks and policies added to the
+// authorizer, and the facts derived by Authorize, so it can be reused
+// for another request. The token's signatures were verified when the
+// authorizer was created and are not verified again: its blocks are
+// loaded anew by the next call to Authorize.
 func (v *authorizer) Reset() {
 	v.world = v.baseWorld.Clone()
 	v.symbols = v.baseSymbols.Clone()
 	v.checks = []Check{}
 	v.policies = []Policy{}
+	v.sourcedPredicates = make(map[string]struct{})
+	v.blocksOrigins = nil
+	v.loaded = false
 	v.dirty = false
 }
 
@@ -328,7 +599,7 @@ func (v *authorizer) LoadPolicies(authorizerPolicies []byte) error {
 	}
 
 	switch pbPolicies.GetVersion() {
-	case 3:
+	case 3, 4, 5, 6:
 		return v.loadPoliciesV2(pbPolicies)
 	default:
 		return fmt.Errorf("verifier: unsupported policies version %d", pbPolicies.GetVersion())
@@ -345,20 +616,20 @@ func (v *authorizer) loadPoliciesV2(pbPolicies *pb.AuthorizerPolicies) error {
 		if err != nil {
 			return fmt.Errorf("verifier: load policies v1: failed to convert datalog fact: %w", err)
 		}
-		v.world.AddFact(*fact)
+		v.world.AddFactWithOrigin(*fact, datalog.NewOrigin(datalog.AuthorizerOrigin))
 	}
 
 	for _, pbRule := range pbPolicies.Rules {
-		rule, err := protoRuleToTokenRuleV2(pbRule)
+		rule, err := protoRuleToTokenRuleV2(pbRule, nil)
 		if err != nil {
 			return fmt.Errorf("verifier: load policies v1: failed to convert datalog rule: %w", err)
 		}
-		v.world.AddRule(*rule)
+		v.addRule(*rule)
 	}
 
 	v.checks = make([]Check, len(pbPolicies.Checks))
 	for i, pbCheck := range pbPolicies.Checks {
-		dlCheck, err := protoCheckToTokenCheckV2(pbCheck)
+		dlCheck, err := protoCheckToTokenCheckV2(pbCheck, nil)
 		if err != nil {
 			return fmt.Errorf("verifier: load policies v1: failed to convert datalog check: %w", err)
 		}
@@ -383,7 +654,7 @@ func (v *authorizer) loadPoliciesV2(pbPolicies *pb.AuthorizerPolicies) error {
 
 		policy.Queries = make([]Rule, len(pbPolicy.Queries))
 		for j, pbRule := range pbPolicy.Queries {
-			dlRule, err := protoRuleToTokenRuleV2(pbRule)
+			dlRule, err := protoRuleToTokenRuleV2(pbRule, nil)
 			if err != nil {
 				return fmt.Errorf("verifier: load policies v1: failed to convert datalog policy rule: %w", err)
 			}
@@ -416,22 +687,26 @@ func (v *authorizer) SerializePolicies() ([]byte, error) {
 
 	protoRules := make([]*pb.RuleV2, len(v.world.Rules()))
 	for i, rule := range v.world.Rules() {
-		protoRule, err := tokenRuleToProtoRuleV2(rule)
+		protoRule, err := tokenRuleToProtoRuleV2(rule, nil)
 		if err != nil {
 			return nil, fmt.Errorf("verifier: failed to convert rule: %w", err)
 		}
 		protoRules[i] = protoRule
 	}
 
+	checks := make([]datalog.Check, len(v.checks))
 	protoChecks := make([]*pb.CheckV2, len(v.checks))
 	for i, check := range v.checks {
-		protoCheck, err := tokenCheckToProtoCheckV2(check.convert(v.symbols))
+		checks[i] = check.convert(v.symbols)
+		protoCheck, err := tokenCheckToProtoCheckV2(checks[i], nil)
 		if err != nil {
 			return nil, fmt.Errorf("verifier: failed to convert check: %w", err)
 		}
 		protoChecks[i] = protoCheck
 	}
 
+	// the policies are versioned like rules
+	rules := append([]datalog.Rule{}, v.world.Rules()...)
 	protoPolicies := make([]*pb.Policy, len(v.policies))
 	for i, policy := range v.policies {
 		protoPolicy := &pb.Policy{}
@@ -448,7 +723,9 @@ func (v *authorizer) SerializePolicies() ([]byte, error) {
 
 		protoPolicy.Queries = make([]*pb.RuleV2, len(policy.Queries))
 		for j, rule := range policy.Queries {
-			protoRule, err := tokenRuleToProtoRuleV2(rule.convert(v.symbols))
+			dlRule := rule.convert(v.symbols)
+			rules = append(rules, dlRule)
+			protoRule, err := tokenRuleToProtoRuleV2(dlRule, nil)
 			if err != nil {
 				return nil, fmt.Errorf("verifier: failed to convert policy rule: %w", err)
 			}
@@ -458,7 +735,9 @@ func (v *authorizer) SerializePolicies() ([]byte, error) {
 		protoPolicies[i] = protoPolicy
 	}
 
-	version := MaxSchemaVersion
+	// the lowest version able to hold the snapshot, so that it can be
+	// loaded by the readers not supporting the features it does not use
+	version := blockSchemaVersion(v.world.Facts(), rules, checks)
 	return proto.Marshal(&pb.AuthorizerPolicies{
 		Symbols:  *v.symbols.Clone(),
 		Version:  proto.Uint32(version),
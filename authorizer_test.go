@@ -1,11 +1,17 @@
 package biscuit
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/rand"
 	"testing"
+	"time"
+
+	"github.com/biscuit-auth/biscuit-go/v2/datalog"
+	"github.com/biscuit-auth/biscuit-go/v2/pb"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestVerifierDefaultPolicy(t *testing.T) {
@@ -37,6 +43,432 @@ func TestVerifierDefaultPolicy(t *testing.T) {
 	require.NoError(t, v.Authorize())
 }
 
+func TestAuthorizerWithDefaultPolicy(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	b, err := NewBuilder(privateRoot).Build()
+	require.NoError(t, err)
+
+	// the policy does not match, as there is no resource fact
+	policy := Policy{Kind: PolicyKindAllow, Queries: []Rule{{
+		Head: Predicate{Name: "allow"},
+		Body: []Predicate{{Name: "resource", IDs: []Term{Variable("r")}}},
+	}}}
+
+	v, err := b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddPolicy(policy)
+	require.Equal(t, ErrNoMatchingPolicy, v.Authorize())
+
+	v, err = b.Authorizer(publicRoot, WithDefaultPolicy(PolicyKindDeny))
+	require.NoError(t, err)
+	v.AddPolicy(policy)
+	require.Equal(t, ErrNoMatchingPolicy, v.Authorize())
+
+	v, err = b.Authorizer(publicRoot, WithDefaultPolicy(PolicyKindAllow))
+	require.NoError(t, err)
+	v.AddPolicy(policy)
+	require.NoError(t, v.Authorize())
+
+	// matching policies and failing checks still apply
+	v, err = b.Authorizer(publicRoot, WithDefaultPolicy(PolicyKindAllow))
+	require.NoError(t, err)
+	v.AddPolicy(DefaultDenyPolicy)
+	require.Equal(t, ErrPolicyDenied, v.Authorize())
+
+	v, err = b.Authorizer(publicRoot, WithDefaultPolicy(PolicyKindAllow))
+	require.NoError(t, err)
+	v.AddCheck(Check{Queries: []Rule{{
+		Head: Predicate{Name: "query"},
+		Body: []Predicate{{Name: "resource", IDs: []Term{Variable("r")}}},
+	}}})
+	require.Error(t, v.Authorize())
+}
+
+func TestAuthorizerReset(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate{Name: "right", IDs: []Term{String("/a")}}}))
+	require.NoError(t, builder.AddAuthorityCheck(Check{Queries: []Rule{{
+		Head: Predicate{Name: "query"},
+		Body: []Predicate{
+			{Name: "resource", IDs: []Term{Variable("r")}},
+			{Name: "right", IDs: []Term{Variable("r")}},
+		},
+	}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	v, err := b.Authorizer(publicRoot)
+	require.NoError(t, err)
+
+	v.AddFact(Fact{Predicate{Name: "resource", IDs: []Term{String("/a")}}})
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+
+	// the facts of the previous request must not leak into the next one
+	v.Reset()
+	v.AddFact(Fact{Predicate{Name: "resource", IDs: []Term{String("/b")}}})
+	v.AddPolicy(DefaultAllowPolicy)
+	require.Error(t, v.Authorize())
+	facts, err := v.Query(Rule{
+		Head: Predicate{Name: "resource", IDs: []Term{Variable("r")}},
+		Body: []Predicate{{Name: "resource", IDs: []Term{Variable("r")}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, FactSet{{Predicate{Name: "resource", IDs: []Term{String("/b")}}}}, facts)
+
+	v.Reset()
+	v.AddFact(Fact{Predicate{Name: "resource", IDs: []Term{String("/a")}}})
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+}
+
+func TestAuthorizeTwice(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate{Name: "right", IDs: []Term{String("/a")}}}))
+	require.NoError(t, builder.AddAuthorityRule(Rule{
+		Head: Predicate{Name: "allowed", IDs: []Term{Variable("r")}},
+		Body: []Predicate{
+			{Name: "resource", IDs: []Term{Variable("r")}},
+			{Name: "right", IDs: []Term{Variable("r")}},
+		},
+	}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	block := b.CreateBlock()
+	require.NoError(t, block.AddRule(Rule{
+		Head: Predicate{Name: "read", IDs: []Term{Variable("r")}},
+		Body: []Predicate{{Name: "allowed", IDs: []Term{Variable("r")}}},
+	}))
+	b, err = b.Append(rng, block.Build())
+	require.NoError(t, err)
+
+	v, err := b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddFact(Fact{Predicate{Name: "resource", IDs: []Term{String("/a")}}})
+	v.AddPolicy(Policy{Kind: PolicyKindAllow, Queries: []Rule{{
+		Head: Predicate{Name: "query"},
+		Body: []Predicate{{Name: "allowed", IDs: []Term{String("/a")}}},
+	}}})
+
+	a := v.(*authorizer)
+	require.NoError(t, v.Authorize())
+	rules := len(a.world.Rules())
+	facts := len(*a.world.Facts())
+	require.Equal(t, 2, rules)
+
+	require.NoError(t, v.Authorize())
+	_, err = v.AuthorizeWithResult()
+	require.NoError(t, err)
+	require.NoError(t, v.AuthorizeContext(context.Background()))
+	require.Equal(t, rules, len(a.world.Rules()))
+	require.Equal(t, facts, len(*a.world.Facts()))
+
+	// the blocks are loaded again after a reset
+	v.Reset()
+	v.AddFact(Fact{Predicate{Name: "resource", IDs: []Term{String("/a")}}})
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+	require.Equal(t, rules, len(a.world.Rules()))
+}
+
+func TestWithFactSource(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate{Name: "right", IDs: []Term{String("/a")}}}))
+	require.NoError(t, builder.AddAuthorityCheck(Check{Queries: []Rule{{
+		Head: Predicate{Name: "query"},
+		Body: []Predicate{
+			{Name: "resource", IDs: []Term{Variable("r")}},
+			{Name: "right", IDs: []Term{Variable("r")}},
+		},
+	}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	var requested []string
+	source := func(name string) []Fact {
+		requested = append(requested, name)
+		switch name {
+		case "resource":
+			return []Fact{{Predicate{Name: "resource", IDs: []Term{String("/a")}}}}
+		case "ambient":
+			return []Fact{{Predicate{Name: "ambient", IDs: []Term{String("unused")}}}}
+		}
+		return nil
+	}
+
+	v, err := b.Authorizer(publicRoot, WithFactSource(source))
+	require.NoError(t, err)
+	v.AddPolicy(Policy{Kind: PolicyKindAllow, Queries: []Rule{{
+		Head: Predicate{Name: "allow"},
+		Body: []Predicate{{Name: "operation", IDs: []Term{String("read")}}},
+	}}})
+	v.AddFact(Fact{Predicate{Name: "operation", IDs: []Term{String("read")}}})
+	require.NoError(t, v.Authorize())
+	// right and operation already have facts, ambient is never referenced
+	require.Equal(t, []string{"resource"}, requested)
+
+	// the source is consulted once per predicate
+	facts, err := v.Query(Rule{
+		Head: Predicate{Name: "resource", IDs: []Term{Variable("r")}},
+		Body: []Predicate{{Name: "resource", IDs: []Term{Variable("r")}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, FactSet{{Predicate{Name: "resource", IDs: []Term{String("/a")}}}}, facts)
+	require.Equal(t, []string{"resource"}, requested)
+
+	// the facts are requested again after a reset
+	v.Reset()
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+	require.Equal(t, []string{"resource", "resource"}, requested)
+
+	// without the lazily provided facts, the check fails
+	v, err = b.Authorizer(publicRoot, WithFactSource(func(string) []Fact { return nil }))
+	require.NoError(t, err)
+	v.AddPolicy(DefaultAllowPolicy)
+	require.Error(t, v.Authorize())
+}
+
+func TestAuthorizeWithResult(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	authorityCheck := Check{Queries: []Rule{{
+		Head:        Predicate{Name: "query", IDs: []Term{}},
+		Body:        []Predicate{{Name: "resource", IDs: []Term{Variable("r")}}},
+		Expressions: []Expression{},
+	}}}
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityCheck(authorityCheck))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	operationPolicy := func(kind PolicyKind, op string) Policy {
+		return Policy{Kind: kind, Queries: []Rule{{
+			Head: Predicate{Name: "policy"},
+			Body: []Predicate{{Name: "operation", IDs: []Term{String(op)}}},
+		}}}
+	}
+	authorizerCheck := Check{Queries: []Rule{{
+		Head: Predicate{Name: "query"},
+		Body: []Predicate{{Name: "operation", IDs: []Term{Variable("op")}}},
+	}}}
+
+	v, err := b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddFact(Fact{Predicate{Name: "resource", IDs: []Term{String("/a")}}})
+	v.AddFact(Fact{Predicate{Name: "operation", IDs: []Term{String("write")}}})
+	v.AddCheck(authorizerCheck)
+	v.AddPolicy(operationPolicy(PolicyKindAllow, "read"))
+	v.AddPolicy(operationPolicy(PolicyKindAllow, "write"))
+	v.AddPolicy(DefaultDenyPolicy)
+	res, err := v.AuthorizeWithResult()
+	require.NoError(t, err)
+	require.Equal(t, AuthorizationResult{
+		PolicyIndex: 1,
+		PolicyKind:  PolicyKindAllow,
+		Checks: []CheckResult{
+			{BlockID: -1, Index: 0, Check: authorizerCheck, Success: true},
+			{BlockID: 0, Index: 0, Check: authorityCheck, Success: true},
+		},
+	}, res)
+
+	// the deny policy matches
+	v.Reset()
+	v.AddFact(Fact{Predicate{Name: "operation", IDs: []Term{String("delete")}}})
+	v.AddPolicy(operationPolicy(PolicyKindAllow, "read"))
+	v.AddPolicy(operationPolicy(PolicyKindAllow, "write"))
+	v.AddPolicy(DefaultDenyPolicy)
+	res, err = v.AuthorizeWithResult()
+	require.Error(t, err)
+	require.Equal(t, 2, res.PolicyIndex)
+	require.EqualValues(t, PolicyKindDeny, res.PolicyKind)
+	require.Equal(t, []CheckResult{{BlockID: 0, Index: 0, Check: authorityCheck, Success: false}}, res.Checks)
+
+	// no policy matches
+	v.Reset()
+	v.AddFact(Fact{Predicate{Name: "resource", IDs: []Term{String("/a")}}})
+	v.AddPolicy(operationPolicy(PolicyKindAllow, "read"))
+	res, err = v.AuthorizeWithResult()
+	require.Equal(t, ErrNoMatchingPolicy, err)
+	require.Equal(t, -1, res.PolicyIndex)
+	require.EqualValues(t, PolicyKindDeny, res.PolicyKind)
+}
+
+func TestAuthorizeContext(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate{Name: "right", IDs: []Term{String("/a")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	v, err := b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.AuthorizeContext(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	v, err = b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddPolicy(DefaultAllowPolicy)
+	require.ErrorIs(t, v.AuthorizeContext(ctx), context.Canceled)
+
+	// the deadline stops a world run which would exceed it
+	v, err = b.Authorizer(publicRoot, WithWorldOptions(
+		datalog.WithMaxDuration(time.Minute),
+		datalog.WithMaxFacts(1<<30),
+		datalog.WithMaxIterations(10000),
+	))
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		v.AddFact(Fact{Predicate{Name: "edge", IDs: []Term{Integer(int64(i)), Integer(int64(i + 1))}}})
+	}
+	v.AddRule(Rule{
+		Head: Predicate{Name: "path", IDs: []Term{Variable("a"), Variable("b")}},
+		Body: []Predicate{{Name: "edge", IDs: []Term{Variable("a"), Variable("b")}}},
+	})
+	v.AddRule(Rule{
+		Head: Predicate{Name: "path", IDs: []Term{Variable("a"), Variable("c")}},
+		Body: []Predicate{
+			{Name: "path", IDs: []Term{Variable("a"), Variable("b")}},
+			{Name: "edge", IDs: []Term{Variable("b"), Variable("c")}},
+		},
+	})
+	v.AddPolicy(DefaultAllowPolicy)
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	require.ErrorIs(t, v.AuthorizeContext(ctx), context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestTimeLimit(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	b, err := NewBuilder(privateRoot).Build()
+	require.NoError(t, err)
+
+	limit := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	block := b.CreateBlock()
+	require.NoError(t, block.AddTimeLimitCheck(limit))
+	b, err = b.Append(rng, block.Build())
+	require.NoError(t, err)
+	checks, err := b.CheckStrings()
+	require.NoError(t, err)
+	require.Equal(t, []string{"check if time($time), $time <= 2030-01-01T00:00:00Z"}, checks[1])
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = Unmarshal(serialized)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		desc  string
+		now   *time.Time
+		valid bool
+	}{
+		{desc: "before the limit", now: timePtr(limit.Add(-time.Hour)), valid: true},
+		{desc: "at the limit", now: timePtr(limit), valid: true},
+		{desc: "after the limit", now: timePtr(limit.Add(time.Second)), valid: false},
+		{desc: "no current time", valid: false},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			v, err := b.Authorizer(publicRoot)
+			require.NoError(t, err)
+			if tc.now != nil {
+				v.AddCurrentTime(*tc.now)
+			}
+			v.AddPolicy(DefaultAllowPolicy)
+			if tc.valid {
+				require.NoError(t, v.Authorize())
+			} else {
+				require.Error(t, v.Authorize())
+			}
+		})
+	}
+
+	// time is a default symbol
+	v, err := b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	symbolCount := len(*v.(*authorizer).symbols)
+	v.AddCurrentTime(limit)
+	require.Len(t, *v.(*authorizer).symbols, symbolCount)
+}
+
+func TestProhibitionCheck(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	b, err := NewBuilder(privateRoot).Build()
+	require.NoError(t, err)
+
+	block := b.CreateBlock()
+	require.NoError(t, block.AddProhibitionCheck("operation", String("delete")))
+	require.NoError(t, block.AddProhibitionCheck("sudo", Variable("user")))
+	b, err = b.Append(rng, block.Build())
+	require.NoError(t, err)
+	checks, err := b.CheckStrings()
+	require.NoError(t, err)
+	require.Equal(t, []string{`reject if operation("delete")`, `reject if sudo($user)`}, checks[1])
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = Unmarshal(serialized)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		desc  string
+		facts []Fact
+		valid bool
+	}{
+		{desc: "no fact", valid: true},
+		{desc: "other operation", facts: []Fact{
+			{Predicate: Predicate{Name: "operation", IDs: []Term{String("read")}}},
+		}, valid: true},
+		{desc: "prohibited operation", facts: []Fact{
+			{Predicate: Predicate{Name: "operation", IDs: []Term{String("read")}}},
+			{Predicate: Predicate{Name: "operation", IDs: []Term{String("delete")}}},
+		}, valid: false},
+		{desc: "prohibited predicate", facts: []Fact{
+			{Predicate: Predicate{Name: "sudo", IDs: []Term{String("alice")}}},
+		}, valid: false},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			v, err := b.Authorizer(publicRoot)
+			require.NoError(t, err)
+			for _, f := range tc.facts {
+				v.AddFact(f)
+			}
+			v.AddPolicy(DefaultAllowPolicy)
+			if tc.valid {
+				require.NoError(t, v.Authorize())
+			} else {
+				require.ErrorContains(t, v.Authorize(), "failed to verify block #1 check")
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
 func TestVerifierPolicies(t *testing.T) {
 	rng := rand.Reader
 	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
@@ -162,4 +594,27 @@ func TestVerifierSerializeLoad(t *testing.T) {
 	require.Equal(t, v1.(*authorizer).world.Rules(), v2.(*authorizer).world.Rules())
 	require.Equal(t, v1.(*authorizer).checks, v2.(*authorizer).checks)
 	require.Equal(t, v1.(*authorizer).policies, v2.(*authorizer).policies)
+
+	// snapshots use the lowest version able to hold them
+	version := func(snapshot []byte) uint32 {
+		pbPolicies := &pb.AuthorizerPolicies{}
+		require.NoError(t, proto.Unmarshal(snapshot, pbPolicies))
+		return pbPolicies.GetVersion()
+	}
+	require.Equal(t, MinSchemaVersion, version(s))
+
+	v1.AddPolicy(Policy{Kind: PolicyKindDeny, Queries: []Rule{{
+		Head:        Predicate{Name: "deny"},
+		Body:        []Predicate{{Name: "operation", IDs: []Term{Variable("op")}}},
+		Expressions: []Expression{{Value{Variable("op")}, Value{String("write")}, BinaryNotEqual}},
+	}}})
+	s, err = v1.SerializePolicies()
+	require.NoError(t, err)
+	require.Equal(t, notEqualSchemaVersion, version(s))
+
+	v1.AddCheck(Check{Kind: CheckKindReject, Queries: []Rule{rule1}})
+	s, err = v1.SerializePolicies()
+	require.NoError(t, err)
+	require.Equal(t, rejectSchemaVersion, version(s))
+	require.NoError(t, v2.LoadPolicies(s))
 }
@@ -3,13 +3,16 @@ package biscuit
 import (
 	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 
-	//"crypto/sha256"
 	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 
 	"github.com/biscuit-auth/biscuit-go/v2/datalog"
 	"github.com/biscuit-auth/biscuit-go/v2/pb"
@@ -22,10 +25,21 @@ import (
 // It contains multiple `Block` elements, the associated symbol table,
 // and a serialized version of this data
 type Biscuit struct {
-	authority *Block
-	blocks    []*Block
-	symbols   *datalog.SymbolTable
-	container *pb.Biscuit
+	authority  *Block
+	blocks     []*Block
+	symbols    *datalog.SymbolTable
+	publicKeys publicKeyTable
+	container  *pb.Biscuit
+
+	// trusted is set by UnmarshalTrusted, authorizers then skip
+	// the verification of the signatures.
+	trusted bool
+
+	// previousSecrets holds the next secret of each block before the last
+	// one, by block index, when it is known: blocks appended in this process
+	// keep the secret of the block they were appended to. They are used by
+	// WithoutLastBlock and never serialized.
+	previousSecrets [][]byte
 }
 
 var (
@@ -52,13 +66,31 @@ var (
 	ErrInvalidSignatureSize = errors.New("biscuit: invalid signature size")
 
 	ErrInvalidKeySize = errors.New("biscuit: invalid key size")
+	// ErrKeyPairMismatch is returned when the private key given to
+	// WithNextKeyPair is not the one of its public key
+	ErrKeyPairMismatch = errors.New("biscuit: private key does not match the public key")
 
 	UnsupportedAlgorithm = errors.New("biscuit: unsupported signature algorithm")
+
+	// ErrInvalidContainer is returned when a token misses its authority block or its proof
+	ErrInvalidContainer = errors.New("biscuit: invalid container")
+	// ErrUntrustedExternalKey is returned when a third party block is signed
+	// by a key which is not in the list given to WithTrustedExternalKeys
+	ErrUntrustedExternalKey = errors.New("biscuit: untrusted external key")
+	// ErrDuplicateSymbol is returned when serializing a block whose symbol table
+	// contains the same symbol several times
+	ErrDuplicateSymbol = errors.New("biscuit: duplicate symbol in block")
+	// ErrPreviousSecretUnavailable is returned by WithoutLastBlock when the token
+	// does not know the next secret of the block before its last one
+	ErrPreviousSecretUnavailable = errors.New("biscuit: the secret of the previous block is not available")
 )
 
 type biscuitOptions struct {
 	rng       io.Reader
 	rootKeyID *uint32
+
+	nextPublicKey  ed25519.PublicKey
+	nextPrivateKey ed25519.PrivateKey
 }
 
 type biscuitOption interface {
@@ -75,6 +107,7 @@ func newBiscuit(root ed25519.PrivateKey, baseSymbols *datalog.SymbolTable, autho
 		}
 	}
 
+	authority = authority.withDefaults()
 	symbols := baseSymbols.Clone()
 
 	if !symbols.IsDisjoint(authority.symbols) {
@@ -83,9 +116,13 @@ func newBiscuit(root ed25519.PrivateKey, baseSymbols *datalog.SymbolTable, autho
 
 	symbols.Extend(authority.symbols)
 
-	nextPublicKey, nextPrivateKey, _ := ed25519.GenerateKey(options.rng)
+	nextPublicKey, nextPrivateKey := options.nextPublicKey, options.nextPrivateKey
+	if nextPrivateKey == nil {
+		nextPublicKey, nextPrivateKey, _ = ed25519.GenerateKey(options.rng)
+	}
 
-	protoAuthority, err := tokenBlockToProtoBlock(authority)
+	publicKeys := publicKeyTable{}
+	protoAuthority, err := tokenBlockToProtoBlock(authority, &publicKeys)
 	if err != nil {
 		return nil, err
 	}
@@ -125,12 +162,16 @@ func newBiscuit(root ed25519.PrivateKey, baseSymbols *datalog.SymbolTable, autho
 	}
 
 	return &Biscuit{
-		authority: authority,
-		symbols:   symbols,
-		container: container,
+		authority:  authority,
+		symbols:    symbols,
+		publicKeys: publicKeys,
+		container:  container,
 	}, nil
 }
 
+// New creates a biscuit with the given authority block. The rng is used to
+// generate the key pair of the next block, and defaults to crypto/rand.Reader
+// when nil.
 func New(rng io.Reader, root ed25519.PrivateKey, baseSymbols *datalog.SymbolTable, authority *Block) (*Biscuit, error) {
 	var opts []biscuitOption
 	if rng != nil {
@@ -139,11 +180,122 @@ func New(rng io.Reader, root ed25519.PrivateKey, baseSymbols *datalog.SymbolTabl
 	return newBiscuit(root, baseSymbols, authority, opts...)
 }
 
+// NewDeterministic creates a biscuit like New, but derives all the generated
+// keys from the seed, so the same inputs always produce a byte-identical
+// token. It is meant for golden files and tests: the seed must be kept
+// secret for tokens used in production, since it gives access to the
+// private key of the next block.
+func NewDeterministic(seed []byte, root ed25519.PrivateKey, baseSymbols *datalog.SymbolTable, authority *Block) (*Biscuit, error) {
+	return New(newDeterministicReader(seed), root, baseSymbols, authority)
+}
+
+// deterministicReader is an io.Reader producing the stream
+// SHA256(seed || counter) for increasing 64 bits little endian counters.
+type deterministicReader struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func newDeterministicReader(seed []byte) *deterministicReader {
+	return &deterministicReader{seed: append([]byte{}, seed...)}
+}
+
+func (r *deterministicReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			counter := make([]byte, 8)
+			binary.LittleEndian.PutUint64(counter, r.counter)
+			r.counter++
+			block := sha256.Sum256(append(append([]byte{}, r.seed...), counter...))
+			r.buf = block[:]
+		}
+		copied := copy(p[n:], r.buf)
+		r.buf = r.buf[copied:]
+		n += copied
+	}
+	return n, nil
+}
+
 func (b *Biscuit) CreateBlock() BlockBuilder {
 	return NewBlockBuilder(b.symbols.Clone())
 }
 
 func (b *Biscuit) Append(rng io.Reader, block *Block) (*Biscuit, error) {
+	privateKey, err := b.nextPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	block = block.withDefaults()
+	if !b.symbols.IsDisjoint(block.symbols) {
+		return nil, ErrSymbolTableOverlap
+	}
+
+	symbols := b.symbols.Clone()
+	symbols.Extend(block.symbols)
+
+	// serialize the new block
+	publicKeys := b.publicKeys.Clone()
+	protoBlock, err := tokenBlockToProtoBlock(block, &publicKeys)
+	if err != nil {
+		return nil, err
+	}
+	marshalledBlock, err := proto.Marshal(protoBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.appendBlock(rng, privateKey, block, marshalledBlock, nil, symbols, publicKeys), nil
+}
+
+// AppendAll appends the blocks in order, as successive calls to Append would,
+// but copies the token only once. Each block must be disjoint from the symbols
+// of the token and of the blocks before it, so the blocks are usually created from
+// a BlockBuilder whose symbols include the ones of the previous blocks.
+func (b *Biscuit) AppendAll(rng io.Reader, blocks ...*Block) (*Biscuit, error) {
+	if len(blocks) == 0 {
+		return nil, errors.New("biscuit: append failed, no block to append")
+	}
+
+	privateKey, err := b.nextPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := b.symbols.Clone()
+	publicKeys := b.publicKeys.Clone()
+	appended := make([]*Block, len(blocks))
+	signedBlocks := make([]*pb.SignedBlock, len(blocks))
+	privateKeys := make([]ed25519.PrivateKey, len(blocks))
+	for i, block := range blocks {
+		block = block.withDefaults()
+		if !symbols.IsDisjoint(block.symbols) {
+			return nil, fmt.Errorf("%w: block %d", ErrSymbolTableOverlap, i)
+		}
+		symbols.Extend(block.symbols)
+
+		protoBlock, err := tokenBlockToProtoBlock(block, &publicKeys)
+		if err != nil {
+			return nil, err
+		}
+		marshalledBlock, err := proto.Marshal(protoBlock)
+		if err != nil {
+			return nil, err
+		}
+
+		appended[i] = block
+		signedBlocks[i], privateKeys[i] = signBlock(rng, privateKey, marshalledBlock, nil)
+		privateKey = privateKeys[i]
+	}
+
+	return b.withSignedBlocks(appended, signedBlocks, privateKeys, symbols, publicKeys), nil
+}
+
+// nextPrivateKey returns the key signing the next block,
+// or an error when the token is sealed.
+func (b *Biscuit) nextPrivateKey() (ed25519.PrivateKey, error) {
 	if b.container == nil {
 		return nil, errors.New("biscuit: append failed, token is sealed")
 	}
@@ -157,77 +309,267 @@ func (b *Biscuit) Append(rng io.Reader, block *Block) (*Biscuit, error) {
 		return nil, ErrInvalidKeySize
 	}
 
-	privateKey = ed25519.NewKeyFromSeed(privateKey)
+	return ed25519.NewKeyFromSeed(privateKey), nil
+}
 
-	if !b.symbols.IsDisjoint(block.symbols) {
-		return nil, ErrSymbolTableOverlap
+// appendBlock signs the serialized block with privateKey and returns
+// a copy of the token ending with it.
+func (b *Biscuit) appendBlock(rng io.Reader, privateKey ed25519.PrivateKey, block *Block, marshalledBlock []byte, externalSignature *pb.ExternalSignature, symbols *datalog.SymbolTable, publicKeys publicKeyTable) *Biscuit {
+	signedBlock, nextPrivateKey := signBlock(rng, privateKey, marshalledBlock, externalSignature)
+	return b.withSignedBlocks([]*Block{block}, []*pb.SignedBlock{signedBlock}, []ed25519.PrivateKey{nextPrivateKey}, symbols, publicKeys)
+}
+
+// signBlock signs the serialized block with privateKey, and returns it
+// along with the private key of its next key, generated from rng.
+func signBlock(rng io.Reader, privateKey ed25519.PrivateKey, marshalledBlock []byte, externalSignature *pb.ExternalSignature) (*pb.SignedBlock, ed25519.PrivateKey) {
+	nextPublicKey, nextPrivateKey, _ := ed25519.GenerateKey(rng)
+
+	// sign the new block
+	algorithm := pb.PublicKey_Ed25519
+	signature := ed25519.Sign(privateKey, blockSignaturePayload(marshalledBlock, externalSignature, algorithm, nextPublicKey))
+	nextKey := &pb.PublicKey{
+		Algorithm: &algorithm,
+		Key:       nextPublicKey,
 	}
 
-	// clone biscuit fields and append new block
+	signedBlock := &pb.SignedBlock{
+		Block:             marshalledBlock,
+		NextKey:           nextKey,
+		Signature:         signature,
+		ExternalSignature: externalSignature,
+	}
+
+	return signedBlock, nextPrivateKey
+}
+
+// blockSignaturePayload returns the data signed by the previous key of a block.
+func blockSignaturePayload(marshalledBlock []byte, externalSignature *pb.ExternalSignature, algorithm pb.PublicKey_Algorithm, nextKey []byte) []byte {
+	toSignAlgorithm := make([]byte, 4)
+	binary.LittleEndian.PutUint32(toSignAlgorithm[0:], uint32(algorithm))
+	toSign := append([]byte{}, marshalledBlock...)
+	if externalSignature != nil {
+		toSign = append(toSign, externalSignature.Signature...)
+	}
+	toSign = append(toSign, toSignAlgorithm...)
+	return append(toSign, nextKey...)
+}
+
+// withSignedBlocks returns a copy of the token ending with the signed blocks,
+// nextPrivateKeys being the private keys of their next keys.
+// The last one becomes the proof of the token.
+func (b *Biscuit) withSignedBlocks(newBlocks []*Block, signedBlocks []*pb.SignedBlock, nextPrivateKeys []ed25519.PrivateKey, symbols *datalog.SymbolTable, publicKeys publicKeyTable) *Biscuit {
+	// clone biscuit fields and append new blocks
 	authority := new(Block)
 	*authority = *b.authority
 
-	blocks := make([]*Block, len(b.blocks)+1)
+	blocks := make([]*Block, len(b.blocks), len(b.blocks)+len(newBlocks))
 	for i, oldBlock := range b.blocks {
 		blocks[i] = new(Block)
 		*blocks[i] = *oldBlock
 	}
-	blocks[len(b.blocks)] = block
+	blocks = append(blocks, newBlocks...)
 
+	previousSecrets := make([][]byte, len(b.blocks), len(b.blocks)+len(newBlocks))
+	copy(previousSecrets, b.previousSecrets)
+	previousSecrets = append(previousSecrets, b.container.Proof.GetNextSecret())
+	for _, key := range nextPrivateKeys[:len(nextPrivateKeys)-1] {
+		previousSecrets = append(previousSecrets, key.Seed())
+	}
+
+	proof := &pb.Proof{
+		Content: &pb.Proof_NextSecret{
+			NextSecret: nextPrivateKeys[len(nextPrivateKeys)-1].Seed(),
+		},
+	}
+
+	// clone container and append new marshalled blocks and public keys
+	container := &pb.Biscuit{
+		RootKeyId: b.container.RootKeyId,
+		Authority: b.container.Authority,
+		Blocks:    append([]*pb.SignedBlock{}, b.container.Blocks...),
+		Proof:     proof,
+	}
+
+	container.Blocks = append(container.Blocks, signedBlocks...)
+
+	return &Biscuit{
+		authority:  authority,
+		blocks:     blocks,
+		symbols:    symbols,
+		publicKeys: publicKeys,
+		container:  container,
+
+		previousSecrets: previousSecrets,
+	}
+}
+
+// WithoutLastBlock returns a copy of the token without its last block, its
+// proof being the next secret of the block before it. That secret cannot be
+// derived from the token: it is only known when the removed block was appended
+// in this process, with Append, AppendAll, AppendSigned or AppendThirdParty,
+// to a token holding its proof. ErrPreviousSecretUnavailable is returned otherwise,
+// such as for an unmarshalled token. Sealed tokens and tokens without blocks
+// after the authority block are rejected.
+func (b *Biscuit) WithoutLastBlock() (*Biscuit, error) {
+	if b.container == nil || b.container.Proof.GetNextSecret() == nil {
+		return nil, errors.New("biscuit: cannot remove a block, token is sealed")
+	}
+	if len(b.blocks) == 0 {
+		return nil, errors.New("biscuit: cannot remove the authority block")
+	}
+	last := len(b.blocks) - 1
+	if last >= len(b.previousSecrets) || b.previousSecrets[last] == nil {
+		return nil, ErrPreviousSecretUnavailable
+	}
+
+	// the token's symbols start with the base symbols, followed by the
+	// symbols of the authority block and of the blocks signed by the holder
+	baseLength := b.symbols.Len() - b.authority.symbols.Len()
+	for _, block := range b.blocks {
+		if block.externalKey == nil {
+			baseLength -= block.symbols.Len()
+		}
+	}
 	symbols := b.symbols.Clone()
-	symbols.Extend(block.symbols)
+	symbols.SplitOff(baseLength)
 
-	nextPublicKey, nextPrivateKey, _ := ed25519.GenerateKey(rng)
+	container := &pb.Biscuit{
+		RootKeyId: b.container.RootKeyId,
+		Authority: b.container.Authority,
+		Blocks:    append([]*pb.SignedBlock{}, b.container.Blocks[:last]...),
+		Proof: &pb.Proof{
+			Content: &pb.Proof_NextSecret{
+				NextSecret: b.previousSecrets[last],
+			},
+		},
+	}
 
-	// serialize and sign the new block
-	protoBlock, err := tokenBlockToProtoBlock(block)
+	truncated, err := fromContainer(container, symbols, math.MaxInt)
 	if err != nil {
 		return nil, err
 	}
-	marshalledBlock, err := proto.Marshal(protoBlock)
+	truncated.previousSecrets = append([][]byte{}, b.previousSecrets[:last]...)
+
+	return truncated, nil
+}
+
+// AppendSigned appends a block signed on another machine with the private key
+// of the token's last next key, such as one signed by a copy of the token.
+// The block is decoded from the signed bytes, and its signature is verified.
+// nextPrivateKey is the private key of the signed block's next key: it becomes
+// the proof of the returned token, and signs the following block.
+// Third party blocks are appended with AppendThirdParty.
+func (b *Biscuit) AppendSigned(signed *pb.SignedBlock, nextPrivateKey ed25519.PrivateKey) (*Biscuit, error) {
+	if b.container == nil || b.container.Proof.GetNextSecret() == nil {
+		return nil, errors.New("biscuit: append failed, token is sealed")
+	}
+	if signed == nil || signed.NextKey == nil {
+		return nil, fmt.Errorf("%w: missing next key", ErrInvalidContainer)
+	}
+	if signed.ExternalSignature != nil {
+		return nil, errors.New("biscuit: third party blocks must be appended with AppendThirdParty")
+	}
+	if signed.NextKey.GetAlgorithm() != pb.PublicKey_Ed25519 {
+		return nil, UnsupportedAlgorithm
+	}
+	if len(signed.NextKey.Key) != ed25519.PublicKeySize || len(nextPrivateKey) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidKeySize
+	}
+	if len(signed.Signature) != ed25519.SignatureSize {
+		return nil, ErrInvalidSignatureSize
+	}
+
+	previousKey := ed25519.PublicKey(b.lastSignedBlock().NextKey.Key)
+	payload := blockSignaturePayload(signed.Block, nil, signed.NextKey.GetAlgorithm(), signed.NextKey.Key)
+	if !ed25519.Verify(previousKey, payload, signed.Signature) {
+		return nil, ErrInvalidSignature
+	}
+	if !nextPrivateKey.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(signed.NextKey.Key)) {
+		return nil, errors.New("biscuit: next private key does not match the next key of the block")
+	}
+
+	pbBlock := new(pb.Block)
+	if err := proto.Unmarshal(signed.Block, pbBlock); err != nil {
+		return nil, err
+	}
+	publicKeys := b.publicKeys.Clone()
+	block, err := protoBlockToTokenBlock(pbBlock, &publicKeys)
 	if err != nil {
 		return nil, err
 	}
+	if !b.symbols.IsDisjoint(block.symbols) {
+		return nil, ErrSymbolTableOverlap
+	}
+	symbols := b.symbols.Clone()
+	symbols.Extend(block.symbols)
 
-	algorithm := pb.PublicKey_Ed25519
-	toSignAlgorithm := make([]byte, 4)
-	binary.LittleEndian.PutUint32(toSignAlgorithm[0:], uint32(pb.PublicKey_Ed25519))
-	toSign := append(marshalledBlock[:], toSignAlgorithm...)
-	toSign = append(toSign, nextPublicKey[:]...)
+	return b.withSignedBlocks([]*Block{block}, []*pb.SignedBlock{proto.Clone(signed).(*pb.SignedBlock)}, []ed25519.PrivateKey{nextPrivateKey}, symbols, publicKeys), nil
+}
 
-	signature := ed25519.Sign(privateKey, toSign)
-	nextKey := &pb.PublicKey{
-		Algorithm: &algorithm,
-		Key:       nextPublicKey,
-	}
+// Attenuate creates a new block, lets fn populate it, appends it to the token
+// and returns the serialized attenuated token.
+func (b *Biscuit) Attenuate(rng io.Reader, fn func(BlockBuilder)) ([]byte, error) {
+	block := b.CreateBlock()
+	fn(block)
 
-	signedBlock := &pb.SignedBlock{
-		Block:     marshalledBlock,
-		NextKey:   nextKey,
-		Signature: signature,
+	attenuated, err := b.Append(rng, block.Build())
+	if err != nil {
+		return nil, err
 	}
 
-	proof := &pb.Proof{
-		Content: &pb.Proof_NextSecret{
-			NextSecret: nextPrivateKey.Seed(),
-		},
+	return attenuated.Serialize()
+}
+
+// AppendParsedBlock validates the parsed block with ParsedBlock.Validate
+// before appending it to the token.
+func (b *Biscuit) AppendParsedBlock(rng io.Reader, parsed ParsedBlock) (*Biscuit, error) {
+	if err := parsed.Validate(false); err != nil {
+		return nil, err
 	}
 
-	// clone container and append new marshalled block and public key
-	container := &pb.Biscuit{
-		Authority: b.container.Authority,
-		Blocks:    append([]*pb.SignedBlock{}, b.container.Blocks...),
-		Proof:     proof,
+	block := b.CreateBlock()
+	if err := block.AddBlock(parsed); err != nil {
+		return nil, err
 	}
 
-	container.Blocks = append(container.Blocks, signedBlock)
+	return b.Append(rng, block.Build())
+}
 
-	return &Biscuit{
-		authority: authority,
-		blocks:    blocks,
-		symbols:   symbols,
-		container: container,
-	}, nil
+// IsSealed returns true when the token ends with a final signature,
+// so no block can be appended to it.
+func (b *Biscuit) IsSealed() bool {
+	return b.container != nil && b.container.Proof.GetFinalSignature() != nil
+}
+
+// ProofType returns the kind of proof ending the token: "next_secret" when
+// blocks can be appended to it, "final_signature" when it is sealed,
+// or an empty string when it has no proof.
+func (b *Biscuit) ProofType() string {
+	if b.container == nil {
+		return ""
+	}
+	switch b.container.Proof.GetContent().(type) {
+	case *pb.Proof_NextSecret:
+		return "next_secret"
+	case *pb.Proof_FinalSignature:
+		return "final_signature"
+	default:
+		return ""
+	}
+}
+
+// NextPublicKey returns the next public key of the last block, which signs
+// the next block or the final signature. It returns false when the last block
+// does not hold an ed25519 public key.
+func (b *Biscuit) NextPublicKey() (ed25519.PublicKey, bool) {
+	if b.container == nil || b.container.Authority == nil {
+		return nil, false
+	}
+	nextKey := b.lastSignedBlock().NextKey
+	if nextKey.GetAlgorithm() != pb.PublicKey_Ed25519 || len(nextKey.GetKey()) != ed25519.PublicKeySize {
+		return nil, false
+	}
+	return ed25519.PublicKey(nextKey.Key), true
 }
 
 func (b *Biscuit) Seal(rng io.Reader) (*Biscuit, error) {
@@ -264,7 +606,7 @@ func (b *Biscuit) Seal(rng io.Reader) (*Biscuit, error) {
 	}
 
 	toSignAlgorithm := make([]byte, 4)
-	binary.LittleEndian.PutUint32(toSignAlgorithm[0:], uint32(lastBlock.NextKey.Algorithm.Number()))
+	binary.LittleEndian.PutUint32(toSignAlgorithm[0:], uint32(lastBlock.NextKey.GetAlgorithm().Number()))
 	toSign := append(lastBlock.Block[:], toSignAlgorithm...)
 	toSign = append(toSign, lastBlock.NextKey.Key[:]...)
 	toSign = append(toSign, lastBlock.Signature[:]...)
@@ -279,6 +621,7 @@ func (b *Biscuit) Seal(rng io.Reader) (*Biscuit, error) {
 
 	// clone container and append new marshalled block and public key
 	container := &pb.Biscuit{
+		RootKeyId: b.container.RootKeyId,
 		Authority: b.container.Authority,
 		Blocks:    append([]*pb.SignedBlock{}, b.container.Blocks...),
 		Proof:     proof,
@@ -287,10 +630,11 @@ func (b *Biscuit) Seal(rng io.Reader) (*Biscuit, error) {
 	symbols := b.symbols.Clone()
 
 	return &Biscuit{
-		authority: authority,
-		blocks:    blocks,
-		symbols:   symbols,
-		container: container,
+		authority:  authority,
+		blocks:     blocks,
+		symbols:    symbols,
+		publicKeys: b.publicKeys.Clone(),
+		container:  container,
 	}, nil
 }
 
@@ -330,45 +674,107 @@ func WithRootPublicKeys(keysByID map[uint32]ed25519.PublicKey, defaultKey *ed255
 }
 
 func (b *Biscuit) authorizerFor(root ed25519.PublicKey, opts ...AuthorizerOption) (Authorizer, error) {
+	verifier := newAuthorizer(b, opts...)
+	if blockCount := len(b.container.Blocks) + 1; verifier.maxBlocks > 0 && blockCount > verifier.maxBlocks {
+		return nil, fmt.Errorf("%w: token has %d blocks, limit is %d", ErrTooManyBlocks, blockCount, verifier.maxBlocks)
+	}
+
+	if !b.trusted {
+		if err := b.verify(root); err != nil {
+			return nil, err
+		}
+	}
+
+	if verifier.trustedExternalKeys != nil {
+		for i, block := range b.blocks {
+			if block.externalKey != nil && !verifier.trustsExternalKey(block.externalKey) {
+				return nil, fmt.Errorf("%w: block %d is signed by ed25519/%s", ErrUntrustedExternalKey, i+1, hex.EncodeToString(block.externalKey))
+			}
+		}
+	}
+
+	return verifier, nil
+}
+
+// verify checks the signatures of the blocks, starting from the root key,
+// and the proof of the token.
+func (b *Biscuit) verify(root ed25519.PublicKey) error {
+	return b.verifyChain(root, nil)
+}
+
+// VerifyChain verifies the signatures of the blocks in order, starting with the
+// authority block at index 0, with the root public key selected from keySource.
+// onBlock is called after each block is verified: when it returns an error,
+// verification stops and the error is returned, so the following blocks
+// and the proof of the token are not checked.
+func (b *Biscuit) VerifyChain(keySource PublickKeyByIDProjection, onBlock func(index int) error) error {
+	root, err := b.rootPublicKey(keySource)
+	if err != nil {
+		return err
+	}
+	return b.verifyChain(root, onBlock)
+}
+
+func (b *Biscuit) verifyChain(root ed25519.PublicKey, onBlock func(index int) error) error {
+	if onBlock == nil {
+		onBlock = func(int) error { return nil }
+	}
+
 	currentKey := root
 
 	// for now we only support Ed25519
-	if *b.container.Authority.NextKey.Algorithm != pb.PublicKey_Ed25519 {
-		return nil, UnsupportedAlgorithm
+	if b.container.Authority.NextKey.GetAlgorithm() != pb.PublicKey_Ed25519 {
+		return UnsupportedAlgorithm
 	}
 
 	algorithm := make([]byte, 4)
-	binary.LittleEndian.PutUint32(algorithm[0:], uint32(b.container.Authority.NextKey.Algorithm.Number()))
+	binary.LittleEndian.PutUint32(algorithm[0:], uint32(b.container.Authority.NextKey.GetAlgorithm().Number()))
 
 	toVerify := append(b.container.Authority.Block[:], algorithm...)
 	toVerify = append(toVerify, b.container.Authority.NextKey.Key[:]...)
 
 	if ok := ed25519.Verify(currentKey, toVerify, b.container.Authority.Signature); !ok {
-		return nil, ErrInvalidSignature
+		return ErrInvalidSignature
 	}
 
 	currentKey = b.container.Authority.NextKey.Key
 	if len(currentKey) != 32 {
-		return nil, ErrInvalidKeySize
+		return ErrInvalidKeySize
+	}
+	if err := onBlock(0); err != nil {
+		return err
 	}
 
-	for _, block := range b.container.Blocks {
-		if *block.NextKey.Algorithm != pb.PublicKey_Ed25519 {
-			return nil, UnsupportedAlgorithm
+	for i, block := range b.container.Blocks {
+		if block.NextKey.GetAlgorithm() != pb.PublicKey_Ed25519 {
+			return UnsupportedAlgorithm
 		}
 
 		algorithm := make([]byte, 4)
-		binary.LittleEndian.PutUint32(algorithm[0:], uint32(block.NextKey.Algorithm.Number()))
-		toVerify := append(block.Block[:], algorithm...)
+		binary.LittleEndian.PutUint32(algorithm[0:], uint32(block.NextKey.GetAlgorithm().Number()))
+		toVerify := append([]byte{}, block.Block...)
+		if block.ExternalSignature != nil {
+			toVerify = append(toVerify, block.ExternalSignature.Signature...)
+		}
+		toVerify = append(toVerify, algorithm...)
 		toVerify = append(toVerify, block.NextKey.Key[:]...)
 
 		if ok := ed25519.Verify(currentKey, toVerify, block.Signature); !ok {
-			return nil, ErrInvalidSignature
+			return ErrInvalidSignature
+		}
+
+		if block.ExternalSignature != nil {
+			if err := verifyExternalSignature(block.Block, block.ExternalSignature, currentKey); err != nil {
+				return err
+			}
 		}
 
 		currentKey = block.NextKey.Key
 		if len(currentKey) != 32 {
-			return nil, ErrInvalidKeySize
+			return ErrInvalidKeySize
+		}
+		if err := onBlock(i + 1); err != nil {
+			return err
 		}
 	}
 
@@ -377,12 +783,12 @@ func (b *Biscuit) authorizerFor(root ed25519.PublicKey, opts ...AuthorizerOption
 		{
 			privateKey := b.container.Proof.GetNextSecret()
 			if privateKey == nil {
-				return nil, errors.New("biscuit: sealed token verification not implemented")
+				return errors.New("biscuit: sealed token verification not implemented")
 			}
 
 			publicKey := ed25519.NewKeyFromSeed(privateKey).Public()
 			if !bytes.Equal(currentKey, publicKey.(ed25519.PublicKey)) {
-				return nil, errors.New("biscuit: invalid last signature")
+				return errors.New("biscuit: invalid last signature")
 			}
 		}
 	case b.container.Proof.GetFinalSignature() != nil:
@@ -396,20 +802,20 @@ func (b *Biscuit) authorizerFor(root ed25519.PublicKey, opts ...AuthorizerOption
 			}
 
 			algorithm := make([]byte, 4)
-			binary.LittleEndian.PutUint32(algorithm[0:], uint32(lastBlock.NextKey.Algorithm.Number()))
+			binary.LittleEndian.PutUint32(algorithm[0:], uint32(lastBlock.NextKey.GetAlgorithm().Number()))
 			toVerify := append(lastBlock.Block[:], algorithm...)
 			toVerify = append(toVerify, lastBlock.NextKey.Key[:]...)
 			toVerify = append(toVerify, lastBlock.Signature[:]...)
 
 			if ok := ed25519.Verify(currentKey, toVerify, signature); !ok {
-				return nil, errors.New("biscuit: invalid last signature")
+				return errors.New("biscuit: invalid last signature")
 			}
 		}
 	default:
-		return nil, errors.New("biscuit: cannot find proof")
+		return errors.New("biscuit: cannot find proof")
 	}
 
-	return NewVerifier(b, opts...)
+	return nil
 }
 
 // AuthorizerFor selects from the supplied source a root public key to use to verify the signatures
@@ -417,6 +823,15 @@ func (b *Biscuit) authorizerFor(root ed25519.PublicKey, opts ...AuthorizerOption
 // no such public key is available. If the signatures are valid, it creates an [Authorizer], which
 // can then test the authorization policies and accept or refuse the request.
 func (b *Biscuit) AuthorizerFor(keySource PublickKeyByIDProjection, opts ...AuthorizerOption) (Authorizer, error) {
+	rootPublicKey, err := b.rootPublicKey(keySource)
+	if err != nil {
+		return nil, err
+	}
+	return b.authorizerFor(rootPublicKey, opts...)
+}
+
+// rootPublicKey selects from the supplied source the root public key of the token.
+func (b *Biscuit) rootPublicKey(keySource PublickKeyByIDProjection) (ed25519.PublicKey, error) {
 	if keySource == nil {
 		return nil, errors.New("root public key source must not be nil")
 	}
@@ -427,7 +842,7 @@ func (b *Biscuit) AuthorizerFor(keySource PublickKeyByIDProjection, opts ...Auth
 	if len(rootPublicKey) == 0 {
 		return nil, ErrNoPublicKeyAvailable
 	}
-	return b.authorizerFor(rootPublicKey, opts...)
+	return rootPublicKey, nil
 }
 
 // TODO: Add "Deprecated" note to the "(*Biscuit).Authorizer" method, recommending use of
@@ -437,18 +852,73 @@ func (b *Biscuit) AuthorizerFor(keySource PublickKeyByIDProjection, opts ...Auth
 // Authorizer checks the signature and creates an [Authorizer]. The Authorizer can then test the
 // authorizaion policies and accept or refuse the request.
 func (b *Biscuit) Authorizer(root ed25519.PublicKey, opts ...AuthorizerOption) (Authorizer, error) {
-	return b.authorizerFor(root)
+	return b.authorizerFor(root, opts...)
+}
+
+// RunCheck evaluates check as an authorizer check, against the facts and
+// rules of the token and the ambient facts, and returns whether it passes.
+// The signatures of the token are not verified, and its own checks are not
+// evaluated: it is meant to debug checks, not to authorize requests.
+func (b *Biscuit) RunCheck(check Check, ambientFacts []Fact) (bool, error) {
+	v := newAuthorizer(b)
+	v.AddFacts(ambientFacts)
+	if _, err := v.loadBlocks(append([]*Block{b.authority}, b.blocks...)); err != nil {
+		return false, err
+	}
+	if err := v.world.Run(v.symbols); err != nil {
+		return false, err
+	}
+
+	return v.checkMatches(check.convert(v.symbols), defaultOrigins, datalog.AuthorizerOrigin), nil
+}
+
+// ForEachBlock calls fn with each block of the token, starting with the
+// authority block at index 0, then each block in the order they were added.
+// It stops at the first error returned by fn, and returns it.
+func (b *Biscuit) ForEachBlock(fn func(index int, blk *Block) error) error {
+	if err := fn(0, b.authority); err != nil {
+		return err
+	}
+	for i, block := range b.blocks {
+		if err := fn(i+1, block); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (b *Biscuit) Checks() [][]datalog.Check {
 	result := make([][]datalog.Check, 0, len(b.blocks)+1)
-	result = append(result, b.authority.checks)
-	for _, block := range b.blocks {
+	_ = b.ForEachBlock(func(_ int, block *Block) error {
 		result = append(result, block.checks)
-	}
+		return nil
+	})
 	return result
 }
 
+// CheckStrings returns the datalog source of the checks of each block,
+// starting with the authority block, as printed by Check.String.
+func (b *Biscuit) CheckStrings() ([][]string, error) {
+	result := make([][]string, 0, len(b.blocks)+1)
+	err := b.ForEachBlock(func(_ int, block *Block) error {
+		symbols := b.blockSymbols(block)
+		checks := make([]string, 0, len(block.checks))
+		for _, c := range block.checks {
+			check, err := fromDatalogCheck(symbols, c)
+			if err != nil {
+				return err
+			}
+			checks = append(checks, check.String(symbols))
+		}
+		result = append(result, checks)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func (b *Biscuit) GetContext() string {
 	if b == nil || b.authority == nil {
 		return ""
@@ -461,6 +931,15 @@ func (b *Biscuit) Serialize() ([]byte, error) {
 	return proto.Marshal(b.container)
 }
 
+// SerializedSize returns the length of the output of Serialize,
+// without marshaling the token.
+func (b *Biscuit) SerializedSize() (int, error) {
+	if b.container == nil {
+		return 0, fmt.Errorf("%w: missing authority block", ErrInvalidContainer)
+	}
+	return proto.Size(b.container), nil
+}
+
 var ErrFactNotFound = errors.New("biscuit: fact not found")
 
 // GetBlockID returns the first block index containing a fact
@@ -477,9 +956,13 @@ func (b *Biscuit) GetBlockID(fact Fact) (int, error) {
 		}
 	}
 
-	for i, b := range b.blocks {
-		for _, f := range *b.facts {
-			if f.Equal(datalogFact) {
+	for i, block := range b.blocks {
+		blockFact := datalogFact
+		if block.externalKey != nil {
+			blockFact = fact.Predicate.convert(b.blockSymbols(block))
+		}
+		for _, f := range *block.facts {
+			if f.Equal(blockFact) {
 				return i + 1, nil
 			}
 		}
@@ -488,6 +971,40 @@ func (b *Biscuit) GetBlockID(fact Fact) (int, error) {
 	return 0, ErrFactNotFound
 }
 
+// FactsByName runs the rules of the token over its facts, without any
+// authorizer facts, and returns the facts named name, including the
+// generated ones. As in the authorizer, each rule only uses the facts of
+// the blocks it trusts.
+func (b *Biscuit) FactsByName(name string) ([]Fact, error) {
+	symbols := b.symbols.Clone()
+	world, err := b.generateWorld(symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	sym, ok := symbols.Sym(name).(datalog.String)
+	if !ok {
+		return nil, nil
+	}
+
+	matches := &datalog.FactSet{}
+	for _, f := range *world.Facts() {
+		if f.Name == sym {
+			matches.Insert(f)
+		}
+	}
+
+	facts := make([]Fact, 0, len(*matches))
+	for _, f := range *matches {
+		fact, err := fromDatalogFact(symbols, f)
+		if err != nil {
+			return nil, err
+		}
+		facts = append(facts, *fact)
+	}
+	return facts, nil
+}
+
 /*
 // SHA256Sum returns a hash of `count` biscuit blocks + the authority block
 // along with their respective keys.
@@ -522,6 +1039,33 @@ func (b *Biscuit) SHA256Sum(count int) ([]byte, error) {
 	return h.Sum(nil), nil
 }*/
 
+// PublicKeys returns the next key of each block, starting with the authority block.
+func (b *Biscuit) PublicKeys() []ed25519.PublicKey {
+	keys := make([]ed25519.PublicKey, 0, len(b.container.Blocks)+1)
+	keys = append(keys, b.container.Authority.NextKey.Key)
+	for _, block := range b.container.Blocks {
+		keys = append(keys, block.NextKey.Key)
+	}
+	return keys
+}
+
+// ExternalKeys returns the public key of the external signature of each block,
+// starting with the authority block. The key is nil for blocks without an
+// external signature.
+func (b *Biscuit) ExternalKeys() []*ed25519.PublicKey {
+	keys := make([]*ed25519.PublicKey, 0, len(b.container.Blocks)+1)
+	keys = append(keys, nil)
+	for _, block := range b.container.Blocks {
+		if block.ExternalSignature == nil {
+			keys = append(keys, nil)
+			continue
+		}
+		key := ed25519.PublicKey(block.ExternalSignature.PublicKey.GetKey())
+		keys = append(keys, &key)
+	}
+	return keys
+}
+
 func (b *Biscuit) BlockCount() int {
 	return len(b.container.Blocks)
 }
@@ -533,7 +1077,7 @@ func (b *Biscuit) RootKeyID() *uint32 {
 func (b *Biscuit) String() string {
 	blocks := make([]string, len(b.blocks))
 	for i, block := range b.blocks {
-		blocks[i] = block.String(b.symbols)
+		blocks[i] = block.String(b.blockSymbols(block))
 	}
 
 	return fmt.Sprintf(`
@@ -551,11 +1095,90 @@ Biscuit {
 func (b *Biscuit) Code() []string {
 	blocks := make([]string, len(b.blocks))
 	for i, block := range b.blocks {
-		blocks[i] = block.Code(b.symbols)
+		blocks[i] = block.Code(b.blockSymbols(block))
 	}
 	return blocks
 }
 
+// tokenJSON is the JSON document produced by ToJSON.
+type tokenJSON struct {
+	RootKeyID     *uint32     `json:"root_key_id,omitempty"`
+	Blocks        []blockJSON `json:"blocks"`
+	RevocationIDs []string    `json:"revocation_ids"`
+}
+
+type blockJSON struct {
+	Symbols     []string `json:"symbols"`
+	Context     string   `json:"context,omitempty"`
+	Version     uint32   `json:"version"`
+	ExternalKey string   `json:"external_key,omitempty"`
+	Facts       []string `json:"facts"`
+	Rules       []string `json:"rules"`
+	Checks      []string `json:"checks"`
+}
+
+// ToJSON returns a JSON description of the token, for inspection and logging.
+// The blocks, starting with the authority block, list the symbols they add to
+// the token, and their facts, rules and checks as datalog. Revocation ids and
+// external keys are hex encoded.
+// The document cannot be converted back to a token.
+func (b *Biscuit) ToJSON() ([]byte, error) {
+	doc := tokenJSON{RootKeyID: b.RootKeyID()}
+	_ = b.ForEachBlock(func(_ int, block *Block) error {
+		debug := &datalog.SymbolDebugger{SymbolTable: b.blockSymbols(block)}
+		out := blockJSON{
+			Symbols: append([]string{}, *block.symbols...),
+			Context: block.context,
+			Version: block.version,
+			Facts:   make([]string, 0, len(*block.facts)),
+			Rules:   make([]string, 0, len(block.rules)),
+			Checks:  make([]string, 0, len(block.checks)),
+		}
+		if block.externalKey != nil {
+			out.ExternalKey = hex.EncodeToString(block.externalKey)
+		}
+		for _, f := range *block.facts {
+			out.Facts = append(out.Facts, debug.Predicate(f.Predicate))
+		}
+		for _, r := range block.rules {
+			out.Rules = append(out.Rules, debug.Rule(r))
+		}
+		for _, c := range block.checks {
+			out.Checks = append(out.Checks, debug.Check(c))
+		}
+		doc.Blocks = append(doc.Blocks, out)
+		return nil
+	})
+	for _, id := range b.RevocationIds() {
+		doc.RevocationIDs = append(doc.RevocationIDs, hex.EncodeToString(id))
+	}
+
+	return json.Marshal(doc)
+}
+
+// blockSymbols returns the symbol table used by the block:
+// third party blocks have their own, other blocks share the token's table.
+func (b *Biscuit) blockSymbols(block *Block) *datalog.SymbolTable {
+	if block.externalKey == nil {
+		return b.symbols
+	}
+
+	symbols := defaultSymbolTable.Clone()
+	symbols.Extend(block.symbols)
+	return symbols
+}
+
+// externalKeyBlocks returns the indexes of the blocks signed by the external key.
+func (b *Biscuit) externalKeyBlocks(publicKey []byte) []uint64 {
+	var ids []uint64
+	for i, block := range b.blocks {
+		if block.externalKey != nil && bytes.Equal(block.externalKey, publicKey) {
+			ids = append(ids, uint64(i+1))
+		}
+	}
+	return ids
+}
+
 /*
 func (b *Biscuit) checkRootKey(root ed25519.PublicKey) error {
 	if len(b.container.Keys) == 0 {
@@ -568,32 +1191,21 @@ func (b *Biscuit) checkRootKey(root ed25519.PublicKey) error {
 	return nil
 }*/
 
+// generateWorld loads the facts and rules of the blocks as the authorizer does,
+// converted to symbols: each block is decoded with its own symbol table, and its
+// rules only see the facts of the blocks they trust. It then runs the rules.
 func (b *Biscuit) generateWorld(symbols *datalog.SymbolTable) (*datalog.World, error) {
-	world := datalog.NewWorld()
-
-	for _, fact := range *b.authority.facts {
-		world.AddFact(fact)
-	}
-
-	for _, rule := range b.authority.rules {
-		world.AddRule(rule)
-	}
-
-	for _, block := range b.blocks {
-		for _, fact := range *block.facts {
-			world.AddFact(fact)
-		}
-
-		for _, rule := range block.rules {
-			world.AddRule(rule)
-		}
+	v := newAuthorizer(b)
+	v.symbols = symbols
+	if _, err := v.loadBlocks(append([]*Block{b.authority}, b.blocks...)); err != nil {
+		return nil, err
 	}
 
-	if err := world.Run(symbols); err != nil {
+	if err := v.world.Run(symbols); err != nil {
 		return nil, err
 	}
 
-	return world, nil
+	return v.world, nil
 }
 
 func (b *Biscuit) RevocationIds() [][]byte {
@@ -3,11 +3,18 @@ package biscuit
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/biscuit-auth/biscuit-go/v2/datalog"
+	"github.com/biscuit-auth/biscuit-go/v2/pb"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestBiscuit(t *testing.T) {
@@ -316,6 +323,65 @@ func TestCheckRootKey(t *testing.T) {
 	require.Equal(t, ErrInvalidSignature, err)
 }
 
+func TestMultipleRootKeys(t *testing.T) {
+	rng := rand.Reader
+	publicRoot1, privateRoot1, _ := ed25519.GenerateKey(rng)
+	publicRoot2, privateRoot2, _ := ed25519.GenerateKey(rng)
+	publicDefault, privateDefault, _ := ed25519.GenerateKey(rng)
+
+	keys := WithRootPublicKeys(map[uint32]ed25519.PublicKey{
+		1: publicRoot1,
+		2: publicRoot2,
+	}, &publicDefault)
+
+	build := func(privateRoot ed25519.PrivateKey, opts ...builderOption) []byte {
+		b, err := NewBuilder(privateRoot, opts...).Build()
+		require.NoError(t, err)
+		// the key ID is kept by attenuation and sealing
+		b, err = b.Append(rng, b.CreateBlock().Build())
+		require.NoError(t, err)
+		b, err = b.Seal(rng)
+		require.NoError(t, err)
+		serialized, err := b.Serialize()
+		require.NoError(t, err)
+		return serialized
+	}
+
+	for _, tc := range []struct {
+		desc  string
+		token []byte
+		id    *uint32
+		err   error
+	}{
+		{desc: "key 1", token: build(privateRoot1, WithRootKeyID(1)), id: uint32Ptr(1)},
+		{desc: "key 2", token: build(privateRoot2, WithRootKeyID(2)), id: uint32Ptr(2)},
+		{desc: "default key", token: build(privateDefault)},
+		{desc: "wrong key", token: build(privateRoot1, WithRootKeyID(2)), id: uint32Ptr(2), err: ErrInvalidSignature},
+		{desc: "unknown key", token: build(privateRoot1, WithRootKeyID(3)), id: uint32Ptr(3), err: ErrNoPublicKeyAvailable},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			b, err := Unmarshal(tc.token)
+			require.NoError(t, err)
+			require.Equal(t, tc.id, b.RootKeyID())
+			require.Len(t, b.blocks, 1)
+
+			_, err = b.AuthorizerFor(keys)
+			_, verifyErr := UnmarshalAndVerify(tc.token, keys)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				require.ErrorIs(t, verifyErr, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, verifyErr)
+		})
+	}
+}
+
+func uint32Ptr(v uint32) *uint32 {
+	return &v
+}
+
 func TestGenerateWorld(t *testing.T) {
 	rng := rand.Reader
 	_, privateRoot, _ := ed25519.GenerateKey(rng)
@@ -352,11 +418,13 @@ func TestGenerateWorld(t *testing.T) {
 	world, err := b.generateWorld(defaultSymbolTable.Clone())
 	require.NoError(t, err)
 
+	authorityOrigin := datalog.NewOrigin(0)
+	authorityTrusted := datalog.NewOrigin(0, datalog.AuthorizerOrigin)
 	expectedWorld := datalog.NewWorld()
-	expectedWorld.AddFact(authorityFact1.convert(StringTable))
-	expectedWorld.AddFact(authorityFact2.convert(StringTable))
-	expectedWorld.AddRule(authorityRule1.convert(StringTable))
-	expectedWorld.AddRule(authorityRule2.convert(StringTable))
+	expectedWorld.AddFactWithOrigin(authorityFact1.convert(StringTable), authorityOrigin)
+	expectedWorld.AddFactWithOrigin(authorityFact2.convert(StringTable), authorityOrigin)
+	expectedWorld.AddRuleWithOrigin(authorityRule1.convert(StringTable), 0, authorityTrusted)
+	expectedWorld.AddRuleWithOrigin(authorityRule2.convert(StringTable), 0, authorityTrusted)
 	require.Equal(t, expectedWorld, world)
 
 	blockBuild := b.CreateBlock()
@@ -380,14 +448,12 @@ func TestGenerateWorld(t *testing.T) {
 	require.NoError(t, err)
 
 	expectedWorld = datalog.NewWorld()
-	expectedWorld.AddFact(authorityFact1.convert(&allStrings))
-	expectedWorld.AddFact(authorityFact2.convert(&allStrings))
-	expectedWorld.AddFact(blockFact.convert(&allStrings))
-	expectedWorld.AddRule(authorityRule1.convert(&allStrings))
-	expectedWorld.AddRule(authorityRule2.convert(&allStrings))
-	expectedWorld.AddRule(
-		blockRule.convert(&allStrings),
-	)
+	expectedWorld.AddFactWithOrigin(authorityFact1.convert(&allStrings), authorityOrigin)
+	expectedWorld.AddFactWithOrigin(authorityFact2.convert(&allStrings), authorityOrigin)
+	expectedWorld.AddFactWithOrigin(blockFact.convert(&allStrings), datalog.NewOrigin(1))
+	expectedWorld.AddRuleWithOrigin(authorityRule1.convert(&allStrings), 0, authorityTrusted)
+	expectedWorld.AddRuleWithOrigin(authorityRule2.convert(&allStrings), 0, authorityTrusted)
+	expectedWorld.AddRuleWithOrigin(blockRule.convert(&allStrings), 1, datalog.NewOrigin(0, 1, datalog.AuthorizerOrigin))
 	require.Equal(t, expectedWorld, world)
 }
 
@@ -427,6 +493,97 @@ func TestAppendErrors(t *testing.T) {
 	})
 }
 
+func TestAttenuate(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("file1"), String("read")}}})
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	expiration := time.Now().Add(time.Hour)
+	serialized, err := b.Attenuate(rng, func(block BlockBuilder) {
+		require.NoError(t, block.AddCheck(Check{Queries: []Rule{
+			{
+				Head: Predicate{Name: "query", IDs: []Term{}},
+				Body: []Predicate{{Name: "time", IDs: []Term{Variable("time")}}},
+				Expressions: []Expression{
+					{
+						Value{Variable("time")},
+						Value{Date(expiration)},
+						BinaryLessOrEqual,
+					},
+				},
+			},
+		}}))
+	})
+	require.NoError(t, err)
+
+	attenuated, err := Unmarshal(serialized)
+	require.NoError(t, err)
+	require.Equal(t, 1, attenuated.BlockCount())
+
+	for _, tc := range []struct {
+		name  string
+		now   time.Time
+		valid bool
+	}{
+		{name: "before expiration", now: time.Now(), valid: true},
+		{name: "after expiration", now: expiration.Add(time.Minute), valid: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := attenuated.Authorizer(publicRoot)
+			require.NoError(t, err)
+			v.AddFact(Fact{Predicate: Predicate{Name: "time", IDs: []Term{Date(tc.now)}}})
+			v.AddPolicy(DefaultAllowPolicy)
+
+			if tc.valid {
+				require.NoError(t, v.Authorize())
+			} else {
+				require.Error(t, v.Authorize())
+			}
+		})
+	}
+
+	t.Run("append error", func(t *testing.T) {
+		sealed, err := b.Seal(rng)
+		require.NoError(t, err)
+
+		_, err = sealed.Attenuate(rng, func(block BlockBuilder) {})
+		require.Error(t, err)
+	})
+}
+
+func TestMaxBlocks(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	for i := 0; i < 9; i++ {
+		b, err = b.Append(rng, b.CreateBlock().Build())
+		require.NoError(t, err)
+	}
+
+	t.Run("within limit", func(t *testing.T) {
+		v, err := b.AuthorizerFor(WithSingularRootPublicKey(publicRoot), WithMaxBlocks(10))
+		require.NoError(t, err)
+		v.AddPolicy(DefaultAllowPolicy)
+		require.NoError(t, v.Authorize())
+	})
+
+	t.Run("over limit", func(t *testing.T) {
+		_, err := b.AuthorizerFor(WithSingularRootPublicKey(publicRoot), WithMaxBlocks(5))
+		require.ErrorIs(t, err, ErrTooManyBlocks)
+
+		_, err = b.Authorizer(publicRoot, WithMaxBlocks(5))
+		require.ErrorIs(t, err, ErrTooManyBlocks)
+	})
+}
+
 func TestNewErrors(t *testing.T) {
 	rng := rand.Reader
 
@@ -439,6 +596,25 @@ func TestNewErrors(t *testing.T) {
 	})
 }
 
+func TestNewDeterministic(t *testing.T) {
+	_, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	build := func(seed []byte) []byte {
+		builder := NewBlockBuilder(defaultSymbolTable.Clone())
+		require.NoError(t, builder.AddFact(Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("/a"), String("read")}}}))
+		block := builder.Build()
+
+		b, err := NewDeterministic(seed, privateRoot, defaultSymbolTable, block)
+		require.NoError(t, err)
+		serialized, err := b.Serialize()
+		require.NoError(t, err)
+		return serialized
+	}
+
+	require.Equal(t, build([]byte("seed")), build([]byte("seed")))
+	require.NotEqual(t, build([]byte("seed")), build([]byte("other seed")))
+}
+
 func TestBiscuitVerifyErrors(t *testing.T) {
 	rng := rand.Reader
 	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
@@ -616,3 +792,1631 @@ func TestInvalidRuleGeneration(t *testing.T) {
 	t.Log(verifier.PrintWorld())
 	require.Error(t, err)
 }
+
+func TestBiscuitScopes(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+	externalKey, _, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "authority_fact", IDs: []Term{Integer(1)}}})
+	b1, err := builder.Build()
+	require.NoError(t, err)
+
+	block2 := b1.CreateBlock()
+	block2.AddFact(Fact{Predicate: Predicate{Name: "block1_fact", IDs: []Term{Integer(1)}}})
+	b2, err := b1.Append(rng, block2.Build())
+	require.NoError(t, err)
+
+	check := func(scope ...Scope) Check {
+		return Check{Queries: []Rule{{
+			Head:  Predicate{Name: "query"},
+			Body:  []Predicate{{Name: "block1_fact", IDs: []Term{Variable("var")}}},
+			Scope: scope,
+		}}}
+	}
+
+	testCases := []struct {
+		desc    string
+		check   Check
+		success bool
+	}{
+		{
+			desc:    "default scope",
+			check:   check(),
+			success: false,
+		},
+		{
+			desc:    "trusting authority",
+			check:   check(Scope{Type: ScopeTypeAuthority}),
+			success: false,
+		},
+		{
+			desc:    "trusting previous",
+			check:   check(Scope{Type: ScopeTypePrevious}),
+			success: true,
+		},
+		{
+			desc:    "trusting an external key",
+			check:   check(Scope{Type: ScopeTypePublicKey, PublicKey: externalKey}),
+			success: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			block3 := b2.CreateBlock()
+			require.NoError(t, block3.AddCheck(tc.check))
+			b3, err := b2.Append(rng, block3.Build())
+			require.NoError(t, err)
+
+			serialized, err := b3.Serialize()
+			require.NoError(t, err)
+			b3, err = Unmarshal(serialized)
+			require.NoError(t, err)
+			require.Equal(t, tc.check.Queries[0].Scope, func() []Scope {
+				c, err := fromDatalogCheck(b3.symbols, b3.blocks[1].checks[0])
+				require.NoError(t, err)
+				return c.Queries[0].Scope
+			}())
+
+			v, err := b3.AuthorizerFor(WithSingularRootPublicKey(publicRoot))
+			require.NoError(t, err)
+			v.AddPolicy(DefaultAllowPolicy)
+			if tc.success {
+				require.NoError(t, v.Authorize())
+			} else {
+				require.Error(t, v.Authorize())
+			}
+		})
+	}
+}
+
+func TestBiscuitPublicKeys(t *testing.T) {
+	rng := rand.Reader
+	_, privateRoot, _ := ed25519.GenerateKey(rng)
+	externalPublic, externalPrivate, _ := ed25519.GenerateKey(rng)
+
+	b, err := NewBuilder(privateRoot).Build()
+	require.NoError(t, err)
+
+	b, err = b.Append(rng, b.CreateBlock().Build())
+	require.NoError(t, err)
+
+	request, err := b.ThirdPartyRequest()
+	require.NoError(t, err)
+	thirdPartyBlock, err := request.Sign(externalPrivate, request.CreateBlock().Build())
+	require.NoError(t, err)
+	b, err = b.AppendThirdParty(rng, thirdPartyBlock)
+	require.NoError(t, err)
+
+	b, err = b.Append(rng, b.CreateBlock().Build())
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = Unmarshal(serialized)
+	require.NoError(t, err)
+
+	publicKeys := b.PublicKeys()
+	require.Len(t, publicKeys, 4)
+	require.Equal(t, ed25519.PublicKey(b.container.Authority.NextKey.Key), publicKeys[0])
+	for i, block := range b.container.Blocks {
+		require.Equal(t, ed25519.PublicKey(block.NextKey.Key), publicKeys[i+1])
+	}
+
+	externalKeys := b.ExternalKeys()
+	require.Len(t, externalKeys, 4)
+	require.Nil(t, externalKeys[0])
+	require.Nil(t, externalKeys[1])
+	require.NotNil(t, externalKeys[2])
+	require.Equal(t, externalPublic, *externalKeys[2])
+	require.Nil(t, externalKeys[3])
+}
+
+func TestBiscuitCollections(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{
+		Name: "config",
+		IDs: []Term{
+			Map{String("owner"): String("alice"), Integer(1): Array{String("read"), String("write")}},
+		},
+	}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+	require.Equal(t, collectionsSchemaVersion, b.authority.version)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = Unmarshal(serialized)
+	require.NoError(t, err)
+
+	v, err := b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddCheck(Check{Queries: []Rule{{
+		Head: Predicate{Name: "query"},
+		Body: []Predicate{{Name: "config", IDs: []Term{Variable("config")}}},
+		Expressions: []Expression{
+			{
+				Value{Variable("config")},
+				Value{String("owner")},
+				BinaryGet,
+				Value{String("alice")},
+				BinaryEqual,
+			},
+			{
+				Value{Variable("config")},
+				Value{Integer(1)},
+				BinaryGet,
+				Value{Integer(0)},
+				BinaryGet,
+				Value{String("read")},
+				BinaryEqual,
+			},
+		},
+	}}})
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+
+	t.Run("invalid map keys", func(t *testing.T) {
+		invalid := Map{Date(time.Unix(0, 0)): Integer(1)}
+		builder := NewBuilder(privateRoot)
+		require.ErrorIs(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "config", IDs: []Term{Array{invalid}}}}), ErrInvalidMapKey)
+		require.ErrorIs(t, builder.AddAuthorityRule(Rule{
+			Head:        Predicate{Name: "config", IDs: []Term{Variable("c")}},
+			Body:        []Predicate{{Name: "config", IDs: []Term{Variable("c")}}},
+			Expressions: []Expression{{Value{Variable("c")}, Value{Map{String("a"): invalid}}, BinaryEqual}},
+		}), ErrInvalidMapKey)
+
+		block := b.CreateBlock()
+		require.ErrorIs(t, block.AddCheck(Check{Queries: []Rule{{
+			Head: Predicate{Name: "query"},
+			Body: []Predicate{{Name: "config", IDs: []Term{Map{Bool(true): Integer(1)}}}},
+		}}}), ErrInvalidMapKey)
+	})
+}
+
+func TestBuilderSchemaVersion(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	fact := Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("/a")}}}
+	scopedCheck := Check{Queries: []Rule{{
+		Head:  Predicate{Name: "query"},
+		Body:  []Predicate{{Name: "right", IDs: []Term{String("/a")}}},
+		Scope: []Scope{{Type: ScopeTypeAuthority}},
+	}}}
+
+	t.Run("pinned to a lower version", func(t *testing.T) {
+		builder := NewBuilder(privateRoot, WithSchemaVersion(MinSchemaVersion))
+		require.NoError(t, builder.AddAuthorityFact(fact))
+		b, err := builder.Build()
+		require.NoError(t, err)
+		require.Equal(t, MinSchemaVersion, b.authority.version)
+
+		serialized, err := b.Serialize()
+		require.NoError(t, err)
+		b, err = Unmarshal(serialized)
+		require.NoError(t, err)
+		require.Equal(t, MinSchemaVersion, b.authority.version)
+		_, err = b.Authorizer(publicRoot)
+		require.NoError(t, err)
+	})
+
+	t.Run("pinned to a higher version", func(t *testing.T) {
+		builder := NewBuilder(privateRoot, WithSchemaVersion(MaxSchemaVersion))
+		require.NoError(t, builder.AddAuthorityFact(fact))
+		b, err := builder.Build()
+		require.NoError(t, err)
+		require.Equal(t, MaxSchemaVersion, b.authority.version)
+	})
+
+	t.Run("block requires a higher version", func(t *testing.T) {
+		builder := NewBuilder(privateRoot, WithSchemaVersion(MinSchemaVersion))
+		require.NoError(t, builder.AddAuthorityFact(fact))
+		require.NoError(t, builder.AddAuthorityCheck(scopedCheck))
+		_, err := builder.Build()
+		require.ErrorIs(t, err, ErrSchemaVersionTooLow)
+	})
+
+	t.Run("not equal requires a higher version", func(t *testing.T) {
+		notEqualCheck := Check{Queries: []Rule{{
+			Head: Predicate{Name: "query"},
+			Body: []Predicate{{Name: "right", IDs: []Term{Variable("r")}}},
+			Expressions: []Expression{{
+				Value{Term: Variable("r")},
+				Value{Term: String("/b")},
+				BinaryNotEqual,
+			}},
+		}}}
+
+		builder := NewBuilder(privateRoot)
+		require.NoError(t, builder.AddAuthorityFact(fact))
+		require.NoError(t, builder.AddAuthorityCheck(notEqualCheck))
+		b, err := builder.Build()
+		require.NoError(t, err)
+		require.Equal(t, notEqualSchemaVersion, b.authority.version)
+
+		serialized, err := b.Serialize()
+		require.NoError(t, err)
+		b, err = Unmarshal(serialized)
+		require.NoError(t, err)
+		v, err := b.Authorizer(publicRoot)
+		require.NoError(t, err)
+		v.AddPolicy(DefaultAllowPolicy)
+		require.NoError(t, v.Authorize())
+
+		builder = NewBuilder(privateRoot, WithSchemaVersion(MinSchemaVersion))
+		require.NoError(t, builder.AddAuthorityCheck(notEqualCheck))
+		_, err = builder.Build()
+		require.ErrorIs(t, err, ErrSchemaVersionTooLow)
+	})
+
+	t.Run("check all requires a higher version", func(t *testing.T) {
+		checkAll := Check{Kind: CheckKindAll, Queries: []Rule{{
+			Head: Predicate{Name: "query"},
+			Body: []Predicate{{Name: "right", IDs: []Term{Variable("r")}}},
+		}}}
+
+		builder := NewBuilder(privateRoot)
+		require.NoError(t, builder.AddAuthorityCheck(checkAll))
+		b, err := builder.Build()
+		require.NoError(t, err)
+		require.Equal(t, checkAllSchemaVersion, b.authority.version)
+
+		builder = NewBuilder(privateRoot, WithSchemaVersion(MinSchemaVersion))
+		require.NoError(t, builder.AddAuthorityCheck(checkAll))
+		_, err = builder.Build()
+		require.ErrorIs(t, err, ErrSchemaVersionTooLow)
+	})
+
+	t.Run("reject if requires a higher version", func(t *testing.T) {
+		reject := Check{Kind: CheckKindReject, Queries: []Rule{{
+			Head: Predicate{Name: "query"},
+			Body: []Predicate{{Name: "right", IDs: []Term{Variable("r")}}},
+		}}}
+
+		builder := NewBuilder(privateRoot)
+		require.NoError(t, builder.AddAuthorityCheck(reject))
+		b, err := builder.Build()
+		require.NoError(t, err)
+		require.Equal(t, uint32(6), b.authority.version)
+
+		builder = NewBuilder(privateRoot, WithSchemaVersion(4))
+		require.NoError(t, builder.AddAuthorityCheck(reject))
+		_, err = builder.Build()
+		require.ErrorIs(t, err, ErrSchemaVersionTooLow)
+	})
+
+	t.Run("operations", func(t *testing.T) {
+		testCases := []struct {
+			desc    string
+			expr    Expression
+			version uint32
+		}{
+			{desc: "bitwise and", expr: Expression{Value{Integer(3)}, Value{Integer(1)}, BinaryBitwiseAnd, Value{Integer(1)}, BinaryEqual}, version: 4},
+			{desc: "subset", expr: Expression{Value{Set{Integer(1)}}, Value{Set{Integer(1)}}, BinarySubset}, version: extensionsSchemaVersion},
+			{desc: "superset", expr: Expression{Value{Set{Integer(1)}}, Value{Set{Integer(1)}}, BinarySuperset}, version: extensionsSchemaVersion},
+			{desc: "matches insensitive", expr: Expression{Value{String("A")}, Value{String("a")}, BinaryRegexInsensitive}, version: extensionsSchemaVersion},
+			{desc: "char length", expr: Expression{Value{String("a")}, UnaryCharLength, Value{Integer(1)}, BinaryEqual}, version: extensionsSchemaVersion},
+			{desc: "to date", expr: Expression{Value{Integer(0)}, UnaryToDate, Value{Date(time.Unix(0, 0))}, BinaryEqual}, version: extensionsSchemaVersion},
+			{desc: "to int", expr: Expression{Value{Date(time.Unix(0, 0))}, UnaryToInt, Value{Integer(0)}, BinaryEqual}, version: extensionsSchemaVersion},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.desc, func(t *testing.T) {
+				check := Check{Queries: []Rule{{
+					Head:        Predicate{Name: "query"},
+					Expressions: []Expression{tc.expr},
+				}}}
+
+				builder := NewBuilder(privateRoot)
+				require.NoError(t, builder.AddAuthorityCheck(check))
+				b, err := builder.Build()
+				require.NoError(t, err)
+				require.Equal(t, tc.version, b.authority.version)
+
+				builder = NewBuilder(privateRoot, WithSchemaVersion(tc.version-1))
+				require.NoError(t, builder.AddAuthorityCheck(check))
+				_, err = builder.Build()
+				require.ErrorIs(t, err, ErrSchemaVersionTooLow)
+			})
+		}
+	})
+
+	t.Run("tagged terms require the extensions version", func(t *testing.T) {
+		tagged := Fact{Predicate: Predicate{Name: "id", IDs: []Term{Set{Tagged{Tag: "uuid", Value: Bytes{1}}}}}}
+
+		builder := NewBuilder(privateRoot)
+		require.NoError(t, builder.AddAuthorityFact(tagged))
+		b, err := builder.Build()
+		require.NoError(t, err)
+		require.Equal(t, extensionsSchemaVersion, b.authority.version)
+	})
+
+	t.Run("unsupported versions", func(t *testing.T) {
+		for _, version := range []uint32{MinSchemaVersion - 1, MaxSchemaVersion + 1} {
+			_, err := NewBuilder(privateRoot, WithSchemaVersion(version)).Build()
+			require.ErrorIs(t, err, ErrUnsupportedSchemaVersion)
+		}
+	})
+}
+
+func TestForEachBlock(t *testing.T) {
+	rng := rand.Reader
+	_, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("/a")}}}))
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("/b")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	block := b.CreateBlock()
+	require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "group", IDs: []Term{String("admin")}}}))
+	b, err = b.Append(rng, block.Build())
+	require.NoError(t, err)
+	b, err = b.Append(rng, b.CreateBlock().Build())
+	require.NoError(t, err)
+
+	var indexes []int
+	factCount := 0
+	require.NoError(t, b.ForEachBlock(func(index int, blk *Block) error {
+		indexes = append(indexes, index)
+		factCount += len(*blk.facts)
+		return nil
+	}))
+	require.Equal(t, []int{0, 1, 2}, indexes)
+	require.Equal(t, 3, factCount)
+
+	errStop := errors.New("stop")
+	indexes = nil
+	err = b.ForEachBlock(func(index int, blk *Block) error {
+		indexes = append(indexes, index)
+		if index == 1 {
+			return errStop
+		}
+		return nil
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, []int{0, 1}, indexes)
+}
+
+func TestEmptyAuthorityBlock(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	fromBuilder, err := NewBuilder(privateRoot).Build()
+	require.NoError(t, err)
+	serialized, err := fromBuilder.Serialize()
+	require.NoError(t, err)
+	fromBuilder, err = Unmarshal(serialized)
+	require.NoError(t, err)
+
+	// a zero Block has no symbol table, fact set or version
+	fromZeroBlock, err := New(rng, privateRoot, defaultSymbolTable, &Block{})
+	require.NoError(t, err)
+	fromZeroBlock, err = fromZeroBlock.Append(rng, &Block{})
+	require.NoError(t, err)
+	serialized, err = fromZeroBlock.Serialize()
+	require.NoError(t, err)
+	fromZeroBlock, err = Unmarshal(serialized)
+	require.NoError(t, err)
+
+	for name, b := range map[string]*Biscuit{"builder": fromBuilder, "zero block": fromZeroBlock} {
+		t.Run(name, func(t *testing.T) {
+			world, err := b.generateWorld(b.symbols)
+			require.NoError(t, err)
+			require.Empty(t, *world.Facts())
+
+			v, err := b.Authorizer(publicRoot)
+			require.NoError(t, err)
+			require.Equal(t, ErrNoMatchingPolicy, v.Authorize())
+
+			v.Reset()
+			v.AddPolicy(DefaultDenyPolicy)
+			require.Equal(t, ErrPolicyDenied, v.Authorize())
+
+			v.Reset()
+			v.AddPolicy(DefaultAllowPolicy)
+			require.NoError(t, v.Authorize())
+		})
+	}
+}
+
+func TestBlockContext(t *testing.T) {
+	rng := rand.Reader
+	_, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	builder.SetContext("authority")
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	block := b.CreateBlock()
+	block.SetContext("gw-1")
+	require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "issuer", IDs: []Term{String("gw-1")}}}))
+	b, err = b.Append(rng, block.Build())
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = Unmarshal(serialized)
+	require.NoError(t, err)
+
+	var contexts []string
+	require.NoError(t, b.ForEachBlock(func(_ int, blk *Block) error {
+		contexts = append(contexts, blk.Context())
+		return nil
+	}))
+	require.Equal(t, []string{"authority", "gw-1"}, contexts)
+	require.Equal(t, "authority", b.GetContext())
+	require.Contains(t, b.String(), `context: "gw-1"`)
+}
+
+func TestUnmarshalAndVerify(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("/a")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+	b, err = b.Append(rng, b.CreateBlock().Build())
+	require.NoError(t, err)
+
+	data, err := b.Serialize()
+	require.NoError(t, err)
+
+	verified, err := UnmarshalAndVerify(data, WithSingularRootPublicKey(publicRoot))
+	require.NoError(t, err)
+	require.Equal(t, b.Code(), verified.Code())
+
+	publicNotRoot, _, _ := ed25519.GenerateKey(rng)
+	_, err = UnmarshalAndVerify(data, WithSingularRootPublicKey(publicNotRoot))
+	require.Equal(t, ErrInvalidSignature, err)
+
+	_, err = UnmarshalAndVerify(data, WithRootPublicKeys(nil, nil))
+	require.ErrorIs(t, err, ErrNoPublicKeyAvailable)
+
+	container := new(pb.Biscuit)
+	require.NoError(t, proto.Unmarshal(data, container))
+	container.Blocks[0].Signature[0] ^= 1
+	tampered, err := proto.Marshal(container)
+	require.NoError(t, err)
+
+	_, err = UnmarshalAndVerify(tampered, WithSingularRootPublicKey(publicRoot))
+	require.Equal(t, ErrInvalidSignature, err)
+}
+
+func TestFactsByName(t *testing.T) {
+	rng := rand.Reader
+	_, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	right := func(resource, operation string) Fact {
+		return Fact{Predicate: Predicate{Name: "right", IDs: []Term{String(resource), String(operation)}}}
+	}
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(right("/a", "read")))
+	require.NoError(t, builder.AddAuthorityFact(right("/b", "read")))
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "owner", IDs: []Term{String("/c")}}}))
+	// owners can write their resources
+	require.NoError(t, builder.AddAuthorityRule(Rule{
+		Head: Predicate{Name: "right", IDs: []Term{Variable("r"), String("write")}},
+		Body: []Predicate{{Name: "owner", IDs: []Term{Variable("r")}}},
+	}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	block := b.CreateBlock()
+	require.NoError(t, block.AddFact(right("/a", "write")))
+	// the authority rule does not trust this block, so /e is not writable
+	require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "owner", IDs: []Term{String("/e")}}}))
+	b, err = b.Append(rng, block.Build())
+	require.NoError(t, err)
+
+	// third party blocks are decoded with their own symbol table
+	_, externalPrivate, _ := ed25519.GenerateKey(rng)
+	request, err := b.ThirdPartyRequest()
+	require.NoError(t, err)
+	thirdPartyBlock := request.CreateBlock()
+	require.NoError(t, thirdPartyBlock.AddFact(right("/d", "read")))
+	signed, err := request.Sign(externalPrivate, thirdPartyBlock.Build())
+	require.NoError(t, err)
+	b, err = b.AppendThirdParty(rng, signed)
+	require.NoError(t, err)
+
+	facts, err := b.FactsByName("right")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []Fact{
+		right("/a", "read"),
+		right("/b", "read"),
+		right("/a", "write"),
+		right("/c", "write"),
+		right("/d", "read"),
+	}, facts)
+
+	facts, err = b.FactsByName("unknown")
+	require.NoError(t, err)
+	require.Empty(t, facts)
+}
+
+func TestVerifyChain(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	b, err := NewBuilder(privateRoot).Build()
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		b, err = b.Append(rng, b.CreateBlock().Build())
+		require.NoError(t, err)
+	}
+
+	var indexes []int
+	require.NoError(t, b.VerifyChain(WithSingularRootPublicKey(publicRoot), func(index int) error {
+		indexes = append(indexes, index)
+		return nil
+	}))
+	require.Equal(t, []int{0, 1, 2, 3}, indexes)
+
+	require.NoError(t, b.VerifyChain(WithSingularRootPublicKey(publicRoot), nil))
+
+	errStop := errors.New("stop")
+	indexes = nil
+	err = b.VerifyChain(WithSingularRootPublicKey(publicRoot), func(index int) error {
+		indexes = append(indexes, index)
+		if index == 1 {
+			return errStop
+		}
+		return nil
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, []int{0, 1}, indexes)
+
+	// the callback is not called for blocks after an invalid signature
+	b.container.Blocks[1].Signature[0] ^= 1
+	indexes = nil
+	err = b.VerifyChain(WithSingularRootPublicKey(publicRoot), func(index int) error {
+		indexes = append(indexes, index)
+		return nil
+	})
+	require.Equal(t, ErrInvalidSignature, err)
+	require.Equal(t, []int{0, 1}, indexes)
+
+	publicNotRoot, _, _ := ed25519.GenerateKey(rng)
+	indexes = nil
+	err = b.VerifyChain(WithSingularRootPublicKey(publicNotRoot), func(index int) error {
+		indexes = append(indexes, index)
+		return nil
+	})
+	require.Equal(t, ErrInvalidSignature, err)
+	require.Empty(t, indexes)
+}
+
+func TestFromContainer(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("/a")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+	block := b.CreateBlock()
+	require.NoError(t, block.AddCheck(Check{Queries: []Rule{{
+		Head: Predicate{Name: "query"},
+		Body: []Predicate{{Name: "right", IDs: []Term{Variable("r")}}},
+	}}}))
+	b, err = b.Append(rng, block.Build())
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	container := new(pb.Biscuit)
+	require.NoError(t, proto.Unmarshal(serialized, container))
+
+	fromContainer, err := FromContainer(container)
+	require.NoError(t, err)
+	require.Equal(t, b.String(), fromContainer.String())
+	require.Equal(t, b.Code(), fromContainer.Code())
+	require.Equal(t, b.RevocationIds(), fromContainer.RevocationIds())
+
+	fromContainerSerialized, err := fromContainer.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, serialized, fromContainerSerialized)
+
+	require.NoError(t, fromContainer.VerifyChain(WithSingularRootPublicKey(publicRoot), nil))
+	v, err := fromContainer.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+
+	// the container is copied
+	container.Authority.Signature[0] ^= 1
+	require.NoError(t, fromContainer.VerifyChain(WithSingularRootPublicKey(publicRoot), nil))
+	tampered, err := FromContainer(container)
+	require.NoError(t, err)
+	require.Equal(t, ErrInvalidSignature, tampered.VerifyChain(WithSingularRootPublicKey(publicRoot), nil))
+
+	_, err = FromContainer(nil)
+	require.ErrorIs(t, err, ErrInvalidContainer)
+	_, err = FromContainer(&pb.Biscuit{Proof: container.Proof})
+	require.ErrorIs(t, err, ErrInvalidContainer)
+	_, err = FromContainer(&pb.Biscuit{Authority: container.Authority})
+	require.ErrorIs(t, err, ErrInvalidContainer)
+	_, err = FromContainer(&pb.Biscuit{Authority: &pb.SignedBlock{}, Proof: container.Proof})
+	require.ErrorIs(t, err, ErrInvalidContainer)
+
+	noAlgorithm := proto.Clone(container).(*pb.Biscuit)
+	noAlgorithm.Authority.NextKey.Algorithm = nil
+	_, err = FromContainer(noAlgorithm)
+	require.ErrorIs(t, err, ErrInvalidContainer)
+
+	unknownAlgorithm := proto.Clone(container).(*pb.Biscuit)
+	algorithm := pb.PublicKey_Algorithm(42)
+	unknownAlgorithm.Blocks[0].NextKey.Algorithm = &algorithm
+	_, err = FromContainer(unknownAlgorithm)
+	require.ErrorIs(t, err, UnsupportedAlgorithm)
+}
+
+func TestLegacyAuthorityIndex(t *testing.T) {
+	rng := rand.Reader
+	_, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("/a")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	container := new(pb.Biscuit)
+	require.NoError(t, proto.Unmarshal(serialized, container))
+	authority := container.Authority.Block
+
+	for _, tc := range []struct {
+		desc  string
+		index uint64
+		err   error
+	}{
+		{desc: "index 0", index: 0},
+		{desc: "index 1", index: 1, err: ErrInvalidAuthorityIndex},
+		{desc: "index 42", index: 42, err: ErrInvalidAuthorityIndex},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			// the index of the previous formats was the field 1, as a varint
+			legacy := protowire.AppendTag(nil, 1, protowire.VarintType)
+			legacy = protowire.AppendVarint(legacy, tc.index)
+			container.Authority.Block = append(legacy, authority...)
+
+			_, err := FromContainer(container)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAppendAll(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "company", IDs: []Term{String("acme")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	sequentialRng := newDeterministicReader([]byte("append"))
+	sequential := b
+	var blocks []*Block
+	for _, project := range []string{"apollo", "gemini", "mercury"} {
+		block := sequential.CreateBlock()
+		require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "project", IDs: []Term{String(project)}}}))
+		require.NoError(t, block.AddCheck(Check{Queries: []Rule{{
+			Head: Predicate{Name: "query"},
+			Body: []Predicate{{Name: "company", IDs: []Term{String("acme")}}},
+		}}}))
+		blocks = append(blocks, block.Build())
+		sequential, err = sequential.Append(sequentialRng, blocks[len(blocks)-1])
+		require.NoError(t, err)
+	}
+
+	all, err := b.AppendAll(newDeterministicReader([]byte("append")), blocks...)
+	require.NoError(t, err)
+	require.Len(t, all.blocks, 3)
+
+	expected, err := sequential.Serialize()
+	require.NoError(t, err)
+	serialized, err := all.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, expected, serialized)
+
+	// the original token is not modified
+	require.Empty(t, b.blocks)
+	require.Empty(t, b.container.Blocks)
+
+	v, err := all.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+
+	// the appended token can be attenuated again
+	block := all.CreateBlock()
+	require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "project", IDs: []Term{String("venus")}}}))
+	_, err = all.Append(rng, block.Build())
+	require.NoError(t, err)
+
+	// both blocks define the "project" and "apollo" symbols
+	first := b.CreateBlock()
+	require.NoError(t, first.AddFact(Fact{Predicate: Predicate{Name: "project", IDs: []Term{String("apollo")}}}))
+	second := b.CreateBlock()
+	require.NoError(t, second.AddFact(Fact{Predicate: Predicate{Name: "project", IDs: []Term{String("apollo")}}}))
+	_, err = b.AppendAll(rng, first.Build(), second.Build())
+	require.ErrorIs(t, err, ErrSymbolTableOverlap)
+
+	_, err = b.AppendAll(rng)
+	require.Error(t, err)
+
+	sealed, err := b.Seal(rng)
+	require.NoError(t, err)
+	_, err = sealed.AppendAll(rng, blocks...)
+	require.Error(t, err)
+}
+
+func TestWithoutLastBlock(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "company", IDs: []Term{String("acme")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	_, err = b.WithoutLastBlock()
+	require.Error(t, err)
+
+	appendProject := func(b *Biscuit, project string) *Biscuit {
+		block := b.CreateBlock()
+		require.NoError(t, block.AddCheck(Check{Queries: []Rule{{
+			Head: Predicate{Name: "query"},
+			Body: []Predicate{{Name: "project", IDs: []Term{String(project)}}},
+		}}}))
+		attenuated, err := b.Append(rng, block.Build())
+		require.NoError(t, err)
+		return attenuated
+	}
+
+	apollo := appendProject(b, "apollo")
+	both := appendProject(apollo, "gemini")
+
+	// removing the last block restores the previous token, proof included
+	rolledBack, err := both.WithoutLastBlock()
+	require.NoError(t, err)
+	expected, err := apollo.Serialize()
+	require.NoError(t, err)
+	serialized, err := rolledBack.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, expected, serialized)
+	require.Equal(t, apollo.symbols, rolledBack.symbols)
+	require.Len(t, both.blocks, 2)
+
+	rolledBack, err = rolledBack.WithoutLastBlock()
+	require.NoError(t, err)
+	expected, err = b.Serialize()
+	require.NoError(t, err)
+	serialized, err = rolledBack.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, expected, serialized)
+
+	// the check of the removed block is not applied anymore, and the
+	// rolled back token can be attenuated with the same symbols again
+	rolledBack, err = both.WithoutLastBlock()
+	require.NoError(t, err)
+	for _, tc := range []struct {
+		token *Biscuit
+		valid bool
+	}{
+		{token: both, valid: false},
+		{token: rolledBack, valid: true},
+		{token: appendProject(rolledBack, "gemini"), valid: false},
+	} {
+		v, err := tc.token.Authorizer(publicRoot)
+		require.NoError(t, err)
+		v.AddFact(Fact{Predicate: Predicate{Name: "project", IDs: []Term{String("apollo")}}})
+		v.AddPolicy(DefaultAllowPolicy)
+		if tc.valid {
+			require.NoError(t, v.Authorize())
+		} else {
+			require.Error(t, v.Authorize())
+		}
+	}
+
+	// the secrets of the blocks appended together are kept
+	blocks := []*Block{}
+	symbols := b.symbols.Clone()
+	for _, project := range []string{"apollo", "gemini"} {
+		block := NewBlockBuilder(symbols.Clone())
+		require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "project", IDs: []Term{String(project)}}}))
+		blocks = append(blocks, block.Build())
+		symbols.Extend(blocks[len(blocks)-1].symbols)
+	}
+	all, err := b.AppendAll(newDeterministicReader([]byte("rollback")), blocks...)
+	require.NoError(t, err)
+	first, err := b.Append(newDeterministicReader([]byte("rollback")), blocks[0])
+	require.NoError(t, err)
+	rolledBack, err = all.WithoutLastBlock()
+	require.NoError(t, err)
+	expected, err = first.Serialize()
+	require.NoError(t, err)
+	serialized, err = rolledBack.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, expected, serialized)
+
+	// the secret of the previous block is not part of the serialized token
+	serialized, err = both.Serialize()
+	require.NoError(t, err)
+	unmarshalled, err := Unmarshal(serialized)
+	require.NoError(t, err)
+	_, err = unmarshalled.WithoutLastBlock()
+	require.ErrorIs(t, err, ErrPreviousSecretUnavailable)
+
+	// blocks appended to an unmarshalled token can be removed, but not the ones before
+	appended := appendProject(unmarshalled, "mercury")
+	rolledBack, err = appended.WithoutLastBlock()
+	require.NoError(t, err)
+	_, err = rolledBack.WithoutLastBlock()
+	require.ErrorIs(t, err, ErrPreviousSecretUnavailable)
+
+	sealed, err := both.Seal(rng)
+	require.NoError(t, err)
+	_, err = sealed.WithoutLastBlock()
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrPreviousSecretUnavailable)
+}
+
+func TestToJSON(t *testing.T) {
+	rng := rand.Reader
+	_, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot, WithRootKeyID(7))
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "company", IDs: []Term{String("acme")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	out, err := b.ToJSON()
+	require.NoError(t, err)
+	require.Contains(t, string(out), `"root_key_id":7`)
+
+	block := b.CreateBlock()
+	block.SetContext("attenuation")
+	require.NoError(t, block.AddRule(Rule{
+		Head: Predicate{Name: "project", IDs: []Term{Variable("c")}},
+		Body: []Predicate{{Name: "company", IDs: []Term{Variable("c")}}},
+	}))
+	require.NoError(t, block.AddCheck(Check{Queries: []Rule{{
+		Head: Predicate{Name: "query"},
+		Body: []Predicate{{Name: "project", IDs: []Term{String("acme")}}},
+	}}}))
+	b, err = b.Append(rng, block.Build())
+	require.NoError(t, err)
+
+	out, err = b.ToJSON()
+	require.NoError(t, err)
+	require.True(t, json.Valid(out))
+
+	var doc struct {
+		RootKeyID *uint32 `json:"root_key_id"`
+		Blocks    []struct {
+			Symbols []string `json:"symbols"`
+			Context string   `json:"context"`
+			Facts   []string `json:"facts"`
+			Rules   []string `json:"rules"`
+			Checks  []string `json:"checks"`
+		} `json:"blocks"`
+		RevocationIDs []string `json:"revocation_ids"`
+	}
+	require.NoError(t, json.Unmarshal(out, &doc))
+
+	require.Len(t, doc.Blocks, 2)
+	require.Equal(t, []string{"acme", "company"}, doc.Blocks[0].Symbols)
+	require.Equal(t, []string{`company("acme")`}, doc.Blocks[0].Facts)
+	require.Empty(t, doc.Blocks[0].Rules)
+	require.Empty(t, doc.Blocks[0].Checks)
+	require.Equal(t, "attenuation", doc.Blocks[1].Context)
+	require.Empty(t, doc.Blocks[1].Facts)
+	require.Equal(t, []string{`project($c) <- company($c)`}, doc.Blocks[1].Rules)
+	require.Equal(t, []string{`check if project("acme")`}, doc.Blocks[1].Checks)
+
+	require.Len(t, doc.RevocationIDs, 2)
+	for i, id := range b.RevocationIds() {
+		require.Equal(t, hex.EncodeToString(id), doc.RevocationIDs[i])
+	}
+}
+
+func TestValidateStructure(t *testing.T) {
+	rng := rand.Reader
+	_, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	b, err := NewBuilder(privateRoot).Build()
+	require.NoError(t, err)
+	b, err = b.Append(rng, b.CreateBlock().Build())
+	require.NoError(t, err)
+	sealed, err := b.Seal(rng)
+	require.NoError(t, err)
+
+	for _, token := range []*Biscuit{b, sealed} {
+		serialized, err := token.Serialize()
+		require.NoError(t, err)
+		require.NoError(t, ValidateStructure(serialized))
+	}
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	require.Error(t, ValidateStructure(serialized[:len(serialized)/2]))
+	require.Error(t, ValidateStructure(nil))
+
+	// modify returns the serialized token after changing a copy of its container
+	modify := func(fn func(container *pb.Biscuit)) []byte {
+		container := proto.Clone(b.container).(*pb.Biscuit)
+		fn(container)
+		// partial containers are rejected by proto.Unmarshal
+		serialized, err := proto.MarshalOptions{AllowPartial: true}.Marshal(container)
+		require.NoError(t, err)
+		return serialized
+	}
+	withVersion := func(version uint32) func(*pb.Biscuit) {
+		return func(container *pb.Biscuit) {
+			block := new(pb.Block)
+			require.NoError(t, proto.Unmarshal(container.Blocks[0].Block, block))
+			block.Version = &version
+			marshalled, err := proto.Marshal(block)
+			require.NoError(t, err)
+			container.Blocks[0].Block = marshalled
+		}
+	}
+
+	for _, tc := range []struct {
+		desc   string
+		modify func(container *pb.Biscuit)
+		err    error
+	}{
+		{
+			desc:   "short authority next key",
+			modify: func(c *pb.Biscuit) { c.Authority.NextKey.Key = c.Authority.NextKey.Key[:16] },
+			err:    ErrInvalidKeySize,
+		},
+		{
+			desc:   "long block next key",
+			modify: func(c *pb.Biscuit) { c.Blocks[0].NextKey.Key = append(c.Blocks[0].NextKey.Key, 0) },
+			err:    ErrInvalidKeySize,
+		},
+		{
+			desc:   "missing next key",
+			modify: func(c *pb.Biscuit) { c.Blocks[0].NextKey = nil },
+		},
+		{
+			desc:   "short signature",
+			modify: func(c *pb.Biscuit) { c.Blocks[0].Signature = c.Blocks[0].Signature[:32] },
+			err:    ErrInvalidSignatureSize,
+		},
+		{
+			desc:   "short next secret",
+			modify: func(c *pb.Biscuit) { c.Proof.Content = &pb.Proof_NextSecret{NextSecret: []byte{1}} },
+			err:    ErrInvalidKeySize,
+		},
+		{
+			desc:   "short final signature",
+			modify: func(c *pb.Biscuit) { c.Proof.Content = &pb.Proof_FinalSignature{FinalSignature: []byte{1}} },
+			err:    ErrInvalidSignatureSize,
+		},
+		{
+			desc:   "missing proof",
+			modify: func(c *pb.Biscuit) { c.Proof = nil },
+		},
+		{
+			desc:   "version too high",
+			modify: withVersion(MaxSchemaVersion + 1),
+			err:    ErrUnsupportedSchemaVersion,
+		},
+		{
+			desc:   "version too low",
+			modify: withVersion(MinSchemaVersion - 1),
+			err:    ErrUnsupportedSchemaVersion,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := ValidateStructure(modify(tc.modify))
+			require.Error(t, err)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+
+	// the signatures are not verified
+	invalidSignature := modify(func(c *pb.Biscuit) { c.Blocks[0].Signature[0] ^= 1 })
+	require.NoError(t, ValidateStructure(invalidSignature))
+	_, err = UnmarshalAndVerify(invalidSignature, WithSingularRootPublicKey(privateRoot.Public().(ed25519.PublicKey)))
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestUnmarshalTrusted(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("/a")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+
+	trusted, err := UnmarshalTrusted(serialized)
+	require.NoError(t, err)
+	require.Equal(t, b.Code(), trusted.Code())
+	v, err := trusted.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+
+	container := new(pb.Biscuit)
+	require.NoError(t, proto.Unmarshal(serialized, container))
+	container.Authority.Signature[0] ^= 1
+	tampered, err := proto.Marshal(container)
+	require.NoError(t, err)
+
+	// the signatures are only verified for untrusted data
+	untrusted, err := Unmarshal(tampered)
+	require.NoError(t, err)
+	_, err = untrusted.Authorizer(publicRoot)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	trusted, err = UnmarshalTrusted(tampered)
+	require.NoError(t, err)
+	v, err = trusted.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+
+	// explicit verifications and attenuated tokens are still verified
+	require.ErrorIs(t, trusted.VerifyChain(WithSingularRootPublicKey(publicRoot), nil), ErrInvalidSignature)
+	appended, err := trusted.Append(rng, trusted.CreateBlock().Build())
+	require.NoError(t, err)
+	_, err = appended.Authorizer(publicRoot)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = UnmarshalTrusted([]byte("invalid"))
+	require.Error(t, err)
+}
+
+func TestWithNextKeyPair(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+	nextPublic, nextPrivate, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot, WithNextKeyPair(nextPublic, nextPrivate))
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("/a")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	require.Equal(t, nextPrivate.Seed(), b.container.Proof.GetNextSecret())
+	require.Equal(t, []byte(nextPublic), b.container.Authority.NextKey.Key)
+
+	b, err = b.Append(rng, b.CreateBlock().Build())
+	require.NoError(t, err)
+	require.NoError(t, b.VerifyChain(WithSingularRootPublicKey(publicRoot), nil))
+
+	_, err = NewBuilder(privateRoot, WithNextKeyPair(nextPublic[:16], nextPrivate)).Build()
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	_, err = NewBuilder(privateRoot, WithNextKeyPair(nextPublic, nil)).Build()
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	otherPublic, _, _ := ed25519.GenerateKey(rng)
+	_, err = NewBuilder(privateRoot, WithNextKeyPair(otherPublic, nextPrivate)).Build()
+	require.ErrorIs(t, err, ErrKeyPairMismatch)
+
+	// a builder with another root key generates its own next key pair
+	b, err = builder.WithRootKey(privateRoot).Build()
+	require.NoError(t, err)
+	require.NotEqual(t, []byte(nextPublic), b.container.Authority.NextKey.Key)
+	require.NotEqual(t, nextPrivate.Seed(), b.container.Proof.GetNextSecret())
+}
+
+func TestAppendSigned(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("/a"), String("read")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+
+	// the block is signed by a copy of the token
+	signer, err := Unmarshal(serialized)
+	require.NoError(t, err)
+	block := signer.CreateBlock()
+	require.NoError(t, block.AddCheck(Check{Queries: []Rule{{
+		Head: Predicate{Name: "query"},
+		Body: []Predicate{{Name: "right", IDs: []Term{String("/a"), Variable("op")}}},
+	}}}))
+	require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "group", IDs: []Term{String("admin")}}}))
+	signed, err := signer.Append(rng, block.Build())
+	require.NoError(t, err)
+	signedBlock := signed.container.Blocks[0]
+	nextPrivateKey := ed25519.NewKeyFromSeed(signed.container.Proof.GetNextSecret())
+
+	appended, err := b.AppendSigned(signedBlock, nextPrivateKey)
+	require.NoError(t, err)
+	expected, err := signed.Serialize()
+	require.NoError(t, err)
+	appendedSerialized, err := appended.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, expected, appendedSerialized)
+	require.Equal(t, signed.Code(), appended.Code())
+
+	v, err := appended.AuthorizerFor(WithSingularRootPublicKey(publicRoot))
+	require.NoError(t, err)
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+
+	// the appended token can be attenuated further
+	appended, err = appended.Append(rng, appended.CreateBlock().Build())
+	require.NoError(t, err)
+	_, err = appended.AuthorizerFor(WithSingularRootPublicKey(publicRoot))
+	require.NoError(t, err)
+
+	t.Run("other token", func(t *testing.T) {
+		other, err := NewBuilder(privateRoot).Build()
+		require.NoError(t, err)
+		_, err = other.AppendSigned(signedBlock, nextPrivateKey)
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("tampered block", func(t *testing.T) {
+		tampered := proto.Clone(signedBlock).(*pb.SignedBlock)
+		tampered.Block[len(tampered.Block)-1] ^= 1
+		_, err := b.AppendSigned(tampered, nextPrivateKey)
+		require.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("wrong next private key", func(t *testing.T) {
+		_, otherPrivate, _ := ed25519.GenerateKey(rng)
+		_, err := b.AppendSigned(signedBlock, otherPrivate)
+		require.Error(t, err)
+		_, err = b.AppendSigned(signedBlock, nil)
+		require.ErrorIs(t, err, ErrInvalidKeySize)
+	})
+}
+
+func TestWithMaxSymbols(t *testing.T) {
+	rng := rand.Reader
+	_, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "company", IDs: []Term{String("acme")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	block := b.CreateBlock()
+	require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "project", IDs: []Term{String("apollo")}}}))
+	b, err = b.Append(rng, block.Build())
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+
+	// company, acme, project, apollo
+	_, err = Unmarshal(serialized, WithMaxSymbols(4))
+	require.NoError(t, err)
+	_, err = Unmarshal(serialized)
+	require.NoError(t, err)
+
+	_, err = Unmarshal(serialized, WithMaxSymbols(3))
+	require.ErrorIs(t, err, ErrTooManySymbols)
+	_, err = Unmarshal(serialized, WithMaxSymbols(1))
+	require.ErrorIs(t, err, ErrTooManySymbols)
+
+	_, err = (&Unmarshaler{Symbols: defaultSymbolTable.Clone(), MaxSymbols: 3}).Unmarshal(serialized)
+	require.ErrorIs(t, err, ErrTooManySymbols)
+}
+
+func TestWithRejectUnknownFields(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "company", IDs: []Term{String("acme")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+	b, err = b.Append(rng, b.CreateBlock().Build())
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	_, err = Unmarshal(serialized, WithRejectUnknownFields())
+	require.NoError(t, err)
+
+	// a varint in the field 100, which is not in the schema
+	unknown := protowire.AppendTag(nil, 100, protowire.VarintType)
+	unknown = protowire.AppendVarint(unknown, 1)
+
+	testCases := []struct {
+		desc   string
+		modify func(container *pb.Biscuit)
+		err    string
+	}{
+		{
+			desc:   "container",
+			modify: func(container *pb.Biscuit) { container.ProtoReflect().SetUnknown(unknown) },
+			err:    "biscuit: unknown protobuf fields in container",
+		},
+		{
+			desc:   "signed block",
+			modify: func(container *pb.Biscuit) { container.Blocks[0].ProtoReflect().SetUnknown(unknown) },
+			err:    "biscuit: unknown protobuf fields in container",
+		},
+		{
+			desc:   "proof",
+			modify: func(container *pb.Biscuit) { container.Proof.ProtoReflect().SetUnknown(unknown) },
+			err:    "biscuit: unknown protobuf fields in container",
+		},
+		{
+			desc:   "authority block",
+			modify: func(container *pb.Biscuit) { container.Authority.Block = append(container.Authority.Block, unknown...) },
+			err:    "biscuit: unknown protobuf fields in block 0",
+		},
+		{
+			desc: "block fact",
+			modify: func(container *pb.Biscuit) {
+				block := new(pb.Block)
+				require.NoError(t, proto.Unmarshal(container.Authority.Block, block))
+				block.FactsV2[0].ProtoReflect().SetUnknown(unknown)
+				marshalled, err := proto.Marshal(block)
+				require.NoError(t, err)
+				container.Authority.Block = marshalled
+			},
+			err: "biscuit: unknown protobuf fields in block 0",
+		},
+		{
+			desc:   "second block",
+			modify: func(container *pb.Biscuit) { container.Blocks[0].Block = append(container.Blocks[0].Block, unknown...) },
+			err:    "biscuit: unknown protobuf fields in block 1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			container := new(pb.Biscuit)
+			require.NoError(t, proto.Unmarshal(serialized, container))
+			tc.modify(container)
+			modified, err := proto.Marshal(container)
+			require.NoError(t, err)
+
+			// unknown fields are ignored by default
+			_, err = Unmarshal(modified)
+			require.NoError(t, err)
+
+			_, err = Unmarshal(modified, WithRejectUnknownFields())
+			require.ErrorIs(t, err, ErrUnknownFields)
+			require.EqualError(t, err, tc.err)
+			_, err = UnmarshalAndVerify(modified, WithSingularRootPublicKey(publicRoot), WithRejectUnknownFields())
+			require.ErrorIs(t, err, ErrUnknownFields)
+		})
+	}
+}
+
+func TestBlockSymbolsOffset(t *testing.T) {
+	rng := rand.Reader
+	_, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "company", IDs: []Term{String("acme")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	block := b.CreateBlock()
+	require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "project", IDs: []Term{String("apollo")}}}))
+	b, err = b.Append(rng, block.Build())
+	require.NoError(t, err)
+
+	block = b.CreateBlock()
+	require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "label", IDs: []Term{String("gemini")}}}))
+	b, err = b.Append(rng, block.Build())
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = Unmarshal(serialized)
+	require.NoError(t, err)
+
+	// both blocks only serialize the symbols they add
+	require.Equal(t, &datalog.SymbolTable{"apollo", "project"}, b.blocks[0].symbols)
+	require.Equal(t, &datalog.SymbolTable{"gemini", "label"}, b.blocks[1].symbols)
+
+	// the symbols of the second block come after the ones of the first block
+	// in the token's table, they do not reuse their indexes
+	label := (*b.blocks[1].facts)[0]
+	require.Equal(t, datalog.String(datalog.OFFSET+5), label.Name)
+	require.Equal(t, []datalog.Term{datalog.String(datalog.OFFSET + 4)}, label.Terms)
+
+	for i, expected := range []Fact{
+		{Predicate: Predicate{Name: "project", IDs: []Term{String("apollo")}}},
+		{Predicate: Predicate{Name: "label", IDs: []Term{String("gemini")}}},
+	} {
+		facts := *b.blocks[i].facts
+		require.Len(t, facts, 1)
+		fact, err := fromDatalogFact(b.symbols, facts[0])
+		require.NoError(t, err)
+		require.Equal(t, expected, *fact)
+	}
+}
+
+func TestCheckStrings(t *testing.T) {
+	rng := rand.Reader
+	_, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityCheck(Check{Queries: []Rule{{
+		Head: Predicate{Name: "query", IDs: []Term{}},
+		Body: []Predicate{{Name: "right", IDs: []Term{String("/a"), Variable("op")}}},
+	}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	block := b.CreateBlock()
+	b, err = b.Append(rng, block.Build())
+	require.NoError(t, err)
+
+	block = b.CreateBlock()
+	require.NoError(t, block.AddCheck(Check{Queries: []Rule{
+		{
+			Head: Predicate{Name: "query", IDs: []Term{}},
+			Body: []Predicate{{Name: "operation", IDs: []Term{String("read")}}},
+		},
+		{
+			Head:        Predicate{Name: "query", IDs: []Term{}},
+			Body:        []Predicate{{Name: "time", IDs: []Term{Variable("t")}}},
+			Expressions: []Expression{{Value{Variable("t")}, Value{Integer(10)}, BinaryLessThan}},
+		},
+	}}))
+	require.NoError(t, block.AddCheck(Check{Kind: CheckKindAll, Queries: []Rule{{
+		Head: Predicate{Name: "query", IDs: []Term{}},
+		Body: []Predicate{{Name: "resource", IDs: []Term{Variable("r")}}},
+	}}}))
+	b, err = b.Append(rng, block.Build())
+	require.NoError(t, err)
+
+	_, externalPrivate, _ := ed25519.GenerateKey(rng)
+	request, err := b.ThirdPartyRequest()
+	require.NoError(t, err)
+	thirdPartyBlock := request.CreateBlock()
+	require.NoError(t, thirdPartyBlock.AddCheck(Check{Queries: []Rule{{
+		Head: Predicate{Name: "query", IDs: []Term{}},
+		Body: []Predicate{{Name: "group", IDs: []Term{String("admin")}}},
+	}}}))
+	signed, err := request.Sign(externalPrivate, thirdPartyBlock.Build())
+	require.NoError(t, err)
+	b, err = b.AppendThirdParty(rng, signed)
+	require.NoError(t, err)
+
+	checks, err := b.CheckStrings()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{`check if right("/a", $op)`},
+		{},
+		{
+			`check if operation("read") or time($t), $t < 10`,
+			`check all resource($r)`,
+		},
+		{`check if group("admin")`},
+	}, checks)
+}
+
+func TestSerializedSize(t *testing.T) {
+	rng := rand.Reader
+	_, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("/a"), String("read")}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	size, err := b.SerializedSize()
+	require.NoError(t, err)
+	require.Equal(t, len(serialized), size)
+
+	block := b.CreateBlock()
+	require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "project", IDs: []Term{String("apollo"), Integer(1234)}}}))
+	require.NoError(t, block.AddCheck(Check{Queries: []Rule{{
+		Head:        Predicate{Name: "query", IDs: []Term{}},
+		Body:        []Predicate{{Name: "time", IDs: []Term{Variable("t")}}},
+		Expressions: []Expression{{Value{Variable("t")}, Value{Date(time.Unix(1700000000, 0))}, BinaryLessThan}},
+	}}}))
+	block.SetContext("attenuation")
+	estimate, err := block.EstimatedSize()
+	require.NoError(t, err)
+
+	attenuated, err := b.Append(rng, block.Build())
+	require.NoError(t, err)
+	serialized, err = attenuated.Serialize()
+	require.NoError(t, err)
+	attenuatedSize, err := attenuated.SerializedSize()
+	require.NoError(t, err)
+	require.Equal(t, len(serialized), attenuatedSize)
+	require.InDelta(t, attenuatedSize-size, estimate, 8)
+
+	// the estimate does not change the block
+	require.Contains(t, attenuated.String(), `project("apollo", 1234)`)
+}
+
+func TestBuilderWithRootKey(t *testing.T) {
+	rng := rand.Reader
+	oldPublic, oldPrivate, _ := ed25519.GenerateKey(rng)
+	newPublic, newPrivate, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(oldPrivate, WithRootKeyID(1))
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "project", IDs: []Term{String("apollo")}}}))
+	require.NoError(t, builder.AddAuthorityCheck(Check{Queries: []Rule{{
+		Head: Predicate{Name: "query", IDs: []Term{}},
+		Body: []Predicate{{Name: "operation", IDs: []Term{String("read")}}},
+	}}}))
+	builder.SetContext("rotated")
+
+	rotated := builder.WithRootKey(newPrivate)
+	// the builders are independent
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "company", IDs: []Term{String("acme")}}}))
+
+	b, err := rotated.Build()
+	require.NoError(t, err)
+	require.NoError(t, b.VerifyChain(WithSingularRootPublicKey(newPublic), nil))
+	require.ErrorIs(t, b.VerifyChain(WithSingularRootPublicKey(oldPublic), nil), ErrInvalidSignature)
+	require.Equal(t, uint32(1), *b.RootKeyID())
+	require.Equal(t, "rotated", b.GetContext())
+
+	require.Contains(t, b.String(), `project("apollo")`)
+	require.Contains(t, b.String(), `check if operation("read")`)
+	require.NotContains(t, b.String(), `company("acme")`)
+
+	original, err := builder.Build()
+	require.NoError(t, err)
+	require.NoError(t, original.VerifyChain(WithSingularRootPublicKey(oldPublic), nil))
+	require.Contains(t, original.String(), `company("acme")`)
+}
+
+func TestProofTypeAndNextPublicKey(t *testing.T) {
+	rng := rand.Reader
+	_, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	b, err := NewBuilder(privateRoot).Build()
+	require.NoError(t, err)
+	require.Equal(t, "next_secret", b.ProofType())
+	nextKey, ok := b.NextPublicKey()
+	require.True(t, ok)
+	require.Equal(t, ed25519.PublicKey(b.container.Authority.NextKey.Key), nextKey)
+
+	b, err = b.Append(rng, b.CreateBlock().Build())
+	require.NoError(t, err)
+	require.Equal(t, "next_secret", b.ProofType())
+	nextKey, ok = b.NextPublicKey()
+	require.True(t, ok)
+	require.Equal(t, ed25519.NewKeyFromSeed(b.container.Proof.GetNextSecret()).Public(), nextKey)
+
+	sealed, err := b.Seal(rng)
+	require.NoError(t, err)
+	require.Equal(t, "final_signature", sealed.ProofType())
+	sealedKey, ok := sealed.NextPublicKey()
+	require.True(t, ok)
+	require.Equal(t, nextKey, sealedKey)
+
+	serialized, err := sealed.Serialize()
+	require.NoError(t, err)
+	sealed, err = Unmarshal(serialized)
+	require.NoError(t, err)
+	require.Equal(t, "final_signature", sealed.ProofType())
+	sealedKey, ok = sealed.NextPublicKey()
+	require.True(t, ok)
+	require.Equal(t, nextKey, sealedKey)
+}
+
+func TestIsSealed(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	b, err := NewBuilder(privateRoot).Build()
+	require.NoError(t, err)
+	require.False(t, b.IsSealed())
+
+	b, err = b.Append(rng, b.CreateBlock().Build())
+	require.NoError(t, err)
+	require.False(t, b.IsSealed())
+
+	sealed, err := b.Seal(rng)
+	require.NoError(t, err)
+	require.True(t, sealed.IsSealed())
+	require.False(t, b.IsSealed())
+
+	serialized, err := sealed.Serialize()
+	require.NoError(t, err)
+	sealed, err = Unmarshal(serialized)
+	require.NoError(t, err)
+	require.True(t, sealed.IsSealed())
+	require.NoError(t, sealed.VerifyChain(WithSingularRootPublicKey(publicRoot), nil))
+	_, err = sealed.Append(rng, sealed.CreateBlock().Build())
+	require.Error(t, err)
+
+	serialized, err = b.Serialize()
+	require.NoError(t, err)
+	b, err = Unmarshal(serialized)
+	require.NoError(t, err)
+	require.False(t, b.IsSealed())
+}
+
+func TestWithSortedSymbols(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+	nextPublic, nextPrivate, _ := ed25519.GenerateKey(rng)
+
+	facts := []Fact{
+		{Predicate: Predicate{Name: "project", IDs: []Term{String("zeta"), Set{String("beta"), String("alpha")}}}},
+		{Predicate: Predicate{Name: "company", IDs: []Term{String("acme")}}},
+		{Predicate: Predicate{Name: "label", IDs: []Term{Map{String("env"): String("prod")}, Array{String("x"), Integer(1)}}}},
+		{Predicate: Predicate{Name: "id", IDs: []Term{Tagged{Tag: "uuid", Value: Bytes{0xca, 0xfe}}}}},
+	}
+	rule := Rule{
+		Head: Predicate{Name: "owned", IDs: []Term{Variable("name")}},
+		Body: []Predicate{
+			{Name: "project", IDs: []Term{Variable("name"), Variable("tags")}},
+			{Name: "company", IDs: []Term{String("acme")}},
+		},
+		Expressions: []Expression{{Value{Variable("tags")}, Value{String("alpha")}, BinaryContains}},
+	}
+	check := Check{Queries: []Rule{{
+		Head: Predicate{Name: "query", IDs: []Term{}},
+		Body: []Predicate{{Name: "owned", IDs: []Term{String("zeta")}}},
+	}}}
+
+	build := func(sorted bool, order []int) *Biscuit {
+		opts := []builderOption{WithNextKeyPair(nextPublic, nextPrivate)}
+		if sorted {
+			opts = append(opts, WithSortedSymbols())
+		}
+		builder := NewBuilder(privateRoot, opts...)
+		for _, i := range order {
+			require.NoError(t, builder.AddAuthorityFact(facts[i]))
+		}
+		require.NoError(t, builder.AddAuthorityRule(rule))
+		require.NoError(t, builder.AddAuthorityCheck(check))
+		b, err := builder.Build()
+		require.NoError(t, err)
+		return b
+	}
+	serialize := func(b *Biscuit) []byte {
+		serialized, err := b.Serialize()
+		require.NoError(t, err)
+		return serialized
+	}
+
+	first := build(true, []int{0, 1, 2, 3})
+	second := build(true, []int{3, 2, 1, 0})
+	require.Equal(t, serialize(first), serialize(second))
+	require.Equal(t, []string{"acme", "alpha", "beta", "company", "env", "id", "label", "name", "owned", "prod", "project", "tags", "uuid", "x", "zeta"}, []string(*first.authority.symbols))
+
+	require.NotEqual(t, serialize(build(false, []int{0, 1, 2, 3})), serialize(build(false, []int{3, 2, 1, 0})))
+
+	b, err := Unmarshal(serialize(second))
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `owned($name) <- project($name, $tags), company("acme"), $tags.contains("alpha")`)
+	require.Contains(t, b.String(), `label({"env": "prod"}, array:["x", 1])`)
+	require.Contains(t, b.String(), `id(tagged("uuid", hex:cafe))`)
+	v, err := b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+}
@@ -3,26 +3,47 @@ package biscuit
 import (
 	"crypto/ed25519"
 	"errors"
+	"fmt"
 	"io"
+	"sort"
+	"time"
 
 	"github.com/biscuit-auth/biscuit-go/v2/datalog"
 	"github.com/biscuit-auth/biscuit-go/v2/pb"
 
 	//"github.com/biscuit-auth/biscuit-go/sig"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 var (
 	ErrDuplicateFact     = errors.New("biscuit: fact already exists")
 	ErrInvalidBlockIndex = errors.New("biscuit: invalid block index")
+	// ErrUnsupportedSchemaVersion is returned when building a block with a version
+	// outside of [MinSchemaVersion, MaxSchemaVersion]
+	ErrUnsupportedSchemaVersion = errors.New("biscuit: unsupported schema version")
+	// ErrSchemaVersionTooLow is returned when a block uses features
+	// not supported by the version it was pinned to
+	ErrSchemaVersionTooLow = errors.New("biscuit: schema version too low for the block content")
+	// ErrTooManySymbols is returned when unmarshaling a token whose blocks
+	// declare more symbols than allowed by WithMaxSymbols
+	ErrTooManySymbols = errors.New("biscuit: too many symbols")
 )
 
+// DefaultMaxSymbols is the maximum number of symbols, over all the blocks,
+// a token can declare to be unmarshaled, unless changed with WithMaxSymbols.
+const DefaultMaxSymbols = 1 << 16
+
 type Builder interface {
 	AddBlock(block ParsedBlock) error
 	AddAuthorityFact(fact Fact) error
 	AddAuthorityRule(rule Rule) error
 	AddAuthorityCheck(check Check) error
+	AddAuthorityFactFromString(fact string) error
+	AddAuthorityRuleFromString(rule string) error
+	AddAuthorityCheckFromString(check string) error
 	SetContext(string)
+	WithRootKey(newRoot ed25519.PrivateKey) Builder
 	Build() (*Biscuit, error)
 }
 
@@ -31,12 +52,17 @@ type builderOptions struct {
 	rootKey   ed25519.PrivateKey
 	rootKeyID *uint32
 
+	nextKeyPair *nextKeyPairOption
+
 	symbolsStart int
 	symbols      *datalog.SymbolTable
 	facts        *datalog.FactSet
 	rules        []datalog.Rule
 	checks       []datalog.Check
 	context      string
+
+	schemaVersion *uint32
+	sortedSymbols bool
 }
 
 type builderOption interface {
@@ -57,6 +83,34 @@ func WithSymbols(symbols *datalog.SymbolTable) builderOption {
 	return symbolsOption{symbols}
 }
 
+type schemaVersionOption uint32
+
+func (o schemaVersionOption) applyToBuilder(b *builderOptions) {
+	v := uint32(o)
+	b.schemaVersion = &v
+}
+
+// WithSchemaVersion pins the version of the authority block, so the token
+// can be read by verifiers supporting older versions. By default, the lowest
+// version supporting the block's content is used. Build fails if the version
+// is not supported, or if the block uses features requiring a higher version.
+func WithSchemaVersion(v uint32) builderOption {
+	return schemaVersionOption(v)
+}
+
+type sortedSymbolsOption struct{}
+
+func (sortedSymbolsOption) applyToBuilder(b *builderOptions) {
+	b.sortedSymbols = true
+}
+
+// WithSortedSymbols sorts the symbols and the facts of the authority block
+// when building it, so that the same content gives the same serialized block
+// whatever the order it was added in. Rules and checks keep their order.
+func WithSortedSymbols() builderOption {
+	return sortedSymbolsOption{}
+}
+
 func NewBuilder(root ed25519.PrivateKey, opts ...builderOption) Builder {
 	b := &builderOptions{
 		rootKey:      root,
@@ -95,6 +149,9 @@ func (b *builderOptions) AddBlock(block ParsedBlock) error {
 }
 
 func (b *builderOptions) AddAuthorityFact(fact Fact) error {
+	if err := fact.checkMapKeys(); err != nil {
+		return err
+	}
 	dlFact := fact.convert(b.symbols)
 	if !b.facts.Insert(dlFact) {
 		return ErrDuplicateFact
@@ -104,48 +161,369 @@ func (b *builderOptions) AddAuthorityFact(fact Fact) error {
 }
 
 func (b *builderOptions) AddAuthorityRule(rule Rule) error {
+	if err := rule.checkMapKeys(); err != nil {
+		return err
+	}
 	dlRule := rule.convert(b.symbols)
 	b.rules = append(b.rules, dlRule)
 	return nil
 }
 
 func (b *builderOptions) AddAuthorityCheck(check Check) error {
+	if err := check.checkMapKeys(); err != nil {
+		return err
+	}
 	b.checks = append(b.checks, check.convert(b.symbols))
 	return nil
 }
 
+// AddAuthorityFactFromString parses a single datalog fact and adds it to
+// the authority block. It requires the parser package to be imported.
+func (b *builderOptions) AddAuthorityFactFromString(fact string) error {
+	if ParseFact == nil {
+		return ErrNoDatalogParser
+	}
+	f, err := ParseFact(fact)
+	if err != nil {
+		return err
+	}
+	return b.AddAuthorityFact(f)
+}
+
+// AddAuthorityRuleFromString parses a single datalog rule and adds it to
+// the authority block. It requires the parser package to be imported.
+func (b *builderOptions) AddAuthorityRuleFromString(rule string) error {
+	if ParseRule == nil {
+		return ErrNoDatalogParser
+	}
+	r, err := ParseRule(rule)
+	if err != nil {
+		return err
+	}
+	return b.AddAuthorityRule(r)
+}
+
+// AddAuthorityCheckFromString parses a single datalog check and adds it to
+// the authority block. It requires the parser package to be imported.
+func (b *builderOptions) AddAuthorityCheckFromString(check string) error {
+	if ParseCheck == nil {
+		return ErrNoDatalogParser
+	}
+	c, err := ParseCheck(check)
+	if err != nil {
+		return err
+	}
+	return b.AddAuthorityCheck(c)
+}
+
 func (b *builderOptions) SetContext(context string) {
 	b.context = context
 }
 
+// WithRootKey returns a copy of the builder which signs the token with newRoot,
+// keeping the authority facts, rules, checks and context added so far, along
+// with the other options, such as the root key ID. The builders can then be
+// modified independently. The key pair given with WithNextKeyPair is not
+// kept, so that the tokens do not share their next secret.
+func (b *builderOptions) WithRootKey(newRoot ed25519.PrivateKey) Builder {
+	symbols := append(datalog.SymbolTable{}, *b.symbols...)
+	facts := append(datalog.FactSet{}, *b.facts...)
+
+	clone := *b
+	clone.rootKey = newRoot
+	clone.nextKeyPair = nil
+	clone.symbols = &symbols
+	clone.facts = &facts
+	clone.rules = append([]datalog.Rule{}, b.rules...)
+	clone.checks = append([]datalog.Check{}, b.checks...)
+	return &clone
+}
+
 func (b *builderOptions) Build() (*Biscuit, error) {
-	opts := make([]biscuitOption, 0, 2)
+	opts := make([]biscuitOption, 0, 3)
 	if v := b.rng; v != nil {
 		opts = append(opts, WithRNG(b.rng))
 	}
 	if v := b.rootKeyID; v != nil {
 		opts = append(opts, WithRootKeyID(*v))
 	}
+	if v := b.nextKeyPair; v != nil {
+		opts = append(opts, *v)
+	}
+
+	version := blockSchemaVersion(b.facts, b.rules, b.checks)
+	if v := b.schemaVersion; v != nil {
+		if *v < MinSchemaVersion || *v > MaxSchemaVersion {
+			return nil, fmt.Errorf("%w: %d, supported versions are %d to %d", ErrUnsupportedSchemaVersion, *v, MinSchemaVersion, MaxSchemaVersion)
+		}
+		if *v < version {
+			return nil, fmt.Errorf("%w: the block requires version %d, got %d", ErrSchemaVersionTooLow, version, *v)
+		}
+		version = *v
+	}
+
+	symbols, facts, rules, checks := b.symbols, b.facts, b.rules, b.checks
+	if b.sortedSymbols {
+		symbols, facts, rules, checks = sortSymbols(b.symbols, b.symbolsStart, b.facts, b.rules, b.checks)
+	}
+
 	return newBiscuit(
 		b.rootKey,
-		b.symbols,
+		symbols,
 		&Block{
-			symbols: b.symbols.SplitOff(b.symbolsStart),
-			facts:   b.facts,
-			rules:   b.rules,
-			checks:  b.checks,
+			symbols: symbols.SplitOff(b.symbolsStart),
+			facts:   facts,
+			rules:   rules,
+			checks:  checks,
 			context: b.context,
-			version: MaxSchemaVersion,
+			version: version,
 		},
 		opts...)
 }
 
+// sortSymbols returns a copy of the table where the symbols from start are
+// sorted, and the facts, rules and checks using the new indexes. The facts are
+// sorted by their datalog representation.
+func sortSymbols(symbols *datalog.SymbolTable, start int, facts *datalog.FactSet, rules []datalog.Rule, checks []datalog.Check) (*datalog.SymbolTable, *datalog.FactSet, []datalog.Rule, []datalog.Check) {
+	blockSymbols := append([]string{}, (*symbols)[start:]...)
+	sort.Strings(blockSymbols)
+
+	indexes := make(map[uint64]uint64, len(blockSymbols))
+	for i, s := range (*symbols)[start:] {
+		indexes[uint64(datalog.OFFSET+start+i)] = uint64(datalog.OFFSET + start + sort.SearchStrings(blockSymbols, s))
+	}
+	sorted := append(datalog.SymbolTable{}, (*symbols)[:start]...)
+	sorted = append(sorted, blockSymbols...)
+
+	var remapTerm func(t datalog.Term) datalog.Term
+	remapTerm = func(t datalog.Term) datalog.Term {
+		switch t := t.(type) {
+		case datalog.String:
+			if i, ok := indexes[uint64(t)]; ok {
+				return datalog.String(i)
+			}
+		case datalog.Variable:
+			if i, ok := indexes[uint64(t)]; ok {
+				return datalog.Variable(i)
+			}
+		case datalog.Tagged:
+			if i, ok := indexes[uint64(t.Tag)]; ok {
+				return datalog.Tagged{Tag: datalog.String(i), Value: t.Value}
+			}
+		case datalog.Set:
+			res := make(datalog.Set, len(t))
+			for i, e := range t {
+				res[i] = remapTerm(e)
+			}
+			return res
+		case datalog.Array:
+			res := make(datalog.Array, len(t))
+			for i, e := range t {
+				res[i] = remapTerm(e)
+			}
+			return res
+		case datalog.Map:
+			res := make(datalog.Map, len(t))
+			for k, v := range t {
+				res[remapTerm(k)] = remapTerm(v)
+			}
+			return res
+		}
+		return t
+	}
+	remapPredicate := func(p datalog.Predicate) datalog.Predicate {
+		res := datalog.Predicate{
+			Name:  remapTerm(p.Name).(datalog.String),
+			Terms: make([]datalog.Term, len(p.Terms)),
+		}
+		for i, t := range p.Terms {
+			res.Terms[i] = remapTerm(t)
+		}
+		return res
+	}
+	remapRule := func(r datalog.Rule) datalog.Rule {
+		res := datalog.Rule{
+			Head:        remapPredicate(r.Head),
+			Body:        make([]datalog.Predicate, len(r.Body)),
+			Expressions: make([]datalog.Expression, len(r.Expressions)),
+			Scope:       r.Scope,
+		}
+		for i, p := range r.Body {
+			res.Body[i] = remapPredicate(p)
+		}
+		for i, e := range r.Expressions {
+			res.Expressions[i] = make(datalog.Expression, len(e))
+			for j, op := range e {
+				if v, ok := op.(datalog.Value); ok {
+					op = datalog.Value{ID: remapTerm(v.ID)}
+				}
+				res.Expressions[i][j] = op
+			}
+		}
+		return res
+	}
+
+	sortedFacts := make(datalog.FactSet, len(*facts))
+	for i, f := range *facts {
+		sortedFacts[i] = datalog.Fact{Predicate: remapPredicate(f.Predicate)}
+	}
+	debug := datalog.SymbolDebugger{SymbolTable: &sorted}
+	sort.Slice(sortedFacts, func(i, j int) bool {
+		return debug.Predicate(sortedFacts[i].Predicate) < debug.Predicate(sortedFacts[j].Predicate)
+	})
+
+	sortedRules := make([]datalog.Rule, len(rules))
+	for i, r := range rules {
+		sortedRules[i] = remapRule(r)
+	}
+	sortedChecks := make([]datalog.Check, len(checks))
+	for i, c := range checks {
+		sortedChecks[i] = datalog.Check{Kind: c.Kind, Queries: make([]datalog.Rule, len(c.Queries))}
+		for j, q := range c.Queries {
+			sortedChecks[i].Queries[j] = remapRule(q)
+		}
+	}
+
+	return &sorted, &sortedFacts, sortedRules, sortedChecks
+}
+
+// ErrUnknownFields is returned when unmarshalling, with WithRejectUnknownFields,
+// a token holding protobuf fields which are not in the schema
+var ErrUnknownFields = errors.New("biscuit: unknown protobuf fields")
+
 type Unmarshaler struct {
 	Symbols *datalog.SymbolTable
+	// MaxSymbols limits the number of symbols declared by the token blocks.
+	// DefaultMaxSymbols is used when it is 0.
+	MaxSymbols int
+	// RejectUnknownFields rejects the tokens holding fields which are not
+	// in the protobuf schema, see WithRejectUnknownFields.
+	RejectUnknownFields bool
+}
+
+type UnmarshalOption func(u *Unmarshaler)
+
+// WithMaxSymbols limits the number of symbols the blocks of a token can declare,
+// all blocks combined. Tokens over the limit are rejected with ErrTooManySymbols
+// once the protobuf message of the block exceeding it is decoded, before its
+// facts, rules and checks are converted.
+func WithMaxSymbols(n int) UnmarshalOption {
+	return func(u *Unmarshaler) {
+		u.MaxSymbols = n
+	}
+}
+
+// WithRejectUnknownFields rejects, with ErrUnknownFields, the tokens holding
+// protobuf fields which are not in the schema, in the container or in a block.
+// They are ignored by default, but can denote a token crafted to be read
+// differently by another implementation. This also rejects the tokens with
+// blocks serialized by the first format version, which held their index.
+func WithRejectUnknownFields() UnmarshalOption {
+	return func(u *Unmarshaler) {
+		u.RejectUnknownFields = true
+	}
 }
 
-func Unmarshal(serialized []byte) (*Biscuit, error) {
-	return (&Unmarshaler{Symbols: defaultSymbolTable.Clone()}).Unmarshal(serialized)
+func Unmarshal(serialized []byte, opts ...UnmarshalOption) (*Biscuit, error) {
+	u := &Unmarshaler{Symbols: defaultSymbolTable.Clone()}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u.Unmarshal(serialized)
+}
+
+// UnmarshalAndVerify parses a token and verifies its signatures with the root
+// public key selected from keySource, without creating an Authorizer. It returns
+// ErrInvalidSignature when a signature does not match.
+func UnmarshalAndVerify(serialized []byte, keySource PublickKeyByIDProjection, opts ...UnmarshalOption) (*Biscuit, error) {
+	b, err := Unmarshal(serialized, opts...)
+	if err != nil {
+		return nil, err
+	}
+	root, err := b.rootPublicKey(keySource)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.verify(root); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// UnmarshalTrusted parses a token like Unmarshal, for data which comes from
+// a trusted store, such as a cache of tokens which were already verified.
+// The authorizers created from the returned token do not verify its
+// signatures, whatever root key they are given: the token is trusted to have been
+// signed by the expected root key. It must never be used with data received from
+// a client. Tokens appended to or sealed from the returned one are verified again, and
+// VerifyChain still verifies the signatures.
+func UnmarshalTrusted(serialized []byte, opts ...UnmarshalOption) (*Biscuit, error) {
+	b, err := Unmarshal(serialized, opts...)
+	if err != nil {
+		return nil, err
+	}
+	b.trusted = true
+	return b, nil
+}
+
+// ValidateStructure checks a serialized token is well formed, without
+// verifying its signatures nor decoding its datalog: the token must have an
+// authority block and a proof with a 32 bytes next secret or a 64 bytes final
+// signature, each block must have an ed25519 next key of 32 bytes and a
+// signature of 64 bytes, and a version within [MinSchemaVersion, MaxSchemaVersion].
+// It is cheaper than Unmarshal or UnmarshalAndVerify, to reject malformed
+// tokens early, but a valid structure does not make a valid token.
+func ValidateStructure(serialized []byte) error {
+	container := new(pb.Biscuit)
+	if err := proto.Unmarshal(serialized, container); err != nil {
+		return err
+	}
+	if container.Authority == nil {
+		return fmt.Errorf("%w: missing authority block", ErrInvalidContainer)
+	}
+
+	switch proof := container.Proof.GetContent().(type) {
+	case *pb.Proof_NextSecret:
+		if len(proof.NextSecret) != ed25519.SeedSize {
+			return fmt.Errorf("%w: next secret", ErrInvalidKeySize)
+		}
+	case *pb.Proof_FinalSignature:
+		if len(proof.FinalSignature) != ed25519.SignatureSize {
+			return fmt.Errorf("%w: final signature", ErrInvalidSignatureSize)
+		}
+	default:
+		return fmt.Errorf("%w: missing proof", ErrInvalidContainer)
+	}
+
+	for i, sb := range append([]*pb.SignedBlock{container.Authority}, container.Blocks...) {
+		if sb == nil || sb.NextKey == nil {
+			return fmt.Errorf("%w: missing next key in block %d", ErrInvalidContainer, i)
+		}
+		if sb.NextKey.GetAlgorithm() != pb.PublicKey_Ed25519 {
+			return UnsupportedAlgorithm
+		}
+		if len(sb.NextKey.Key) != ed25519.PublicKeySize {
+			return fmt.Errorf("%w: next key of block %d", ErrInvalidKeySize, i)
+		}
+		if len(sb.Signature) != ed25519.SignatureSize {
+			return fmt.Errorf("%w: block %d", ErrInvalidSignatureSize, i)
+		}
+		if sb.ExternalSignature != nil {
+			if _, err := protoExternalSignatureKey(sb.ExternalSignature); err != nil {
+				return fmt.Errorf("block %d external signature: %w", i, err)
+			}
+		}
+
+		block := new(pb.Block)
+		if err := proto.Unmarshal(sb.Block, block); err != nil {
+			return fmt.Errorf("biscuit: block %d: %w", i, err)
+		}
+		if v := block.GetVersion(); v < MinSchemaVersion || v > MaxSchemaVersion {
+			return fmt.Errorf("%w: block %d has version %d, supported versions are %d to %d", ErrUnsupportedSchemaVersion, i, v, MinSchemaVersion, MaxSchemaVersion)
+		}
+	}
+
+	return nil
 }
 
 func (u *Unmarshaler) Unmarshal(serialized []byte) (*Biscuit, error) {
@@ -153,12 +531,104 @@ func (u *Unmarshaler) Unmarshal(serialized []byte) (*Biscuit, error) {
 		return nil, errors.New("biscuit: unmarshaler requires a symbol table")
 	}
 
-	symbols := u.Symbols.Clone()
-
 	container := new(pb.Biscuit)
 	if err := proto.Unmarshal(serialized, container); err != nil {
 		return nil, err
 	}
+	if u.RejectUnknownFields {
+		if err := checkUnknownFields(container); err != nil {
+			return nil, err
+		}
+	}
+
+	maxSymbols := u.MaxSymbols
+	if maxSymbols == 0 {
+		maxSymbols = DefaultMaxSymbols
+	}
+	return fromContainer(container, u.Symbols.Clone(), maxSymbols)
+}
+
+// checkUnknownFields returns ErrUnknownFields when the container,
+// or one of its serialized blocks, holds unknown fields.
+func checkUnknownFields(container *pb.Biscuit) error {
+	if hasUnknownFields(container.ProtoReflect()) {
+		return fmt.Errorf("%w in container", ErrUnknownFields)
+	}
+	for i, sb := range append([]*pb.SignedBlock{container.Authority}, container.Blocks...) {
+		if sb == nil {
+			continue
+		}
+		block := new(pb.Block)
+		if err := proto.Unmarshal(sb.Block, block); err != nil {
+			return err
+		}
+		if hasUnknownFields(block.ProtoReflect()) {
+			return fmt.Errorf("%w in block %d", ErrUnknownFields, i)
+		}
+	}
+	return nil
+}
+
+// hasUnknownFields returns true when m, or a message it contains,
+// holds unknown fields.
+func hasUnknownFields(m protoreflect.Message) bool {
+	if len(m.GetUnknown()) > 0 {
+		return true
+	}
+
+	found := false
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, value protoreflect.Value) bool {
+					found = hasUnknownFields(value.Message())
+					return !found
+				})
+			}
+		case fd.Message() == nil:
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len() && !found; i++ {
+				found = hasUnknownFields(list.Get(i).Message())
+			}
+		default:
+			found = hasUnknownFields(v.Message())
+		}
+		return !found
+	})
+	return found
+}
+
+// FromContainer creates a token from its protobuf representation, such as one
+// generated by another implementation. It checks the container has an authority
+// block and a proof, and decodes the blocks, but does not verify the signatures:
+// use VerifyChain or AuthorizerFor for that. The container is copied.
+func FromContainer(container *pb.Biscuit) (*Biscuit, error) {
+	if container == nil {
+		return nil, fmt.Errorf("%w: missing authority block", ErrInvalidContainer)
+	}
+	return fromContainer(proto.Clone(container).(*pb.Biscuit), defaultSymbolTable.Clone(), DefaultMaxSymbols)
+}
+
+func fromContainer(container *pb.Biscuit, symbols *datalog.SymbolTable, maxSymbols int) (*Biscuit, error) {
+	if container.Authority == nil {
+		return nil, fmt.Errorf("%w: missing authority block", ErrInvalidContainer)
+	}
+	if container.Proof == nil {
+		return nil, fmt.Errorf("%w: missing proof", ErrInvalidContainer)
+	}
+	for _, sb := range append([]*pb.SignedBlock{container.Authority}, container.Blocks...) {
+		if sb == nil || sb.NextKey == nil {
+			return nil, fmt.Errorf("%w: missing next key", ErrInvalidContainer)
+		}
+		if sb.NextKey.Algorithm == nil {
+			return nil, fmt.Errorf("%w: missing next key algorithm", ErrInvalidContainer)
+		}
+		if sb.NextKey.GetAlgorithm() != pb.PublicKey_Ed25519 {
+			return nil, UnsupportedAlgorithm
+		}
+	}
 
 	if len(container.Authority.NextKey.Key) != 32 {
 		return nil, ErrInvalidKeySize
@@ -171,8 +641,16 @@ func (u *Unmarshaler) Unmarshal(serialized []byte) (*Biscuit, error) {
 	if err := proto.Unmarshal(container.Authority.Block, pbAuthority); err != nil {
 		return nil, err
 	}
+	if index, ok := legacyBlockIndex(pbAuthority); ok && index != 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidAuthorityIndex, index)
+	}
+	symbolCount := len(pbAuthority.Symbols)
+	if symbolCount > maxSymbols {
+		return nil, fmt.Errorf("%w: %d symbols in authority block, limit is %d", ErrTooManySymbols, symbolCount, maxSymbols)
+	}
 
-	authority, err := protoBlockToTokenBlock(pbAuthority)
+	publicKeys := publicKeyTable{}
+	authority, err := protoBlockToTokenBlock(pbAuthority, &publicKeys)
 	if err != nil {
 		return nil, err
 	}
@@ -192,8 +670,29 @@ func (u *Unmarshaler) Unmarshal(serialized []byte) (*Biscuit, error) {
 		if err := proto.Unmarshal(sb.Block, pbBlock); err != nil {
 			return nil, err
 		}
+		symbolCount += len(pbBlock.Symbols)
+		if symbolCount > maxSymbols {
+			return nil, fmt.Errorf("%w: %d symbols up to block %d, limit is %d", ErrTooManySymbols, symbolCount, i+1, maxSymbols)
+		}
+
+		if sb.ExternalSignature != nil {
+			externalKey, err := protoExternalSignatureKey(sb.ExternalSignature)
+			if err != nil {
+				return nil, err
+			}
+
+			// third party blocks have their own symbols, but share the public keys
+			block, err := protoBlockToTokenBlock(pbBlock, &publicKeys)
+			if err != nil {
+				return nil, err
+			}
+			block.externalKey = externalKey
+			blocks[i] = block
+			publicKeys.Insert(externalKey)
+			continue
+		}
 
-		block, err := protoBlockToTokenBlock(pbBlock)
+		block, err := protoBlockToTokenBlock(pbBlock, &publicKeys)
 		if err != nil {
 			return nil, err
 		}
@@ -202,10 +701,11 @@ func (u *Unmarshaler) Unmarshal(serialized []byte) (*Biscuit, error) {
 	}
 
 	return &Biscuit{
-		authority: authority,
-		symbols:   symbols,
-		blocks:    blocks,
-		container: container,
+		authority:  authority,
+		symbols:    symbols,
+		publicKeys: publicKeys,
+		blocks:     blocks,
+		container:  container,
 	}, nil
 }
 
@@ -214,8 +714,12 @@ type BlockBuilder interface {
 	AddFact(fact Fact) error
 	AddRule(rule Rule) error
 	AddCheck(check Check) error
+	AddTimeLimitCheck(before time.Time) error
+	AddProhibitionCheck(predicateName string, terms ...Term) error
+	Validate() error
 	SetContext(string)
 	Build() *Block
+	EstimatedSize() (int, error)
 }
 
 type blockBuilder struct {
@@ -261,6 +765,9 @@ func (b *blockBuilder) AddBlock(block ParsedBlock) error {
 }
 
 func (b *blockBuilder) AddFact(fact Fact) error {
+	if err := fact.checkMapKeys(); err != nil {
+		return err
+	}
 	dlFact := fact.convert(b.symbols)
 	if !b.facts.Insert(dlFact) {
 		return ErrDuplicateFact
@@ -270,6 +777,9 @@ func (b *blockBuilder) AddFact(fact Fact) error {
 }
 
 func (b *blockBuilder) AddRule(rule Rule) error {
+	if err := rule.checkMapKeys(); err != nil {
+		return err
+	}
 	dlRule := rule.convert(b.symbols)
 	b.rules = append(b.rules, dlRule)
 
@@ -277,16 +787,98 @@ func (b *blockBuilder) AddRule(rule Rule) error {
 }
 
 func (b *blockBuilder) AddCheck(check Check) error {
+	if err := check.checkMapKeys(); err != nil {
+		return err
+	}
 	dlCheck := check.convert(b.symbols)
 	b.checks = append(b.checks, dlCheck)
 
 	return nil
 }
 
+// AddTimeLimitCheck adds the expiration check
+// `check if time($time), $time <= before`, which passes when the authorizer
+// provides a current time, with Authorizer.AddCurrentTime, up to before.
+func (b *blockBuilder) AddTimeLimitCheck(before time.Time) error {
+	return b.AddCheck(Check{Queries: []Rule{{
+		Head: Predicate{Name: "query"},
+		Body: []Predicate{{Name: "time", IDs: []Term{Variable("time")}}},
+		Expressions: []Expression{{
+			Value{Term: Variable("time")},
+			Value{Term: Date(before)},
+			BinaryLessOrEqual,
+		}},
+	}}})
+}
+
+// AddProhibitionCheck adds the check `reject if predicateName(terms...)`,
+// which fails the authorization when such a fact exists, e.g. when
+// the authorizer provides it. Variables in terms match any value.
+func (b *blockBuilder) AddProhibitionCheck(predicateName string, terms ...Term) error {
+	return b.AddCheck(Check{Kind: CheckKindReject, Queries: []Rule{{
+		Head: Predicate{Name: "query"},
+		Body: []Predicate{{Name: predicateName, IDs: terms}},
+	}}})
+}
+
+// Validate checks the facts and rules added to the block with
+// ParsedBlock.Validate, returning a ReservedPredicateError when they
+// use reserved predicates, which only the authority block can declare.
+func (b *blockBuilder) Validate() error {
+	var parsed ParsedBlock
+	for _, f := range *b.facts {
+		fact, err := fromDatalogFact(b.symbols, f)
+		if err != nil {
+			return err
+		}
+		parsed.Facts = append(parsed.Facts, *fact)
+	}
+	for _, r := range b.rules {
+		rule, err := fromDatalogRule(b.symbols, r)
+		if err != nil {
+			return err
+		}
+		parsed.Rules = append(parsed.Rules, *rule)
+	}
+	return parsed.Validate(false)
+}
+
+// SetContext sets the context of the block, a free form string
+// usually identifying what issued it.
 func (b *blockBuilder) SetContext(context string) {
 	b.context = context
 }
 
+// EstimatedSize returns the approximate number of bytes the block will add
+// to the serialized token once appended with Append: the encoded block, its
+// signature and next public key. The public keys used in scopes are counted
+// even if the token already contains them.
+func (b *blockBuilder) EstimatedSize() (int, error) {
+	block := &Block{
+		symbols: b.symbols.Clone().SplitOff(b.symbolsStart),
+		facts:   b.facts,
+		rules:   b.rules,
+		checks:  b.checks,
+		context: b.context,
+		version: blockSchemaVersion(b.facts, b.rules, b.checks),
+	}
+	pbBlock, err := tokenBlockToProtoBlock(block, &publicKeyTable{})
+	if err != nil {
+		return 0, err
+	}
+
+	algorithm := pb.PublicKey_Ed25519
+	container := &pb.Biscuit{Blocks: []*pb.SignedBlock{{
+		Block: make([]byte, proto.Size(pbBlock)),
+		NextKey: &pb.PublicKey{
+			Algorithm: &algorithm,
+			Key:       make([]byte, ed25519.PublicKeySize),
+		},
+		Signature: make([]byte, ed25519.SignatureSize),
+	}}}
+	return proto.Size(container), nil
+}
+
 func (b *blockBuilder) Build() *Block {
 	b.symbols = b.symbols.SplitOff(b.symbolsStart)
 
@@ -305,6 +897,6 @@ func (b *blockBuilder) Build() *Block {
 		rules:   rules,
 		checks:  checks,
 		context: b.context,
-		version: MaxSchemaVersion,
+		version: blockSchemaVersion(&facts, rules, checks),
 	}
 }
@@ -0,0 +1,632 @@
+package biscuit_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/biscuit-auth/biscuit-go/v2"
+	"github.com/biscuit-auth/biscuit-go/v2/datalog"
+	"github.com/biscuit-auth/biscuit-go/v2/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilderFromString(t *testing.T) {
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	builder := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFactFromString(`right("/a", "read")`))
+	require.NoError(t, builder.AddAuthorityRuleFromString(`can_read($r) <- right($r, "read")`))
+	require.NoError(t, builder.AddAuthorityCheckFromString(`check if resource($r), can_read($r)`))
+
+	require.Error(t, builder.AddAuthorityFactFromString(`right("/a", "read"), right("/b", "read")`))
+	require.Error(t, builder.AddAuthorityFactFromString(`right($r, "read")`))
+	require.Error(t, builder.AddAuthorityRuleFromString(`right("/a", "read")`))
+	require.Error(t, builder.AddAuthorityCheckFromString(`can_read($r) <- right($r, "read")`))
+
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	expected := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, expected.AddAuthorityFact(parser.New().Must().Fact(`right("/a", "read")`, nil)))
+	require.NoError(t, expected.AddAuthorityRule(parser.New().Must().Rule(`can_read($r) <- right($r, "read")`, nil)))
+	require.NoError(t, expected.AddAuthorityCheck(parser.New().Must().Check(`check if resource($r), can_read($r)`, nil)))
+	expectedBiscuit, err := expected.Build()
+	require.NoError(t, err)
+	require.Equal(t, expectedBiscuit.Code(), b.Code())
+
+	for resource, allowed := range map[string]bool{"/a": true, "/b": false} {
+		v, err := b.Authorizer(publicRoot)
+		require.NoError(t, err)
+		v.AddFact(biscuit.Fact{Predicate: biscuit.Predicate{Name: "resource", IDs: []biscuit.Term{biscuit.String(resource)}}})
+		v.AddPolicy(biscuit.DefaultAllowPolicy)
+		if allowed {
+			require.NoError(t, v.Authorize())
+		} else {
+			require.Error(t, v.Authorize())
+		}
+	}
+}
+
+func TestAuthorizerAddPolicyFromString(t *testing.T) {
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	builder := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFactFromString(`resource("/a")`))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	policy := `allow if resource($r), operation("read")`
+	for operation, allowed := range map[string]bool{"read": true, "write": false} {
+		fromString, err := b.Authorizer(publicRoot)
+		require.NoError(t, err)
+		fromString.AddFact(biscuit.Fact{Predicate: biscuit.Predicate{Name: "operation", IDs: []biscuit.Term{biscuit.String(operation)}}})
+		require.NoError(t, fromString.AddPolicyFromString(policy))
+
+		typed, err := b.Authorizer(publicRoot)
+		require.NoError(t, err)
+		typed.AddFact(biscuit.Fact{Predicate: biscuit.Predicate{Name: "operation", IDs: []biscuit.Term{biscuit.String(operation)}}})
+		typed.AddPolicy(parser.New().Must().Policy(policy, nil))
+
+		typedErr := typed.Authorize()
+		require.Equal(t, typedErr, fromString.Authorize())
+		if allowed {
+			require.NoError(t, typedErr)
+		} else {
+			require.ErrorIs(t, typedErr, biscuit.ErrNoMatchingPolicy)
+		}
+	}
+
+	v, err := b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	require.Error(t, v.AddPolicyFromString(`allow if resource($r); deny if true`))
+	require.Error(t, v.AddPolicyFromString(`allow if resource($r) deny if true`))
+	require.Error(t, v.AddPolicyFromString(`check if resource($r)`))
+	require.Error(t, v.AddPolicyFromString(``))
+}
+
+func TestAuthorizerAddFacts(t *testing.T) {
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	builder := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityCheckFromString(`check if role("role99")`))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	facts := make([]biscuit.Fact, 0, 100)
+	for i := 0; i < 100; i++ {
+		facts = append(facts, biscuit.Fact{Predicate: biscuit.Predicate{Name: "role", IDs: []biscuit.Term{biscuit.String(fmt.Sprintf("role%d", i))}}})
+	}
+
+	v, err := b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddFacts(facts)
+
+	res, err := v.Query(parser.New().Must().Rule(`data($r) <- role($r)`, nil))
+	require.NoError(t, err)
+	require.Len(t, res, 100)
+	require.Contains(t, v.PrintWorld(), `role("role99")`)
+
+	v.AddPolicy(biscuit.DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+
+	fromString, err := b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	require.NoError(t, fromString.AddFactsFromString(`role("role98"); role("role99"); user(1);`))
+	require.NoError(t, fromString.AddFactsFromString(`role("role100")`))
+	res, err = fromString.Query(parser.New().Must().Rule(`data($r) <- role($r)`, nil))
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+
+	require.ErrorIs(t, fromString.AddFactsFromString(`role("admin"); data($r) <- role($r)`), parser.ErrNotAFact)
+	require.ErrorIs(t, fromString.AddFactsFromString(`role("admin"); check if role("admin")`), parser.ErrNotAFact)
+	require.Error(t, fromString.AddFactsFromString(`role($r)`))
+	res, err = fromString.Query(parser.New().Must().Rule(`data($r) <- role($r)`, nil))
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+}
+
+func TestRunCheck(t *testing.T) {
+	_, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	builder := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFactFromString(`right("/a", "read")`))
+	require.NoError(t, builder.AddAuthorityRuleFromString(`can_read($r) <- right($r, "read")`))
+	require.NoError(t, builder.AddAuthorityCheckFromString(`check if operation("write")`))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	block := b.CreateBlock()
+	require.NoError(t, block.AddBlock(parser.New().Must().Block(`right("/b", "read");`, nil)))
+	b, err = b.Append(rand.Reader, block.Build())
+	require.NoError(t, err)
+
+	resource := func(r string) biscuit.Fact {
+		return biscuit.Fact{Predicate: biscuit.Predicate{Name: "resource", IDs: []biscuit.Term{biscuit.String(r)}}}
+	}
+
+	testCases := []struct {
+		desc    string
+		check   string
+		ambient []biscuit.Fact
+		passes  bool
+	}{
+		{desc: "token fact", check: `check if right("/a", "read")`, passes: true},
+		{desc: "token rule and ambient fact", check: `check if resource($r), can_read($r)`, ambient: []biscuit.Fact{resource("/a")}, passes: true},
+		{desc: "missing ambient fact", check: `check if resource($r), can_read($r)`},
+		{desc: "unmatched ambient fact", check: `check if resource($r), can_read($r)`, ambient: []biscuit.Fact{resource("/b")}},
+		{desc: "check all", check: `check all resource($r), $r.starts_with("/a")`, ambient: []biscuit.Fact{resource("/a")}, passes: true},
+		{desc: "check all failing", check: `check all resource($r), $r.starts_with("/a")`, ambient: []biscuit.Fact{resource("/a"), resource("/b")}},
+		{desc: "block fact not trusted", check: `check if right("/b", "read")`},
+		{desc: "reject", check: `reject if right("/a", "write")`, passes: true},
+		{desc: "in", check: `check if resource($r), $r in ["/a", "/c"]`, ambient: []biscuit.Fact{resource("/a")}, passes: true},
+		{desc: "not in", check: `check if resource($r), $r not in ["/a", "/c"]`, ambient: []biscuit.Fact{resource("/a")}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			// the token's own check on the operation is not evaluated
+			passes, err := b.RunCheck(parser.New().Must().Check(tc.check, nil), tc.ambient)
+			require.NoError(t, err)
+			require.Equal(t, tc.passes, passes)
+		})
+	}
+}
+
+func TestCheckString(t *testing.T) {
+	for _, input := range []string{
+		`check if resource($r)`,
+		`check if resource($r), operation("read"), $r.starts_with("/a/") trusting authority`,
+		`check if time($t), $t <= 2030-01-01T00:00:00Z or admin(true)`,
+		`check if value($v), [1, 2, 3].contains($v), $v * 2 != 4, !($v == 5)`,
+		`check if data($d), $d == hex:aabbcc, $d.length() > 1`,
+		`check all operation($op), ["read", "write"].contains($op)`,
+		`check if roles($r), $r.subset(["admin", "dev"]), ["dev"].superset($r)`,
+	} {
+		t.Run(input, func(t *testing.T) {
+			check, err := parser.FromStringCheck(input)
+			require.NoError(t, err)
+
+			out := check.String(nil)
+			parsed, err := parser.FromStringCheck(out)
+			require.NoError(t, err, out)
+			require.Equal(t, check, parsed)
+		})
+	}
+
+	check := biscuit.Check{Queries: []biscuit.Rule{{
+		Head: biscuit.Predicate{Name: "query"},
+		Body: []biscuit.Predicate{{Name: "resource", IDs: []biscuit.Term{biscuit.Variable("r")}}},
+		Expressions: []biscuit.Expression{{
+			biscuit.Value{Term: biscuit.Variable("r")},
+			biscuit.Value{Term: biscuit.String("/a")},
+			biscuit.BinaryPrefix,
+		}},
+	}}}
+	require.Equal(t, `check if resource($r), $r.starts_with("/a")`, check.String(nil))
+}
+
+func TestExpressionString(t *testing.T) {
+	expr := biscuit.Expression{
+		biscuit.Value{Term: biscuit.Integer(1)},
+		biscuit.Value{Term: biscuit.Integer(2)},
+		biscuit.Value{Term: biscuit.Integer(3)},
+		biscuit.BinaryMul,
+		biscuit.BinaryAdd,
+		biscuit.Value{Term: biscuit.Integer(7)},
+		biscuit.BinaryEqual,
+	}
+	require.Equal(t, `1 + 2 * 3 == 7`, expr.String(nil))
+
+	check, err := parser.FromStringCheck(`check if ` + expr.String(nil))
+	require.NoError(t, err)
+	require.Equal(t, []biscuit.Expression{expr}, check.Queries[0].Expressions)
+
+	expr = biscuit.Expression{
+		biscuit.Value{Term: biscuit.Variable("r")},
+		biscuit.Value{Term: biscuit.String("/a")},
+		biscuit.BinaryPrefix,
+	}
+	symbols := &datalog.SymbolTable{}
+	require.Equal(t, `$r.starts_with("/a")`, expr.String(symbols))
+	require.Empty(t, *symbols)
+}
+
+func TestParsedBlockReservedPredicates(t *testing.T) {
+	block, err := parser.FromStringBlock(`authority("file1"); resource("file1"); ambient($f) <- resource($f);`)
+	require.NoError(t, err)
+	require.NoError(t, block.Validate(true))
+	err = block.Validate(false)
+	require.ErrorIs(t, err, biscuit.ErrInvalidBlockFact)
+	require.EqualError(t, err, `biscuit: reserved predicates outside of the authority block: fact authority("file1"), rule ambient($f)`)
+
+	block, err = parser.FromStringBlock(`resource("file1"); right($f) <- resource($f);`)
+	require.NoError(t, err)
+	require.NoError(t, block.Validate(false))
+}
+
+func TestParsedBlockSymbolTable(t *testing.T) {
+	block, err := parser.FromStringBlock(`
+		project("apollo");
+		member($p, "alice") <- project($p), team("blue");
+		check if resource($r), $r.starts_with("/apollo");
+	`)
+	require.NoError(t, err)
+
+	// "member", "resource" and "team" are default symbols
+	require.Equal(t, &datalog.SymbolTable{"apollo", "project", "p", "blue", "alice", "r", "/apollo"}, block.SymbolTable())
+
+	other, err := parser.FromStringBlock(`project("gemini");`)
+	require.NoError(t, err)
+	require.False(t, block.SymbolTable().IsDisjoint(other.SymbolTable()))
+	other, err = parser.FromStringBlock(`label("gemini");`)
+	require.NoError(t, err)
+	require.True(t, block.SymbolTable().IsDisjoint(other.SymbolTable()))
+
+	require.Empty(t, *biscuit.ParsedBlock{}.SymbolTable())
+}
+
+func TestRejectIf(t *testing.T) {
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	check, err := parser.FromStringCheck(`reject if operation($op), ["delete", "admin"].contains($op)`)
+	require.NoError(t, err)
+	require.Equal(t, biscuit.CheckKindReject, check.Kind)
+
+	builder := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityCheck(check))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = biscuit.Unmarshal(serialized)
+	require.NoError(t, err)
+	require.Equal(t, datalog.CheckKindReject, b.Checks()[0][0].Kind)
+	require.Contains(t, b.String(), `reject if operation($op), ["admin", "delete"].contains($op)`)
+
+	for _, tc := range []struct {
+		operations []string
+		valid      bool
+	}{
+		{operations: []string{"read"}, valid: true},
+		{operations: []string{"read", "delete"}, valid: false},
+		{operations: []string{"admin"}, valid: false},
+	} {
+		v, err := b.Authorizer(publicRoot)
+		require.NoError(t, err)
+		for _, op := range tc.operations {
+			v.AddFact(biscuit.Fact{Predicate: biscuit.Predicate{Name: "operation", IDs: []biscuit.Term{biscuit.String(op)}}})
+		}
+		v.AddPolicy(biscuit.DefaultAllowPolicy)
+		if tc.valid {
+			require.NoError(t, v.Authorize(), tc)
+		} else {
+			require.Error(t, v.Authorize(), tc)
+		}
+	}
+}
+
+func TestCheckAll(t *testing.T) {
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	check, err := parser.FromStringCheck(`check all operation($op), allowed_operations($allowed), $allowed.contains($op)`)
+	require.NoError(t, err)
+	require.Equal(t, biscuit.CheckKindAll, check.Kind)
+
+	builder := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFactFromString(`allowed_operations(["read", "write"])`))
+	require.NoError(t, builder.AddAuthorityCheck(check))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = biscuit.Unmarshal(serialized)
+	require.NoError(t, err)
+	require.Equal(t, datalog.CheckKindAll, b.Checks()[0][0].Kind)
+	require.Contains(t, b.String(), "check all operation($op)")
+
+	for _, tc := range []struct {
+		operations []string
+		valid      bool
+	}{
+		{operations: []string{"read"}, valid: true},
+		{operations: []string{"read", "write"}, valid: true},
+		{operations: []string{"read", "delete"}, valid: false},
+		{operations: nil, valid: false},
+	} {
+		v, err := b.Authorizer(publicRoot)
+		require.NoError(t, err)
+		for _, op := range tc.operations {
+			v.AddFact(biscuit.Fact{Predicate: biscuit.Predicate{Name: "operation", IDs: []biscuit.Term{biscuit.String(op)}}})
+		}
+		v.AddPolicy(biscuit.DefaultAllowPolicy)
+		if tc.valid {
+			require.NoError(t, v.Authorize(), tc.operations)
+		} else {
+			require.Error(t, v.Authorize(), tc.operations)
+		}
+	}
+}
+
+func TestSubsetSuperset(t *testing.T) {
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	builder := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityCheckFromString(`check if roles($r), $r.subset(["admin", "dev"])`))
+	require.NoError(t, builder.AddAuthorityCheckFromString(`check if scopes($s), $s.superset([1, 2])`))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = biscuit.Unmarshal(serialized)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `$r.subset(["admin", "dev"])`)
+	require.Contains(t, b.String(), `$s.superset([1, 2])`)
+
+	for _, tc := range []struct {
+		roles  string
+		scopes string
+		valid  bool
+	}{
+		{roles: `["dev"]`, scopes: `[1, 2, 3]`, valid: true},
+		{roles: `["dev", "admin"]`, scopes: `[2, 1]`, valid: true},
+		{roles: `["dev", "ops"]`, scopes: `[1, 2]`, valid: false},
+		{roles: `["dev"]`, scopes: `[1]`, valid: false},
+	} {
+		v, err := b.Authorizer(publicRoot)
+		require.NoError(t, err)
+		v.AddAuthorizer(parser.New().Must().Authorizer(`roles(`+tc.roles+`); scopes(`+tc.scopes+`); allow if true;`, nil))
+		if tc.valid {
+			require.NoError(t, v.Authorize(), tc)
+		} else {
+			require.Error(t, v.Authorize(), tc)
+		}
+	}
+}
+
+func TestContainsStringAndSet(t *testing.T) {
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	builder := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityCheckFromString(`check if path($p), $p.contains("admin")`))
+	require.NoError(t, builder.AddAuthorityCheckFromString(`check if ids($i), $i.contains(1)`))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = biscuit.Unmarshal(serialized)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `$p.contains("admin")`)
+	require.Contains(t, b.String(), `$i.contains(1)`)
+
+	for _, tc := range []struct {
+		path  string
+		ids   string
+		valid bool
+	}{
+		{path: `"/admin/users"`, ids: `[1, 2]`, valid: true},
+		// substring of a string, not an element of a set
+		{path: `["/admin/users"]`, ids: `[1, 2]`, valid: false},
+		{path: `"/admin/users"`, ids: `[2]`, valid: false},
+		{path: `"/users"`, ids: `[1]`, valid: false},
+	} {
+		v, err := b.Authorizer(publicRoot)
+		require.NoError(t, err)
+		v.AddAuthorizer(parser.New().Must().Authorizer(`path(`+tc.path+`); ids(`+tc.ids+`); allow if true;`, nil))
+		if tc.valid {
+			require.NoError(t, v.Authorize(), tc)
+		} else {
+			require.Error(t, v.Authorize(), tc)
+		}
+	}
+}
+
+func TestBitwiseAnd(t *testing.T) {
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	builder := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityCheckFromString(`check if flags($f), $f & 6 == 4`))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = biscuit.Unmarshal(serialized)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `$f & 6 == 4`)
+
+	for _, tc := range []struct {
+		flags int
+		valid bool
+	}{
+		{flags: 4, valid: true},
+		{flags: 13, valid: true},
+		{flags: 6, valid: false},
+		{flags: 1, valid: false},
+	} {
+		v, err := b.Authorizer(publicRoot)
+		require.NoError(t, err)
+		v.AddAuthorizer(parser.New().Must().Authorizer(fmt.Sprintf(`flags(%d); allow if true;`, tc.flags), nil))
+		if tc.valid {
+			require.NoError(t, v.Authorize(), tc)
+		} else {
+			require.Error(t, v.Authorize(), tc)
+		}
+	}
+}
+
+func TestCharLength(t *testing.T) {
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	builder := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityCheckFromString(`check if name($n), $n.char_length() <= 4`))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = biscuit.Unmarshal(serialized)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `$n.char_length() <= 4`)
+
+	for _, tc := range []struct {
+		name  string
+		valid bool
+	}{
+		{name: "abcd", valid: true},
+		{name: "Миша", valid: true},
+		{name: "abcde", valid: false},
+		{name: "Мишаа", valid: false},
+	} {
+		v, err := b.Authorizer(publicRoot)
+		require.NoError(t, err)
+		v.AddAuthorizer(parser.New().Must().Authorizer(fmt.Sprintf(`name(%q); allow if true;`, tc.name), nil))
+		if tc.valid {
+			require.NoError(t, v.Authorize(), tc)
+		} else {
+			require.Error(t, v.Authorize(), tc)
+		}
+	}
+}
+
+func TestDateConversions(t *testing.T) {
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	builder := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityCheckFromString(`check if issued_at($ts), $ts.to_date() <= 2021-01-01T00:00:00Z`))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = biscuit.Unmarshal(serialized)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `$ts.to_date() <= 2021-01-01T00:00:00Z`)
+
+	for _, tc := range []struct {
+		ts    int64
+		valid bool
+	}{
+		{ts: 1609459200, valid: true},
+		{ts: 1600000000, valid: true},
+		{ts: 1609459201, valid: false},
+		{ts: -1, valid: false},
+	} {
+		v, err := b.Authorizer(publicRoot)
+		require.NoError(t, err)
+		v.AddAuthorizer(parser.New().Must().Authorizer(fmt.Sprintf(`issued_at(%d); allow if time($t), $t.to_int() - 3600 <= 1609459200;`, tc.ts), nil))
+		v.AddCurrentTime(time.Unix(1609459200, 0))
+		if tc.valid {
+			require.NoError(t, v.Authorize(), tc)
+		} else {
+			require.Error(t, v.Authorize(), tc)
+		}
+	}
+}
+
+func TestExpressionOnlyChecks(t *testing.T) {
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	for _, tc := range []struct {
+		check string
+		valid bool
+	}{
+		{check: `check if 1 + 1 == 2`, valid: true},
+		{check: `check if 1 == 2`, valid: false},
+		{check: `check all 1 + 1 == 2`, valid: true},
+		{check: `check all 1 == 2`, valid: false},
+		{check: `check if 1 == 2 or true`, valid: true},
+		// arithmetic is evaluated before comparisons
+		{check: `check if 1 + 2 * 3 == 7`, valid: true},
+		{check: `check if (1 + 2) * 3 == 9`, valid: true},
+		{check: `check if 2 + 1 < 2 * 2`, valid: true},
+		{check: `check if 2 * 2 < 2 + 1`, valid: false},
+		{check: `check if 10 - 4 - 3 == 3`, valid: true},
+		{check: `check if 12 / 3 / 2 == 2`, valid: true},
+		{check: `check if !(1 + 1 > 3)`, valid: true},
+		{check: `check if 1 < 2 || 1 > 2 && false`, valid: true},
+		{check: `check if (1 < 2 || 1 > 2) && false`, valid: false},
+	} {
+		t.Run(tc.check, func(t *testing.T) {
+			check, err := parser.FromStringCheck(tc.check)
+			require.NoError(t, err)
+			for _, q := range check.Queries {
+				require.Empty(t, q.Body)
+				require.NotEmpty(t, q.Expressions)
+			}
+
+			builder := biscuit.NewBuilder(privateRoot)
+			require.NoError(t, builder.AddAuthorityCheck(check))
+			b, err := builder.Build()
+			require.NoError(t, err)
+
+			serialized, err := b.Serialize()
+			require.NoError(t, err)
+			b, err = biscuit.Unmarshal(serialized)
+			require.NoError(t, err)
+
+			v, err := b.Authorizer(publicRoot)
+			require.NoError(t, err)
+			v.AddPolicy(biscuit.DefaultAllowPolicy)
+			if tc.valid {
+				require.NoError(t, v.Authorize())
+			} else {
+				require.Error(t, v.Authorize())
+			}
+
+			// the same check added by the authorizer
+			b, err = biscuit.NewBuilder(privateRoot).Build()
+			require.NoError(t, err)
+			v, err = b.Authorizer(publicRoot)
+			require.NoError(t, err)
+			v.AddCheck(check)
+			v.AddPolicy(biscuit.DefaultAllowPolicy)
+			if tc.valid {
+				require.NoError(t, v.Authorize())
+			} else {
+				require.Error(t, v.Authorize())
+			}
+		})
+	}
+}
+
+func TestEmptyStringAndBytes(t *testing.T) {
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	fact := biscuit.Fact{Predicate: biscuit.Predicate{Name: "empty", IDs: []biscuit.Term{biscuit.String(""), biscuit.Bytes{}}}}
+	builder := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(fact))
+	require.NoError(t, builder.AddAuthorityCheckFromString(`check if empty($s, $b), $s.length() == 0, $b.length() == 0, $s == "", $b == hex:`))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = biscuit.Unmarshal(serialized)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `empty("", hex:)`)
+
+	v, err := b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddPolicy(biscuit.DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+
+	facts, err := v.Query(parser.New().Must().Rule(`empty($s, $b) <- empty($s, $b)`, nil))
+	require.NoError(t, err)
+	require.Len(t, facts, 1)
+	require.Equal(t, biscuit.String(""), facts[0].IDs[0])
+	require.Len(t, facts[0].IDs[1], 0)
+	require.Equal(t, biscuit.TermTypeBytes, facts[0].IDs[1].Type())
+
+	// empty values are still values
+	v, err = b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	v.AddAuthorizer(parser.New().Must().Authorizer(`allow if empty($s, $b), $s.length() > 0;`, nil))
+	require.Error(t, v.Authorize())
+}
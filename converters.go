@@ -1,19 +1,60 @@
 package biscuit
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
 	"fmt"
 
 	"github.com/biscuit-auth/biscuit-go/v2/datalog"
 	"github.com/biscuit-auth/biscuit-go/v2/pb"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
-func tokenBlockToProtoBlock(input *Block) (*pb.Block, error) {
+// publicKeyTable interns the public keys referenced by scopes. As for symbols,
+// the table is shared by the blocks of a token, each block only carrying
+// the keys it adds to the table.
+type publicKeyTable []ed25519.PublicKey
+
+func (t *publicKeyTable) Insert(key ed25519.PublicKey) uint64 {
+	for i, k := range *t {
+		if bytes.Equal(k, key) {
+			return uint64(i)
+		}
+	}
+	*t = append(*t, key)
+	return uint64(len(*t) - 1)
+}
+
+func (t publicKeyTable) Get(index int64) (ed25519.PublicKey, error) {
+	if index < 0 || index >= int64(len(t)) {
+		return nil, fmt.Errorf("biscuit: invalid public key index: %d", index)
+	}
+	return t[index], nil
+}
+
+func (t publicKeyTable) Clone() publicKeyTable {
+	return append(publicKeyTable{}, t...)
+}
+
+// tokenBlockToProtoBlock converts the block, adding the public keys it
+// references to keys, which holds the keys of the previous blocks.
+func tokenBlockToProtoBlock(input *Block, keys *publicKeyTable) (*pb.Block, error) {
+	seen := make(map[string]int, len(*input.symbols))
+	for i, s := range *input.symbols {
+		if j, ok := seen[s]; ok {
+			return nil, fmt.Errorf("%w: %q at indexes %d and %d", ErrDuplicateSymbol, s, j, i)
+		}
+		seen[s] = i
+	}
+
 	out := &pb.Block{
 		Symbols: *input.symbols,
 		Context: proto.String(input.context),
 		Version: proto.Uint32(input.version),
 	}
+	keysStart := len(*keys)
 
 	facts := input.facts
 	if facts != nil {
@@ -31,7 +72,7 @@ func tokenBlockToProtoBlock(input *Block) (*pb.Block, error) {
 	if rules != nil {
 		out.RulesV2 = make([]*pb.RuleV2, len(rules))
 		for i, rule := range rules {
-			r, err := tokenRuleToProtoRuleV2(rule)
+			r, err := tokenRuleToProtoRuleV2(rule, keys)
 			if err != nil {
 				return nil, err
 			}
@@ -43,7 +84,7 @@ func tokenBlockToProtoBlock(input *Block) (*pb.Block, error) {
 	if checks != nil {
 		out.ChecksV2 = make([]*pb.CheckV2, len(checks))
 		for i, check := range checks {
-			c, err := tokenCheckToProtoCheckV2(check)
+			c, err := tokenCheckToProtoCheckV2(check, keys)
 			if err != nil {
 				return nil, err
 			}
@@ -51,10 +92,55 @@ func tokenBlockToProtoBlock(input *Block) (*pb.Block, error) {
 		}
 	}
 
+	for _, s := range input.scopes {
+		scope, err := tokenScopeToProtoScope(s, keys)
+		if err != nil {
+			return nil, err
+		}
+		out.Scope = append(out.Scope, scope)
+	}
+
+	algorithm := pb.PublicKey_Ed25519
+	for _, key := range (*keys)[keysStart:] {
+		out.PublicKeys = append(out.PublicKeys, &pb.PublicKey{
+			Algorithm: &algorithm,
+			Key:       key,
+		})
+	}
+
 	return out, nil
 }
 
-func protoBlockToTokenBlock(input *pb.Block) (*Block, error) {
+// legacyBlockIndex returns the index of a block serialized by a previous format
+// version, where the field 1 was the block index. As the field now holds
+// the symbols, the decoder keeps such an integer as an unknown field.
+func legacyBlockIndex(input *pb.Block) (uint64, bool) {
+	unknown := input.ProtoReflect().GetUnknown()
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return 0, false
+		}
+		unknown = unknown[n:]
+		if num == 1 && typ == protowire.VarintType {
+			index, n := protowire.ConsumeVarint(unknown)
+			if n < 0 {
+				return 0, false
+			}
+			return index, true
+		}
+		n = protowire.ConsumeFieldValue(num, typ, unknown)
+		if n < 0 {
+			return 0, false
+		}
+		unknown = unknown[n:]
+	}
+	return 0, false
+}
+
+// protoBlockToTokenBlock converts the block, adding the public keys it
+// declares to keys, which holds the keys of the previous blocks.
+func protoBlockToTokenBlock(input *pb.Block, keys *publicKeyTable) (*Block, error) {
 	symbols := datalog.SymbolTable(input.Symbols)
 
 	var facts datalog.FactSet
@@ -76,8 +162,26 @@ func protoBlockToTokenBlock(input *pb.Block) (*Block, error) {
 		)
 	}
 
+	if input.GetVersion() < 4 && usesScopes(input) {
+		return nil, fmt.Errorf(
+			"biscuit: failed to convert proto block to token block: scopes require block version 4, got %d",
+			input.GetVersion(),
+		)
+	}
+
+	for _, pbKey := range input.PublicKeys {
+		if pbKey.GetAlgorithm() != pb.PublicKey_Ed25519 {
+			return nil, UnsupportedAlgorithm
+		}
+		if len(pbKey.Key) != ed25519.PublicKeySize {
+			return nil, ErrInvalidKeySize
+		}
+		keys.Insert(pbKey.Key)
+	}
+
+	var scopes []datalog.Scope
 	switch input.GetVersion() {
-	case 3:
+	case 3, 4, 5, 6:
 		facts = make(datalog.FactSet, len(input.FactsV2))
 		rules = make([]datalog.Rule, len(input.RulesV2))
 		checks = make([]datalog.Check, len(input.ChecksV2))
@@ -91,7 +195,7 @@ func protoBlockToTokenBlock(input *pb.Block) (*Block, error) {
 		}
 
 		for i, pbRule := range input.RulesV2 {
-			r, err := protoRuleToTokenRuleV2(pbRule)
+			r, err := protoRuleToTokenRuleV2(pbRule, *keys)
 			if err != nil {
 				return nil, err
 			}
@@ -99,12 +203,20 @@ func protoBlockToTokenBlock(input *pb.Block) (*Block, error) {
 		}
 
 		for i, pbCheck := range input.ChecksV2 {
-			c, err := protoCheckToTokenCheckV2(pbCheck)
+			c, err := protoCheckToTokenCheckV2(pbCheck, *keys)
 			if err != nil {
 				return nil, err
 			}
 			checks[i] = *c
 		}
+
+		for _, pbScope := range input.Scope {
+			s, err := protoScopeToTokenScope(pbScope, *keys)
+			if err != nil {
+				return nil, err
+			}
+			scopes = append(scopes, *s)
+		}
 	default:
 		return nil, fmt.Errorf("biscuit: failed to convert proto block to token block: unsupported version: %d", input.GetVersion())
 	}
@@ -114,11 +226,49 @@ func protoBlockToTokenBlock(input *pb.Block) (*Block, error) {
 		facts:   &facts,
 		rules:   rules,
 		checks:  checks,
+		scopes:  scopes,
 		context: input.GetContext(),
 		version: input.GetVersion(),
 	}, nil
 }
 
+func usesScopes(input *pb.Block) bool {
+	if len(input.Scope) > 0 || len(input.PublicKeys) > 0 {
+		return true
+	}
+	for _, r := range input.RulesV2 {
+		if len(r.Scope) > 0 {
+			return true
+		}
+	}
+	for _, c := range input.ChecksV2 {
+		for _, q := range c.Queries {
+			if len(q.Scope) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// protoExternalSignatureKey validates the external signature of a third party
+// block and returns its public key.
+func protoExternalSignatureKey(input *pb.ExternalSignature) (ed25519.PublicKey, error) {
+	if input == nil {
+		return nil, errors.New("biscuit: missing external signature")
+	}
+	if input.PublicKey.GetAlgorithm() != pb.PublicKey_Ed25519 {
+		return nil, UnsupportedAlgorithm
+	}
+	if len(input.PublicKey.GetKey()) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKeySize
+	}
+	if len(input.Signature) != ed25519.SignatureSize {
+		return nil, ErrInvalidSignatureSize
+	}
+	return input.PublicKey.Key, nil
+}
+
 /*func tokenSignatureToProtoSignature(ts *sig.TokenSignature) *pb.Signature {
 	params, z := ts.Encode()
 	return &pb.Signature{
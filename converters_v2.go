@@ -1,9 +1,11 @@
 package biscuit
 
 import (
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 
 	"github.com/biscuit-auth/biscuit-go/v2/datalog"
 	"github.com/biscuit-auth/biscuit-go/v2/pb"
@@ -92,6 +94,19 @@ func tokenIDToProtoIDV2(input datalog.Term) (*pb.TermV2, error) {
 		pbId = &pb.TermV2{
 			Content: &pb.TermV2_Bool{Bool: bool(input.(datalog.Bool))},
 		}
+	case datalog.TermTypeTagged:
+		tagged := input.(datalog.Tagged)
+		tag := uint64(tagged.Tag)
+		value := tagged.Value
+		if value == nil {
+			value = []byte{}
+		}
+		pbId = &pb.TermV2{
+			Content: &pb.TermV2_Tagged{Tagged: &pb.Tagged{
+				Tag:   &tag,
+				Value: value,
+			}},
+		}
 	case datalog.TermTypeSet:
 		datalogSet := input.(datalog.Set)
 		if len(datalogSet) == 0 {
@@ -104,6 +119,8 @@ func tokenIDToProtoIDV2(input datalog.Term) (*pb.TermV2, error) {
 			return nil, errors.New("biscuit: failed to convert token ID to proto ID: set cannot contains variable")
 		case datalog.TermTypeSet:
 			return nil, errors.New("biscuit: failed to convert token ID to proto ID: set cannot contains other sets")
+		case datalog.TermTypeArray, datalog.TermTypeMap:
+			return nil, errors.New("biscuit: failed to convert token ID to proto ID: set cannot contains arrays or maps")
 		}
 
 		protoSet := make([]*pb.TermV2, 0, len(datalogSet))
@@ -130,13 +147,76 @@ func tokenIDToProtoIDV2(input datalog.Term) (*pb.TermV2, error) {
 				},
 			},
 		}
+	case datalog.TermTypeArray:
+		datalogArray := input.(datalog.Array)
+		protoArray := make([]*pb.TermV2, 0, len(datalogArray))
+		for _, datalogElt := range datalogArray {
+			protoElt, err := tokenIDToProtoIDV2(datalogElt)
+			if err != nil {
+				return nil, err
+			}
+			protoArray = append(protoArray, protoElt)
+		}
+		pbId = &pb.TermV2{
+			Content: &pb.TermV2_Array{
+				Array: &pb.Array{
+					Array: protoArray,
+				},
+			},
+		}
+	case datalog.TermTypeMap:
+		datalogMap := input.(datalog.Map)
+		protoEntries := make([]*pb.MapEntry, 0, len(datalogMap))
+		for k, v := range datalogMap {
+			key := &pb.MapKey{}
+			switch k.Type() {
+			case datalog.TermTypeInteger:
+				key.Content = &pb.MapKey_Integer{Integer: int64(k.(datalog.Integer))}
+			case datalog.TermTypeString:
+				key.Content = &pb.MapKey_String_{String_: uint64(k.(datalog.String))}
+			default:
+				return nil, fmt.Errorf("biscuit: failed to convert token ID to proto ID: unsupported map key type: %v", k.Type())
+			}
+
+			value, err := tokenIDToProtoIDV2(v)
+			if err != nil {
+				return nil, err
+			}
+			protoEntries = append(protoEntries, &pb.MapEntry{Key: key, Value: value})
+		}
+		// map iteration order is random, sort the entries to produce a stable encoding
+		sort.Slice(protoEntries, func(i, j int) bool {
+			return lessProtoMapKey(protoEntries[i].Key, protoEntries[j].Key)
+		})
+		pbId = &pb.TermV2{
+			Content: &pb.TermV2_Map{
+				Map: &pb.Map{
+					Entries: protoEntries,
+				},
+			},
+		}
 	default:
 		return nil, fmt.Errorf("biscuit: failed to convert token ID to proto ID: unsupported id type: %v", input.Type())
 	}
 	return pbId, nil
 }
 
+// ErrTermTooDeep is returned when decoding a term with more than
+// maxTermDepth levels of nested arrays and maps.
+var ErrTermTooDeep = errors.New("biscuit: term nesting too deep")
+
+// maxTermDepth bounds the recursion when decoding hostile tokens.
+const maxTermDepth = 64
+
 func protoIDToTokenIDV2(input *pb.TermV2) (*datalog.Term, error) {
+	return protoIDToTokenIDV2Depth(input, 0)
+}
+
+func protoIDToTokenIDV2Depth(input *pb.TermV2, depth int) (*datalog.Term, error) {
+	if depth > maxTermDepth {
+		return nil, fmt.Errorf("%w: more than %d levels", ErrTermTooDeep, maxTermDepth)
+	}
+
 	var id datalog.Term
 	switch input.Content.(type) {
 	case *pb.TermV2_String_:
@@ -151,6 +231,9 @@ func protoIDToTokenIDV2(input *pb.TermV2) (*datalog.Term, error) {
 		id = datalog.Bytes(input.GetBytes())
 	case *pb.TermV2_Bool:
 		id = datalog.Bool(input.GetBool())
+	case *pb.TermV2_Tagged:
+		tagged := input.GetTagged()
+		id = datalog.Tagged{Tag: datalog.String(tagged.GetTag()), Value: datalog.Bytes(tagged.GetValue())}
 	case *pb.TermV2_Set:
 		elts := input.GetSet().Set
 		if len(elts) == 0 {
@@ -163,6 +246,8 @@ func protoIDToTokenIDV2(input *pb.TermV2) (*datalog.Term, error) {
 			return nil, errors.New("biscuit: failed to convert proto ID to token ID: set cannot contains variable")
 		case reflect.TypeOf(&pb.TermV2_Set{}):
 			return nil, errors.New("biscuit: failed to convert proto ID to token ID: set cannot contains other sets")
+		case reflect.TypeOf(&pb.TermV2_Array{}), reflect.TypeOf(&pb.TermV2_Map{}):
+			return nil, errors.New("biscuit: failed to convert proto ID to token ID: set cannot contains arrays or maps")
 		}
 
 		datalogSet := make(datalog.Set, 0, len(elts))
@@ -175,13 +260,48 @@ func protoIDToTokenIDV2(input *pb.TermV2) (*datalog.Term, error) {
 				)
 			}
 
-			datalogElt, err := protoIDToTokenIDV2(protoElt)
+			datalogElt, err := protoIDToTokenIDV2Depth(protoElt, depth+1)
 			if err != nil {
 				return nil, err
 			}
 			datalogSet = append(datalogSet, *datalogElt)
 		}
 		id = datalogSet
+	case *pb.TermV2_Array:
+		elts := input.GetArray().Array
+		datalogArray := make(datalog.Array, 0, len(elts))
+		for _, protoElt := range elts {
+			datalogElt, err := protoIDToTokenIDV2Depth(protoElt, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			datalogArray = append(datalogArray, *datalogElt)
+		}
+		id = datalogArray
+	case *pb.TermV2_Map:
+		entries := input.GetMap().Entries
+		datalogMap := make(datalog.Map, len(entries))
+		for _, entry := range entries {
+			var key datalog.Term
+			switch entry.GetKey().GetContent().(type) {
+			case *pb.MapKey_Integer:
+				key = datalog.Integer(entry.Key.GetInteger())
+			case *pb.MapKey_String_:
+				key = datalog.String(entry.Key.GetString_())
+			default:
+				return nil, fmt.Errorf("biscuit: failed to convert proto ID to token ID: unsupported map key type: %T", entry.GetKey().GetContent())
+			}
+			if _, ok := datalogMap[key]; ok {
+				return nil, fmt.Errorf("biscuit: failed to convert proto ID to token ID: duplicate map key: %v", key)
+			}
+
+			value, err := protoIDToTokenIDV2Depth(entry.Value, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			datalogMap[key] = *value
+		}
+		id = datalogMap
 	default:
 		return nil, fmt.Errorf("biscuit: failed to convert proto ID to token ID: unsupported id type: %T", input.Content)
 	}
@@ -189,7 +309,22 @@ func protoIDToTokenIDV2(input *pb.TermV2) (*datalog.Term, error) {
 	return &id, nil
 }
 
-func tokenRuleToProtoRuleV2(input datalog.Rule) (*pb.RuleV2, error) {
+// lessProtoMapKey orders integer keys before string keys,
+// then each kind of key by value.
+func lessProtoMapKey(a, b *pb.MapKey) bool {
+	_, aIsInt := a.Content.(*pb.MapKey_Integer)
+	_, bIsInt := b.Content.(*pb.MapKey_Integer)
+	switch {
+	case aIsInt && bIsInt:
+		return a.GetInteger() < b.GetInteger()
+	case aIsInt != bIsInt:
+		return aIsInt
+	default:
+		return a.GetString_() < b.GetString_()
+	}
+}
+
+func tokenRuleToProtoRuleV2(input datalog.Rule, keys *publicKeyTable) (*pb.RuleV2, error) {
 	pbBody := make([]*pb.PredicateV2, len(input.Body))
 	for i, p := range input.Body {
 		pred, err := tokenPredicateToProtoPredicateV2(p)
@@ -213,14 +348,24 @@ func tokenRuleToProtoRuleV2(input datalog.Rule) (*pb.RuleV2, error) {
 		return nil, err
 	}
 
+	var pbScopes []*pb.Scope
+	for _, s := range input.Scope {
+		scope, err := tokenScopeToProtoScope(s, keys)
+		if err != nil {
+			return nil, err
+		}
+		pbScopes = append(pbScopes, scope)
+	}
+
 	return &pb.RuleV2{
 		Head:        pbHead,
 		Body:        pbBody,
 		Expressions: pbExpressions,
+		Scope:       pbScopes,
 	}, nil
 }
 
-func protoRuleToTokenRuleV2(input *pb.RuleV2) (*datalog.Rule, error) {
+func protoRuleToTokenRuleV2(input *pb.RuleV2, keys publicKeyTable) (*datalog.Rule, error) {
 	body := make([]datalog.Predicate, len(input.Body))
 	for i, pb := range input.Body {
 		b, err := protoPredicateToTokenPredicateV2(pb)
@@ -243,13 +388,67 @@ func protoRuleToTokenRuleV2(input *pb.RuleV2) (*datalog.Rule, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	var scopes []datalog.Scope
+	for _, pbScope := range input.Scope {
+		s, err := protoScopeToTokenScope(pbScope, keys)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, *s)
+	}
+
 	return &datalog.Rule{
 		Head:        *head,
 		Body:        body,
 		Expressions: expressions,
+		Scope:       scopes,
 	}, nil
 }
 
+// tokenScopeToProtoScope interns public keys in keys, which can be nil
+// when the serialization format has no public key table.
+func tokenScopeToProtoScope(input datalog.Scope, keys *publicKeyTable) (*pb.Scope, error) {
+	switch input.Type {
+	case datalog.ScopeTypeAuthority:
+		return &pb.Scope{Content: &pb.Scope_ScopeType_{ScopeType: pb.Scope_Authority}}, nil
+	case datalog.ScopeTypePrevious:
+		return &pb.Scope{Content: &pb.Scope_ScopeType_{ScopeType: pb.Scope_Previous}}, nil
+	case datalog.ScopeTypePublicKey:
+		if keys == nil {
+			return nil, errors.New("biscuit: failed to convert scope: public keys are not supported")
+		}
+		if len(input.PublicKey) != ed25519.PublicKeySize {
+			return nil, ErrInvalidKeySize
+		}
+		return &pb.Scope{Content: &pb.Scope_PublicKey{PublicKey: int64(keys.Insert(input.PublicKey))}}, nil
+	default:
+		return nil, fmt.Errorf("biscuit: failed to convert scope: unsupported scope type: %v", input.Type)
+	}
+}
+
+func protoScopeToTokenScope(input *pb.Scope, keys publicKeyTable) (*datalog.Scope, error) {
+	switch content := input.Content.(type) {
+	case *pb.Scope_ScopeType_:
+		switch content.ScopeType {
+		case pb.Scope_Authority:
+			return &datalog.Scope{Type: datalog.ScopeTypeAuthority}, nil
+		case pb.Scope_Previous:
+			return &datalog.Scope{Type: datalog.ScopeTypePrevious}, nil
+		default:
+			return nil, fmt.Errorf("biscuit: failed to convert proto scope: unsupported scope type: %v", content.ScopeType)
+		}
+	case *pb.Scope_PublicKey:
+		key, err := keys.Get(content.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		return &datalog.Scope{Type: datalog.ScopeTypePublicKey, PublicKey: key}, nil
+	default:
+		return nil, fmt.Errorf("biscuit: failed to convert proto scope: unsupported content: %T", input.Content)
+	}
+}
+
 func tokenExpressionToProtoExpressionV2(input datalog.Expression) (*pb.ExpressionV2, error) {
 	pbExpr := &pb.ExpressionV2{
 		Ops: make([]*pb.Op, len(input)),
@@ -320,6 +519,12 @@ func tokenExprUnaryToProtoExprUnary(op datalog.UnaryOp) (*pb.OpUnary, error) {
 		pbUnaryKind = pb.OpUnary_Parens
 	case datalog.UnaryLength:
 		pbUnaryKind = pb.OpUnary_Length
+	case datalog.UnaryCharLength:
+		pbUnaryKind = pb.OpUnary_CharLength
+	case datalog.UnaryToDate:
+		pbUnaryKind = pb.OpUnary_ToDate
+	case datalog.UnaryToInt:
+		pbUnaryKind = pb.OpUnary_ToInt
 	default:
 		return nil, fmt.Errorf("biscuit: unsupported UnaryOpFunc type: %v", op.UnaryOpFunc.Type())
 	}
@@ -335,6 +540,12 @@ func protoExprUnaryToTokenExprUnary(op *pb.OpUnary) (datalog.UnaryOpFunc, error)
 		unaryOp = datalog.Parens{}
 	case pb.OpUnary_Length:
 		unaryOp = datalog.Length{}
+	case pb.OpUnary_CharLength:
+		unaryOp = datalog.CharLength{}
+	case pb.OpUnary_ToDate:
+		unaryOp = datalog.ToDate{}
+	case pb.OpUnary_ToInt:
+		unaryOp = datalog.ToInt{}
 	default:
 		return nil, fmt.Errorf("biscuit: unsupported proto OpUnary type: %v", op.Kind)
 	}
@@ -378,6 +589,18 @@ func tokenExprBinaryToProtoExprBinary(op datalog.BinaryOp) (*pb.OpBinary, error)
 		pbBinaryKind = pb.OpBinary_Intersection
 	case datalog.BinaryUnion:
 		pbBinaryKind = pb.OpBinary_Union
+	case datalog.BinaryGet:
+		pbBinaryKind = pb.OpBinary_Get
+	case datalog.BinaryNotEqual:
+		pbBinaryKind = pb.OpBinary_NotEqual
+	case datalog.BinarySubset:
+		pbBinaryKind = pb.OpBinary_Subset
+	case datalog.BinarySuperset:
+		pbBinaryKind = pb.OpBinary_Superset
+	case datalog.BinaryBitwiseAnd:
+		pbBinaryKind = pb.OpBinary_BitwiseAnd
+	case datalog.BinaryRegexInsensitive:
+		pbBinaryKind = pb.OpBinary_RegexInsensitive
 	default:
 		return nil, fmt.Errorf("biscuit: unsupported BinaryOpFunc type: %v", op.BinaryOpFunc.Type())
 	}
@@ -421,38 +644,77 @@ func protoExprBinaryToTokenExprBinary(op *pb.OpBinary) (datalog.BinaryOpFunc, er
 		binaryOp = datalog.Intersection{}
 	case pb.OpBinary_Union:
 		binaryOp = datalog.Union{}
+	case pb.OpBinary_Get:
+		binaryOp = datalog.Get{}
+	case pb.OpBinary_NotEqual:
+		binaryOp = datalog.NotEqual{}
+	case pb.OpBinary_Subset:
+		binaryOp = datalog.Subset{}
+	case pb.OpBinary_Superset:
+		binaryOp = datalog.Superset{}
+	case pb.OpBinary_BitwiseAnd:
+		binaryOp = datalog.BitwiseAnd{}
+	case pb.OpBinary_RegexInsensitive:
+		binaryOp = datalog.RegexInsensitive{}
 	default:
 		return nil, fmt.Errorf("biscuit: unsupported proto OpBinary type: %v", op.Kind)
 	}
 	return binaryOp, nil
 }
 
-func tokenCheckToProtoCheckV2(input datalog.Check) (*pb.CheckV2, error) {
+func tokenCheckToProtoCheckV2(input datalog.Check, keys *publicKeyTable) (*pb.CheckV2, error) {
 	pbQueries := make([]*pb.RuleV2, len(input.Queries))
 	for i, query := range input.Queries {
-		q, err := tokenRuleToProtoRuleV2(query)
+		q, err := tokenRuleToProtoRuleV2(query, keys)
 		if err != nil {
 			return nil, err
 		}
 		pbQueries[i] = q
 	}
 
-	return &pb.CheckV2{
+	pbCheck := &pb.CheckV2{
 		Queries: pbQueries,
-	}, nil
+	}
+	switch input.Kind {
+	case datalog.CheckKindOne:
+		// the default kind is omitted
+	case datalog.CheckKindAll:
+		kind := pb.CheckV2_All
+		pbCheck.Kind = &kind
+	case datalog.CheckKindReject:
+		kind := pb.CheckV2_Reject
+		pbCheck.Kind = &kind
+	default:
+		return nil, fmt.Errorf("biscuit: unsupported check kind: %v", input.Kind)
+	}
+
+	return pbCheck, nil
 }
 
-func protoCheckToTokenCheckV2(input *pb.CheckV2) (*datalog.Check, error) {
+func protoCheckToTokenCheckV2(input *pb.CheckV2, keys publicKeyTable) (*datalog.Check, error) {
 	queries := make([]datalog.Rule, len(input.Queries))
 	for i, query := range input.Queries {
-		q, err := protoRuleToTokenRuleV2(query)
+		q, err := protoRuleToTokenRuleV2(query, keys)
 		if err != nil {
 			return nil, err
 		}
 		queries[i] = *q
 	}
 
+	var kind datalog.CheckKind
+	switch input.GetKind() {
+	case pb.CheckV2_One:
+		kind = datalog.CheckKindOne
+	case pb.CheckV2_All:
+		kind = datalog.CheckKindAll
+	case pb.CheckV2_Reject:
+		kind = datalog.CheckKindReject
+	default:
+		return nil, fmt.Errorf("biscuit: unsupported proto check kind: %v", input.GetKind())
+	}
+
 	return &datalog.Check{
 		Queries: queries,
+		Kind:    kind,
 	}, nil
 }
@@ -1,6 +1,8 @@
 package biscuit
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"math"
 	"testing"
 	"time"
@@ -266,6 +268,21 @@ func TestExpressionConvertV2(t *testing.T) {
 				},
 			},
 		},
+		{
+			Desc: "string regexp insensitive",
+			Input: datalog.Expression{
+				datalog.Value{ID: datalog.Variable(15)},
+				datalog.Value{ID: syms.Insert("abcd")},
+				datalog.BinaryOp{BinaryOpFunc: datalog.RegexInsensitive{}},
+			},
+			Expected: &pb.ExpressionV2{
+				Ops: []*pb.Op{
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Variable{Variable: 15}}}},
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_String_{String_: syms.Index("abcd")}}}},
+					{Content: &pb.Op_Binary{Binary: &pb.OpBinary{Kind: pb.OpBinary_RegexInsensitive.Enum()}}},
+				},
+			},
+		},
 		{
 			Desc: "bytes equal",
 			Input: datalog.Expression{
@@ -451,6 +468,160 @@ func TestExpressionConvertV2(t *testing.T) {
 				},
 			},
 		},
+		{
+			Desc: "get",
+			Input: datalog.Expression{
+				datalog.Value{ID: datalog.Array{datalog.Integer(1), datalog.Integer(2)}},
+				datalog.Value{ID: datalog.Integer(0)},
+				datalog.BinaryOp{BinaryOpFunc: datalog.Get{}},
+			},
+			Expected: &pb.ExpressionV2{
+				Ops: []*pb.Op{
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Array{Array: &pb.Array{Array: []*pb.TermV2{
+						{Content: &pb.TermV2_Integer{Integer: 1}},
+						{Content: &pb.TermV2_Integer{Integer: 2}},
+					}}}}}},
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Integer{Integer: 0}}}},
+					{Content: &pb.Op_Binary{Binary: &pb.OpBinary{Kind: pb.OpBinary_Get.Enum()}}},
+				},
+			},
+		},
+		{
+			Desc: "not equal",
+			Input: datalog.Expression{
+				datalog.Value{ID: datalog.Variable(14)},
+				datalog.Value{ID: datalog.Integer(1)},
+				datalog.BinaryOp{BinaryOpFunc: datalog.NotEqual{}},
+			},
+			Expected: &pb.ExpressionV2{
+				Ops: []*pb.Op{
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Variable{Variable: 14}}}},
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Integer{Integer: 1}}}},
+					{Content: &pb.Op_Binary{Binary: &pb.OpBinary{Kind: pb.OpBinary_NotEqual.Enum()}}},
+				},
+			},
+		},
+		{
+			// string contains is serialized as set membership, the left value
+			// deciding which one is evaluated
+			Desc: "string contains substring",
+			Input: datalog.Expression{
+				datalog.Value{ID: syms.Insert("abc")},
+				datalog.Value{ID: syms.Insert("b")},
+				datalog.BinaryOp{BinaryOpFunc: datalog.Contains{}},
+			},
+			Expected: &pb.ExpressionV2{
+				Ops: []*pb.Op{
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_String_{String_: syms.Index("abc")}}}},
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_String_{String_: syms.Index("b")}}}},
+					{Content: &pb.Op_Binary{Binary: &pb.OpBinary{Kind: pb.OpBinary_Contains.Enum()}}},
+				},
+			},
+		},
+		{
+			Desc: "subset superset",
+			Input: datalog.Expression{
+				datalog.Value{ID: datalog.Variable(15)},
+				datalog.Value{ID: datalog.Set{datalog.Integer(1)}},
+				datalog.BinaryOp{BinaryOpFunc: datalog.Superset{}},
+				datalog.Value{ID: datalog.Set{datalog.Integer(1), datalog.Integer(2)}},
+				datalog.BinaryOp{BinaryOpFunc: datalog.Subset{}},
+			},
+			Expected: &pb.ExpressionV2{
+				Ops: []*pb.Op{
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Variable{Variable: 15}}}},
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Set{Set: &pb.TermSet{Set: []*pb.TermV2{{Content: &pb.TermV2_Integer{Integer: 1}}}}}}}},
+					{Content: &pb.Op_Binary{Binary: &pb.OpBinary{Kind: pb.OpBinary_Superset.Enum()}}},
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Set{Set: &pb.TermSet{Set: []*pb.TermV2{{Content: &pb.TermV2_Integer{Integer: 1}}, {Content: &pb.TermV2_Integer{Integer: 2}}}}}}}},
+					{Content: &pb.Op_Binary{Binary: &pb.OpBinary{Kind: pb.OpBinary_Subset.Enum()}}},
+				},
+			},
+		},
+		{
+			Desc: "length",
+			Input: datalog.Expression{
+				datalog.Value{ID: datalog.Variable(12)},
+				datalog.UnaryOp{UnaryOpFunc: datalog.Length{}},
+				datalog.Value{ID: datalog.Integer(3)},
+				datalog.BinaryOp{BinaryOpFunc: datalog.Equal{}},
+			},
+			Expected: &pb.ExpressionV2{
+				Ops: []*pb.Op{
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Variable{Variable: 12}}}},
+					{Content: &pb.Op_Unary{Unary: &pb.OpUnary{Kind: pb.OpUnary_Length.Enum()}}},
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Integer{Integer: 3}}}},
+					{Content: &pb.Op_Binary{Binary: &pb.OpBinary{Kind: pb.OpBinary_Equal.Enum()}}},
+				},
+			},
+		},
+		{
+			Desc: "char length",
+			Input: datalog.Expression{
+				datalog.Value{ID: datalog.Variable(12)},
+				datalog.UnaryOp{UnaryOpFunc: datalog.CharLength{}},
+			},
+			Expected: &pb.ExpressionV2{
+				Ops: []*pb.Op{
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Variable{Variable: 12}}}},
+					{Content: &pb.Op_Unary{Unary: &pb.OpUnary{Kind: pb.OpUnary_CharLength.Enum()}}},
+				},
+			},
+		},
+		{
+			Desc: "date conversions",
+			Input: datalog.Expression{
+				datalog.Value{ID: datalog.Variable(12)},
+				datalog.UnaryOp{UnaryOpFunc: datalog.ToDate{}},
+				datalog.UnaryOp{UnaryOpFunc: datalog.ToInt{}},
+			},
+			Expected: &pb.ExpressionV2{
+				Ops: []*pb.Op{
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Variable{Variable: 12}}}},
+					{Content: &pb.Op_Unary{Unary: &pb.OpUnary{Kind: pb.OpUnary_ToDate.Enum()}}},
+					{Content: &pb.Op_Unary{Unary: &pb.OpUnary{Kind: pb.OpUnary_ToInt.Enum()}}},
+				},
+			},
+		},
+		{
+			Desc: "union intersection",
+			Input: datalog.Expression{
+				datalog.Value{ID: datalog.Variable(13)},
+				datalog.Value{ID: datalog.Set{datalog.Integer(1), datalog.Integer(2)}},
+				datalog.BinaryOp{BinaryOpFunc: datalog.Union{}},
+				datalog.Value{ID: datalog.Set{datalog.Integer(2), datalog.Integer(3)}},
+				datalog.BinaryOp{BinaryOpFunc: datalog.Intersection{}},
+			},
+			Expected: &pb.ExpressionV2{
+				Ops: []*pb.Op{
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Variable{Variable: 13}}}},
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Set{Set: &pb.TermSet{Set: []*pb.TermV2{
+						{Content: &pb.TermV2_Integer{Integer: 1}},
+						{Content: &pb.TermV2_Integer{Integer: 2}},
+					}}}}}},
+					{Content: &pb.Op_Binary{Binary: &pb.OpBinary{Kind: pb.OpBinary_Union.Enum()}}},
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Set{Set: &pb.TermSet{Set: []*pb.TermV2{
+						{Content: &pb.TermV2_Integer{Integer: 2}},
+						{Content: &pb.TermV2_Integer{Integer: 3}},
+					}}}}}},
+					{Content: &pb.Op_Binary{Binary: &pb.OpBinary{Kind: pb.OpBinary_Intersection.Enum()}}},
+				},
+			},
+		},
+		{
+			Desc: "bitwise and",
+			Input: datalog.Expression{
+				datalog.Value{ID: datalog.Variable(14)},
+				datalog.Value{ID: datalog.Integer(6)},
+				datalog.BinaryOp{BinaryOpFunc: datalog.BitwiseAnd{}},
+			},
+			Expected: &pb.ExpressionV2{
+				Ops: []*pb.Op{
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Variable{Variable: 14}}}},
+					{Content: &pb.Op_Value{Value: &pb.TermV2{Content: &pb.TermV2_Integer{Integer: 6}}}},
+					{Content: &pb.Op_Binary{Binary: &pb.OpBinary{Kind: pb.OpBinary_BitwiseAnd.Enum()}}},
+				},
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -536,10 +707,10 @@ func TestRuleConvertV2(t *testing.T) {
 		},
 	}
 
-	pbRule, err := tokenRuleToProtoRuleV2(*in)
+	pbRule, err := tokenRuleToProtoRuleV2(*in, nil)
 	require.NoError(t, err)
 	require.Equal(t, expectedPbRule, pbRule)
-	out, err := protoRuleToTokenRuleV2(pbRule)
+	out, err := protoRuleToTokenRuleV2(pbRule, nil)
 	require.NoError(t, err)
 	require.Equal(t, in, out)
 }
@@ -592,6 +763,148 @@ func TestFactConvertV2(t *testing.T) {
 	require.Equal(t, in, out)
 }
 
+func TestEmptyTermsConvertV2(t *testing.T) {
+	syms := &datalog.SymbolTable{}
+
+	in := datalog.Fact{Predicate: datalog.Predicate{
+		Name:  syms.Insert("empty"),
+		Terms: []datalog.Term{syms.Insert(""), datalog.Bytes{}},
+	}}
+
+	pbFact, err := tokenFactToProtoFactV2(in)
+	require.NoError(t, err)
+	require.Equal(t, &pb.TermV2{Content: &pb.TermV2_String_{String_: syms.Index("")}}, pbFact.Predicate.Terms[0])
+	require.IsType(t, &pb.TermV2_Bytes{}, pbFact.Predicate.Terms[1].Content)
+
+	// the empty bytes are not dropped by the protobuf encoding
+	data, err := proto.Marshal(pbFact)
+	require.NoError(t, err)
+	decoded := &pb.FactV2{}
+	require.NoError(t, proto.Unmarshal(data, decoded))
+
+	out, err := protoFactToTokenFactV2(decoded)
+	require.NoError(t, err)
+	require.Len(t, out.Predicate.Terms, 2)
+	require.Equal(t, syms.Insert(""), out.Predicate.Terms[0])
+	require.Equal(t, "", syms.Str(out.Predicate.Terms[0].(datalog.String)))
+	require.Equal(t, datalog.TermTypeBytes, out.Predicate.Terms[1].Type())
+	require.Len(t, out.Predicate.Terms[1], 0)
+}
+
+func TestCollectionsConvertV2(t *testing.T) {
+	syms := &datalog.SymbolTable{}
+
+	testCases := []struct {
+		desc     string
+		in       datalog.Term
+		expected *pb.TermV2
+	}{
+		{
+			desc: "array",
+			in:   datalog.Array{datalog.Integer(1), syms.Insert("abc"), datalog.Array{datalog.Bool(true)}},
+			expected: &pb.TermV2{Content: &pb.TermV2_Array{Array: &pb.Array{Array: []*pb.TermV2{
+				{Content: &pb.TermV2_Integer{Integer: 1}},
+				{Content: &pb.TermV2_String_{String_: syms.Index("abc")}},
+				{Content: &pb.TermV2_Array{Array: &pb.Array{Array: []*pb.TermV2{
+					{Content: &pb.TermV2_Bool{Bool: true}},
+				}}}},
+			}}}},
+		},
+		{
+			desc: "empty array",
+			in:   datalog.Array{},
+			expected: &pb.TermV2{Content: &pb.TermV2_Array{Array: &pb.Array{
+				Array: []*pb.TermV2{},
+			}}},
+		},
+		{
+			desc: "map",
+			in: datalog.Map{
+				syms.Insert("abc"):  datalog.Integer(1),
+				datalog.Integer(2):  datalog.Array{datalog.Integer(3)},
+				datalog.Integer(-1): datalog.Bool(false),
+			},
+			expected: &pb.TermV2{Content: &pb.TermV2_Map{Map: &pb.Map{Entries: []*pb.MapEntry{
+				{
+					Key:   &pb.MapKey{Content: &pb.MapKey_Integer{Integer: -1}},
+					Value: &pb.TermV2{Content: &pb.TermV2_Bool{Bool: false}},
+				},
+				{
+					Key: &pb.MapKey{Content: &pb.MapKey_Integer{Integer: 2}},
+					Value: &pb.TermV2{Content: &pb.TermV2_Array{Array: &pb.Array{Array: []*pb.TermV2{
+						{Content: &pb.TermV2_Integer{Integer: 3}},
+					}}}},
+				},
+				{
+					Key:   &pb.MapKey{Content: &pb.MapKey_String_{String_: syms.Index("abc")}},
+					Value: &pb.TermV2{Content: &pb.TermV2_Integer{Integer: 1}},
+				},
+			}}}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			out, err := tokenIDToProtoIDV2(tc.in)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, out)
+
+			serialized, err := proto.Marshal(out)
+			require.NoError(t, err)
+			decoded := new(pb.TermV2)
+			require.NoError(t, proto.Unmarshal(serialized, decoded))
+
+			dlout, err := protoIDToTokenIDV2(decoded)
+			require.NoError(t, err)
+			require.True(t, tc.in.Equal(*dlout))
+		})
+	}
+
+	t.Run("invalid map key", func(t *testing.T) {
+		_, err := tokenIDToProtoIDV2(datalog.Map{datalog.Bool(true): datalog.Integer(1)})
+		require.Error(t, err)
+	})
+
+	t.Run("duplicate map key", func(t *testing.T) {
+		_, err := protoIDToTokenIDV2(&pb.TermV2{Content: &pb.TermV2_Map{Map: &pb.Map{Entries: []*pb.MapEntry{
+			{Key: &pb.MapKey{Content: &pb.MapKey_Integer{Integer: 1}}, Value: &pb.TermV2{Content: &pb.TermV2_Bool{Bool: false}}},
+			{Key: &pb.MapKey{Content: &pb.MapKey_Integer{Integer: 1}}, Value: &pb.TermV2{Content: &pb.TermV2_Bool{Bool: true}}},
+		}}}})
+		require.Error(t, err)
+	})
+}
+
+func TestTaggedConvertV2(t *testing.T) {
+	syms := &datalog.SymbolTable{}
+	tag := syms.Insert("uuid")
+
+	for _, in := range []datalog.Term{
+		datalog.Tagged{Tag: tag, Value: datalog.Bytes{1, 2, 3}},
+		datalog.Tagged{Tag: tag, Value: datalog.Bytes{}},
+		datalog.Set{datalog.Tagged{Tag: tag, Value: datalog.Bytes{1}}, datalog.Tagged{Tag: tag, Value: datalog.Bytes{2}}},
+		datalog.Array{datalog.Tagged{Tag: tag, Value: datalog.Bytes{1}}, datalog.Bytes{1}},
+	} {
+		out, err := tokenIDToProtoIDV2(in)
+		require.NoError(t, err)
+
+		serialized, err := proto.Marshal(out)
+		require.NoError(t, err)
+		decoded := new(pb.TermV2)
+		require.NoError(t, proto.Unmarshal(serialized, decoded))
+
+		dlout, err := protoIDToTokenIDV2(decoded)
+		require.NoError(t, err)
+		require.True(t, in.Equal(*dlout), "%v", in)
+	}
+
+	out, err := tokenIDToProtoIDV2(datalog.Tagged{Tag: tag, Value: datalog.Bytes{1, 2, 3}})
+	require.NoError(t, err)
+	require.Equal(t, &pb.TermV2{Content: &pb.TermV2_Tagged{Tagged: &pb.Tagged{
+		Tag:   proto.Uint64(syms.Index("uuid")),
+		Value: []byte{1, 2, 3},
+	}}}, out)
+}
+
 func TestConvertInvalTermsets(t *testing.T) {
 	syms := &datalog.SymbolTable{}
 
@@ -682,6 +995,45 @@ func TestConvertInvalTermsets(t *testing.T) {
 	}
 }
 
+func TestConvertNestedTerms(t *testing.T) {
+	nest := func(depth int, wrap func(*pb.TermV2) *pb.TermV2) *pb.TermV2 {
+		term := &pb.TermV2{Content: &pb.TermV2_Integer{Integer: 1}}
+		for i := 0; i < depth; i++ {
+			term = wrap(term)
+		}
+		return term
+	}
+	array := func(t *pb.TermV2) *pb.TermV2 {
+		return &pb.TermV2{Content: &pb.TermV2_Array{Array: &pb.Array{Array: []*pb.TermV2{t}}}}
+	}
+	mapValue := func(t *pb.TermV2) *pb.TermV2 {
+		return &pb.TermV2{Content: &pb.TermV2_Map{Map: &pb.Map{Entries: []*pb.MapEntry{{
+			Key:   &pb.MapKey{Content: &pb.MapKey_Integer{Integer: 0}},
+			Value: t,
+		}}}}}
+	}
+	set := func(t *pb.TermV2) *pb.TermV2 {
+		return &pb.TermV2{Content: &pb.TermV2_Set{Set: &pb.TermSet{Set: []*pb.TermV2{t}}}}
+	}
+
+	_, err := protoIDToTokenIDV2(nest(maxTermDepth, array))
+	require.NoError(t, err)
+	_, err = protoIDToTokenIDV2(nest(maxTermDepth, mapValue))
+	require.NoError(t, err)
+
+	_, err = protoIDToTokenIDV2(nest(maxTermDepth+1, array))
+	require.ErrorIs(t, err, ErrTermTooDeep)
+	_, err = protoIDToTokenIDV2(nest(100000, array))
+	require.ErrorIs(t, err, ErrTermTooDeep)
+	_, err = protoIDToTokenIDV2(nest(100000, mapValue))
+	require.ErrorIs(t, err, ErrTermTooDeep)
+
+	// sets cannot contain sets, nested sets are rejected at the first level
+	_, err = protoIDToTokenIDV2(nest(100000, set))
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrTermTooDeep)
+}
+
 func TestBlockConvertV2(t *testing.T) {
 	syms := &datalog.SymbolTable{}
 
@@ -742,16 +1094,29 @@ func TestBlockConvertV2(t *testing.T) {
 		Version:  proto.Uint32(version),
 	}
 
-	pbBlock, err := tokenBlockToProtoBlock(in)
+	pbBlock, err := tokenBlockToProtoBlock(in, &publicKeyTable{})
 	require.NoError(t, err)
 	require.Equal(t, expectedPbBlock, pbBlock)
 
-	out, err := protoBlockToTokenBlock(pbBlock)
+	out, err := protoBlockToTokenBlock(pbBlock, &publicKeyTable{})
 	require.NoError(t, err)
 	require.Equal(t, in, out)
 
 	version = uint32(MaxSchemaVersion + 1)
 	pbBlock.Version = proto.Uint32(version)
-	_, err = protoBlockToTokenBlock(pbBlock)
+	_, err = protoBlockToTokenBlock(pbBlock, &publicKeyTable{})
 	require.Error(t, err)
 }
+
+func TestBlockDuplicateSymbols(t *testing.T) {
+	_, err := tokenBlockToProtoBlock(&Block{symbols: &datalog.SymbolTable{"a", "a"}, facts: &datalog.FactSet{}}, &publicKeyTable{})
+	require.ErrorIs(t, err, ErrDuplicateSymbol)
+
+	pbBlock, err := tokenBlockToProtoBlock(&Block{symbols: &datalog.SymbolTable{"a", "b"}, facts: &datalog.FactSet{}}, &publicKeyTable{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, pbBlock.Symbols)
+
+	_, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+	_, err = New(rand.Reader, privateRoot, defaultSymbolTable.Clone(), &Block{symbols: &datalog.SymbolTable{"a", "b", "a"}})
+	require.ErrorIs(t, err, ErrDuplicateSymbol)
+}
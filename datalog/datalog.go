@@ -3,9 +3,11 @@ package datalog
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 	"time"
@@ -21,6 +23,9 @@ const (
 	TermTypeBytes
 	TermTypeBool
 	TermTypeSet
+	TermTypeArray
+	TermTypeMap
+	TermTypeTagged
 )
 
 type Term interface {
@@ -38,13 +43,13 @@ func (s Set) Equal(t Term) bool {
 		return false
 	}
 
-	cmap := make(map[Term]struct{}, len(c))
+	cmap := make(map[string]struct{}, len(c))
 	for _, v := range c {
-		cmap[v] = struct{}{}
+		cmap[termKey(v)] = struct{}{}
 	}
 
 	for _, id := range s {
-		if _, ok := cmap[id]; !ok {
+		if _, ok := cmap[termKey(id)]; !ok {
 			return false
 		}
 	}
@@ -58,32 +63,49 @@ func (s Set) String() string {
 	sort.Strings(eltStr)
 	return fmt.Sprintf("[%s]", strings.Join(eltStr, ", "))
 }
+
+// IsSubset returns true when all the elements of s are in t.
+func (s Set) IsSubset(t Set) bool {
+	for _, e := range s {
+		found := false
+		for _, e2 := range t {
+			if e.Equal(e2) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
 func (s Set) Intersect(t Set) Set {
-	other := make(map[Term]struct{}, len(t))
+	other := make(map[string]struct{}, len(t))
 	for _, v := range t {
-		other[v] = struct{}{}
+		other[termKey(v)] = struct{}{}
 	}
 
 	result := Set{}
 
 	for _, id := range s {
-		if _, ok := other[id]; ok {
+		if _, ok := other[termKey(id)]; ok {
 			result = append(result, id)
 		}
 	}
 	return result
 }
 func (s Set) Union(t Set) Set {
-	this := make(map[Term]struct{}, len(s))
+	this := make(map[string]struct{}, len(s))
 	for _, v := range s {
-		this[v] = struct{}{}
+		this[termKey(v)] = struct{}{}
 	}
 
 	result := Set{}
 	result = append(result, s...)
 
 	for _, id := range t {
-		if _, ok := this[id]; !ok {
+		if _, ok := this[termKey(id)]; !ok {
 			result = append(result, id)
 		}
 	}
@@ -91,6 +113,56 @@ func (s Set) Union(t Set) Set {
 	return result
 }
 
+// Array is an ordered list of terms.
+type Array []Term
+
+func (Array) Type() TermType { return TermTypeArray }
+func (a Array) Equal(t Term) bool {
+	c, ok := t.(Array)
+	if !ok || len(c) != len(a) {
+		return false
+	}
+	for i, v := range a {
+		if !v.Equal(c[i]) {
+			return false
+		}
+	}
+	return true
+}
+func (a Array) String() string {
+	eltStr := make([]string, 0, len(a))
+	for _, e := range a {
+		eltStr = append(eltStr, e.String())
+	}
+	return fmt.Sprintf("[%s]", strings.Join(eltStr, ", "))
+}
+
+// Map associates terms to Integer or String keys.
+type Map map[Term]Term
+
+func (Map) Type() TermType { return TermTypeMap }
+func (m Map) Equal(t Term) bool {
+	c, ok := t.(Map)
+	if !ok || len(c) != len(m) {
+		return false
+	}
+	for k, v := range m {
+		other, ok := c[k]
+		if !ok || !v.Equal(other) {
+			return false
+		}
+	}
+	return true
+}
+func (m Map) String() string {
+	eltStr := make([]string, 0, len(m))
+	for k, v := range m {
+		eltStr = append(eltStr, fmt.Sprintf("%s: %s", k.String(), v.String()))
+	}
+	sort.Strings(eltStr)
+	return fmt.Sprintf("{%s}", strings.Join(eltStr, ", "))
+}
+
 type Variable uint32
 
 func (Variable) Type() TermType      { return TermTypeVariable }
@@ -131,6 +203,24 @@ func (b Bytes) String() string {
 	return fmt.Sprintf("hex:%s", hex.EncodeToString(b))
 }
 
+// Tagged is an opaque value of a domain specific type: its bytes are
+// interpreted by the application according to its tag, a string stored
+// in the symbol table. Two tagged values are equal when they have the
+// same tag and the same bytes.
+type Tagged struct {
+	Tag   String
+	Value Bytes
+}
+
+func (Tagged) Type() TermType { return TermTypeTagged }
+func (t Tagged) Equal(o Term) bool {
+	c, ok := o.(Tagged)
+	return ok && t.Tag == c.Tag && bytes.Equal(t.Value, c.Value)
+}
+func (t Tagged) String() string {
+	return fmt.Sprintf("tagged(%s, %s)", t.Tag, t.Value)
+}
+
 type Bool bool
 
 func (Bool) Type() TermType      { return TermTypeBool }
@@ -180,6 +270,80 @@ func (p Predicate) Clone() Predicate {
 	return res
 }
 
+// key returns a binary encoding of the predicate, such that two predicates
+// are equal if and only if they have the same key.
+func (p Predicate) key() string {
+	buf := binary.AppendUvarint(nil, uint64(p.Name))
+	for _, t := range p.Terms {
+		buf = appendTermKey(buf, t)
+	}
+	return string(buf)
+}
+
+// termKey returns the binary encoding of a term, such that two terms
+// are equal if and only if they have the same key. Unlike the terms,
+// which can hold slices, keys can be used in maps.
+func termKey(t Term) string {
+	return string(appendTermKey(nil, t))
+}
+
+func appendTermKey(buf []byte, t Term) []byte {
+	buf = append(buf, byte(t.Type()))
+	switch t := t.(type) {
+	case Variable:
+		buf = binary.AppendUvarint(buf, uint64(t))
+	case Integer:
+		buf = binary.AppendVarint(buf, int64(t))
+	case String:
+		buf = binary.AppendUvarint(buf, uint64(t))
+	case Date:
+		buf = binary.AppendUvarint(buf, uint64(t))
+	case Bytes:
+		buf = binary.AppendUvarint(buf, uint64(len(t)))
+		buf = append(buf, t...)
+	case Tagged:
+		buf = binary.AppendUvarint(buf, uint64(t.Tag))
+		buf = binary.AppendUvarint(buf, uint64(len(t.Value)))
+		buf = append(buf, t.Value...)
+	case Bool:
+		if t {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+	case Array:
+		buf = binary.AppendUvarint(buf, uint64(len(t)))
+		for _, e := range t {
+			buf = appendTermKey(buf, e)
+		}
+	case Set:
+		// sets are unordered, their elements are sorted by key
+		keys := make([]string, len(t))
+		for i, e := range t {
+			keys[i] = string(appendTermKey(nil, e))
+		}
+		buf = appendSortedKeys(buf, keys)
+	case Map:
+		keys := make([]string, 0, len(t))
+		for k, v := range t {
+			keys = append(keys, string(appendTermKey(appendTermKey(nil, k), v)))
+		}
+		buf = appendSortedKeys(buf, keys)
+	default:
+		buf = append(buf, t.String()...)
+	}
+	return buf
+}
+
+func appendSortedKeys(buf []byte, keys []string) []byte {
+	sort.Strings(keys)
+	buf = binary.AppendUvarint(buf, uint64(len(keys)))
+	for _, k := range keys {
+		buf = append(buf, k...)
+	}
+	return buf
+}
+
 type Fact struct {
 	Predicate
 }
@@ -188,6 +352,121 @@ type Rule struct {
 	Head        Predicate
 	Body        []Predicate
 	Expressions []Expression
+	Scope       []Scope
+}
+
+type ScopeType byte
+
+const (
+	ScopeTypeAuthority ScopeType = iota
+	ScopeTypePrevious
+	ScopeTypePublicKey
+)
+
+// Scope restricts the blocks a rule accepts facts from, as declared
+// by a `trusting` annotation.
+// PublicKey is only set for ScopeTypePublicKey.
+type Scope struct {
+	Type      ScopeType
+	PublicKey []byte
+}
+
+// Origin is the sorted set of block indexes a fact was derived from.
+// Facts added without any origin are visible to every rule.
+type Origin []uint64
+
+// AuthorizerOrigin is the block index used for the facts and rules
+// provided by the authorizer.
+const AuthorizerOrigin uint64 = math.MaxUint64
+
+func NewOrigin(ids ...uint64) Origin {
+	o := Origin{}
+	for _, id := range ids {
+		o = o.Insert(id)
+	}
+	return o
+}
+
+// Insert returns an origin containing the receiver's ids and id.
+// The receiver is never modified.
+func (o Origin) Insert(id uint64) Origin {
+	i := sort.Search(len(o), func(i int) bool { return o[i] >= id })
+	if i < len(o) && o[i] == id {
+		return o
+	}
+
+	res := make(Origin, 0, len(o)+1)
+	res = append(res, o[:i]...)
+	res = append(res, id)
+	return append(res, o[i:]...)
+}
+
+func (o Origin) Union(other Origin) Origin {
+	res := o
+	for _, id := range other {
+		res = res.Insert(id)
+	}
+	return res
+}
+
+func (o Origin) Contains(id uint64) bool {
+	i := sort.Search(len(o), func(i int) bool { return o[i] >= id })
+	return i < len(o) && o[i] == id
+}
+
+// IsSubset returns true if every id of the receiver is in other.
+func (o Origin) IsSubset(other Origin) bool {
+	for _, id := range o {
+		if !other.Contains(id) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o Origin) Equal(other Origin) bool {
+	if len(o) != len(other) {
+		return false
+	}
+	for i, id := range o {
+		if other[i] != id {
+			return false
+		}
+	}
+	return true
+}
+
+// TrustedOrigins returns the origins a rule from currentBlock can match facts from.
+// Rules always trust their own block and the authorizer. Without any scope,
+// the rule trusts defaultOrigins, otherwise each scope adds the blocks it designates.
+// publicKeyBlocks returns the indexes of the blocks signed by a given external key,
+// it can be nil when the token has none.
+func TrustedOrigins(scopes []Scope, defaultOrigins Origin, currentBlock uint64, publicKeyBlocks func(publicKey []byte) []uint64) Origin {
+	origins := NewOrigin(currentBlock, AuthorizerOrigin)
+	if len(scopes) == 0 {
+		return origins.Union(defaultOrigins)
+	}
+
+	for _, scope := range scopes {
+		switch scope.Type {
+		case ScopeTypeAuthority:
+			origins = origins.Insert(0)
+		case ScopeTypePrevious:
+			// the authorizer has no previous block
+			if currentBlock != AuthorizerOrigin {
+				for i := uint64(0); i < currentBlock; i++ {
+					origins = origins.Insert(i)
+				}
+			}
+		case ScopeTypePublicKey:
+			if publicKeyBlocks != nil {
+				for _, id := range publicKeyBlocks(scope.PublicKey) {
+					origins = origins.Insert(id)
+				}
+			}
+		}
+	}
+	return origins
 }
 
 type InvalidRuleError struct {
@@ -199,7 +478,18 @@ func (e InvalidRuleError) Error() string {
 	return fmt.Sprintf("datalog: variable %d in head is missing from body and/or constraints", e.MissingVariable)
 }
 
+// Apply adds to newFacts the facts generated by the rule, evaluating
+// its expressions with the default limits.
 func (r Rule) Apply(facts *FactSet, newFacts *FactSet, syms *SymbolTable) error {
+	return r.apply(facts, nil, syms, defaultRunLimits, func(f Fact, _ Origin) {
+		newFacts.Insert(f)
+	})
+}
+
+// apply calls emit for every fact generated by the rule, along with the union of
+// the origins of the facts it was derived from. origins, if not nil, holds the
+// origin of each fact. The expressions are evaluated within limits.
+func (r Rule) apply(facts *FactSet, origins []Origin, syms *SymbolTable, limits runLimits, emit func(Fact, Origin)) error {
 	// extract all variables from the rule body
 	variables := make(MatchedVariables)
 	for _, predicate := range r.Body {
@@ -212,7 +502,7 @@ func (r Rule) Apply(facts *FactSet, newFacts *FactSet, syms *SymbolTable) error
 		}
 	}
 
-	combinations := combine(variables, r.Body, r.Expressions, facts, syms)
+	combinations := combine(variables, r.Body, r.Expressions, facts, origins, syms, limits)
 
 	for res := range combinations {
 		if res.error != nil {
@@ -232,14 +522,30 @@ func (r Rule) Apply(facts *FactSet, newFacts *FactSet, syms *SymbolTable) error
 
 			predicate.Terms[i] = *v
 		}
-		newFacts.Insert(Fact{predicate})
+		emit(Fact{predicate}, res.Origin)
 	}
 
 	return nil
 }
 
+// CheckKind defines how the queries of a check are matched.
+type CheckKind byte
+
+const (
+	// CheckKindOne succeeds when one combination of facts matches a query,
+	// written `check if`.
+	CheckKindOne CheckKind = iota
+	// CheckKindAll succeeds when the facts match a query and all the
+	// matching combinations satisfy its expressions, written `check all`.
+	CheckKindAll
+	// CheckKindReject fails when one combination of facts matches a query,
+	// written `reject if`.
+	CheckKindReject
+)
+
 type Check struct {
 	Queries []Rule
+	Kind    CheckKind
 }
 
 type FactSet []Fact
@@ -280,21 +586,32 @@ func (s *FactSet) Equal(x *FactSet) bool {
 }
 
 type runLimits struct {
-	maxFacts      int
-	maxIterations int
-	maxDuration   time.Duration
+	maxFacts       int
+	maxIterations  int
+	maxDuration    time.Duration
+	maxNewSymbols  int
+	maxRegexLength int
+	// maxExpressionOps is always positive
+	maxExpressionOps int
 }
 
 var defaultRunLimits = runLimits{
-	maxFacts:      1000,
-	maxIterations: 100,
-	maxDuration:   2 * time.Millisecond,
+	maxFacts:         1000,
+	maxIterations:    100,
+	maxDuration:      2 * time.Millisecond,
+	maxNewSymbols:    1000,
+	maxRegexLength:   DefaultMaxRegexLength,
+	maxExpressionOps: DefaultMaxExpressionOps,
 }
 
 var (
 	ErrWorldRunLimitMaxFacts      = errors.New("datalog: world runtime limit: too many facts")
 	ErrWorldRunLimitMaxIterations = errors.New("datalog: world runtime limit: too many iterations")
 	ErrWorldRunLimitTimeout       = errors.New("datalog: world runtime limit: timeout")
+	ErrWorldRunLimitMaxNewSymbols = errors.New("datalog: world runtime limit: too many new symbols")
+	// ErrUnsafeRule is returned by World.AddRuleChecked when a variable
+	// of the rule head or expressions does not appear in its body
+	ErrUnsafeRule = errors.New("datalog: unsafe rule")
 )
 
 type WorldOption func(w *World)
@@ -317,13 +634,63 @@ func WithMaxDuration(maxDuration time.Duration) WorldOption {
 	}
 }
 
+// WithMaxNewSymbols limits the number of strings a run adds to the symbol table,
+// such as the ones created by concatenating strings in rule expressions.
+// The limit is checked after each rule application, and the run fails with
+// ErrWorldRunLimitMaxNewSymbols when it is exceeded.
+func WithMaxNewSymbols(maxNewSymbols int) WorldOption {
+	return func(w *World) {
+		w.runLimits.maxNewSymbols = maxNewSymbols
+	}
+}
+
+// WithMaxRegexLength limits the length, in bytes, of the patterns of the
+// matches operations evaluated by the world. Longer patterns make the
+// evaluation fail with ErrRegexTooLong.
+func WithMaxRegexLength(maxRegexLength int) WorldOption {
+	return func(w *World) {
+		w.runLimits.maxRegexLength = maxRegexLength
+	}
+}
+
+// WithMaxExpressionOps limits the number of operations, values included,
+// of the expressions evaluated by the world. Longer expressions, such as the
+// ones of hostile tokens, make the evaluation fail with ErrExpressionTooLong
+// without evaluating them. Values lower than 1, which would make every
+// expression fail, are ignored.
+func WithMaxExpressionOps(maxExpressionOps int) WorldOption {
+	return func(w *World) {
+		if maxExpressionOps > 0 {
+			w.runLimits.maxExpressionOps = maxExpressionOps
+		}
+	}
+}
+
 type World struct {
 	facts *FactSet
-	rules []Rule
+	// origins[i] is the origin of the i-th fact
+	origins []Origin
+	rules   []Rule
+	// scopes[i] is the scope of the i-th rule
+	scopes []ruleScope
+
+	// index maps the key of a predicate to the positions of the facts
+	// using it, indexed is the number of facts it covers
+	index   map[string][]int
+	indexed int
 
 	runLimits runLimits
 }
 
+// ruleScope holds the block a rule comes from and the origins
+// of the facts it can match.
+type ruleScope struct {
+	origin  Origin
+	trusted Origin
+	// rules added without scope match facts from any origin
+	unscoped bool
+}
+
 func NewWorld(opts ...WorldOption) *World {
 	w := &World{
 		facts:     &FactSet{},
@@ -338,7 +705,51 @@ func NewWorld(opts ...WorldOption) *World {
 }
 
 func (w *World) AddFact(f Fact) {
-	w.facts.Insert(f)
+	w.AddFactWithOrigin(f, nil)
+}
+
+// AddFactWithOrigin adds a fact coming from the given origin. The same fact
+// can be stored several times, once for each origin it was produced from.
+func (w *World) AddFactWithOrigin(f Fact, origin Origin) bool {
+	w.updateIndex()
+	return w.addIndexedFact(f, origin)
+}
+
+// AddFactsWithOrigin adds facts coming from the same origin, like
+// AddFactWithOrigin, updating the index once for all of them.
+func (w *World) AddFactsWithOrigin(facts []Fact, origin Origin) {
+	w.updateIndex()
+	for _, f := range facts {
+		w.addIndexedFact(f, origin)
+	}
+}
+
+// addIndexedFact adds a fact to a world whose index is up to date.
+func (w *World) addIndexedFact(f Fact, origin Origin) bool {
+	key := f.Predicate.key()
+	for _, i := range w.index[key] {
+		if w.origins[i].Equal(origin) {
+			return false
+		}
+	}
+	w.index[key] = append(w.index[key], len(*w.facts))
+	w.indexed++
+	*w.facts = append(*w.facts, f)
+	w.origins = append(w.origins, origin)
+	return true
+}
+
+// updateIndex adds the facts appended without AddFactWithOrigin to the index,
+// or rebuilds it when facts were removed.
+func (w *World) updateIndex() {
+	if w.index == nil || w.indexed > len(*w.facts) {
+		w.index = make(map[string][]int, len(*w.facts))
+		w.indexed = 0
+	}
+	for ; w.indexed < len(*w.facts); w.indexed++ {
+		key := (*w.facts)[w.indexed].Predicate.key()
+		w.index[key] = append(w.index[key], w.indexed)
+	}
 }
 
 func (w *World) Facts() *FactSet {
@@ -347,65 +758,177 @@ func (w *World) Facts() *FactSet {
 
 func (w *World) AddRule(r Rule) {
 	w.rules = append(w.rules, r)
+	w.scopes = append(w.scopes, ruleScope{unscoped: true})
+}
+
+// AddRuleChecked adds the rule after checking it is safe: every variable
+// of its head and expressions must appear in a body predicate, otherwise
+// it never generates a fact. It returns ErrUnsafeRule naming the first
+// unbound variable. AddRule accepts unsafe rules.
+func (w *World) AddRuleChecked(r Rule) error {
+	if err := r.checkSafe(); err != nil {
+		return err
+	}
+	w.AddRule(r)
+	return nil
+}
+
+// checkSafe returns ErrUnsafeRule when a variable of the head or
+// the expressions is not bound by a body predicate.
+func (r Rule) checkSafe() error {
+	bound := make(map[Variable]struct{})
+	for _, predicate := range r.Body {
+		for _, term := range predicate.Terms {
+			if v, ok := term.(Variable); ok {
+				bound[v] = struct{}{}
+			}
+		}
+	}
+
+	for _, term := range r.Head.Terms {
+		if v, ok := term.(Variable); ok {
+			if _, ok := bound[v]; !ok {
+				return fmt.Errorf("%w: head variable %s does not appear in the body", ErrUnsafeRule, v)
+			}
+		}
+	}
+	for _, e := range r.Expressions {
+		for _, op := range e {
+			value, ok := op.(Value)
+			if !ok {
+				continue
+			}
+			if v, ok := value.ID.(Variable); ok {
+				if _, ok := bound[v]; !ok {
+					return fmt.Errorf("%w: expression variable %s does not appear in the body", ErrUnsafeRule, v)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// AddRuleWithOrigin adds a rule defined in the origin block, which only
+// matches facts whose origin is included in trusted.
+func (w *World) AddRuleWithOrigin(r Rule, origin uint64, trusted Origin) {
+	w.rules = append(w.rules, r)
+	w.scopes = append(w.scopes, ruleScope{origin: NewOrigin(origin), trusted: trusted})
 }
 
 func (w *World) ResetRules() {
 	w.rules = make([]Rule, 0)
+	w.scopes = make([]ruleScope, 0)
+}
+
+// Reset removes all the facts and rules of the world, keeping its run limits
+// and the memory already allocated, so it can be reused for another evaluation.
+// Like the other methods of World, it must not be called concurrently.
+func (w *World) Reset() {
+	*w.facts = (*w.facts)[:0]
+	w.origins = w.origins[:0]
+	w.rules = w.rules[:0]
+	w.scopes = w.scopes[:0]
+	for k := range w.index {
+		delete(w.index, k)
+	}
+	w.indexed = 0
 }
 
 func (w *World) Rules() []Rule {
 	return w.rules
 }
 
+// Run applies the rules until no new fact is generated. The order of the
+// facts is deterministic: each iteration applies the rules in the order they
+// were added, each rule matching the facts in the order they were added, and
+// the generated facts are appended in that order at the end of the iteration.
+// Query results, such as the ones of QueryRuleBindings, follow that order.
 func (w *World) Run(syms *SymbolTable) error {
-	done := make(chan error)
-	ctx, cancel := context.WithTimeout(context.Background(), w.runLimits.maxDuration)
-	defer cancel()
-
-	go func() {
-		for i := 0; i < w.runLimits.maxIterations; i++ {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				var newFacts FactSet
-				for _, r := range w.rules {
-					select {
-					case <-ctx.Done():
-						return
-					default:
-						if err := r.Apply(w.facts, &newFacts, syms); err != nil {
-							done <- err
-							return
-						}
-					}
-				}
+	return w.RunContext(context.Background(), syms)
+}
 
-				prevCount := len(*w.facts)
-				w.facts.InsertAll([]Fact(newFacts))
+// RunContext runs the rules like Run, and stops early with the context's
+// error when it is canceled or its deadline is exceeded.
+func (w *World) RunContext(parent context.Context, syms *SymbolTable) error {
+	if err := parent.Err(); err != nil {
+		return err
+	}
 
-				newCount := len(*w.facts)
-				if newCount >= w.runLimits.maxFacts {
-					done <- ErrWorldRunLimitMaxFacts
-					return
-				}
+	ctx, cancel := context.WithTimeout(parent, w.runLimits.maxDuration)
+	defer cancel()
+	// stopped returns the error to report once the context is done, it is
+	// checked between rule applications, so that the world is never
+	// modified after RunContext returns
+	stopped := func() error {
+		if ctx.Err() == nil {
+			return nil
+		}
+		if err := parent.Err(); err != nil {
+			return err
+		}
+		return ErrWorldRunLimitTimeout
+	}
 
-				// last iteration did not generate any new facts, so we can stop here
-				if newCount == prevCount {
-					done <- nil
-					return
-				}
+	symbolCount := len(*syms)
+	// number of facts generated by the last iteration
+	var generated int
+	for i := 0; i < w.runLimits.maxIterations; i++ {
+		newFacts := NewWorld()
+		for j, r := range w.rules {
+			if err := stopped(); err != nil {
+				return err
+			}
+			scope := w.scopes[j]
+			facts, origins := w.trustedFacts(scope)
+			if err := r.apply(facts, origins, syms, w.runLimits, func(f Fact, origin Origin) {
+				newFacts.AddFactWithOrigin(f, origin.Union(scope.origin))
+			}); err != nil {
+				return err
 			}
+			if newSymbols := len(*syms) - symbolCount; newSymbols > w.runLimits.maxNewSymbols {
+				return fmt.Errorf("%w: %d symbols added, limit is %d", ErrWorldRunLimitMaxNewSymbols, newSymbols, w.runLimits.maxNewSymbols)
+			}
+		}
+		if err := stopped(); err != nil {
+			return err
 		}
-		done <- ErrWorldRunLimitMaxIterations
-	}()
 
-	select {
-	case <-ctx.Done():
-		return ErrWorldRunLimitTimeout
-	case err := <-done:
-		return err
+		prevCount := len(*w.facts)
+		for j, f := range *newFacts.facts {
+			w.AddFactWithOrigin(f, newFacts.origins[j])
+		}
+		newCount := len(*w.facts)
+		if newCount >= w.runLimits.maxFacts {
+			return ErrWorldRunLimitMaxFacts
+		}
+
+		// last iteration did not generate any new facts, so we can stop here
+		if newCount == prevCount {
+			return nil
+		}
+		generated = newCount - prevCount
 	}
+	// the fixpoint was not reached: rules were still generating facts,
+	// which usually denotes a recursive rule that does not terminate
+	return fmt.Errorf("%w: %d new facts generated by the last of %d iterations, rules did not reach a fixpoint", ErrWorldRunLimitMaxIterations, generated, w.runLimits.maxIterations)
+}
+
+// trustedFacts returns the facts visible to a rule with the given scope,
+// along with their origins.
+func (w *World) trustedFacts(scope ruleScope) (*FactSet, []Origin) {
+	if scope.unscoped {
+		return w.facts, w.origins
+	}
+
+	facts := &FactSet{}
+	var origins []Origin
+	for i, f := range *w.facts {
+		if w.origins[i].IsSubset(scope.trusted) {
+			*facts = append(*facts, f)
+			origins = append(origins, w.origins[i])
+		}
+	}
+	return facts, origins
 }
 
 func (w *World) Query(pred Predicate) *FactSet {
@@ -444,16 +967,129 @@ func (w *World) Query(pred Predicate) *FactSet {
 
 func (w *World) QueryRule(rule Rule, syms *SymbolTable) *FactSet {
 	newFacts := &FactSet{}
-	rule.Apply(w.facts, newFacts, syms)
+	rule.apply(w.facts, nil, syms, w.runLimits, func(f Fact, _ Origin) {
+		newFacts.Insert(f)
+	})
 	return newFacts
 }
 
+// QueryRuleTrusting applies the rule on the facts whose origin is included in trusted.
+func (w *World) QueryRuleTrusting(rule Rule, trusted Origin, syms *SymbolTable) *FactSet {
+	facts, _ := w.trustedFacts(ruleScope{trusted: trusted})
+	newFacts := &FactSet{}
+	rule.apply(facts, nil, syms, w.runLimits, func(f Fact, _ Origin) {
+		newFacts.Insert(f)
+	})
+	return newFacts
+}
+
+// QueryRuleBindings returns the values of the body variables for each
+// combination of facts matching the rule and satisfying its expressions,
+// in the order they are found: the facts matching the first predicate of the
+// body in the order of the world, then for each of them the facts matching
+// the second predicate, and so on. The head of the rule is ignored.
+func (w *World) QueryRuleBindings(rule Rule, syms *SymbolTable) ([]map[Variable]Term, error) {
+	variables := make(MatchedVariables)
+	for _, predicate := range rule.Body {
+		for _, term := range predicate.Terms {
+			if v, ok := term.(Variable); ok {
+				variables[v] = nil
+			}
+		}
+	}
+
+	var bindings []map[Variable]Term
+	for res := range combine(variables, rule.Body, rule.Expressions, w.facts, nil, syms, w.runLimits) {
+		if res.error != nil {
+			return nil, res.error
+		}
+		binding := make(map[Variable]Term, len(res.MatchedVariables))
+		for k, v := range res.MatchedVariables {
+			binding[k] = *v
+		}
+		bindings = append(bindings, binding)
+	}
+	return bindings, nil
+}
+
+// QueryMatchAllTrusting returns true when the body of the rule matches the facts
+// whose origin is included in trusted, and every matching combination satisfies
+// the rule's expressions.
+func (w *World) QueryMatchAllTrusting(rule Rule, trusted Origin, syms *SymbolTable) (bool, error) {
+	facts, _ := w.trustedFacts(ruleScope{trusted: trusted})
+	return rule.matchAll(facts, syms, w.runLimits)
+}
+
+func (r Rule) matchAll(facts *FactSet, syms *SymbolTable, limits runLimits) (bool, error) {
+	variables := make(MatchedVariables)
+	for _, predicate := range r.Body {
+		for _, term := range predicate.Terms {
+			if v, ok := term.(Variable); ok {
+				variables[v] = nil
+			}
+		}
+	}
+
+	found := false
+	valid := true
+	var err error
+	// the expressions are evaluated here rather than by combine, and the
+	// channel is drained so its goroutine can exit
+	for res := range combine(variables, r.Body, nil, facts, nil, syms, limits) {
+		if !valid || err != nil {
+			continue
+		}
+		found = true
+		for _, e := range r.Expressions {
+			var t Term
+			t, err = e.evaluate(res.MatchedVariables, syms, limits, nil)
+			if err != nil {
+				break
+			}
+			if !t.Equal(Bool(true)) {
+				valid = false
+				break
+			}
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	return found && valid, nil
+}
+
+// Trusting returns a world restricted to the facts and rules whose origin
+// is included in trusted. Facts coming from several origins appear only once.
+func (w *World) Trusting(trusted Origin) *World {
+	res := &World{
+		facts:     &FactSet{},
+		runLimits: w.runLimits,
+	}
+
+	for i, f := range *w.facts {
+		if w.origins[i].IsSubset(trusted) {
+			res.facts.Insert(f)
+		}
+	}
+	res.origins = make([]Origin, len(*res.facts))
+
+	for i, r := range w.rules {
+		if w.scopes[i].unscoped || w.scopes[i].origin.IsSubset(trusted) {
+			res.rules = append(res.rules, r)
+			res.scopes = append(res.scopes, w.scopes[i])
+		}
+	}
+	return res
+}
+
 func (w *World) Clone() *World {
 	newFacts := new(FactSet)
-	*newFacts = *w.facts
+	*newFacts = append(FactSet{}, *w.facts...)
 	return &World{
 		facts:     newFacts,
+		origins:   append([]Origin{}, w.origins...),
 		rules:     append([]Rule{}, w.rules...),
+		scopes:    append([]ruleScope{}, w.scopes...),
 		runLimits: w.runLimits,
 	}
 }
@@ -486,17 +1122,20 @@ func (m MatchedVariables) Clone() MatchedVariables {
 	return res
 }
 
-func combine(variables MatchedVariables, predicates []Predicate, expressions []Expression, facts *FactSet, syms *SymbolTable) <-chan struct {
+func combine(variables MatchedVariables, predicates []Predicate, expressions []Expression, facts *FactSet, origins []Origin, syms *SymbolTable, limits runLimits) <-chan struct {
 	MatchedVariables
+	Origin
 	error
 } {
 	c := make(chan struct {
 		MatchedVariables
+		Origin
 		error
 	})
 
 	go func(c chan struct {
 		MatchedVariables
+		Origin
 		error
 	}) {
 		defer close(c)
@@ -566,13 +1205,14 @@ func combine(variables MatchedVariables, predicates []Predicate, expressions []E
 					//fmt.Printf("variables are complete, evaluating expressions\n")
 					valid := true
 					for _, e := range expressions {
-						res, err := e.Evaluate(complete_vars, syms)
+						res, err := e.evaluate(complete_vars, syms, limits, nil)
 						if err != nil {
 							fmt.Printf("expression error: %+v", err)
 							c <- struct {
 								MatchedVariables
+								Origin
 								error
-							}{complete_vars, err}
+							}{complete_vars, nil, err}
 
 							return
 						}
@@ -583,11 +1223,19 @@ func combine(variables MatchedVariables, predicates []Predicate, expressions []E
 					}
 
 					if valid {
+						var origin Origin
+						if origins != nil {
+							for i := range predicates {
+								origin = origin.Union(origins[indexes[i]])
+							}
+						}
+
 						//fmt.Printf("sending valid variables %+v\n", complete_vars)
 						c <- struct {
 							MatchedVariables
+							Origin
 							error
-						}{complete_vars, nil}
+						}{complete_vars, origin, nil}
 					}
 				} else {
 					// if all predicates match but variables are not complete, it means
@@ -1,8 +1,10 @@
 package datalog
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"fmt"
 	"testing"
 	"time"
 
@@ -142,6 +144,61 @@ func TestNumbers(t *testing.T) {
 	}
 }
 
+func TestQueryRuleBindings(t *testing.T) {
+	w := NewWorld()
+	syms := &SymbolTable{}
+
+	abc := syms.Insert("abc")
+	def := syms.Insert("def")
+	aaa := syms.Insert("AAA")
+	bbb := syms.Insert("BBB")
+	ccc := syms.Insert("CCC")
+	t1 := syms.Insert("t1")
+	t2 := syms.Insert("t2")
+	join := syms.Insert("join")
+
+	w.AddFact(Fact{Predicate{t1, []Term{Integer(0), abc}}})
+	w.AddFact(Fact{Predicate{t1, []Term{Integer(1), def}}})
+	w.AddFact(Fact{Predicate{t2, []Term{Integer(0), aaa, Integer(0)}}})
+	w.AddFact(Fact{Predicate{t2, []Term{Integer(1), bbb, Integer(0)}}})
+	w.AddFact(Fact{Predicate{t2, []Term{Integer(2), ccc, Integer(1)}}})
+
+	id, left, t2ID, right := hashVar("id"), hashVar("left"), hashVar("t2_id"), hashVar("right")
+	rule := Rule{
+		Head: Predicate{join, []Term{left, right}},
+		Body: []Predicate{
+			{t1, []Term{id, left}},
+			{t2, []Term{t2ID, right, id}},
+		},
+	}
+
+	bindings, err := w.QueryRuleBindings(rule, syms)
+	require.NoError(t, err)
+	require.Equal(t, []map[Variable]Term{
+		{id: Integer(0), left: abc, t2ID: Integer(0), right: aaa},
+		{id: Integer(0), left: abc, t2ID: Integer(1), right: bbb},
+		{id: Integer(1), left: def, t2ID: Integer(2), right: ccc},
+	}, bindings)
+
+	rule.Expressions = []Expression{{Value{t2ID}, Value{Integer(1)}, BinaryOp{GreaterOrEqual{}}}}
+	bindings, err = w.QueryRuleBindings(rule, syms)
+	require.NoError(t, err)
+	require.Equal(t, []map[Variable]Term{
+		{id: Integer(0), left: abc, t2ID: Integer(1), right: bbb},
+		{id: Integer(1), left: def, t2ID: Integer(2), right: ccc},
+	}, bindings)
+
+	rule.Expressions = []Expression{{Value{left}, Value{Integer(1)}, BinaryOp{Add{}}}}
+	_, err = w.QueryRuleBindings(rule, syms)
+	require.Error(t, err)
+
+	rule.Body = []Predicate{{join, []Term{left}}}
+	rule.Expressions = nil
+	bindings, err = w.QueryRuleBindings(rule, syms)
+	require.NoError(t, err)
+	require.Empty(t, bindings)
+}
+
 func TestString(t *testing.T) {
 	w := NewWorld()
 	syms := &SymbolTable{}
@@ -395,6 +452,25 @@ func TestSymbolTable(t *testing.T) {
 	require.Equal(t, s2, s1)
 }
 
+func TestSymbolTableStrUnknown(t *testing.T) {
+	s := &SymbolTable{"a", "b"}
+
+	testCases := []struct {
+		sym      String
+		expected string
+	}{
+		{sym: 0, expected: DEFAULT_SYMBOLS[0]},
+		{sym: 1024, expected: "a"},
+		{sym: 1025, expected: "b"},
+		{sym: String(len(DEFAULT_SYMBOLS)), expected: fmt.Sprintf("<sym:%d>", len(DEFAULT_SYMBOLS))},
+		{sym: 1026, expected: "<sym:1026>"},
+		{sym: 1 << 63, expected: "<sym:9223372036854775808>"},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, s.Str(tc.sym))
+	}
+}
+
 func TestSymbolTableInsertAndSym(t *testing.T) {
 	s := new(SymbolTable)
 	require.Equal(t, String(1024), s.Insert("a"))
@@ -415,6 +491,20 @@ func TestSymbolTableInsertAndSym(t *testing.T) {
 	require.Equal(t, nil, s.Sym("e"))
 }
 
+func TestNewSymbolTableWithBase(t *testing.T) {
+	s := NewSymbolTableWithBase([]string{"app", "tenant_id", "app", "read"})
+	require.Equal(t, &SymbolTable{"app", "tenant_id"}, s)
+
+	require.Equal(t, String(OFFSET), s.Insert("app"))
+	require.Equal(t, String(OFFSET+1), s.Insert("tenant_id"))
+	require.Equal(t, String(OFFSET+2), s.Insert("a"))
+	require.Equal(t, String(OFFSET+3), s.Insert("b"))
+	require.Equal(t, "b", s.Str(String(OFFSET+3)))
+
+	// default symbols keep their reserved index
+	require.Equal(t, String(0), s.Insert("read"))
+}
+
 func TestSymbolTableClone(t *testing.T) {
 	s := new(SymbolTable)
 
@@ -452,6 +542,18 @@ func TestSetEqual(t *testing.T) {
 			s2:    Set{syms.Insert("b"), syms.Insert("c"), syms.Insert("a")},
 			equal: true,
 		},
+		{
+			desc:  "equal bytes",
+			s1:    Set{Bytes("a"), Bytes("b")},
+			s2:    Set{Bytes("b"), Bytes("a")},
+			equal: true,
+		},
+		{
+			desc:  "not equal bytes",
+			s1:    Set{Bytes("a"), Bytes("b")},
+			s2:    Set{Bytes("b"), Bytes("c")},
+			equal: false,
+		},
 		{
 			desc:  "not equal when length mismatch",
 			s1:    Set{syms.Insert("a"), syms.Insert("b"), syms.Insert("c")},
@@ -550,6 +652,688 @@ func TestWorldRunLimits(t *testing.T) {
 		}
 
 		w.AddRule(r1)
-		require.Equal(t, tc.expectedErr, w.Run(syms))
+		if tc.expectedErr == nil {
+			require.NoError(t, w.Run(syms))
+		} else {
+			require.ErrorIs(t, w.Run(syms), tc.expectedErr)
+		}
+	}
+}
+
+func TestWorldRunLimitNewSymbols(t *testing.T) {
+	syms := &SymbolTable{}
+	item := syms.Insert("item")
+	pair := syms.Insert("pair")
+
+	newWorld := func(items int, opts ...WorldOption) *World {
+		w := NewWorld(append([]WorldOption{WithMaxDuration(time.Minute)}, opts...)...)
+		for i := 0; i < items; i++ {
+			w.AddFact(Fact{Predicate{item, []Term{syms.Insert(fmt.Sprintf("i%d", i))}}})
+		}
+		// each pair of items concatenates to a new string
+		w.AddRule(Rule{
+			Head: Predicate{pair, []Term{hashVar("x"), hashVar("y")}},
+			Body: []Predicate{
+				{item, []Term{hashVar("x")}},
+				{item, []Term{hashVar("y")}},
+			},
+			Expressions: []Expression{{
+				Value{hashVar("x")},
+				Value{hashVar("y")},
+				BinaryOp{Add{}},
+				Value{syms.Insert("i0i0")},
+				BinaryOp{NotEqual{}},
+			}},
+		})
+		return w
+	}
+
+	// 5 items create 24 new strings, "i0i0" is already known
+	w := newWorld(5)
+	symbolCount := len(*syms)
+	require.NoError(t, w.Run(syms))
+	require.Equal(t, symbolCount+24, len(*syms))
+
+	syms = &SymbolTable{"item", "pair"}
+	require.ErrorIs(t, newWorld(5, WithMaxNewSymbols(10)).Run(syms), ErrWorldRunLimitMaxNewSymbols)
+
+	// the default limit
+	syms = &SymbolTable{"item", "pair"}
+	require.ErrorIs(t, newWorld(40).Run(syms), ErrWorldRunLimitMaxNewSymbols)
+	syms = &SymbolTable{"item", "pair"}
+	require.NoError(t, newWorld(40, WithMaxNewSymbols(2000), WithMaxFacts(2000)).Run(syms))
+}
+
+func TestWorldAddRuleChecked(t *testing.T) {
+	syms := &SymbolTable{}
+	parent := syms.Insert("parent")
+	grandparent := syms.Insert("grandparent")
+	a := syms.Insert("a")
+	b := syms.Insert("b")
+	c := syms.Insert("c")
+
+	w := NewWorld()
+	w.AddFact(Fact{Predicate{parent, []Term{a, b}}})
+	w.AddFact(Fact{Predicate{parent, []Term{b, c}}})
+
+	safe := Rule{
+		Head: Predicate{grandparent, []Term{hashVar("x"), hashVar("z")}},
+		Body: []Predicate{
+			{parent, []Term{hashVar("x"), hashVar("y")}},
+			{parent, []Term{hashVar("y"), hashVar("z")}},
+		},
+		Expressions: []Expression{{
+			Value{hashVar("x")},
+			Value{hashVar("z")},
+			BinaryOp{NotEqual{}},
+		}},
+	}
+	require.NoError(t, w.AddRuleChecked(safe))
+
+	for _, tc := range []struct {
+		desc string
+		rule Rule
+		err  string
+	}{
+		{
+			desc: "head variable",
+			rule: Rule{
+				Head: Predicate{grandparent, []Term{hashVar("x"), hashVar("w")}},
+				Body: []Predicate{{parent, []Term{hashVar("x"), hashVar("y")}}},
+			},
+			err: fmt.Sprintf("datalog: unsafe rule: head variable %s does not appear in the body", hashVar("w")),
+		},
+		{
+			desc: "expression variable",
+			rule: Rule{
+				Head: Predicate{grandparent, []Term{hashVar("x"), hashVar("y")}},
+				Body: []Predicate{{parent, []Term{hashVar("x"), hashVar("y")}}},
+				Expressions: []Expression{{
+					Value{hashVar("x")},
+					Value{hashVar("w")},
+					BinaryOp{Equal{}},
+				}},
+			},
+			err: fmt.Sprintf("datalog: unsafe rule: expression variable %s does not appear in the body", hashVar("w")),
+		},
+		{
+			desc: "empty body",
+			rule: Rule{Head: Predicate{grandparent, []Term{hashVar("x"), a}}},
+			err:  fmt.Sprintf("datalog: unsafe rule: head variable %s does not appear in the body", hashVar("x")),
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := w.AddRuleChecked(tc.rule)
+			require.ErrorIs(t, err, ErrUnsafeRule)
+			require.EqualError(t, err, tc.err)
+		})
+	}
+
+	// only the safe rule was added
+	require.Len(t, w.rules, 1)
+	require.NoError(t, w.Run(syms))
+	require.Equal(t, &FactSet{{Predicate{grandparent, []Term{a, c}}}}, w.Query(Predicate{grandparent, []Term{hashVar("x"), hashVar("z")}}))
+}
+
+func TestWorldRunContext(t *testing.T) {
+	syms := &SymbolTable{}
+	edge := syms.Insert("edge")
+	path := syms.Insert("path")
+
+	// the transitive closure of a long chain takes many slow iterations
+	w := NewWorld(WithMaxIterations(10000), WithMaxFacts(1<<30), WithMaxDuration(time.Minute))
+	for i := 0; i < 1000; i++ {
+		w.AddFact(Fact{Predicate{edge, []Term{Integer(i), Integer(i + 1)}}})
+	}
+	w.AddRule(Rule{
+		Head: Predicate{path, []Term{hashVar("a"), hashVar("b")}},
+		Body: []Predicate{{edge, []Term{hashVar("a"), hashVar("b")}}},
+	})
+	w.AddRule(Rule{
+		Head: Predicate{path, []Term{hashVar("a"), hashVar("c")}},
+		Body: []Predicate{
+			{path, []Term{hashVar("a"), hashVar("b")}},
+			{edge, []Term{hashVar("b"), hashVar("c")}},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, w.RunContext(ctx, syms), context.Canceled)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	timedOut := w.Clone()
+	require.ErrorIs(t, timedOut.RunContext(ctx, syms), context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	// the world is not modified after the run stopped, so it can be reused,
+	// which the race detector checks
+	count := len(*timedOut.Facts())
+	timedOut.Reset()
+	timedOut.AddFact(Fact{Predicate{edge, []Term{Integer(0), Integer(1)}}})
+	time.Sleep(10 * time.Millisecond)
+	require.Len(t, *timedOut.Facts(), 1)
+	require.Greater(t, count, 1000)
+	timedOut.AddRule(Rule{
+		Head: Predicate{path, []Term{hashVar("a"), hashVar("b")}},
+		Body: []Predicate{{edge, []Term{hashVar("a"), hashVar("b")}}},
+	})
+	require.NoError(t, timedOut.Run(syms))
+	require.Len(t, *timedOut.Facts(), 2)
+
+	// the world limits still apply
+	small := NewWorld(WithMaxDuration(0))
+	small.AddFact(Fact{Predicate{edge, []Term{Integer(0), Integer(1)}}})
+	require.ErrorIs(t, small.RunContext(context.Background(), syms), ErrWorldRunLimitTimeout)
+}
+
+func TestWorldRunFixpoint(t *testing.T) {
+	syms := &SymbolTable{}
+	edge := syms.Insert("edge")
+	path := syms.Insert("path")
+
+	// path is the transitive closure of a chain of edges, each iteration
+	// extends the known paths by one edge
+	newWorld := func(maxIterations, chainLength int) *World {
+		w := NewWorld(WithMaxIterations(maxIterations), WithMaxDuration(time.Second))
+		for i := 0; i < chainLength; i++ {
+			w.AddFact(Fact{Predicate{edge, []Term{Integer(i), Integer(i + 1)}}})
+		}
+		w.AddRule(Rule{
+			Head: Predicate{path, []Term{hashVar("a"), hashVar("b")}},
+			Body: []Predicate{{edge, []Term{hashVar("a"), hashVar("b")}}},
+		})
+		w.AddRule(Rule{
+			Head: Predicate{path, []Term{hashVar("a"), hashVar("c")}},
+			Body: []Predicate{
+				{path, []Term{hashVar("a"), hashVar("b")}},
+				{edge, []Term{hashVar("b"), hashVar("c")}},
+			},
+		})
+		return w
+	}
+
+	// a short chain reaches a fixpoint before the limit
+	w := newWorld(5, 3)
+	require.NoError(t, w.Run(syms))
+	require.Len(t, *w.Query(Predicate{path, []Term{hashVar("a"), hashVar("b")}}), 6)
+
+	// a long chain is still generating paths when the limit is hit
+	w = newWorld(5, 12)
+	err := w.Run(syms)
+	require.ErrorIs(t, err, ErrWorldRunLimitMaxIterations)
+	require.Contains(t, err.Error(), "did not reach a fixpoint")
+}
+
+func TestWorldRunDeterministic(t *testing.T) {
+	syms := &SymbolTable{}
+	edge := syms.Insert("edge")
+	path := syms.Insert("path")
+
+	newWorld := func() *World {
+		w := NewWorld()
+		for _, e := range [][2]int{{3, 4}, {0, 1}, {2, 3}, {1, 2}, {0, 2}} {
+			w.AddFact(Fact{Predicate{edge, []Term{Integer(e[0]), Integer(e[1])}}})
+		}
+		w.AddRule(Rule{
+			Head: Predicate{path, []Term{hashVar("a"), hashVar("b")}},
+			Body: []Predicate{{edge, []Term{hashVar("a"), hashVar("b")}}},
+		})
+		w.AddRule(Rule{
+			Head: Predicate{path, []Term{hashVar("a"), hashVar("c")}},
+			Body: []Predicate{
+				{path, []Term{hashVar("a"), hashVar("b")}},
+				{edge, []Term{hashVar("b"), hashVar("c")}},
+			},
+		})
+		return w
+	}
+	query := Rule{
+		Head: Predicate{path, []Term{hashVar("a"), hashVar("c")}},
+		Body: []Predicate{{path, []Term{hashVar("a"), hashVar("c")}}},
+	}
+
+	w := newWorld()
+	require.NoError(t, w.Run(syms))
+	expected, err := w.QueryRuleBindings(query, syms)
+	require.NoError(t, err)
+
+	// the paths of the edges come first, in the order of the edges, then the
+	// paths found by each iteration, in the order of the paths they extend
+	pairs := make([][2]Term, len(expected))
+	for i, binding := range expected {
+		pairs[i] = [2]Term{binding[hashVar("a")], binding[hashVar("c")]}
+	}
+	require.Equal(t, [][2]Term{
+		{Integer(3), Integer(4)}, {Integer(0), Integer(1)}, {Integer(2), Integer(3)}, {Integer(1), Integer(2)}, {Integer(0), Integer(2)},
+		{Integer(2), Integer(4)}, {Integer(1), Integer(3)}, {Integer(0), Integer(3)},
+		{Integer(1), Integer(4)}, {Integer(0), Integer(4)},
+	}, pairs)
+
+	for i := 0; i < 20; i++ {
+		other := newWorld()
+		require.NoError(t, other.Run(syms))
+		require.Equal(t, *w.facts, *other.facts)
+		bindings, err := other.QueryRuleBindings(query, syms)
+		require.NoError(t, err)
+		require.Equal(t, expected, bindings)
+	}
+}
+
+func TestWorldOrigins(t *testing.T) {
+	syms := &SymbolTable{}
+	a := syms.Insert("A")
+	b := syms.Insert("B")
+	c := syms.Insert("C")
+	parent := syms.Insert("parent")
+	grandparent := syms.Insert("grandparent")
+
+	w := NewWorld()
+	w.AddFactWithOrigin(Fact{Predicate{parent, []Term{a, b}}}, NewOrigin(0))
+	w.AddFactWithOrigin(Fact{Predicate{parent, []Term{b, c}}}, NewOrigin(1))
+
+	r := Rule{
+		Head: Predicate{grandparent, []Term{hashVar("grandparent"), hashVar("grandchild")}},
+		Body: []Predicate{
+			{parent, []Term{hashVar("grandparent"), hashVar("parent")}},
+			{parent, []Term{hashVar("parent"), hashVar("grandchild")}},
+		},
 	}
+
+	// a rule from block 2 with the default scope does not see the facts of block 1
+	w.AddRuleWithOrigin(r, 2, TrustedOrigins(nil, NewOrigin(0), 2, nil))
+	require.NoError(t, w.Run(syms))
+	require.Empty(t, *w.Query(Predicate{grandparent, []Term{hashVar("grandparent"), hashVar("grandchild")}}))
+
+	// trusting previous blocks makes them visible, and the generated fact
+	// keeps track of all the blocks it comes from
+	w.AddRuleWithOrigin(r, 2, TrustedOrigins([]Scope{{Type: ScopeTypePrevious}}, NewOrigin(0), 2, nil))
+	require.NoError(t, w.Run(syms))
+	expected := Fact{Predicate{grandparent, []Term{a, c}}}
+	require.Equal(t, &FactSet{expected}, w.Query(Predicate{grandparent, []Term{hashVar("grandparent"), hashVar("grandchild")}}))
+
+	require.Empty(t, *w.QueryRuleTrusting(Rule{
+		Head: Predicate{grandparent, []Term{a, c}},
+		Body: []Predicate{{grandparent, []Term{a, c}}},
+	}, NewOrigin(0, 2), syms))
+	require.Equal(t, &FactSet{expected}, w.QueryRuleTrusting(Rule{
+		Head: Predicate{grandparent, []Term{a, c}},
+		Body: []Predicate{{grandparent, []Term{a, c}}},
+	}, NewOrigin(0, 1, 2), syms))
+}
+
+func TestWorldAddFactsWithOrigin(t *testing.T) {
+	syms := &SymbolTable{}
+	role := syms.Insert("role")
+	admin := syms.Insert("admin")
+	user := syms.Insert("user")
+
+	w := NewWorld()
+	w.AddFactWithOrigin(Fact{Predicate{role, []Term{admin}}}, NewOrigin(0))
+	w.AddFactsWithOrigin([]Fact{
+		{Predicate{role, []Term{admin}}},
+		{Predicate{role, []Term{user}}},
+		{Predicate{role, []Term{user}}},
+	}, NewOrigin(1))
+
+	// duplicates are only stored once per origin, including within the batch
+	require.Equal(t, 3, len(*w.Facts()))
+	require.Equal(t, []Origin{NewOrigin(0), NewOrigin(1), NewOrigin(1)}, w.origins)
+	require.False(t, w.AddFactWithOrigin(Fact{Predicate{role, []Term{user}}}, NewOrigin(1)))
+}
+
+func TestTrustedOrigins(t *testing.T) {
+	key := []byte("key")
+	publicKeyBlocks := func(k []byte) []uint64 {
+		if string(k) == string(key) {
+			return []uint64{3}
+		}
+		return nil
+	}
+
+	testCases := []struct {
+		desc     string
+		scopes   []Scope
+		block    uint64
+		expected Origin
+	}{
+		{
+			desc:     "default",
+			block:    2,
+			expected: NewOrigin(0, 2, AuthorizerOrigin),
+		},
+		{
+			desc:     "authority",
+			scopes:   []Scope{{Type: ScopeTypeAuthority}},
+			block:    2,
+			expected: NewOrigin(0, 2, AuthorizerOrigin),
+		},
+		{
+			desc:     "previous",
+			scopes:   []Scope{{Type: ScopeTypePrevious}},
+			block:    2,
+			expected: NewOrigin(0, 1, 2, AuthorizerOrigin),
+		},
+		{
+			desc:     "previous from the authorizer",
+			scopes:   []Scope{{Type: ScopeTypePrevious}},
+			block:    AuthorizerOrigin,
+			expected: NewOrigin(AuthorizerOrigin),
+		},
+		{
+			desc:     "public key",
+			scopes:   []Scope{{Type: ScopeTypePublicKey, PublicKey: key}},
+			block:    4,
+			expected: NewOrigin(3, 4, AuthorizerOrigin),
+		},
+		{
+			desc:     "unknown public key",
+			scopes:   []Scope{{Type: ScopeTypePublicKey, PublicKey: []byte("other")}},
+			block:    4,
+			expected: NewOrigin(4, AuthorizerOrigin),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.Equal(t, tc.expected, TrustedOrigins(tc.scopes, NewOrigin(0), tc.block, publicKeyBlocks))
+		})
+	}
+}
+
+func TestContainsBoundSet(t *testing.T) {
+	syms := &SymbolTable{}
+	roles := syms.Insert("roles")
+	admin := syms.Insert("admin")
+	alice := syms.Insert("alice")
+	bob := syms.Insert("bob")
+
+	w := NewWorld()
+	w.AddFact(Fact{Predicate{roles, []Term{alice, Set{syms.Insert("admin"), syms.Insert("dev")}}}})
+	w.AddFact(Fact{Predicate{roles, []Term{bob, Set{syms.Insert("dev")}}}})
+
+	// admin($user) <- roles($user, $roles), $roles.contains("admin")
+	w.AddRule(Rule{
+		Head: Predicate{admin, []Term{hashVar("user")}},
+		Body: []Predicate{{roles, []Term{hashVar("user"), hashVar("roles")}}},
+		Expressions: []Expression{{
+			Value{hashVar("roles")},
+			Value{syms.Insert("admin")},
+			BinaryOp{Contains{}},
+		}},
+	})
+	require.NoError(t, w.Run(syms))
+
+	res := w.Query(Predicate{admin, []Term{hashVar("user")}})
+	require.Equal(t, &FactSet{{Predicate{admin, []Term{alice}}}}, res)
+}
+
+func TestQueryMatchAll(t *testing.T) {
+	syms := &SymbolTable{}
+	operation := syms.Insert("operation")
+	query := syms.Insert("query")
+	read := syms.Insert("read")
+	write := syms.Insert("write")
+
+	w := NewWorld()
+	w.AddFactWithOrigin(Fact{Predicate{operation, []Term{read}}}, NewOrigin(0))
+	w.AddFactWithOrigin(Fact{Predicate{operation, []Term{write}}}, NewOrigin(0))
+
+	// operation($op), {allowed}.contains($op)
+	rule := func(allowed Term) Rule {
+		return Rule{
+			Head: Predicate{query, []Term{}},
+			Body: []Predicate{{operation, []Term{hashVar("op")}}},
+			Expressions: []Expression{{
+				Value{allowed},
+				Value{hashVar("op")},
+				BinaryOp{Contains{}},
+			}},
+		}
+	}
+
+	testCases := []struct {
+		desc     string
+		rule     Rule
+		trusted  Origin
+		expected bool
+		err      bool
+	}{
+		{
+			desc:     "all match",
+			rule:     rule(Set{read, write}),
+			trusted:  NewOrigin(0),
+			expected: true,
+		},
+		{
+			desc:     "one match",
+			rule:     rule(Set{read}),
+			trusted:  NewOrigin(0),
+			expected: false,
+		},
+		{
+			desc:     "no fact",
+			rule:     rule(Set{read, write}),
+			trusted:  NewOrigin(1),
+			expected: false,
+		},
+		{
+			desc:    "expression error",
+			rule:    rule(Integer(1)),
+			trusted: NewOrigin(0),
+			err:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			res, err := w.QueryMatchAllTrusting(tc.rule, tc.trusted, syms)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, res)
+		})
+	}
+
+	// a regular query matches as soon as one combination does
+	require.Len(t, *w.QueryRuleTrusting(rule(Set{read}), NewOrigin(0), syms), 1)
+}
+
+func TestPredicateKey(t *testing.T) {
+	syms := &SymbolTable{}
+	name := syms.Insert("name")
+	a := syms.Insert("a")
+	b := syms.Insert("b")
+
+	predicates := []Predicate{
+		{name, []Term{}},
+		{name, []Term{Integer(1)}},
+		{name, []Term{Integer(-1)}},
+		{name, []Term{String(1)}},
+		{name, []Term{Date(1)}},
+		{name, []Term{Variable(1)}},
+		{name, []Term{Bool(true)}},
+		{name, []Term{Bool(false)}},
+		{name, []Term{Bytes{1}}},
+		{name, []Term{Bytes{1}, Bytes{}}},
+		{name, []Term{Bytes{}, Bytes{1}}},
+		{name, []Term{Set{a, b}}},
+		{name, []Term{Set{a}, Set{b}}},
+		{name, []Term{Array{a, b}}},
+		{name, []Term{Array{b, a}}},
+		{name, []Term{Map{a: b}}},
+		{name, []Term{Map{b: a}}},
+		{a, []Term{Integer(1)}},
+	}
+	for i, p1 := range predicates {
+		for j, p2 := range predicates {
+			require.Equal(t, p1.Equal(p2), p1.key() == p2.key(), "%v and %v", p1, p2)
+			require.Equal(t, i == j, p1.Equal(p2), "%v and %v", p1, p2)
+		}
+	}
+
+	// sets and maps are unordered
+	require.Equal(t, Predicate{name, []Term{Set{a, b}}}.key(), Predicate{name, []Term{Set{b, a}}}.key())
+	require.Equal(t,
+		Predicate{name, []Term{Map{a: Integer(1), b: Integer(2)}}}.key(),
+		Predicate{name, []Term{Map{b: Integer(2), a: Integer(1)}}}.key(),
+	)
+}
+
+func TestWorldFactIndex(t *testing.T) {
+	syms := &SymbolTable{}
+	roles := syms.Insert("roles")
+	admin := syms.Insert("admin")
+	dev := syms.Insert("dev")
+
+	w := NewWorld()
+	require.True(t, w.AddFactWithOrigin(Fact{Predicate{roles, []Term{Set{admin, dev}}}}, NewOrigin(0)))
+	require.False(t, w.AddFactWithOrigin(Fact{Predicate{roles, []Term{Set{dev, admin}}}}, NewOrigin(0)))
+	// the same fact is stored once for each origin
+	require.True(t, w.AddFactWithOrigin(Fact{Predicate{roles, []Term{Set{dev, admin}}}}, NewOrigin(1)))
+	require.True(t, w.AddFactWithOrigin(Fact{Predicate{roles, []Term{Set{dev}}}}, NewOrigin(0)))
+	require.Len(t, *w.Facts(), 3)
+
+	// facts of a cloned world are indexed as well
+	c := w.Clone()
+	require.False(t, c.AddFactWithOrigin(Fact{Predicate{roles, []Term{Set{dev}}}}, NewOrigin(0)))
+	require.True(t, c.AddFactWithOrigin(Fact{Predicate{roles, []Term{Set{admin}}}}, NewOrigin(0)))
+	require.Len(t, *c.Facts(), 4)
+	require.Len(t, *w.Facts(), 3)
+
+	tw := w.Trusting(NewOrigin(0))
+	require.False(t, tw.AddFactWithOrigin(Fact{Predicate{roles, []Term{Set{dev}}}}, nil))
+	require.Len(t, *tw.Facts(), 2)
+}
+
+func BenchmarkTransitiveClosure(b *testing.B) {
+	syms := &SymbolTable{}
+	edge := syms.Insert("edge")
+	path := syms.Insert("path")
+
+	for n := 0; n < b.N; n++ {
+		w := NewWorld(WithMaxFacts(10000), WithMaxIterations(100), WithMaxDuration(time.Minute))
+		for i := 0; i < 40; i++ {
+			w.AddFact(Fact{Predicate{edge, []Term{Integer(i), Integer(i + 1)}}})
+		}
+		w.AddRule(Rule{
+			Head: Predicate{path, []Term{hashVar("a"), hashVar("b")}},
+			Body: []Predicate{{edge, []Term{hashVar("a"), hashVar("b")}}},
+		})
+		w.AddRule(Rule{
+			Head: Predicate{path, []Term{hashVar("a"), hashVar("c")}},
+			Body: []Predicate{
+				{path, []Term{hashVar("a"), hashVar("b")}},
+				{edge, []Term{hashVar("b"), hashVar("c")}},
+			},
+		})
+		if err := w.Run(syms); err != nil {
+			b.Fatal(err)
+		}
+		if len(*w.Query(Predicate{path, []Term{hashVar("a"), hashVar("b")}})) != 40*41/2 {
+			b.Fatal("unexpected number of paths")
+		}
+	}
+}
+
+func BenchmarkWorldAddFact(b *testing.B) {
+	syms := &SymbolTable{}
+	fact := syms.Insert("fact")
+
+	for n := 0; n < b.N; n++ {
+		w := NewWorld()
+		for i := 0; i < 5000; i++ {
+			w.AddFactWithOrigin(Fact{Predicate{fact, []Term{Integer(i)}}}, NewOrigin(0))
+		}
+	}
+}
+
+func TestWorldReset(t *testing.T) {
+	syms := &SymbolTable{}
+	a := syms.Insert("A")
+	b := syms.Insert("B")
+	c := syms.Insert("C")
+	d := syms.Insert("D")
+	parent := syms.Insert("parent")
+	grandparent := syms.Insert("grandparent")
+
+	// the scenario of TestFamily
+	run := func(w *World) *FactSet {
+		w.AddFact(Fact{Predicate{parent, []Term{a, b}}})
+		w.AddFact(Fact{Predicate{parent, []Term{b, c}}})
+		w.AddFact(Fact{Predicate{parent, []Term{c, d}}})
+		w.AddRule(Rule{
+			Head: Predicate{grandparent, []Term{hashVar("grandparent"), hashVar("grandchild")}},
+			Body: []Predicate{
+				{parent, []Term{hashVar("grandparent"), hashVar("parent")}},
+				{parent, []Term{hashVar("parent"), hashVar("grandchild")}},
+			},
+		})
+		require.NoError(t, w.Run(syms))
+		return w.Query(Predicate{grandparent, []Term{hashVar("grandparent"), hashVar("grandchild")}})
+	}
+
+	expected := run(NewWorld())
+	require.Len(t, *expected, 2)
+
+	w := NewWorld(WithMaxFacts(10))
+	w.AddFact(Fact{Predicate{parent, []Term{d, a}}})
+	w.AddRule(Rule{
+		Head: Predicate{parent, []Term{hashVar("child"), hashVar("parent")}},
+		Body: []Predicate{{parent, []Term{hashVar("parent"), hashVar("child")}}},
+	})
+	require.NoError(t, w.Run(syms))
+
+	w.Reset()
+	require.Empty(t, *w.Facts())
+	require.Empty(t, w.Rules())
+	require.Equal(t, 10, w.runLimits.maxFacts)
+	require.Equal(t, expected, run(w))
+
+	// facts removed by Reset are not considered duplicates anymore
+	w.Reset()
+	require.True(t, w.AddFactWithOrigin(Fact{Predicate{parent, []Term{a, b}}}, nil))
+}
+
+func BenchmarkWorldReset(b *testing.B) {
+	syms := &SymbolTable{}
+	edge := syms.Insert("edge")
+	path := syms.Insert("path")
+
+	run := func(w *World) {
+		for i := 0; i < 10; i++ {
+			w.AddFact(Fact{Predicate{edge, []Term{Integer(i), Integer(i + 1)}}})
+		}
+		w.AddRule(Rule{
+			Head: Predicate{path, []Term{hashVar("a"), hashVar("b")}},
+			Body: []Predicate{{edge, []Term{hashVar("a"), hashVar("b")}}},
+		})
+		w.AddRule(Rule{
+			Head: Predicate{path, []Term{hashVar("a"), hashVar("c")}},
+			Body: []Predicate{
+				{path, []Term{hashVar("a"), hashVar("b")}},
+				{edge, []Term{hashVar("b"), hashVar("c")}},
+			},
+		})
+		if err := w.Run(syms); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("new", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			run(NewWorld(WithMaxDuration(time.Second)))
+		}
+	})
+	b.Run("reset", func(b *testing.B) {
+		w := NewWorld(WithMaxDuration(time.Second))
+		for n := 0; n < b.N; n++ {
+			w.Reset()
+			run(w)
+		}
+	})
 }
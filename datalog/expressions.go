@@ -1,31 +1,109 @@
 package datalog
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
 // maxStackSize defines the maximum number of elements that can be stored on the stack.
 // Trying to store more than maxStackSize elements returns an error.
 const maxStackSize = 1000
 
+// DefaultMaxExpressionOps is the default maximum number of operations
+// of an evaluated expression.
+const DefaultMaxExpressionOps = 10000
+
 var (
 	ErrExprDivByZero = errors.New("datalog: Div by zero")
 	ErrInt64Overflow = errors.New("datalog: expression overflowed int64")
+	// ErrExpressionTooLong is returned when evaluating an expression
+	// with more operations than allowed by WithMaxExpressionOps.
+	ErrExpressionTooLong = errors.New("datalog: expression has too many operations")
+	// ErrConversionOutOfRange is returned by ToDate and ToInt when a value
+	// cannot be represented in the converted type.
+	ErrConversionOutOfRange = errors.New("datalog: converted value out of range")
 )
 
 type Expression []Op
 
+// TraceEntry records an operation of an expression evaluation:
+// the source of the sub-expression it computed, and its value.
+type TraceEntry struct {
+	Op    string
+	Value Term
+}
+
+// Evaluate evaluates the expression with the default limits of a World.
 func (e *Expression) Evaluate(values map[Variable]*Term, symbols *SymbolTable) (Term, error) {
+	return e.evaluate(values, symbols, defaultRunLimits, nil)
+}
+
+// EvaluateTrace evaluates the expression like Evaluate, and also returns
+// the value of each unary and binary operation, in evaluation order.
+// On error, the trace holds the operations evaluated before the failure.
+func (e *Expression) EvaluateTrace(values map[Variable]*Term, symbols *SymbolTable) (Term, []TraceEntry, error) {
+	trace := []TraceEntry{}
+	res, err := e.evaluate(values, symbols, defaultRunLimits, &trace)
+	return res, trace, err
+}
+
+// evaluate runs the expression within the limits, recording its operations
+// in trace when it is not nil.
+//
+// And and Or short-circuit: when the left operand is false for And, or true
+// for Or, the right operand is not evaluated and the result is the left operand.
+// As a consequence, errors in a skipped right operand, such as a type mismatch
+// or an unknown variable, are not reported.
+func (e *Expression) evaluate(values map[Variable]*Term, symbols *SymbolTable, limits runLimits, trace *[]TraceEntry) (Term, error) {
+	if len(*e) > limits.maxExpressionOps {
+		return nil, fmt.Errorf("%w: %d operations, maximum is %d", ErrExpressionTooLong, len(*e), limits.maxExpressionOps)
+	}
+
 	s := &stack{}
+	// strs holds the source of the values in s when tracing
+	var strs *stringstack
+	if trace != nil {
+		strs = &stringstack{}
+	}
 
-	for _, op := range *e {
+	ops := *e
+	shortCircuits := e.shortCircuits()
+	for i := 0; i < len(ops); i++ {
+		if j, ok := shortCircuits[i]; ok && len(*s) > 0 {
+			// i starts the right operand of the And or Or at j,
+			// and the left operand is on top of the stack
+			left := (*s)[len(*s)-1]
+			kind := ops[j].(BinaryOp).BinaryOpFunc.Type()
+			if b, ok := left.(Bool); ok && (kind == BinaryAnd && !bool(b) || kind == BinaryOr && bool(b)) {
+				if strs != nil {
+					leftStr, _ := strs.Pop()
+					right := ops[i:j]
+					str := ops[j].(BinaryOp).Print(leftStr, right.Print(symbols))
+					*trace = append(*trace, TraceEntry{Op: str, Value: left})
+					_ = strs.Push(str)
+				}
+				i = j
+				continue
+			}
+		}
+
+		op := ops[i]
 		switch op.Type() {
 		case OpTypeValue:
 			id := op.(Value).ID
+			if strs != nil {
+				if err := strs.Push(printValue(id, symbols)); err != nil {
+					return nil, fmt.Errorf("datalog: expressions: stack overflow")
+				}
+			}
 			switch id.Type() {
 			case TermTypeVariable:
 				idptr, ok := values[id.(Variable)]
@@ -53,6 +131,13 @@ func (e *Expression) Evaluate(values map[Variable]*Term, symbols *SymbolTable) (
 			if err != nil {
 				return nil, fmt.Errorf("datalog: expressions: stack overflow")
 			}
+
+			if strs != nil {
+				str, _ := strs.Pop()
+				str = op.(UnaryOp).Print(str)
+				*trace = append(*trace, TraceEntry{Op: str, Value: res})
+				_ = strs.Push(str)
+			}
 		case OpTypeBinary:
 			right, err := s.Pop()
 			if err != nil {
@@ -63,7 +148,7 @@ func (e *Expression) Evaluate(values map[Variable]*Term, symbols *SymbolTable) (
 				return nil, fmt.Errorf("datalog: expressions: failed to pop binary left value: %w", err)
 			}
 
-			res, err := op.(BinaryOp).Eval(left, right, symbols)
+			res, err := op.(BinaryOp).eval(left, right, symbols, limits)
 			if err != nil {
 				return nil, fmt.Errorf("datalog: expressions: binary eval failed: %w", err)
 			}
@@ -71,6 +156,14 @@ func (e *Expression) Evaluate(values map[Variable]*Term, symbols *SymbolTable) (
 			if err != nil {
 				return nil, fmt.Errorf("datalog: expressions: stack overflow")
 			}
+
+			if strs != nil {
+				rightStr, _ := strs.Pop()
+				leftStr, _ := strs.Pop()
+				str := op.(BinaryOp).Print(leftStr, rightStr)
+				*trace = append(*trace, TraceEntry{Op: str, Value: res})
+				_ = strs.Push(str)
+			}
 		default:
 			return nil, fmt.Errorf("datalog: expressions: unsupported Op: %v", op.Type())
 		}
@@ -84,29 +177,49 @@ func (e *Expression) Evaluate(values map[Variable]*Term, symbols *SymbolTable) (
 	return s.Pop()
 }
 
+// shortCircuits maps the index of the first operation of the right operand
+// of each And and Or to the index of that And or Or. It returns nil when the
+// expression has no And or Or, or is malformed.
+func (e *Expression) shortCircuits() map[int]int {
+	var res map[int]int
+	// starts holds the index of the first operation of each value on the stack
+	starts := make([]int, 0, len(*e))
+	for i, op := range *e {
+		switch op.Type() {
+		case OpTypeValue:
+			starts = append(starts, i)
+		case OpTypeUnary:
+			if len(starts) < 1 {
+				return nil
+			}
+		case OpTypeBinary:
+			if len(starts) < 2 {
+				return nil
+			}
+			rightStart := starts[len(starts)-1]
+			starts = starts[:len(starts)-1]
+			if t := op.(BinaryOp).BinaryOpFunc.Type(); t == BinaryAnd || t == BinaryOr {
+				if res == nil {
+					res = make(map[int]int)
+				}
+				res[rightStart] = i
+			}
+		default:
+			return nil
+		}
+	}
+	return res
+}
+
 func (e *Expression) Print(symbols *SymbolTable) string {
 	s := &stringstack{}
 
 	for _, op := range *e {
 		switch op.Type() {
 		case OpTypeValue:
-			id := op.(Value).ID
-			switch id.Type() {
-			case TermTypeString:
-				err := s.Push(fmt.Sprintf("\"%s\"", symbols.Str(id.(String))))
-				if err != nil {
-					return "<invalid expression: stack overflow>"
-				}
-			case TermTypeVariable:
-				err := s.Push(fmt.Sprintf("$%s", symbols.Var(id.(Variable))))
-				if err != nil {
-					return "<invalid expression: stack overflow>"
-				}
-			default:
-				err := s.Push(id.String())
-				if err != nil {
-					return "<invalid expression: stack overflow>"
-				}
+			err := s.Push(printValue(op.(Value).ID, symbols))
+			if err != nil {
+				return "<invalid expression: stack overflow>"
 			}
 		case OpTypeUnary:
 			v, err := s.Pop()
@@ -148,6 +261,39 @@ func (e *Expression) Print(symbols *SymbolTable) string {
 	return "<invalid expression: invalid resulting stack>"
 }
 
+func printValue(id Term, symbols *SymbolTable) string {
+	switch id.Type() {
+	case TermTypeString:
+		return fmt.Sprintf("\"%s\"", symbols.Str(id.(String)))
+	case TermTypeVariable:
+		return fmt.Sprintf("$%s", symbols.Var(id.(Variable)))
+	case TermTypeArray:
+		elts := make([]string, 0, len(id.(Array)))
+		for _, e := range id.(Array) {
+			elts = append(elts, printValue(e, symbols))
+		}
+		return fmt.Sprintf("array:[%s]", strings.Join(elts, ", "))
+	case TermTypeSet:
+		elts := make([]string, 0, len(id.(Set)))
+		for _, e := range id.(Set) {
+			elts = append(elts, printValue(e, symbols))
+		}
+		sort.Strings(elts)
+		return fmt.Sprintf("[%s]", strings.Join(elts, ", "))
+	case TermTypeMap:
+		elts := make([]string, 0, len(id.(Map)))
+		for k, v := range id.(Map) {
+			elts = append(elts, fmt.Sprintf("%s: %s", printValue(k, symbols), printValue(v, symbols)))
+		}
+		sort.Strings(elts)
+		return fmt.Sprintf("{%s}", strings.Join(elts, ", "))
+	case TermTypeTagged:
+		return fmt.Sprintf("tagged(\"%s\", %s)", symbols.Str(id.(Tagged).Tag), id.(Tagged).Value)
+	default:
+		return id.String()
+	}
+}
+
 type OpType byte
 
 const (
@@ -184,6 +330,12 @@ func (op UnaryOp) Print(value string) string {
 		out = fmt.Sprintf("(%s)", value)
 	case UnaryLength:
 		out = fmt.Sprintf("%s.length()", value)
+	case UnaryCharLength:
+		out = fmt.Sprintf("%s.char_length()", value)
+	case UnaryToDate:
+		out = fmt.Sprintf("%s.to_date()", value)
+	case UnaryToInt:
+		out = fmt.Sprintf("%s.to_int()", value)
 	default:
 		out = fmt.Sprintf("unknown(%s)", value)
 	}
@@ -201,6 +353,9 @@ const (
 	UnaryNegate UnaryOpType = iota
 	UnaryParens
 	UnaryLength
+	UnaryCharLength
+	UnaryToDate
+	UnaryToInt
 )
 
 // Negate returns the negation of a value.
@@ -235,7 +390,9 @@ func (Parens) Eval(value Term, _ *SymbolTable) (Term, error) {
 }
 
 // Length returns the length of a value.
-// It accepts String, Bytes and Set
+// It accepts String, Bytes, Set, Array and Map.
+// The length of a String is its number of bytes in UTF-8,
+// CharLength returns its number of characters.
 type Length struct{}
 
 func (Length) Type() UnaryOpType {
@@ -251,12 +408,66 @@ func (Length) Eval(value Term, symbols *SymbolTable) (Term, error) {
 		out = Integer(len(value.(Bytes)))
 	case TermTypeSet:
 		out = Integer(len(value.(Set)))
+	case TermTypeArray:
+		out = Integer(len(value.(Array)))
+	case TermTypeMap:
+		out = Integer(len(value.(Map)))
 	default:
 		return nil, fmt.Errorf("datalog: unexpected Length value type: %d", value.Type())
 	}
 	return out, nil
 }
 
+// CharLength returns the number of characters, or unicode code points,
+// of a String value. Invalid UTF-8 bytes are counted as one character each.
+type CharLength struct{}
+
+func (CharLength) Type() UnaryOpType {
+	return UnaryCharLength
+}
+func (CharLength) Eval(value Term, symbols *SymbolTable) (Term, error) {
+	if value.Type() != TermTypeString {
+		return nil, fmt.Errorf("datalog: unexpected CharLength value type: %d", value.Type())
+	}
+	return Integer(utf8.RuneCountInString(symbols.Str(value.(String)))), nil
+}
+
+// ToDate converts an Integer, a number of seconds since the unix epoch,
+// to a Date. Negative integers are out of the Date range.
+type ToDate struct{}
+
+func (ToDate) Type() UnaryOpType {
+	return UnaryToDate
+}
+func (ToDate) Eval(value Term, _ *SymbolTable) (Term, error) {
+	i, ok := value.(Integer)
+	if !ok {
+		return nil, fmt.Errorf("datalog: unexpected ToDate value type: %d", value.Type())
+	}
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %d is not a date", ErrConversionOutOfRange, i)
+	}
+	return Date(i), nil
+}
+
+// ToInt converts a Date to an Integer, its number of seconds since the
+// unix epoch. Dates over math.MaxInt64 are out of the Integer range.
+type ToInt struct{}
+
+func (ToInt) Type() UnaryOpType {
+	return UnaryToInt
+}
+func (ToInt) Eval(value Term, _ *SymbolTable) (Term, error) {
+	d, ok := value.(Date)
+	if !ok {
+		return nil, fmt.Errorf("datalog: unexpected ToInt value type: %d", value.Type())
+	}
+	if d > math.MaxInt64 {
+		return nil, fmt.Errorf("%w: %d is not an integer", ErrConversionOutOfRange, uint64(d))
+	}
+	return Integer(d), nil
+}
+
 type BinaryOp struct {
 	BinaryOpFunc
 }
@@ -277,6 +488,8 @@ func (op BinaryOp) Print(left, right string) string {
 		out = fmt.Sprintf("%s >= %s", left, right)
 	case BinaryEqual:
 		out = fmt.Sprintf("%s == %s", left, right)
+	case BinaryNotEqual:
+		out = fmt.Sprintf("%s != %s", left, right)
 	case BinaryContains:
 		out = fmt.Sprintf("%s.contains(%s)", left, right)
 	case BinaryPrefix:
@@ -285,6 +498,8 @@ func (op BinaryOp) Print(left, right string) string {
 		out = fmt.Sprintf("%s.ends_with(%s)", left, right)
 	case BinaryRegex:
 		out = fmt.Sprintf("%s.matches(%s)", left, right)
+	case BinaryRegexInsensitive:
+		out = fmt.Sprintf("%s.matches_insensitive(%s)", left, right)
 	case BinaryAdd:
 		out = fmt.Sprintf("%s + %s", left, right)
 	case BinarySub:
@@ -301,12 +516,32 @@ func (op BinaryOp) Print(left, right string) string {
 		out = fmt.Sprintf("%s.intersection(%s)", left, right)
 	case BinaryUnion:
 		out = fmt.Sprintf("%s.union(%s)", left, right)
+	case BinaryGet:
+		out = fmt.Sprintf("%s.get(%s)", left, right)
+	case BinarySubset:
+		out = fmt.Sprintf("%s.subset(%s)", left, right)
+	case BinarySuperset:
+		out = fmt.Sprintf("%s.superset(%s)", left, right)
+	case BinaryBitwiseAnd:
+		out = fmt.Sprintf("%s & %s", left, right)
 	default:
 		out = fmt.Sprintf("unknown(%s, %s)", left, right)
 	}
 	return out
 }
 
+// eval evaluates the operation within the limits, which bound
+// the length of the regex patterns.
+func (op BinaryOp) eval(left, right Term, symbols *SymbolTable, limits runLimits) (Term, error) {
+	switch f := op.BinaryOpFunc.(type) {
+	case Regex:
+		return f.eval(left, right, symbols, limits.maxRegexLength)
+	case RegexInsensitive:
+		return f.eval(left, right, symbols, limits.maxRegexLength)
+	}
+	return op.Eval(left, right, symbols)
+}
+
 type BinaryOpFunc interface {
 	Type() BinaryOpType
 	Eval(left, right Term, symbols *SymbolTable) (Term, error)
@@ -332,11 +567,17 @@ const (
 	BinaryOr
 	BinaryIntersection
 	BinaryUnion
+	BinaryGet
+	BinaryNotEqual
+	BinarySubset
+	BinarySuperset
+	BinaryBitwiseAnd
+	BinaryRegexInsensitive
 )
 
 // LessThan returns true when left is less than right.
 // It requires left and right to have the same concrete type
-// and only accepts Integer.
+// and only accepts Integer, Date and Bytes.
 type LessThan struct{}
 
 func (LessThan) Type() BinaryOpType {
@@ -353,6 +594,8 @@ func (LessThan) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
 		out = Bool(left.(Integer) < right.(Integer))
 	case TermTypeDate:
 		out = Bool(left.(Date) < right.(Date))
+	case TermTypeBytes:
+		out = Bool(bytes.Compare(left.(Bytes), right.(Bytes)) < 0)
 	default:
 		return nil, fmt.Errorf("datalog: unexpected LessThan value type: %d", left.Type())
 	}
@@ -362,7 +605,7 @@ func (LessThan) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
 
 // LessOrEqual returns true when left is less or equal than right.
 // It requires left and right to have the same concrete type
-// and only accepts Integer and Date.
+// and only accepts Integer, Date and Bytes.
 type LessOrEqual struct{}
 
 func (LessOrEqual) Type() BinaryOpType {
@@ -379,6 +622,8 @@ func (LessOrEqual) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
 		out = Bool(left.(Integer) <= right.(Integer))
 	case TermTypeDate:
 		out = Bool(left.(Date) <= right.(Date))
+	case TermTypeBytes:
+		out = Bool(bytes.Compare(left.(Bytes), right.(Bytes)) <= 0)
 	default:
 		return nil, fmt.Errorf("datalog: unexpected LessOrEqual value type: %d", left.Type())
 	}
@@ -388,7 +633,7 @@ func (LessOrEqual) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
 
 // GreaterThan returns true when left is greater than right.
 // It requires left and right to have the same concrete type
-// and only accepts Integer.
+// and only accepts Integer, Date and Bytes.
 type GreaterThan struct{}
 
 func (GreaterThan) Type() BinaryOpType {
@@ -405,6 +650,8 @@ func (GreaterThan) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
 		out = Bool(left.(Integer) > right.(Integer))
 	case TermTypeDate:
 		out = Bool(left.(Date) > right.(Date))
+	case TermTypeBytes:
+		out = Bool(bytes.Compare(left.(Bytes), right.(Bytes)) > 0)
 	default:
 		return nil, fmt.Errorf("datalog: unexpected GreaterThan value type: %d", left.Type())
 	}
@@ -414,7 +661,7 @@ func (GreaterThan) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
 
 // GreaterOrEqual returns true when left is greater than right.
 // It requires left and right to have the same concrete type
-// and only accepts Integer and Date.
+// and only accepts Integer, Date and Bytes.
 type GreaterOrEqual struct{}
 
 func (GreaterOrEqual) Type() BinaryOpType {
@@ -431,6 +678,8 @@ func (GreaterOrEqual) Eval(left Term, right Term, _ *SymbolTable) (Term, error)
 		out = Bool(left.(Integer) >= right.(Integer))
 	case TermTypeDate:
 		out = Bool(left.(Date) >= right.(Date))
+	case TermTypeBytes:
+		out = Bool(bytes.Compare(left.(Bytes), right.(Bytes)) >= 0)
 	default:
 		return nil, fmt.Errorf("datalog: unexpected GreaterOrEqual value type: %d", left.Type())
 	}
@@ -458,6 +707,9 @@ func (Equal) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
 	case TermTypeDate:
 	case TermTypeBool:
 	case TermTypeSet:
+	case TermTypeArray:
+	case TermTypeMap:
+	case TermTypeTagged:
 
 	default:
 		return nil, fmt.Errorf("datalog: unexpected Equal value type: %d", left.Type())
@@ -466,9 +718,30 @@ func (Equal) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
 	return Bool(left.Equal(right)), nil
 }
 
-// Contains returns true when the right value exists in the left Set.
-// The right value must be an Integer, Bytes, String or Symbol.
-// The left value must be a Set, containing elements of right type.
+// NotEqual returns true when left and right are not equal.
+// It accepts the same types as Equal.
+type NotEqual struct{}
+
+func (NotEqual) Type() BinaryOpType {
+	return BinaryNotEqual
+}
+func (NotEqual) Eval(left Term, right Term, symbols *SymbolTable) (Term, error) {
+	eq, err := Equal{}.Eval(left, right, symbols)
+	if err != nil {
+		return nil, fmt.Errorf("datalog: NotEqual: %w", err)
+	}
+	return !eq.(Bool), nil
+}
+
+// Contains is overloaded on the type of its left value, as in the specification:
+//   - on a String, it returns true when the right String is a substring of
+//     the left one: `"abc".contains("b")`
+//   - on a Set, it returns true when the right value is an element of the
+//     left Set, or, if the right value is a Set, when all its elements are:
+//     `[1, 2].contains(1)`
+//
+// Both forms use the same serialized operation, the left value deciding
+// which one is evaluated.
 type Contains struct{}
 
 func (Contains) Type() BinaryOpType {
@@ -492,6 +765,7 @@ func (Contains) Eval(left Term, right Term, symbols *SymbolTable) (Term, error)
 	case TermTypeDate:
 	case TermTypeBool:
 	case TermTypeSet:
+	case TermTypeTagged:
 
 	default:
 		return nil, fmt.Errorf("datalog: unexpected Contains right value type: %d", right.Type())
@@ -568,6 +842,79 @@ func (Union) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
 	return set.Union(set2), nil
 }
 
+// Subset returns true when all the elements of the left Set are in the right Set.
+// A set is a subset of itself, and the empty set is a subset of any set.
+type Subset struct{}
+
+func (Subset) Type() BinaryOpType {
+	return BinarySubset
+}
+func (Subset) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
+	set, ok := left.(Set)
+	if !ok {
+		return nil, fmt.Errorf("datalog: Subset requires left value to be a Set, got %T", left)
+	}
+	set2, ok := right.(Set)
+	if !ok {
+		return nil, fmt.Errorf("datalog: Subset requires right value to be a Set, got %T", right)
+	}
+
+	return Bool(set.IsSubset(set2)), nil
+}
+
+// Superset returns true when all the elements of the right Set are in the left Set.
+type Superset struct{}
+
+func (Superset) Type() BinaryOpType {
+	return BinarySuperset
+}
+func (Superset) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
+	set, ok := left.(Set)
+	if !ok {
+		return nil, fmt.Errorf("datalog: Superset requires left value to be a Set, got %T", left)
+	}
+	set2, ok := right.(Set)
+	if !ok {
+		return nil, fmt.Errorf("datalog: Superset requires right value to be a Set, got %T", right)
+	}
+
+	return Bool(set2.IsSubset(set)), nil
+}
+
+// Get returns the element of an Array at the Integer index right,
+// or the value of a Map at the key right, which must be an Integer or a String.
+// It fails when the index is out of bounds or the key is missing.
+type Get struct{}
+
+func (Get) Type() BinaryOpType {
+	return BinaryGet
+}
+func (Get) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
+	switch left.Type() {
+	case TermTypeArray:
+		array := left.(Array)
+		index, ok := right.(Integer)
+		if !ok {
+			return nil, fmt.Errorf("datalog: unexpected Get array index type: %d", right.Type())
+		}
+		if index < 0 || int64(index) >= int64(len(array)) {
+			return nil, fmt.Errorf("datalog: Get index %d out of bounds", index)
+		}
+		return array[index], nil
+	case TermTypeMap:
+		if t := right.Type(); t != TermTypeInteger && t != TermTypeString {
+			return nil, fmt.Errorf("datalog: unexpected Get map key type: %d", t)
+		}
+		v, ok := left.(Map)[right]
+		if !ok {
+			return nil, fmt.Errorf("datalog: Get key %s not found", right)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("datalog: unexpected Get value type: %d", left.Type())
+	}
+}
+
 // Prefix returns true when the left string starts with the right string.
 // left and right must be String.
 type Prefix struct{}
@@ -609,13 +956,18 @@ func (Suffix) Eval(left Term, right Term, symbols *SymbolTable) (Term, error) {
 }
 
 // Regex returns true when the right string is a regexp and left matches against it.
-// left and right must be String.
+// left and right must be String. The pattern length is limited to DefaultMaxRegexLength,
+// or to the limit set with WithMaxRegexLength when evaluated by a World,
+// and compiled patterns are cached across evaluations.
 type Regex struct{}
 
 func (Regex) Type() BinaryOpType {
 	return BinaryRegex
 }
-func (Regex) Eval(left Term, right Term, symbols *SymbolTable) (Term, error) {
+func (r Regex) Eval(left Term, right Term, symbols *SymbolTable) (Term, error) {
+	return r.eval(left, right, symbols, DefaultMaxRegexLength)
+}
+func (Regex) eval(left Term, right Term, symbols *SymbolTable, maxLength int) (Term, error) {
 	sleft, ok := left.(String)
 	if !ok {
 		return nil, fmt.Errorf("datalog: Regex requires left value to be a String, got %T", left)
@@ -625,11 +977,80 @@ func (Regex) Eval(left Term, right Term, symbols *SymbolTable) (Term, error) {
 		return nil, fmt.Errorf("datalog: Regex requires right value to be a String, got %T", right)
 	}
 
-	re, err := regexp.Compile(symbols.Str(sright))
+	re, err := compileRegex(symbols.Str(sright), maxLength)
+	if err != nil {
+		return nil, err
+	}
+	return Bool(re.MatchString(symbols.Str(sleft))), nil
+}
+
+// RegexInsensitive is Regex ignoring case: the pattern is compiled
+// with the `(?i)` flag, which counts in its length.
+type RegexInsensitive struct{}
+
+func (RegexInsensitive) Type() BinaryOpType {
+	return BinaryRegexInsensitive
+}
+func (r RegexInsensitive) Eval(left Term, right Term, symbols *SymbolTable) (Term, error) {
+	return r.eval(left, right, symbols, DefaultMaxRegexLength)
+}
+func (RegexInsensitive) eval(left Term, right Term, symbols *SymbolTable, maxLength int) (Term, error) {
+	sleft, ok := left.(String)
+	if !ok {
+		return nil, fmt.Errorf("datalog: RegexInsensitive requires left value to be a String, got %T", left)
+	}
+	sright, ok := right.(String)
+	if !ok {
+		return nil, fmt.Errorf("datalog: RegexInsensitive requires right value to be a String, got %T", right)
+	}
+
+	re, err := compileRegex("(?i)"+symbols.Str(sright), maxLength)
 	if err != nil {
-		return nil, fmt.Errorf("datalog: invalid regex: %q: %v", right, err)
+		return nil, err
 	}
-	return Bool(re.Match([]byte(symbols.Str(sleft)))), nil
+	return Bool(re.MatchString(symbols.Str(sleft))), nil
+}
+
+// DefaultMaxRegexLength is the default maximum length of the patterns accepted by Regex.
+const DefaultMaxRegexLength = 1024
+
+// maxRegexCacheSize bounds the number of compiled patterns kept by Regex.
+const maxRegexCacheSize = 1024
+
+var (
+	ErrRegexTooLong = errors.New("datalog: regex pattern too long")
+
+	regexCacheMu sync.Mutex
+	regexCache   = make(map[string]*regexp.Regexp)
+)
+
+// compileRegex returns the compiled pattern, reusing the result of
+// previous compilations, since the same pattern is usually matched
+// against many facts. Patterns longer than maxLength are rejected.
+func compileRegex(pattern string, maxLength int) (*regexp.Regexp, error) {
+	if len(pattern) > maxLength {
+		return nil, fmt.Errorf("%w: %d bytes, maximum is %d", ErrRegexTooLong, len(pattern), maxLength)
+	}
+
+	regexCacheMu.Lock()
+	re, ok := regexCache[pattern]
+	regexCacheMu.Unlock()
+	if ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("datalog: invalid regex: %q: %v", pattern, err)
+	}
+
+	regexCacheMu.Lock()
+	if len(regexCache) >= maxRegexCacheSize {
+		regexCache = make(map[string]*regexp.Regexp)
+	}
+	regexCache[pattern] = re
+	regexCacheMu.Unlock()
+	return re, nil
 }
 
 // Add performs the addition of left + right and returns the result.
@@ -748,6 +1169,10 @@ func (Div) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
 	if iright == 0 {
 		return nil, ErrExprDivByZero
 	}
+	// the only quotient not representable in an int64
+	if ileft == math.MinInt64 && iright == -1 {
+		return nil, ErrInt64Overflow
+	}
 
 	return Integer(ileft / iright), nil
 }
@@ -792,6 +1217,26 @@ func (Or) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
 	return Bool(bleft || bright), nil
 }
 
+// BitwiseAnd performs a bitwise AND between left and right and returns an Integer.
+// It requires left and right to be Integer.
+type BitwiseAnd struct{}
+
+func (BitwiseAnd) Type() BinaryOpType {
+	return BinaryBitwiseAnd
+}
+func (BitwiseAnd) Eval(left Term, right Term, _ *SymbolTable) (Term, error) {
+	ileft, ok := left.(Integer)
+	if !ok {
+		return nil, fmt.Errorf("datalog: BitwiseAnd requires left value to be an Integer, got %T", left)
+	}
+	iright, ok := right.(Integer)
+	if !ok {
+		return nil, fmt.Errorf("datalog: BitwiseAnd requires right value to be an Integer, got %T", right)
+	}
+
+	return ileft & iright, nil
+}
+
 type stack []Term
 
 func (s *stack) Push(v Term) error {
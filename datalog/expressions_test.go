@@ -3,7 +3,10 @@ package datalog
 import (
 	"errors"
 	"math"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -57,6 +60,118 @@ func TestUnaryParens(t *testing.T) {
 	require.Equal(t, Integer(9), res)
 }
 
+func TestUnaryLength(t *testing.T) {
+	syms := &SymbolTable{}
+
+	testCases := []struct {
+		desc  string
+		value Term
+		res   Integer
+	}{
+		{desc: "string", value: syms.Insert("abc"), res: 3},
+		{desc: "empty string", value: syms.Insert(""), res: 0},
+		{desc: "bytes", value: Bytes("abc"), res: 3},
+		{desc: "empty bytes", value: Bytes{}, res: 0},
+		{desc: "nil bytes", value: Bytes(nil), res: 0},
+		{desc: "set", value: Set{Integer(1), Integer(2)}, res: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ops := Expression{Value{tc.value}, UnaryOp{Length{}}}
+			res, err := ops.Evaluate(nil, syms)
+			require.NoError(t, err)
+			require.Equal(t, tc.res, res)
+		})
+	}
+}
+
+func TestUnaryCharLength(t *testing.T) {
+	require.Equal(t, UnaryCharLength, CharLength{}.Type())
+	syms := &SymbolTable{}
+
+	testCases := []struct {
+		desc       string
+		value      string
+		length     Integer
+		charLength Integer
+	}{
+		{desc: "ascii", value: "abc", length: 3, charLength: 3},
+		{desc: "empty", value: "", length: 0, charLength: 0},
+		{desc: "cyrillic", value: "Миша", length: 8, charLength: 4},
+		{desc: "emoji", value: "a🦀b", length: 6, charLength: 3},
+		{desc: "invalid utf8", value: "a\xffb", length: 3, charLength: 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			value := syms.Insert(tc.value)
+
+			ops := Expression{Value{value}, UnaryOp{Length{}}}
+			res, err := ops.Evaluate(nil, syms)
+			require.NoError(t, err)
+			require.Equal(t, tc.length, res)
+
+			ops = Expression{Value{value}, UnaryOp{CharLength{}}}
+			res, err = ops.Evaluate(nil, syms)
+			require.NoError(t, err)
+			require.Equal(t, tc.charLength, res)
+		})
+	}
+
+	ops := Expression{Value{Bytes("abc")}, UnaryOp{CharLength{}}}
+	_, err := ops.Evaluate(nil, syms)
+	require.Error(t, err)
+}
+
+func TestUnaryDateConversions(t *testing.T) {
+	require.Equal(t, UnaryToDate, ToDate{}.Type())
+	require.Equal(t, UnaryToInt, ToInt{}.Type())
+	syms := &SymbolTable{}
+
+	testCases := []struct {
+		desc        string
+		op          UnaryOpFunc
+		value       Term
+		res         Term
+		expectedErr error
+	}{
+		{desc: "to_date", op: ToDate{}, value: Integer(1609459200), res: Date(1609459200)},
+		{desc: "to_date zero", op: ToDate{}, value: Integer(0), res: Date(0)},
+		{desc: "to_date max", op: ToDate{}, value: Integer(math.MaxInt64), res: Date(math.MaxInt64)},
+		{desc: "to_date negative", op: ToDate{}, value: Integer(-1), expectedErr: ErrConversionOutOfRange},
+		{desc: "to_date string", op: ToDate{}, value: syms.Insert("1609459200")},
+		{desc: "to_int", op: ToInt{}, value: Date(1609459200), res: Integer(1609459200)},
+		{desc: "to_int max", op: ToInt{}, value: Date(math.MaxInt64), res: Integer(math.MaxInt64)},
+		{desc: "to_int overflow", op: ToInt{}, value: Date(math.MaxInt64 + 1), expectedErr: ErrConversionOutOfRange},
+		{desc: "to_int integer", op: ToInt{}, value: Integer(1609459200)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ops := Expression{Value{tc.value}, UnaryOp{tc.op}}
+			res, err := ops.Evaluate(nil, syms)
+			switch {
+			case tc.expectedErr != nil:
+				require.ErrorIs(t, err, tc.expectedErr)
+			case tc.res == nil:
+				require.Error(t, err)
+			default:
+				require.NoError(t, err)
+				require.Equal(t, tc.res, res)
+			}
+		})
+	}
+
+	roundTrip := Expression{Value{Integer(1609459200)}, UnaryOp{ToDate{}}, UnaryOp{ToInt{}}}
+	res, err := roundTrip.Evaluate(nil, syms)
+	require.NoError(t, err)
+	require.Equal(t, Integer(1609459200), res)
+	require.Equal(t, "1609459200.to_date().to_int()", roundTrip.Print(syms))
+
+	roundTrip = Expression{Value{Date(1609459200)}, UnaryOp{ToInt{}}, UnaryOp{ToDate{}}}
+	res, err = roundTrip.Evaluate(nil, syms)
+	require.NoError(t, err)
+	require.Equal(t, Date(1609459200), res)
+}
+
 func TestBinaryLessThan(t *testing.T) {
 	require.Equal(t, BinaryLessThan, LessThan{}.Type())
 	syms := &SymbolTable{}
@@ -98,6 +213,36 @@ func TestBinaryLessThan(t *testing.T) {
 			right: Integer(42),
 			res:   false,
 		},
+		{
+			desc:  "bytes less than equal length",
+			left:  Bytes{1, 2, 3},
+			right: Bytes{1, 2, 4},
+			res:   true,
+		},
+		{
+			desc:  "bytes less than equal length reversed",
+			left:  Bytes{1, 2, 4},
+			right: Bytes{1, 2, 3},
+			res:   false,
+		},
+		{
+			desc:  "bytes less than different length",
+			left:  Bytes{1, 2},
+			right: Bytes{1, 2, 3},
+			res:   true,
+		},
+		{
+			desc:  "bytes less than equal",
+			left:  Bytes{1, 2, 3},
+			right: Bytes{1, 2, 3},
+			res:   false,
+		},
+		{
+			desc:        "integer and bytes errors",
+			left:        Integer(1),
+			right:       Bytes{1},
+			expectedErr: true,
+		},
 		{
 			desc:        "invalid left type errors",
 			left:        String(42),
@@ -202,6 +347,36 @@ func TestBinaryLessOrEqual(t *testing.T) {
 			right: Integer(-1),
 			res:   true,
 		},
+		{
+			desc:  "bytes less or equal equal length",
+			left:  Bytes{1, 2, 3},
+			right: Bytes{1, 2, 4},
+			res:   true,
+		},
+		{
+			desc:  "bytes less or equal equal length reversed",
+			left:  Bytes{1, 2, 4},
+			right: Bytes{1, 2, 3},
+			res:   false,
+		},
+		{
+			desc:  "bytes less or equal different length",
+			left:  Bytes{1, 2},
+			right: Bytes{1, 2, 3},
+			res:   true,
+		},
+		{
+			desc:  "bytes less or equal equal",
+			left:  Bytes{1, 2, 3},
+			right: Bytes{1, 2, 3},
+			res:   true,
+		},
+		{
+			desc:        "integer and bytes errors",
+			left:        Integer(1),
+			right:       Bytes{1},
+			expectedErr: true,
+		},
 		{
 			desc:        "invalid left type errors",
 			left:        String(42),
@@ -282,6 +457,36 @@ func TestBinaryGreaterThan(t *testing.T) {
 			right: Integer(42),
 			res:   false,
 		},
+		{
+			desc:  "bytes greater than equal length",
+			left:  Bytes{1, 2, 3},
+			right: Bytes{1, 2, 4},
+			res:   false,
+		},
+		{
+			desc:  "bytes greater than equal length reversed",
+			left:  Bytes{1, 2, 4},
+			right: Bytes{1, 2, 3},
+			res:   true,
+		},
+		{
+			desc:  "bytes greater than different length",
+			left:  Bytes{1, 2},
+			right: Bytes{1, 2, 3},
+			res:   false,
+		},
+		{
+			desc:  "bytes greater than equal",
+			left:  Bytes{1, 2, 3},
+			right: Bytes{1, 2, 3},
+			res:   false,
+		},
+		{
+			desc:        "integer and bytes errors",
+			left:        Integer(1),
+			right:       Bytes{1},
+			expectedErr: true,
+		},
 		{
 			desc:        "invalid left type errors",
 			left:        String(42),
@@ -386,6 +591,36 @@ func TestBinaryGreaterOrEqual(t *testing.T) {
 			right: Integer(-1),
 			res:   true,
 		},
+		{
+			desc:  "bytes greater or equal equal length",
+			left:  Bytes{1, 2, 3},
+			right: Bytes{1, 2, 4},
+			res:   false,
+		},
+		{
+			desc:  "bytes greater or equal equal length reversed",
+			left:  Bytes{1, 2, 4},
+			right: Bytes{1, 2, 3},
+			res:   true,
+		},
+		{
+			desc:  "bytes greater or equal different length",
+			left:  Bytes{1, 2},
+			right: Bytes{1, 2, 3},
+			res:   false,
+		},
+		{
+			desc:  "bytes greater or equal equal",
+			left:  Bytes{1, 2, 3},
+			right: Bytes{1, 2, 3},
+			res:   true,
+		},
+		{
+			desc:        "integer and bytes errors",
+			left:        Integer(1),
+			right:       Bytes{1},
+			expectedErr: true,
+		},
 		{
 			desc:        "invalid left type errors",
 			left:        String(42),
@@ -472,6 +707,30 @@ func TestBinaryEqual(t *testing.T) {
 			right: syms.Insert("abc"),
 			res:   true,
 		},
+		{
+			desc:  "equal arrays",
+			left:  Array{Integer(1), syms.Insert("abc")},
+			right: Array{Integer(1), syms.Insert("abc")},
+			res:   true,
+		},
+		{
+			desc:  "not equal arrays order",
+			left:  Array{Integer(1), Integer(2)},
+			right: Array{Integer(2), Integer(1)},
+			res:   false,
+		},
+		{
+			desc:  "equal maps",
+			left:  Map{Integer(1): Bool(true), syms.Insert("abc"): Integer(2)},
+			right: Map{syms.Insert("abc"): Integer(2), Integer(1): Bool(true)},
+			res:   true,
+		},
+		{
+			desc:  "not equal maps",
+			left:  Map{Integer(1): Bool(true)},
+			right: Map{Integer(1): Bool(false)},
+			res:   false,
+		},
 		{
 			desc:        "invalid left type errors",
 			left:        String(42),
@@ -505,6 +764,212 @@ func TestBinaryEqual(t *testing.T) {
 	}
 }
 
+func TestBinaryNotEqual(t *testing.T) {
+	require.Equal(t, BinaryNotEqual, NotEqual{}.Type())
+	syms := &SymbolTable{}
+
+	testCases := []struct {
+		desc        string
+		left        Term
+		right       Term
+		res         Bool
+		expectedErr bool
+	}{
+		{
+			desc:  "not equal integers",
+			left:  Integer(3),
+			right: Integer(5),
+			res:   true,
+		},
+		{
+			desc:  "equal integers",
+			left:  Integer(3),
+			right: Integer(3),
+			res:   false,
+		},
+		{
+			desc:  "not equal strings",
+			left:  syms.Insert("x"),
+			right: syms.Insert("y"),
+			res:   true,
+		},
+		{
+			desc:  "equal bytes",
+			left:  Bytes{0xab},
+			right: Bytes{0xab},
+			res:   false,
+		},
+		{
+			desc:  "not equal sets",
+			left:  Set{Integer(1)},
+			right: Set{Integer(2)},
+			res:   true,
+		},
+		{
+			desc:        "type mismatch errors",
+			left:        Integer(42),
+			right:       syms.Insert("abc"),
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ops := Expression{
+				Value{tc.left},
+				Value{tc.right},
+				BinaryOp{NotEqual{}},
+			}
+
+			res, err := ops.Evaluate(nil, syms)
+			if tc.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.res, res)
+			}
+		})
+	}
+
+	require.Equal(t, "1 != 2", (&Expression{Value{Integer(1)}, Value{Integer(2)}, BinaryOp{NotEqual{}}}).Print(syms))
+}
+
+func TestBinarySubsetSuperset(t *testing.T) {
+	require.Equal(t, BinarySubset, Subset{}.Type())
+	require.Equal(t, BinarySuperset, Superset{}.Type())
+	syms := &SymbolTable{}
+	a, b, c := syms.Insert("a"), syms.Insert("b"), syms.Insert("c")
+
+	testCases := []struct {
+		desc        string
+		left        Term
+		right       Term
+		subset      Bool
+		superset    Bool
+		expectedErr bool
+	}{
+		{
+			desc:   "proper integer subset",
+			left:   Set{Integer(1), Integer(2)},
+			right:  Set{Integer(3), Integer(2), Integer(1)},
+			subset: true,
+		},
+		{
+			desc:     "proper string superset",
+			left:     Set{a, b, c},
+			right:    Set{c, a},
+			superset: true,
+		},
+		{
+			desc:     "equal sets in another order",
+			left:     Set{a, b},
+			right:    Set{b, a},
+			subset:   true,
+			superset: true,
+		},
+		{
+			desc:     "empty set",
+			left:     Set{},
+			right:    Set{Integer(1)},
+			subset:   true,
+			superset: false,
+		},
+		{
+			desc:  "overlapping sets",
+			left:  Set{Integer(1), Integer(2)},
+			right: Set{Integer(2), Integer(3)},
+		},
+		{
+			desc:  "different element types",
+			left:  Set{Integer(1)},
+			right: Set{a},
+		},
+		{
+			desc:        "left is not a set",
+			left:        Integer(1),
+			right:       Set{Integer(1)},
+			expectedErr: true,
+		},
+		{
+			desc:        "right is not a set",
+			left:        Set{Integer(1)},
+			right:       Integer(1),
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			for op, expected := range map[BinaryOpFunc]Bool{Subset{}: tc.subset, Superset{}: tc.superset} {
+				ops := Expression{
+					Value{tc.left},
+					Value{tc.right},
+					BinaryOp{op},
+				}
+
+				res, err := ops.Evaluate(nil, syms)
+				if tc.expectedErr {
+					require.Error(t, err)
+				} else {
+					require.NoError(t, err)
+					require.Equal(t, expected, res, "%T", op)
+				}
+			}
+		})
+	}
+
+	require.Equal(t, `["a"].subset(["a", "b"])`, (&Expression{Value{Set{a}}, Value{Set{a, b}}, BinaryOp{Subset{}}}).Print(syms))
+	require.Equal(t, `["a"].superset(["a", "b"])`, (&Expression{Value{Set{a}}, Value{Set{a, b}}, BinaryOp{Superset{}}}).Print(syms))
+}
+
+func TestTagged(t *testing.T) {
+	syms := &SymbolTable{}
+	uuid := syms.Insert("uuid")
+	ip := syms.Insert("ip")
+
+	a := Tagged{Tag: uuid, Value: Bytes{1, 2}}
+	require.Equal(t, TermTypeTagged, a.Type())
+	require.Equal(t, `tagged("uuid", hex:0102)`, printValue(a, syms))
+
+	for _, tc := range []struct {
+		desc  string
+		op    BinaryOpFunc
+		left  Term
+		right Term
+		res   Bool
+		err   bool
+	}{
+		{desc: "equal", op: Equal{}, left: a, right: Tagged{Tag: uuid, Value: Bytes{1, 2}}, res: true},
+		{desc: "other bytes", op: Equal{}, left: a, right: Tagged{Tag: uuid, Value: Bytes{1, 3}}, res: false},
+		{desc: "other tag", op: Equal{}, left: a, right: Tagged{Tag: ip, Value: Bytes{1, 2}}, res: false},
+		{desc: "not equal", op: NotEqual{}, left: a, right: Tagged{Tag: ip, Value: Bytes{1, 2}}, res: true},
+		{desc: "untagged bytes", op: Equal{}, left: a, right: Bytes{1, 2}, err: true},
+		{desc: "in set", op: Contains{}, left: Set{Tagged{Tag: ip}, Tagged{Tag: uuid, Value: Bytes{1, 2}}}, right: a, res: true},
+		{desc: "not in set", op: Contains{}, left: Set{Tagged{Tag: ip, Value: Bytes{1, 2}}}, right: a, res: false},
+		{desc: "set inclusion", op: Contains{}, left: Set{a, Tagged{Tag: ip}}, right: Set{Tagged{Tag: ip}}, res: true},
+		{desc: "no ordering", op: LessThan{}, left: a, right: a, err: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ops := Expression{Value{tc.left}, Value{tc.right}, BinaryOp{tc.op}}
+			res, err := ops.Evaluate(nil, syms)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.res, res)
+		})
+	}
+
+	// facts holding tagged values are deduplicated by tag and bytes
+	fs := &FactSet{}
+	pred := syms.Insert("id")
+	require.True(t, fs.Insert(Fact{Predicate{pred, []Term{a}}}))
+	require.False(t, fs.Insert(Fact{Predicate{pred, []Term{Tagged{Tag: uuid, Value: Bytes{1, 2}}}}}))
+	require.True(t, fs.Insert(Fact{Predicate{pred, []Term{Tagged{Tag: ip, Value: Bytes{1, 2}}}}}))
+	require.True(t, fs.Insert(Fact{Predicate{pred, []Term{Bytes{1, 2}}}}))
+}
+
 func TestBinaryContains(t *testing.T) {
 	require.Equal(t, BinaryContains, Contains{}.Type())
 	syms := &SymbolTable{}
@@ -582,6 +1047,30 @@ func TestBinaryContains(t *testing.T) {
 			right:   Integer(0),
 			wantErr: true,
 		},
+		{
+			name:  "substring",
+			left:  syms.Insert("abc"),
+			right: syms.Insert("b"),
+			want:  Bool(true),
+		},
+		{
+			name:  "not a substring",
+			left:  syms.Insert("abc"),
+			right: syms.Insert("d"),
+			want:  Bool(false),
+		},
+		{
+			name:  "string element of set, not substring",
+			left:  Set{syms.Insert("abc")},
+			right: syms.Insert("b"),
+			want:  Bool(false),
+		},
+		{
+			name:    "substring of non string",
+			left:    syms.Insert("abc"),
+			right:   Set{syms.Insert("b")},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -767,20 +1256,201 @@ func TestBinaryRegex(t *testing.T) {
 	}
 }
 
-func TestBinaryAdd(t *testing.T) {
-	require.Equal(t, BinaryAdd, Add{}.Type())
+func TestBinaryRegexInsensitive(t *testing.T) {
+	require.Equal(t, BinaryRegexInsensitive, RegexInsensitive{}.Type())
 	syms := &SymbolTable{}
-
 	testCases := []struct {
-		desc            string
-		left            Term
-		right           Term
-		res             Term
-		expectedErr     bool
-		expectedErrType error
-	}{
-		{
-			desc:  "normal addition",
+		desc        string
+		op          BinaryOpFunc
+		left        Term
+		right       Term
+		res         Term
+		expectedErr bool
+	}{
+		{
+			desc:  "insensitive match",
+			op:    RegexInsensitive{},
+			left:  syms.Insert("ABC"),
+			right: syms.Insert("abc"),
+			res:   Bool(true),
+		},
+		{
+			desc:  "sensitive no match",
+			op:    Regex{},
+			left:  syms.Insert("ABC"),
+			right: syms.Insert("abc"),
+			res:   Bool(false),
+		},
+		{
+			desc:  "insensitive no match",
+			op:    RegexInsensitive{},
+			left:  syms.Insert("ABD"),
+			right: syms.Insert("^abc$"),
+			res:   Bool(false),
+		},
+		{
+			desc:        "invalid left type",
+			op:          RegexInsensitive{},
+			left:        Integer(1),
+			right:       syms.Insert("abc"),
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ops := Expression{
+				Value{tc.left},
+				Value{tc.right},
+				BinaryOp{tc.op},
+			}
+
+			res, err := ops.Evaluate(nil, syms)
+			if tc.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.res, res)
+			}
+		})
+	}
+
+	abc := syms.Insert("abc")
+	require.Equal(t, `"ABC".matches_insensitive("abc")`, (&Expression{Value{syms.Insert("ABC")}, Value{abc}, BinaryOp{RegexInsensitive{}}}).Print(syms))
+}
+
+func TestBinaryRegexMaxLength(t *testing.T) {
+	syms := &SymbolTable{}
+	limits := defaultRunLimits
+	limits.maxRegexLength = 5
+
+	ops := Expression{
+		Value{syms.Insert("abcdef")},
+		Value{syms.Insert("abcde")},
+		BinaryOp{Regex{}},
+	}
+	res, err := ops.evaluate(nil, syms, limits, nil)
+	require.NoError(t, err)
+	require.Equal(t, Bool(true), res)
+
+	ops = Expression{
+		Value{syms.Insert("abcdef")},
+		Value{syms.Insert("abcdef")},
+		BinaryOp{Regex{}},
+	}
+	_, err = ops.evaluate(nil, syms, limits, nil)
+	require.ErrorIs(t, err, ErrRegexTooLong)
+	// the default limit applies outside of a world
+	_, err = ops.Evaluate(nil, syms)
+	require.NoError(t, err)
+
+	// the limit of a world applies to its rules
+	name := syms.Insert("name")
+	matching := syms.Insert("matching")
+	newWorld := func(opts ...WorldOption) *World {
+		w := NewWorld(opts...)
+		w.AddFact(Fact{Predicate{name, []Term{syms.Insert("abcdef")}}})
+		w.AddRule(Rule{
+			Head:        Predicate{matching, []Term{hashVar("n")}},
+			Body:        []Predicate{{name, []Term{hashVar("n")}}},
+			Expressions: []Expression{{Value{hashVar("n")}, Value{syms.Insert("abcdef")}, BinaryOp{Regex{}}}},
+		})
+		return w
+	}
+	require.ErrorIs(t, newWorld(WithMaxDuration(time.Minute), WithMaxRegexLength(5)).Run(syms), ErrRegexTooLong)
+	require.NoError(t, newWorld(WithMaxDuration(time.Minute)).Run(syms))
+}
+
+func TestExpressionMaxOps(t *testing.T) {
+	syms := &SymbolTable{}
+
+	// 1 + 1 + 1 + ... only needs two stack slots
+	sum := func(n int) *Expression {
+		ops := Expression{Value{Integer(1)}}
+		for i := 1; i < n; i++ {
+			ops = append(ops, Value{Integer(1)}, BinaryOp{Add{}})
+		}
+		return &ops
+	}
+
+	res, err := sum(10).Evaluate(nil, syms)
+	require.NoError(t, err)
+	require.Equal(t, Integer(10), res)
+
+	ops := sum(DefaultMaxExpressionOps/2 + 1)
+	require.Len(t, *ops, DefaultMaxExpressionOps+1)
+	_, err = ops.Evaluate(nil, syms)
+	require.ErrorIs(t, err, ErrExpressionTooLong)
+	_, _, err = ops.EvaluateTrace(nil, syms)
+	require.ErrorIs(t, err, ErrExpressionTooLong)
+
+	ops = sum(1_000_000)
+	start := time.Now()
+	_, err = ops.Evaluate(nil, syms)
+	require.ErrorIs(t, err, ErrExpressionTooLong)
+	require.Less(t, time.Since(start), time.Second)
+
+	// the limit of a world applies to its rules, invalid limits are ignored
+	require.Equal(t, 3, NewWorld(WithMaxExpressionOps(3)).runLimits.maxExpressionOps)
+	require.Equal(t, 3, NewWorld(WithMaxExpressionOps(3), WithMaxExpressionOps(0)).runLimits.maxExpressionOps)
+	require.Equal(t, DefaultMaxExpressionOps, NewWorld(WithMaxExpressionOps(-1)).runLimits.maxExpressionOps)
+
+	value := syms.Insert("value")
+	sumOf := syms.Insert("sum")
+	newWorld := func(n int, opts ...WorldOption) *World {
+		w := NewWorld(append([]WorldOption{WithMaxDuration(time.Minute)}, opts...)...)
+		w.AddFact(Fact{Predicate{value, []Term{Integer(1)}}})
+		w.AddRule(Rule{
+			Head:        Predicate{sumOf, []Term{hashVar("v")}},
+			Body:        []Predicate{{value, []Term{hashVar("v")}}},
+			Expressions: []Expression{append(*sum(n), Value{Integer(n)}, BinaryOp{Equal{}})},
+		})
+		return w
+	}
+	require.NoError(t, newWorld(1, WithMaxExpressionOps(3)).Run(syms))
+	require.ErrorIs(t, newWorld(2, WithMaxExpressionOps(3)).Run(syms), ErrExpressionTooLong)
+	require.NoError(t, newWorld(2).Run(syms))
+}
+
+func BenchmarkBinaryRegex(b *testing.B) {
+	syms := &SymbolTable{}
+	left := syms.Insert("/users/1234/files/report.pdf")
+	pattern := `^/users/[0-9]+/files/[a-z]+\.(pdf|txt)$`
+	right := syms.Insert(pattern)
+
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := (Regex{}).Eval(left, right, syms); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				b.Fatal(err)
+			}
+			re.MatchString(syms.Str(left))
+		}
+	})
+}
+
+func TestBinaryAdd(t *testing.T) {
+	require.Equal(t, BinaryAdd, Add{}.Type())
+	syms := &SymbolTable{}
+
+	testCases := []struct {
+		desc            string
+		left            Term
+		right           Term
+		res             Term
+		expectedErr     bool
+		expectedErrType error
+	}{
+		{
+			desc:  "normal addition",
 			left:  Integer(5),
 			right: Integer(3),
 			res:   Integer(8),
@@ -1029,6 +1699,25 @@ func TestBinaryDiv(t *testing.T) {
 			expectedErr:     true,
 			expectedErrType: ErrExprDivByZero,
 		},
+		{
+			desc:            "overflow",
+			left:            Integer(math.MinInt64),
+			right:           Integer(-1),
+			expectedErr:     true,
+			expectedErrType: ErrInt64Overflow,
+		},
+		{
+			desc:  "min int divided by one",
+			left:  Integer(math.MinInt64),
+			right: Integer(1),
+			res:   Integer(math.MinInt64),
+		},
+		{
+			desc:  "negative divisor",
+			left:  Integer(math.MinInt64 + 1),
+			right: Integer(-1),
+			res:   Integer(math.MaxInt64),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1100,6 +1789,13 @@ func TestBinaryAnd(t *testing.T) {
 			right:       syms.Insert("abc"),
 			expectedErr: true,
 		},
+		{
+			// the right operand is not evaluated when left is false
+			desc:  "invalid right type short-circuited",
+			left:  Bool(false),
+			right: syms.Insert("abc"),
+			res:   false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1121,6 +1817,76 @@ func TestBinaryAnd(t *testing.T) {
 	}
 }
 
+func TestBinaryBitwiseAnd(t *testing.T) {
+	require.Equal(t, BinaryBitwiseAnd, BitwiseAnd{}.Type())
+	syms := &SymbolTable{}
+
+	testCases := []struct {
+		desc        string
+		left        Term
+		right       Term
+		res         Term
+		expectedErr bool
+	}{
+		{
+			desc:  "masked bits",
+			left:  Integer(0b1101),
+			right: Integer(0b0110),
+			res:   Integer(0b0100),
+		},
+		{
+			desc:  "no common bits",
+			left:  Integer(8),
+			right: Integer(7),
+			res:   Integer(0),
+		},
+		{
+			desc:  "negative value",
+			left:  Integer(-1),
+			right: Integer(42),
+			res:   Integer(42),
+		},
+		{
+			desc:        "invalid left type",
+			left:        Bool(true),
+			right:       Integer(1),
+			expectedErr: true,
+		},
+		{
+			desc:        "invalid right type",
+			left:        Integer(1),
+			right:       syms.Insert("abc"),
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ops := Expression{
+				Value{tc.left},
+				Value{tc.right},
+				BinaryOp{BitwiseAnd{}},
+			}
+
+			res, err := ops.Evaluate(nil, syms)
+			if tc.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.res, res)
+			}
+		})
+	}
+
+	require.Equal(t, "5 & 3 == 1", (&Expression{
+		Value{Integer(5)},
+		Value{Integer(3)},
+		BinaryOp{BitwiseAnd{}},
+		Value{Integer(1)},
+		BinaryOp{Equal{}},
+	}).Print(syms))
+}
+
 func TestBinaryOr(t *testing.T) {
 	require.Equal(t, BinaryOr, Or{}.Type())
 	syms := &SymbolTable{}
@@ -1164,10 +1930,17 @@ func TestBinaryOr(t *testing.T) {
 		},
 		{
 			desc:        "invalid right type",
-			left:        Bool(true),
+			left:        Bool(false),
 			right:       syms.Insert("abc"),
 			expectedErr: true,
 		},
+		{
+			// the right operand is not evaluated when left is true
+			desc:  "invalid right type short-circuited",
+			left:  Bool(true),
+			right: syms.Insert("abc"),
+			res:   true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1189,6 +1962,221 @@ func TestBinaryOr(t *testing.T) {
 	}
 }
 
+func TestShortCircuit(t *testing.T) {
+	syms := &SymbolTable{}
+	x := Variable(1)
+	values := map[Variable]*Term{}
+	// unknown is not bound in values, evaluating it fails
+	unknown := Variable(2)
+
+	testCases := []struct {
+		desc        string
+		expr        Expression
+		res         Term
+		expectedErr bool
+	}{
+		{
+			desc: "false && unknown",
+			expr: Expression{Value{Bool(false)}, Value{unknown}, BinaryOp{And{}}},
+			res:  Bool(false),
+		},
+		{
+			desc:        "true && unknown",
+			expr:        Expression{Value{Bool(true)}, Value{unknown}, BinaryOp{And{}}},
+			expectedErr: true,
+		},
+		{
+			desc: "true || unknown.matches(\"a\")",
+			expr: Expression{Value{Bool(true)}, Value{unknown}, Value{syms.Insert("a")}, BinaryOp{Regex{}}, BinaryOp{Or{}}},
+			res:  Bool(true),
+		},
+		{
+			desc: "(false && unknown) || true",
+			expr: Expression{
+				Value{Bool(false)}, Value{unknown}, BinaryOp{And{}}, UnaryOp{Parens{}},
+				Value{Bool(true)},
+				BinaryOp{Or{}},
+			},
+			res: Bool(true),
+		},
+		{
+			desc: "false && (unknown || unknown) || !false",
+			expr: Expression{
+				Value{Bool(false)},
+				Value{unknown}, Value{unknown}, BinaryOp{Or{}}, UnaryOp{Parens{}},
+				BinaryOp{And{}},
+				Value{Bool(false)}, UnaryOp{Negate{}},
+				BinaryOp{Or{}},
+			},
+			res: Bool(true),
+		},
+		{
+			desc: "1 + 2 == 3 && 2 > 1",
+			expr: Expression{
+				Value{Integer(1)}, Value{Integer(2)}, BinaryOp{Add{}}, Value{Integer(3)}, BinaryOp{Equal{}},
+				Value{Integer(2)}, Value{Integer(1)}, BinaryOp{GreaterThan{}},
+				BinaryOp{And{}},
+			},
+			res: Bool(true),
+		},
+		{
+			desc:        "non boolean left is not short-circuited",
+			expr:        Expression{Value{Integer(0)}, Value{Bool(true)}, BinaryOp{Or{}}},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			res, err := tc.expr.Evaluate(values, syms)
+			if tc.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.res, res)
+			}
+		})
+	}
+
+	t.Run("trace", func(t *testing.T) {
+		one := Term(Integer(1))
+		expr := Expression{
+			Value{x}, Value{Integer(2)}, BinaryOp{GreaterThan{}},
+			Value{x}, Value{Integer(3)}, BinaryOp{Mul{}}, Value{Integer(3)}, BinaryOp{Equal{}},
+			BinaryOp{And{}},
+		}
+		res, trace, err := expr.EvaluateTrace(map[Variable]*Term{x: &one}, syms)
+		require.NoError(t, err)
+		require.Equal(t, Bool(false), res)
+		require.Equal(t, []TraceEntry{
+			{Op: "$write > 2", Value: Bool(false)},
+			{Op: "$write > 2 && $write * 3 == 3", Value: Bool(false)},
+		}, trace)
+	})
+}
+
+func BenchmarkShortCircuit(b *testing.B) {
+	syms := &SymbolTable{}
+	s := Term(syms.Insert(strings.Repeat("ab", 500)))
+	v := Variable(1)
+	values := map[Variable]*Term{v: &s}
+	regex := Expression{Value{v}, Value{syms.Insert("^(ab)*c$")}, BinaryOp{Regex{}}}
+
+	for _, bc := range []struct {
+		name string
+		expr Expression
+	}{
+		{
+			name: "short-circuited",
+			expr: append(Expression{Value{Bool(false)}}, append(regex, BinaryOp{And{}})...),
+		},
+		{
+			name: "evaluated",
+			expr: append(Expression{Value{Bool(true)}}, append(regex, BinaryOp{And{}})...),
+		},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := bc.expr.Evaluate(values, syms); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestBinaryGet(t *testing.T) {
+	require.Equal(t, BinaryGet, Get{}.Type())
+	syms := &SymbolTable{}
+
+	testCases := []struct {
+		desc        string
+		left        Term
+		right       Term
+		res         Term
+		expectedErr bool
+	}{
+		{
+			desc:  "array index",
+			left:  Array{Integer(1), syms.Insert("abc")},
+			right: Integer(0),
+			res:   Integer(1),
+		},
+		{
+			desc:  "array last index",
+			left:  Array{Integer(1), syms.Insert("abc")},
+			right: Integer(1),
+			res:   syms.Insert("abc"),
+		},
+		{
+			desc:        "array index out of bounds",
+			left:        Array{Integer(1)},
+			right:       Integer(1),
+			expectedErr: true,
+		},
+		{
+			desc:        "array negative index",
+			left:        Array{Integer(1)},
+			right:       Integer(-1),
+			expectedErr: true,
+		},
+		{
+			desc:        "array invalid index type",
+			left:        Array{Integer(1)},
+			right:       syms.Insert("abc"),
+			expectedErr: true,
+		},
+		{
+			desc:  "map string key",
+			left:  Map{syms.Insert("abc"): Integer(1), Integer(2): Bool(true)},
+			right: syms.Insert("abc"),
+			res:   Integer(1),
+		},
+		{
+			desc:  "map integer key",
+			left:  Map{syms.Insert("abc"): Integer(1), Integer(2): Bool(true)},
+			right: Integer(2),
+			res:   Bool(true),
+		},
+		{
+			desc:        "map missing key",
+			left:        Map{syms.Insert("abc"): Integer(1)},
+			right:       syms.Insert("def"),
+			expectedErr: true,
+		},
+		{
+			desc:        "map invalid key type",
+			left:        Map{syms.Insert("abc"): Integer(1)},
+			right:       Bool(true),
+			expectedErr: true,
+		},
+		{
+			desc:        "invalid left type",
+			left:        Set{Integer(1)},
+			right:       Integer(0),
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ops := Expression{
+				Value{tc.left},
+				Value{tc.right},
+				BinaryOp{Get{}},
+			}
+
+			res, err := ops.Evaluate(nil, syms)
+			if tc.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.res, res)
+			}
+		})
+	}
+}
+
 func TestPrint(t *testing.T) {
 	syms := SymbolTable{}
 	syms.Insert("abc")
@@ -1212,6 +2200,11 @@ func TestPrint(t *testing.T) {
 			expr: Expression{Value{syms.Sym("abc")}, UnaryOp{Length{}}},
 			res:  "\"abc\".length()",
 		},
+		{
+			desc: "char length",
+			expr: Expression{Value{syms.Sym("abc")}, UnaryOp{CharLength{}}},
+			res:  "\"abc\".char_length()",
+		},
 		{
 			desc: "binary",
 			expr: Expression{Value{Integer(9)}, Value{Integer(4)}, BinaryOp{Mul{}}},
@@ -1237,3 +2230,57 @@ func TestPrint(t *testing.T) {
 		})
 	}
 }
+
+func TestEvaluateTrace(t *testing.T) {
+	syms := &SymbolTable{}
+	expr := Expression{
+		Value{Integer(1)},
+		Value{Integer(2)},
+		Value{Integer(3)},
+		BinaryOp{Mul{}},
+		BinaryOp{Add{}},
+		Value{Integer(7)},
+		BinaryOp{Equal{}},
+	}
+
+	res, trace, err := expr.EvaluateTrace(nil, syms)
+	require.NoError(t, err)
+	require.Equal(t, Bool(true), res)
+	require.Equal(t, []TraceEntry{
+		{Op: "2 * 3", Value: Integer(6)},
+		{Op: "1 + 2 * 3", Value: Integer(7)},
+		{Op: "1 + 2 * 3 == 7", Value: Bool(true)},
+	}, trace)
+
+	expected, err := expr.Evaluate(nil, syms)
+	require.NoError(t, err)
+	require.Equal(t, expected, res)
+
+	t.Run("variables", func(t *testing.T) {
+		syms := &SymbolTable{}
+		expr := Expression{
+			Value{Variable(syms.Insert("x"))},
+			Value{Integer(2)},
+			BinaryOp{GreaterThan{}},
+		}
+		value := Term(Integer(1))
+
+		res, trace, err := expr.EvaluateTrace(map[Variable]*Term{Variable(syms.Insert("x")): &value}, syms)
+		require.NoError(t, err)
+		require.Equal(t, Bool(false), res)
+		require.Equal(t, []TraceEntry{{Op: "$x > 2", Value: Bool(false)}}, trace)
+	})
+
+	t.Run("partial trace on error", func(t *testing.T) {
+		expr := Expression{
+			Value{Integer(2)},
+			Value{Integer(3)},
+			BinaryOp{Mul{}},
+			Value{Integer(0)},
+			BinaryOp{Div{}},
+		}
+		_, trace, err := expr.EvaluateTrace(nil, syms)
+		require.Error(t, err)
+		require.Equal(t, []TraceEntry{{Op: "2 * 3", Value: Integer(6)}}, trace)
+	})
+}
@@ -1,6 +1,7 @@
 package datalog
 
 import (
+	"encoding/hex"
 	"fmt"
 	"sort"
 	"strings"
@@ -37,10 +38,25 @@ var DEFAULT_SYMBOLS = [...]string{
 	"query",
 }
 
+// OFFSET is the index of the first symbol of a SymbolTable. The indexes
+// below it are reserved by the specification for DEFAULT_SYMBOLS, so it
+// does not depend on the number of default symbols.
 var OFFSET = 1024
 
 type SymbolTable []string
 
+// NewSymbolTableWithBase returns a table holding the base symbols, such as
+// the ones shared by all the tokens of an application. They are numbered
+// from OFFSET, in order, and the symbols inserted later are numbered after
+// them. Default symbols and duplicates are skipped.
+func NewSymbolTableWithBase(base []string) *SymbolTable {
+	t := &SymbolTable{}
+	for _, s := range base {
+		t.Insert(s)
+	}
+	return t
+}
+
 func (t *SymbolTable) Insert(s string) String {
 	for i, v := range DEFAULT_SYMBOLS {
 		if string(v) == s {
@@ -88,32 +104,34 @@ func (t *SymbolTable) Index(s string) uint64 {
 	panic("index not found")
 }
 
+// Str returns the string for the given symbol index. Unknown indices
+// are rendered as a stable placeholder, such as <sym:42>, instead of
+// failing, so that printing a malformed token stays possible.
 func (t *SymbolTable) Str(sym String) string {
-	if int(sym) < 1024 {
-		if int(sym) > len(DEFAULT_SYMBOLS)-1 {
-			return fmt.Sprintf("<invalid symbol %d>", sym)
-		} else {
-			return DEFAULT_SYMBOLS[int(sym)]
+	if sym < String(OFFSET) {
+		if sym >= String(len(DEFAULT_SYMBOLS)) {
+			return fmt.Sprintf("<sym:%d>", sym)
 		}
+		return DEFAULT_SYMBOLS[int(sym)]
 	}
-	if int(sym)-1024 > len(*t)-1 {
-		return fmt.Sprintf("<invalid symbol %d>", sym)
+	if sym-String(OFFSET) >= String(len(*t)) {
+		return fmt.Sprintf("<sym:%d>", sym)
 	}
-	return (*t)[int(sym)-1024]
+	return (*t)[int(sym)-OFFSET]
 }
 
 func (t *SymbolTable) Var(v Variable) string {
-	if int(v) < 1024 {
+	if int(v) < OFFSET {
 		if int(v) > len(DEFAULT_SYMBOLS)-1 {
 			return fmt.Sprintf("<invalid variable %d>", v)
 		} else {
 			return DEFAULT_SYMBOLS[int(v)]
 		}
 	}
-	if int(v)-1024 > len(*t)-1 {
+	if int(v)-OFFSET > len(*t)-1 {
 		return fmt.Sprintf("<invalid variable %d>", v)
 	}
-	return (*t)[int(v)-1024]
+	return (*t)[int(v)-OFFSET]
 }
 
 func (t *SymbolTable) Clone() *SymbolTable {
@@ -173,15 +191,7 @@ type SymbolDebugger struct {
 func (d SymbolDebugger) Predicate(p Predicate) string {
 	strs := make([]string, len(p.Terms))
 	for i, id := range p.Terms {
-		var s string
-		if sym, ok := id.(String); ok {
-			s = "\"" + d.Str(sym) + "\""
-		} else if variable, ok := id.(Variable); ok {
-			s = "$" + d.Var(variable)
-		} else {
-			s = fmt.Sprintf("%v", id)
-		}
-		strs[i] = s
+		strs[i] = printValue(id, d.SymbolTable)
 	}
 	return fmt.Sprintf("%s(%s)", d.Str(p.Name), strings.Join(strs, ", "))
 }
@@ -202,7 +212,7 @@ func (d SymbolDebugger) Rule(r Rule) string {
 		expressionsStart = ", "
 	}
 
-	return fmt.Sprintf("%s <- %s%s%s", head, strings.Join(preds, ", "), expressionsStart, strings.Join(expressions, ", "))
+	return fmt.Sprintf("%s <- %s%s%s%s", head, strings.Join(preds, ", "), expressionsStart, strings.Join(expressions, ", "), d.Scopes(r.Scope))
 }
 
 func (d SymbolDebugger) CheckQuery(r Rule) string {
@@ -220,7 +230,30 @@ func (d SymbolDebugger) CheckQuery(r Rule) string {
 		expressionsStart = ", "
 	}
 
-	return fmt.Sprintf("%s%s%s", strings.Join(preds, ", "), expressionsStart, strings.Join(expressions, ", "))
+	return fmt.Sprintf("%s%s%s%s", strings.Join(preds, ", "), expressionsStart, strings.Join(expressions, ", "), d.Scopes(r.Scope))
+}
+
+// Scopes renders the trusting annotation of a rule, or an empty string
+// when the rule uses the default scope.
+func (d SymbolDebugger) Scopes(scopes []Scope) string {
+	if len(scopes) == 0 {
+		return ""
+	}
+
+	strs := make([]string, len(scopes))
+	for i, s := range scopes {
+		switch s.Type {
+		case ScopeTypeAuthority:
+			strs[i] = "authority"
+		case ScopeTypePrevious:
+			strs[i] = "previous"
+		case ScopeTypePublicKey:
+			strs[i] = "ed25519/" + hex.EncodeToString(s.PublicKey)
+		default:
+			strs[i] = fmt.Sprintf("<invalid scope %d>", s.Type)
+		}
+	}
+	return " trusting " + strings.Join(strs, ", ")
 }
 
 func (d SymbolDebugger) Expression(e Expression) string {
@@ -232,7 +265,14 @@ func (d SymbolDebugger) Check(c Check) string {
 	for i, q := range c.Queries {
 		queries[i] = d.CheckQuery(q)
 	}
-	return fmt.Sprintf("check if %s", strings.Join(queries, " or "))
+	kind := "check if"
+	switch c.Kind {
+	case CheckKindAll:
+		kind = "check all"
+	case CheckKindReject:
+		kind = "reject if"
+	}
+	return fmt.Sprintf("%s %s", kind, strings.Join(queries, " or "))
 }
 
 func (d SymbolDebugger) World(w *World) string {
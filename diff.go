@@ -0,0 +1,102 @@
+package biscuit
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/biscuit-auth/biscuit-go/v2/datalog"
+)
+
+// ErrNotAnAttenuation is returned by DiffBlocks when the second token
+// does not extend the blocks of the first one.
+var ErrNotAnAttenuation = errors.New("biscuit: token is not an attenuation of the other")
+
+// BlockDiff lists the content added by an attenuation.
+type BlockDiff struct {
+	Blocks []AddedBlock
+}
+
+// AddedBlock is the content of a block appended by an attenuation,
+// which was not already present in the original token.
+type AddedBlock struct {
+	// Index is the position of the block in the attenuated token,
+	// the authority block being 0.
+	Index  int
+	Facts  FactSet
+	Rules  []Rule
+	Checks []Check
+}
+
+// DiffBlocks returns the facts, rules and checks of b which are not in a,
+// b being an attenuation of a: it must start with the same authority and blocks.
+// Elements are compared by their datalog representation.
+func DiffBlocks(a, b *Biscuit) (*BlockDiff, error) {
+	if len(b.blocks) < len(a.blocks) ||
+		!bytes.Equal(a.container.Authority.Signature, b.container.Authority.Signature) {
+		return nil, ErrNotAnAttenuation
+	}
+	for i, sb := range a.container.Blocks {
+		if !bytes.Equal(sb.Signature, b.container.Blocks[i].Signature) {
+			return nil, ErrNotAnAttenuation
+		}
+	}
+
+	existing := make(map[string]struct{})
+	if err := a.ForEachBlock(func(_ int, block *Block) error {
+		debug := &datalog.SymbolDebugger{SymbolTable: a.blockSymbols(block)}
+		for _, f := range *block.facts {
+			existing[debug.Predicate(f.Predicate)] = struct{}{}
+		}
+		for _, r := range block.rules {
+			existing[debug.Rule(r)] = struct{}{}
+		}
+		for _, c := range block.checks {
+			existing[debug.Check(c)] = struct{}{}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	diff := &BlockDiff{}
+	for i, block := range b.blocks[len(a.blocks):] {
+		symbols := b.blockSymbols(block)
+		debug := &datalog.SymbolDebugger{SymbolTable: symbols}
+		added := AddedBlock{Index: len(a.blocks) + i + 1}
+
+		for _, f := range *block.facts {
+			if _, ok := existing[debug.Predicate(f.Predicate)]; ok {
+				continue
+			}
+			fact, err := fromDatalogFact(symbols, f)
+			if err != nil {
+				return nil, err
+			}
+			added.Facts = append(added.Facts, *fact)
+		}
+		for _, r := range block.rules {
+			if _, ok := existing[debug.Rule(r)]; ok {
+				continue
+			}
+			rule, err := fromDatalogRule(symbols, r)
+			if err != nil {
+				return nil, err
+			}
+			added.Rules = append(added.Rules, *rule)
+		}
+		for _, c := range block.checks {
+			if _, ok := existing[debug.Check(c)]; ok {
+				continue
+			}
+			check, err := fromDatalogCheck(symbols, c)
+			if err != nil {
+				return nil, err
+			}
+			added.Checks = append(added.Checks, *check)
+		}
+
+		diff.Blocks = append(diff.Blocks, added)
+	}
+
+	return diff, nil
+}
@@ -0,0 +1,55 @@
+package biscuit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffBlocks(t *testing.T) {
+	rng := rand.Reader
+	_, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("/a"), String("read")}}}))
+	a, err := builder.Build()
+	require.NoError(t, err)
+
+	block := a.CreateBlock()
+	require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "group", IDs: []Term{String("admin")}}}))
+	a, err = a.Append(rng, block.Build())
+	require.NoError(t, err)
+
+	check := Check{Queries: []Rule{{
+		Head:        Predicate{Name: "query", IDs: []Term{}},
+		Body:        []Predicate{{Name: "operation", IDs: []Term{String("read")}}},
+		Expressions: []Expression{},
+	}}}
+	block = a.CreateBlock()
+	// already present in a previous block, it is not reported
+	require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "group", IDs: []Term{String("admin")}}}))
+	require.NoError(t, block.AddCheck(check))
+	b, err := a.Append(rng, block.Build())
+	require.NoError(t, err)
+
+	diff, err := DiffBlocks(a, b)
+	require.NoError(t, err)
+	require.Equal(t, &BlockDiff{Blocks: []AddedBlock{{
+		Index:  2,
+		Checks: []Check{check},
+	}}}, diff)
+
+	diff, err = DiffBlocks(a, a)
+	require.NoError(t, err)
+	require.Empty(t, diff.Blocks)
+
+	_, err = DiffBlocks(b, a)
+	require.ErrorIs(t, err, ErrNotAnAttenuation)
+
+	other, err := NewBuilder(privateRoot).Build()
+	require.NoError(t, err)
+	_, err = DiffBlocks(other, b)
+	require.ErrorIs(t, err, ErrNotAnAttenuation)
+}
@@ -0,0 +1,95 @@
+package biscuit
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidKeyEncoding is returned by ParsePublicKey when the key
+	// is neither hex nor base64 encoded.
+	ErrInvalidKeyEncoding = errors.New("biscuit: invalid key encoding")
+	// ErrInvalidRootKeys is returned by RootKeysFromJSON when the document
+	// is malformed, or when a key has no ID or the same ID as another key.
+	ErrInvalidRootKeys = errors.New("biscuit: invalid root keys")
+)
+
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.RawStdEncoding,
+	base64.URLEncoding,
+	base64.RawURLEncoding,
+}
+
+// ParsePublicKey decodes an ed25519 public key encoded in hex, as written in
+// datalog with or without the `ed25519/` prefix, or in standard or URL base64,
+// padded or not. It returns ErrInvalidKeySize when the key is not 32 bytes long.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "ed25519/")
+
+	// a 32 bytes key is encoded in 64 hex characters, or 43 to 44 base64 ones
+	if len(s) == 2*ed25519.PublicKeySize {
+		if key, err := hex.DecodeString(s); err == nil {
+			return ed25519.PublicKey(key), nil
+		}
+	}
+
+	for _, encoding := range base64Encodings {
+		key, err := encoding.DecodeString(s)
+		if err != nil {
+			continue
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, ErrInvalidKeySize
+		}
+		return ed25519.PublicKey(key), nil
+	}
+
+	return nil, ErrInvalidKeyEncoding
+}
+
+type rootKeysDocument struct {
+	Keys []struct {
+		ID  *uint32 `json:"id"`
+		Key string  `json:"key"`
+	} `json:"keys"`
+}
+
+// RootKeysFromJSON reads root public keys by ID, for WithRootPublicKeys,
+// from a JSON document such as:
+//
+//	{"keys": [{"id": 1, "key": "<hex>"}, {"id": 2, "key": "<hex>"}]}
+//
+// The keys are decoded with ParsePublicKey. It returns ErrEmptyKeys when the
+// document has no keys, and ErrInvalidRootKeys when it is malformed, or a key
+// has no ID or the same ID as another key.
+func RootKeysFromJSON(data []byte) (map[uint32]ed25519.PublicKey, error) {
+	var doc rootKeysDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRootKeys, err)
+	}
+	if len(doc.Keys) == 0 {
+		return nil, ErrEmptyKeys
+	}
+
+	keys := make(map[uint32]ed25519.PublicKey, len(doc.Keys))
+	for i, k := range doc.Keys {
+		if k.ID == nil {
+			return nil, fmt.Errorf("%w: key #%d has no id", ErrInvalidRootKeys, i)
+		}
+		if _, ok := keys[*k.ID]; ok {
+			return nil, fmt.Errorf("%w: duplicate id %d", ErrInvalidRootKeys, *k.ID)
+		}
+		key, err := ParsePublicKey(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", *k.ID, err)
+		}
+		keys[*k.ID] = key
+	}
+	return keys, nil
+}
@@ -0,0 +1,88 @@
+package biscuit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	// chosen so that the base64 encodings contain both '+' and '/', or '-' and '_'
+	key := ed25519.PublicKey{
+		0xfb, 0xff, 0xbf, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c,
+		0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x3e,
+	}
+
+	for _, encoded := range []string{
+		hex.EncodeToString(key),
+		"ed25519/" + hex.EncodeToString(key),
+		base64.StdEncoding.EncodeToString(key),
+		base64.RawStdEncoding.EncodeToString(key),
+		base64.URLEncoding.EncodeToString(key),
+		base64.RawURLEncoding.EncodeToString(key),
+		" " + hex.EncodeToString(key) + "\n",
+	} {
+		t.Run(encoded, func(t *testing.T) {
+			parsed, err := ParsePublicKey(encoded)
+			require.NoError(t, err)
+			require.Equal(t, key, parsed)
+		})
+	}
+
+	_, err := ParsePublicKey(hex.EncodeToString(key[:31]))
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	_, err = ParsePublicKey(base64.StdEncoding.EncodeToString(append(key, 0)))
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	_, err = ParsePublicKey("not a key!")
+	require.ErrorIs(t, err, ErrInvalidKeyEncoding)
+	_, err = ParsePublicKey("")
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+}
+
+func TestRootKeysFromJSON(t *testing.T) {
+	rng := rand.Reader
+	publicRoot1, privateRoot1, _ := ed25519.GenerateKey(rng)
+	publicRoot2, _, _ := ed25519.GenerateKey(rng)
+
+	doc := fmt.Sprintf(`{"keys": [
+		{"id": 1, "key": %q},
+		{"id": 2, "key": %q}
+	]}`, hex.EncodeToString(publicRoot1), "ed25519/"+hex.EncodeToString(publicRoot2))
+	keys, err := RootKeysFromJSON([]byte(doc))
+	require.NoError(t, err)
+	require.Equal(t, map[uint32]ed25519.PublicKey{1: publicRoot1, 2: publicRoot2}, keys)
+
+	b, err := NewBuilder(privateRoot1, WithRootKeyID(1)).Build()
+	require.NoError(t, err)
+	_, err = b.AuthorizerFor(WithRootPublicKeys(keys, nil))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		desc string
+		doc  string
+		err  error
+	}{
+		{desc: "not json", doc: `keys`, err: ErrInvalidRootKeys},
+		{desc: "invalid id", doc: `{"keys": [{"id": -1, "key": ""}]}`, err: ErrInvalidRootKeys},
+		{desc: "no keys", doc: `{"keys": []}`, err: ErrEmptyKeys},
+		{desc: "empty document", doc: `{}`, err: ErrEmptyKeys},
+		{desc: "missing id", doc: fmt.Sprintf(`{"keys": [{"key": %q}]}`, hex.EncodeToString(publicRoot1)), err: ErrInvalidRootKeys},
+		{
+			desc: "duplicate id",
+			doc:  fmt.Sprintf(`{"keys": [{"id": 1, "key": %q}, {"id": 1, "key": %q}]}`, hex.EncodeToString(publicRoot1), hex.EncodeToString(publicRoot2)),
+			err:  ErrInvalidRootKeys,
+		},
+		{desc: "short key", doc: fmt.Sprintf(`{"keys": [{"id": 1, "key": %q}]}`, hex.EncodeToString(publicRoot1[:16])), err: ErrInvalidKeySize},
+		{desc: "invalid key", doc: `{"keys": [{"id": 1, "key": "not a key!"}]}`, err: ErrInvalidKeyEncoding},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := RootKeysFromJSON([]byte(tc.doc))
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
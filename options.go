@@ -1,6 +1,9 @@
 package biscuit
 
-import "io"
+import (
+	"crypto/ed25519"
+	"io"
+)
 
 type compositionOption interface {
 	builderOption
@@ -49,3 +52,31 @@ func (o rootKeyIDOption) applyToBiscuit(b *biscuitOptions) error {
 func WithRootKeyID(id uint32) compositionOption {
 	return rootKeyIDOption(id)
 }
+
+type nextKeyPairOption struct {
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+func (o nextKeyPairOption) applyToBuilder(b *builderOptions) {
+	b.nextKeyPair = &o
+}
+
+func (o nextKeyPairOption) applyToBiscuit(b *biscuitOptions) error {
+	if len(o.pub) != ed25519.PublicKeySize || len(o.priv) != ed25519.PrivateKeySize {
+		return ErrInvalidKeySize
+	}
+	if !o.pub.Equal(o.priv.Public()) {
+		return ErrKeyPairMismatch
+	}
+	b.nextPublicKey = o.pub
+	b.nextPrivateKey = o.priv
+	return nil
+}
+
+// WithNextKeyPair supplies the key pair used as next key of the authority block,
+// instead of generating one from the random number generator. Its private key is
+// stored in the token's proof, and is needed to append the next block.
+func WithNextKeyPair(pub ed25519.PublicKey, priv ed25519.PrivateKey) compositionOption {
+	return nextKeyPairOption{pub: pub, priv: priv}
+}
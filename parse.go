@@ -0,0 +1,18 @@
+package biscuit
+
+import "errors"
+
+// ErrNoDatalogParser is returned by the string based APIs when the parser
+// package was not imported.
+var ErrNoDatalogParser = errors.New("biscuit: no datalog parser, import github.com/biscuit-auth/biscuit-go/v2/parser")
+
+// The string based APIs, such as Builder.AddAuthorityFactFromString, convert
+// datalog text with these functions. The parser package depends on this one,
+// so it cannot be imported from here: it sets them when it is imported.
+var (
+	ParseFact   func(input string) (Fact, error)
+	ParseFacts  func(input string) ([]Fact, error)
+	ParseRule   func(input string) (Rule, error)
+	ParseCheck  func(input string) (Check, error)
+	ParsePolicy func(input string) (Policy, error)
+)
@@ -1,9 +1,11 @@
 package parser
 
 import (
+	"crypto/ed25519"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -13,16 +15,22 @@ import (
 	"github.com/biscuit-auth/biscuit-go/v2"
 )
 
+// Comment is either a line comment, starting with `//`,
+// or a block comment, between `/*` and `*/`, which can span multiple lines.
 type Comment string
 
 func (c *Comment) Capture(values []string) error {
 	if len(values) != 1 {
 		return errors.New("parser: invalid comment values")
 	}
-	if !strings.HasPrefix(values[0], "//") {
+	switch {
+	case strings.HasPrefix(values[0], "//"):
+		*c = Comment(strings.TrimSpace(strings.TrimPrefix(values[0], "//")))
+	case strings.HasPrefix(values[0], "/*") && strings.HasSuffix(values[0], "*/"):
+		*c = Comment(strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(values[0], "/*"), "*/")))
+	default:
 		return errors.New("parser: invalid comment prefix")
 	}
-	*c = Comment(strings.TrimSpace(strings.TrimPrefix(values[0], "//")))
 	return nil
 }
 
@@ -53,6 +61,26 @@ func (p *Parameter) Capture(values []string) error {
 	return nil
 }
 
+type PublicKey []byte
+
+func (k *PublicKey) Capture(values []string) error {
+	if len(values) != 1 {
+		return errors.New("parser: invalid public key values")
+	}
+	if !strings.HasPrefix(values[0], "ed25519/") {
+		return errors.New("parser: invalid public key prefix")
+	}
+	key, err := hex.DecodeString(strings.TrimPrefix(values[0], "ed25519/"))
+	if err != nil {
+		return fmt.Errorf("parser: failed to decode public key: %v", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return errors.New("parser: invalid public key size")
+	}
+	*k = key
+	return nil
+}
+
 type Bool bool
 
 func (b *Bool) Capture(values []string) error {
@@ -73,9 +101,35 @@ type Block struct {
 }
 
 type BlockElement struct {
-	Check     *Check         `@@`
-	Predicate *Predicate     `|@@`
+	Comments  []*Comment     `@Comment*`
+	Check     *Check         `( @@`
+	Predicate *Predicate     `| @@`
 	RuleBody  []*RuleElement `("<-" @@ ("," @@)*)?`
+	Scopes    []*Scope       `("trusting" @@ ("," @@)*)? )`
+}
+
+func (e *BlockElement) ToBiscuit(parameters ParametersMap) (*biscuit.Check, *biscuit.Rule, *biscuit.Fact, error) {
+	switch {
+	case e.Check != nil:
+		c, err := e.Check.ToBiscuit(parameters)
+		return c, nil, nil, err
+	case e.RuleBody != nil:
+		rule := Rule{
+			Head:   e.Predicate,
+			Body:   e.RuleBody,
+			Scopes: e.Scopes,
+		}
+		r, err := rule.ToBiscuit(parameters)
+		return nil, r, nil, err
+	case e.Scopes != nil:
+		return nil, nil, nil, ErrScopeInFact
+	default:
+		p, err := e.Predicate.ToBiscuit(parameters)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return nil, nil, &biscuit.Fact{Predicate: *p}, nil
+	}
 }
 
 type ParametersMap map[string]biscuit.Term
@@ -85,28 +139,17 @@ func (b *Block) ToBiscuit(parameters ParametersMap) (*biscuit.ParsedBlock, error
 	rules := []biscuit.Rule{}
 	checks := []biscuit.Check{}
 	for _, e := range b.Body {
-		if e.Check != nil {
-			c, err := e.Check.ToBiscuit(parameters)
-			if err != nil {
-				return nil, err
-			}
+		c, r, f, err := e.ToBiscuit(parameters)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case c != nil:
 			checks = append(checks, *c)
-		} else if e.Predicate != nil && e.RuleBody != nil {
-			rule := Rule{
-				Head: e.Predicate,
-				Body: e.RuleBody,
-			}
-			r, err := rule.ToBiscuit(parameters)
-			if err != nil {
-				return nil, err
-			}
+		case r != nil:
 			rules = append(rules, *r)
-		} else {
-			p, err := e.Predicate.ToBiscuit(parameters)
-			if err != nil {
-				return nil, err
-			}
-			facts = append(facts, biscuit.Fact{Predicate: *p})
+		case f != nil:
+			facts = append(facts, *f)
 		}
 	}
 	return &biscuit.ParsedBlock{Facts: facts, Rules: rules, Checks: checks}, nil
@@ -118,8 +161,9 @@ type Authorizer struct {
 }
 
 type AuthorizerElement struct {
-	Policy       *Policy       `@@`
-	BlockElement *BlockElement `|@@`
+	Comments     []*Comment    `@Comment*`
+	Policy       *Policy       `( @@`
+	BlockElement *BlockElement `| @@ )`
 }
 
 func (b *Authorizer) ToBiscuit(parameters ParametersMap) (*biscuit.ParsedAuthorizer, error) {
@@ -130,29 +174,17 @@ func (b *Authorizer) ToBiscuit(parameters ParametersMap) (*biscuit.ParsedAuthori
 
 	for _, e := range b.Body {
 		if e.BlockElement != nil {
-			be := e.BlockElement
-			if be.Check != nil {
-				c, err := be.Check.ToBiscuit(parameters)
-				if err != nil {
-					return nil, err
-				}
+			c, r, f, err := e.BlockElement.ToBiscuit(parameters)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case c != nil:
 				checks = append(checks, *c)
-			} else if be.Predicate != nil && be.RuleBody != nil {
-				rule := Rule{
-					Head: be.Predicate,
-					Body: be.RuleBody,
-				}
-				r, err := rule.ToBiscuit(parameters)
-				if err != nil {
-					return nil, err
-				}
+			case r != nil:
 				rules = append(rules, *r)
-			} else {
-				p, err := be.Predicate.ToBiscuit(parameters)
-				if err != nil {
-					return nil, err
-				}
-				facts = append(facts, biscuit.Fact{Predicate: *p})
+			case f != nil:
+				facts = append(facts, *f)
 			}
 		} else if e.Policy != nil {
 			p, err := e.Policy.ToBiscuit(parameters)
@@ -173,6 +205,14 @@ type Rule struct {
 	Comments []*Comment     `@Comment*`
 	Head     *Predicate     `@@`
 	Body     []*RuleElement `"<-" @@ ("," @@)*`
+	Scopes   []*Scope       `("trusting" @@ ("," @@)*)?`
+}
+
+type Scope struct {
+	Authority bool       `@"authority"`
+	Previous  bool       `| @"previous"`
+	PublicKey *PublicKey `| @PublicKey`
+	Parameter *Parameter `| @Parameter`
 }
 
 type RuleElement struct {
@@ -181,16 +221,24 @@ type RuleElement struct {
 }
 
 type Predicate struct {
-	Name *string `@Ident`
-	IDs  []*Term `"(" (@@ ("," @@)*)* ")"`
+	Name *string `( @Ident`
+	// NameParameter is set when the name is a parameter, such as `{type}($id)`
+	NameParameter *Parameter `| @Parameter )`
+	IDs           []*Term    `"(" (@@ ("," @@)*)* ")"`
 }
 
 type Check struct {
-	Queries []*CheckQuery `"check if" @@ ( "or" @@ )*`
+	// All is set for `check all`, which requires all the facts
+	// matching a query to satisfy its expressions
+	All bool `( "check if" | @"check all"`
+	// Reject is set for `reject if`, which fails when a query matches
+	Reject  bool          `| @"reject if" )`
+	Queries []*CheckQuery `@@ ( "or" @@ )*`
 }
 
 type CheckQuery struct {
-	Body []*RuleElement `@@ ("," @@)*`
+	Body   []*RuleElement `@@ ("," @@)*`
+	Scopes []*Scope       `("trusting" @@ ("," @@)*)?`
 }
 
 type Policy struct {
@@ -207,14 +255,27 @@ type Deny struct {
 }
 
 type Term struct {
-	Parameter *Parameter `@Parameter`
-	Variable  *Variable  `| @Variable`
-	Bytes     *HexString `| @@`
-	String    *string    `| @String`
-	Date      *string    `| @DateTime`
-	Integer   *int64     `| @Int`
-	Bool      *Bool      `| @Bool`
-	Set       []*Term    `| "[" @@ ("," @@)* "]"`
+	Parameter *Parameter  `@Parameter`
+	Variable  *Variable   `| @Variable`
+	Bytes     *HexString  `| @@`
+	String    *string     `| @String`
+	Date      *string     `| @DateTime`
+	Integer   *int64      `| @("-"? Int)`
+	Bool      *Bool       `| @Bool`
+	Array     *Array      `| @@`
+	Set       []*Term     `| "[" @@ ("," @@)* "]"`
+	Map       []*MapEntry `| "{" @@ ("," @@)* "}"`
+}
+
+// Array is an ordered list of terms, prefixed with `array:` since brackets
+// alone denote a set, e.g. `array:[1, 2]`.
+type Array struct {
+	Elements []*Term `"array:" "[" (@@ ("," @@)*)? "]"`
+}
+
+type MapEntry struct {
+	Key   *Term `@@ ":"`
+	Value *Term `@@`
 }
 
 type Value struct {
@@ -245,13 +306,22 @@ const (
 	OpIntersection
 	OpUnion
 	OpLength
+	OpCharLength
+	OpToDate
+	OpToInt
 	OpNegate
+	OpGet
+	OpNotEqual
+	OpSubset
+	OpMatchesInsensitive
+	OpSuperset
+	OpBitwiseAnd
 )
 
 var operatorMap = map[string]Operator{
 	"+": OpAdd,
 	"-": OpSub, "*": OpMul, "/": OpDiv, "&&": OpAnd, "||": OpOr, "<=": OpLessOrEqual, ">=": OpGreaterOrEqual, "<": OpLessThan, ">": OpGreaterThan,
-	"==": OpEqual, "!": OpNegate, "contains": OpContains, "starts_with": OpPrefix, "ends_with": OpSuffix, "matches": OpMatches, "intersection": OpIntersection, "union": OpUnion, "length": OpLength}
+	"==": OpEqual, "!": OpNegate, "contains": OpContains, "starts_with": OpPrefix, "ends_with": OpSuffix, "matches": OpMatches, "matches_insensitive": OpMatchesInsensitive, "intersection": OpIntersection, "union": OpUnion, "length": OpLength, "char_length": OpCharLength, "to_date": OpToDate, "to_int": OpToInt, "get": OpGet, "!=": OpNotEqual, "subset": OpSubset, "superset": OpSuperset, "&": OpBitwiseAnd}
 
 func (o *Operator) Capture(s []string) error {
 	*o = operatorMap[s[0]]
@@ -283,14 +353,27 @@ type Expr2 struct {
 	Right *OpExpr3 `@@?`
 }
 
+// OpExpr3 is a comparison, or a set membership: `$x in $set` is the same
+// expression as `$set.contains($x)`, and `$x not in $set` as `!$set.contains($x)`.
 type OpExpr3 struct {
-	Operator Operator `@("<=" | ">=" | "<" | ">" | "==")`
+	Operator Operator `( @("<=" | ">=" | "<" | ">" | "==" | "!=")`
+	Not      bool     `| @"not"?`
+	In       bool     `  @"in" )`
 	Expr3    *Expr3   `@@`
 }
 
+// Expr3 is a sum, optionally followed by other sums combined with a bitwise AND,
+// which has a lower precedence than addition and subtraction.
 type Expr3 struct {
-	Left  *Expr4     `@@`
-	Right []*OpExpr4 `@@*`
+	Left       *Expr4              `@@`
+	Right      []*OpExpr4          `@@*`
+	BitwiseAnd []*OpExprBitwiseAnd `@@*`
+}
+
+type OpExprBitwiseAnd struct {
+	Operator Operator   `@("&")`
+	Left     *Expr4     `@@`
+	Right    []*OpExpr4 `@@*`
 }
 
 type OpExpr4 struct {
@@ -319,113 +402,213 @@ type Expr6 struct {
 }
 
 type OpExpr7 struct {
-	Operator   Operator    `Dot @("matches" | "starts_with" | "ends_with" | "contains" | "union" | "intersection" | "length")`
+	Operator   Operator    `Dot @("matches" | "matches_insensitive" | "starts_with" | "ends_with" | "contains" | "union" | "intersection" | "length" | "char_length" | "to_date" | "to_int" | "get" | "subset" | "superset")`
 	Expression *Expression `"(" @@? ")"`
 }
 
 type ExprTerm struct {
 	Term       *Term       `@@`
-	Expression *Expression `| "(" @@? ")"`
+	Expression *Expression `| "(" @@ ")"`
 }
 
-func (e *Expression) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
-	e.Left.ToExpr(expr, parameters)
+// ToExpr appends the operations of the expression to expr, in reverse polish notation.
+// Nil nodes, which are never produced by the grammar, are skipped.
+func (e *Expression) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if e == nil {
+		return nil
+	}
+	if err := e.Left.ToExpr(expr, parameters); err != nil {
+		return err
+	}
 
 	for _, op := range e.Right {
-		op.ToExpr(expr, parameters)
+		if err := op.ToExpr(expr, parameters); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func (e *Expr1) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
-	e.Left.ToExpr(expr, parameters)
+func (e *Expr1) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if err := e.Left.ToExpr(expr, parameters); err != nil {
+		return err
+	}
 
 	for _, op := range e.Right {
-		op.ToExpr(expr, parameters)
+		if err := op.ToExpr(expr, parameters); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func (e *Expr2) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
-	e.Left.ToExpr(expr, parameters)
-	if e.Right != nil {
+func (e *Expr2) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if e.Right != nil && e.Right.In {
+		// the set is the left operand of contains
+		if err := e.Right.Expr3.ToExpr(expr, parameters); err != nil {
+			return err
+		}
+		if err := e.Left.ToExpr(expr, parameters); err != nil {
+			return err
+		}
+		*expr = append(*expr, biscuit.BinaryContains)
+		if e.Right.Not {
+			*expr = append(*expr, biscuit.UnaryNegate)
+		}
+		return nil
+	}
 
-		e.Right.ToExpr(expr, parameters)
+	if err := e.Left.ToExpr(expr, parameters); err != nil {
+		return err
+	}
+	if e.Right != nil {
+		return e.Right.ToExpr(expr, parameters)
 	}
+	return nil
 }
 
-func (e *Expr3) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
-	e.Left.ToExpr(expr, parameters)
+func (e *Expr3) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if err := e.Left.ToExpr(expr, parameters); err != nil {
+		return err
+	}
 
 	for _, op := range e.Right {
-		op.ToExpr(expr, parameters)
+		if err := op.ToExpr(expr, parameters); err != nil {
+			return err
+		}
+	}
+	for _, op := range e.BitwiseAnd {
+		if err := op.ToExpr(expr, parameters); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func (e *Expr4) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
-	e.Left.ToExpr(expr, parameters)
+func (e *Expr4) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if err := e.Left.ToExpr(expr, parameters); err != nil {
+		return err
+	}
 
 	for _, op := range e.Right {
-		op.ToExpr(expr, parameters)
+		if err := op.ToExpr(expr, parameters); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func (e *Expr5) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
-	e.Expr6.ToExpr(expr, parameters)
+func (e *Expr5) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if e == nil {
+		return nil
+	}
+	if err := e.Expr6.ToExpr(expr, parameters); err != nil {
+		return err
+	}
 	if e.Operator != nil {
 		*expr = append(*expr, biscuit.UnaryNegate)
 	}
+	return nil
 }
 
-func (e *Expr6) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
-	e.Left.ToExpr(expr, parameters)
+func (e *Expr6) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if e == nil {
+		return nil
+	}
+	if err := e.Left.ToExpr(expr, parameters); err != nil {
+		return err
+	}
 	for _, op := range e.Right {
-		op.ToExpr(expr, parameters)
+		if err := op.ToExpr(expr, parameters); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func (e *ExprTerm) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
+func (e *ExprTerm) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if e == nil {
+		return nil
+	}
 
 	switch {
 	case e.Term != nil:
-		//FIXME: error management
-		term, _ := e.Term.ToBiscuit(parameters)
+		term, err := e.Term.ToBiscuit(parameters)
+		if err != nil {
+			return err
+		}
 		*expr = append(*expr, biscuit.Value{Term: term})
 	case e.Expression != nil:
-		e.Expression.ToExpr(expr, parameters)
+		if err := e.Expression.ToExpr(expr, parameters); err != nil {
+			return err
+		}
 		*expr = append(*expr, biscuit.UnaryParens)
 	}
 
+	return nil
 }
 
-func (e *OpExpr1) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
-	e.Expr1.ToExpr(expr, parameters)
+func (e *OpExpr1) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if err := e.Expr1.ToExpr(expr, parameters); err != nil {
+		return err
+	}
 	e.Operator.ToExpr(expr)
+	return nil
 }
 
-func (e *OpExpr2) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
-	e.Expr2.ToExpr(expr, parameters)
+func (e *OpExpr2) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if err := e.Expr2.ToExpr(expr, parameters); err != nil {
+		return err
+	}
 	e.Operator.ToExpr(expr)
+	return nil
 }
 
-func (e *OpExpr3) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
-	e.Expr3.ToExpr(expr, parameters)
+func (e *OpExpr3) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if err := e.Expr3.ToExpr(expr, parameters); err != nil {
+		return err
+	}
 	e.Operator.ToExpr(expr)
+	return nil
 }
 
-func (e *OpExpr4) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
-	e.Expr4.ToExpr(expr, parameters)
+func (e *OpExpr4) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if err := e.Expr4.ToExpr(expr, parameters); err != nil {
+		return err
+	}
+	e.Operator.ToExpr(expr)
+	return nil
+}
+
+func (e *OpExprBitwiseAnd) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if err := e.Left.ToExpr(expr, parameters); err != nil {
+		return err
+	}
+	for _, op := range e.Right {
+		if err := op.ToExpr(expr, parameters); err != nil {
+			return err
+		}
+	}
 	e.Operator.ToExpr(expr)
+	return nil
 }
 
-func (e *OpExpr5) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
-	e.Expr5.ToExpr(expr, parameters)
+func (e *OpExpr5) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
+	if err := e.Expr5.ToExpr(expr, parameters); err != nil {
+		return err
+	}
 	e.Operator.ToExpr(expr)
+	return nil
 }
 
-func (e *OpExpr7) ToExpr(expr *biscuit.Expression, parameters ParametersMap) {
+func (e *OpExpr7) ToExpr(expr *biscuit.Expression, parameters ParametersMap) error {
 	if e.Expression != nil {
-		e.Expression.ToExpr(expr, parameters)
+		if err := e.Expression.ToExpr(expr, parameters); err != nil {
+			return err
+		}
 	}
 	e.Operator.ToExpr(expr)
+	return nil
 }
 
 func (op *Operator) ToExpr(expr *biscuit.Expression) {
@@ -464,10 +647,28 @@ func (op *Operator) ToExpr(expr *biscuit.Expression) {
 		biscuit_op = biscuit.BinaryRegex
 	case OpLength:
 		biscuit_op = biscuit.UnaryLength
+	case OpCharLength:
+		biscuit_op = biscuit.UnaryCharLength
+	case OpToDate:
+		biscuit_op = biscuit.UnaryToDate
+	case OpToInt:
+		biscuit_op = biscuit.UnaryToInt
 	case OpIntersection:
 		biscuit_op = biscuit.BinaryIntersection
 	case OpUnion:
 		biscuit_op = biscuit.BinaryUnion
+	case OpGet:
+		biscuit_op = biscuit.BinaryGet
+	case OpNotEqual:
+		biscuit_op = biscuit.BinaryNotEqual
+	case OpSubset:
+		biscuit_op = biscuit.BinarySubset
+	case OpMatchesInsensitive:
+		biscuit_op = biscuit.BinaryRegexInsensitive
+	case OpSuperset:
+		biscuit_op = biscuit.BinarySuperset
+	case OpBitwiseAnd:
+		biscuit_op = biscuit.BinaryBitwiseAnd
 	}
 
 	*expr = append(*expr, biscuit_op)
@@ -501,6 +702,23 @@ func (h *HexString) String() string {
 }
 
 func (p *Predicate) ToBiscuit(parameters ParametersMap) (*biscuit.Predicate, error) {
+	var name string
+	switch {
+	case p.Name != nil:
+		name = *p.Name
+	case p.NameParameter != nil:
+		paramName := string(*p.NameParameter)
+		paramValue := parameters[paramName]
+		if paramValue == nil {
+			return nil, UnboundParameterError{Name: paramName}
+		}
+		str, ok := paramValue.(biscuit.String)
+		if !ok || str == "" {
+			return nil, fmt.Errorf("%w: parameter %s is %s", ErrInvalidPredicateName, paramName, paramValue)
+		}
+		name = string(str)
+	}
+
 	terms := make([]biscuit.Term, 0, len(p.IDs))
 	for _, a := range p.IDs {
 		biscuitTerm, err := a.ToBiscuit(parameters)
@@ -511,7 +729,7 @@ func (p *Predicate) ToBiscuit(parameters ParametersMap) (*biscuit.Predicate, err
 	}
 
 	return &biscuit.Predicate{
-		Name: *p.Name,
+		Name: name,
 		IDs:  terms,
 	}, nil
 }
@@ -528,14 +746,14 @@ func (a *Term) ToBiscuit(parameters ParametersMap) (biscuit.Term, error) {
 	case a.Date != nil:
 		date, err := time.Parse(time.RFC3339, *a.Date)
 		if err != nil {
-			return nil, fmt.Errorf("parser: failed to decode date: %v", err)
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDate, err)
 		}
 
 		biscuitTerm = biscuit.Date(date)
 	case a.Bytes != nil:
 		b, err := a.Bytes.Decode()
 		if err != nil {
-			return nil, fmt.Errorf("parser: failed to decode hex string: %v", err)
+			return nil, fmt.Errorf("%w: %v", ErrInvalidHex, err)
 		}
 		biscuitTerm = biscuit.Bytes(b)
 	case a.Bool != nil:
@@ -552,22 +770,83 @@ func (a *Term) ToBiscuit(parameters ParametersMap) (biscuit.Term, error) {
 			}
 			biscuitSet = append(biscuitSet, setTerm)
 		}
-		biscuitTerm = biscuitSet
+		biscuitTerm = canonicalSet(biscuitSet)
+	case a.Array != nil:
+		biscuitArray := make(biscuit.Array, 0, len(a.Array.Elements))
+		for _, term := range a.Array.Elements {
+			elt, err := term.ToBiscuit(parameters)
+			if err != nil {
+				return nil, err
+			}
+			if elt.Type() == biscuit.TermTypeVariable {
+				return nil, ErrVariableInArray
+			}
+			biscuitArray = append(biscuitArray, elt)
+		}
+		biscuitTerm = biscuitArray
+	case a.Map != nil:
+		biscuitMap := make(biscuit.Map, len(a.Map))
+		for _, entry := range a.Map {
+			key, err := entry.Key.ToBiscuit(parameters)
+			if err != nil {
+				return nil, err
+			}
+			if t := key.Type(); t != biscuit.TermTypeInteger && t != biscuit.TermTypeString {
+				return nil, ErrInvalidMapKey
+			}
+			if _, ok := biscuitMap[key]; ok {
+				return nil, fmt.Errorf("%w: %s", ErrDuplicateMapKey, key)
+			}
+			value, err := entry.Value.ToBiscuit(parameters)
+			if err != nil {
+				return nil, err
+			}
+			if value.Type() == biscuit.TermTypeVariable {
+				return nil, ErrVariableInMap
+			}
+			biscuitMap[key] = value
+		}
+		biscuitTerm = biscuitMap
 	case a.Parameter != nil:
 		var paramName string = string(*(a.Parameter))
 		paramValue := parameters[paramName]
 		if paramValue == nil {
-			return nil, fmt.Errorf("parser: unbound parameter: %s", paramName)
+			return nil, UnboundParameterError{Name: paramName}
 		}
 		biscuitTerm = paramValue
 
 	default:
-		return nil, errors.New("parser: unsupported predicate, must be one of integer, string, variable, or bytes")
+		return nil, ErrUnsupportedTerm
 	}
 
 	return biscuitTerm, nil
 }
 
+// canonicalSet removes the duplicate elements of a set literal and sorts
+// them, so equal sets are serialized the same way. Integers are sorted by
+// value, other terms by their datalog source.
+func canonicalSet(set biscuit.Set) biscuit.Set {
+	seen := make(map[string]struct{}, len(set))
+	out := make(biscuit.Set, 0, len(set))
+	for _, e := range set {
+		key := biscuit.TermString(e)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, aok := out[i].(biscuit.Integer)
+		b, bok := out[j].(biscuit.Integer)
+		if aok && bok {
+			return a < b
+		}
+		return biscuit.TermString(out[i]) < biscuit.TermString(out[j])
+	})
+	return out
+}
+
 func (r *Rule) ToBiscuit(parameters ParametersMap) (*biscuit.Rule, error) {
 	body := []biscuit.Predicate{}
 	expressions := make([]biscuit.Expression, 0)
@@ -585,7 +864,9 @@ func (r *Rule) ToBiscuit(parameters ParametersMap) (*biscuit.Rule, error) {
 		case p.Expression != nil:
 			{
 				var expr biscuit.Expression
-				(*p.Expression).ToExpr(&expr, parameters)
+				if err := (*p.Expression).ToExpr(&expr, parameters); err != nil {
+					return nil, err
+				}
 
 				expressions = append(expressions, expr)
 			}
@@ -597,13 +878,56 @@ func (r *Rule) ToBiscuit(parameters ParametersMap) (*biscuit.Rule, error) {
 		return nil, err
 	}
 
+	scopes, err := scopesToBiscuit(r.Scopes, parameters)
+	if err != nil {
+		return nil, err
+	}
+
 	return &biscuit.Rule{
 		Head:        *head,
 		Body:        body,
 		Expressions: expressions,
+		Scope:       scopes,
 	}, nil
 }
 
+func (s *Scope) ToBiscuit(parameters ParametersMap) (*biscuit.Scope, error) {
+	switch {
+	case s.Authority:
+		return &biscuit.Scope{Type: biscuit.ScopeTypeAuthority}, nil
+	case s.Previous:
+		return &biscuit.Scope{Type: biscuit.ScopeTypePrevious}, nil
+	case s.PublicKey != nil:
+		return &biscuit.Scope{Type: biscuit.ScopeTypePublicKey, PublicKey: ed25519.PublicKey(*s.PublicKey)}, nil
+	case s.Parameter != nil:
+		// public key parameters are bound to the raw key bytes
+		paramName := string(*s.Parameter)
+		paramValue := parameters[paramName]
+		if paramValue == nil {
+			return nil, UnboundParameterError{Name: paramName}
+		}
+		key, ok := paramValue.(biscuit.Bytes)
+		if !ok || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("parser: parameter %s is not an ed25519 public key", paramName)
+		}
+		return &biscuit.Scope{Type: biscuit.ScopeTypePublicKey, PublicKey: ed25519.PublicKey(key)}, nil
+	default:
+		return nil, errors.New("parser: unsupported scope, must be one of authority, previous or a public key")
+	}
+}
+
+func scopesToBiscuit(scopes []*Scope, parameters ParametersMap) ([]biscuit.Scope, error) {
+	var res []biscuit.Scope
+	for _, s := range scopes {
+		scope, err := s.ToBiscuit(parameters)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, *scope)
+	}
+	return res, nil
+}
+
 func (c *Check) ToBiscuit(parameters ParametersMap) (*biscuit.Check, error) {
 	queries := make([]biscuit.Rule, 0, len(c.Queries))
 	for _, q := range c.Queries {
@@ -615,8 +939,17 @@ func (c *Check) ToBiscuit(parameters ParametersMap) (*biscuit.Check, error) {
 		queries = append(queries, *r)
 	}
 
+	kind := biscuit.CheckKindOne
+	switch {
+	case c.All:
+		kind = biscuit.CheckKindAll
+	case c.Reject:
+		kind = biscuit.CheckKindReject
+	}
+
 	return &biscuit.Check{
 		Queries: queries,
+		Kind:    kind,
 	}, nil
 }
 
@@ -637,7 +970,9 @@ func (r *CheckQuery) ToBiscuit(parameters ParametersMap) (*biscuit.Rule, error)
 		case p.Expression != nil:
 			{
 				var expr biscuit.Expression
-				(*p.Expression).ToExpr(&expr, parameters)
+				if err := (*p.Expression).ToExpr(&expr, parameters); err != nil {
+					return nil, err
+				}
 
 				expressions = append(expressions, expr)
 			}
@@ -649,10 +984,16 @@ func (r *CheckQuery) ToBiscuit(parameters ParametersMap) (*biscuit.Rule, error)
 		IDs:  []biscuit.Term{},
 	}
 
+	scopes, err := scopesToBiscuit(r.Scopes, parameters)
+	if err != nil {
+		return nil, err
+	}
+
 	return &biscuit.Rule{
 		Head:        *head,
 		Body:        body,
 		Expressions: expressions,
+		Scope:       scopes,
 	}, nil
 }
 
@@ -668,7 +1009,7 @@ func (p *Policy) ToBiscuit(parameters ParametersMap) (*biscuit.Policy, error) {
 		}
 	case p.Deny != nil:
 		{
-			parsedQueries = p.Allow.Queries
+			parsedQueries = p.Deny.Queries
 			kind = biscuit.PolicyKindDeny
 			break
 		}
@@ -95,6 +95,16 @@ func TestGrammarPredicate(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `resource(-5, [33, -5])`,
+			Expected: &Predicate{
+				Name: sptr("resource"),
+				IDs: []*Term{
+					{Integer: i64ptr(-5)},
+					{Set: []*Term{{Integer: i64ptr(33)}, {Integer: i64ptr(-5)}}},
+				},
+			},
+		},
 		{
 			Input: `right($1, true, false)`,
 			Expected: &Predicate{
@@ -130,6 +140,79 @@ func TestExprTerm(t *testing.T) {
 	}, parsed)
 }
 
+func TestSetLiteralCanonical(t *testing.T) {
+	parser, err := participle.Build[Term](DefaultParserOptions...)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Input    string
+		Expected biscuit.Set
+	}{
+		{Input: `[3, 1, 2, 1]`, Expected: biscuit.Set{biscuit.Integer(1), biscuit.Integer(2), biscuit.Integer(3)}},
+		{Input: `[10, -5, 9, 10]`, Expected: biscuit.Set{biscuit.Integer(-5), biscuit.Integer(9), biscuit.Integer(10)}},
+		{Input: `["b", "a", "b"]`, Expected: biscuit.Set{biscuit.String("a"), biscuit.String("b")}},
+		{Input: `[hex:02, hex:01, hex:02]`, Expected: biscuit.Set{biscuit.Bytes{1}, biscuit.Bytes{2}}},
+		{Input: `[true, false, true]`, Expected: biscuit.Set{biscuit.Bool(false), biscuit.Bool(true)}},
+		{
+			Input: `[2024-01-01T00:00:00Z, 2023-01-01T00:00:00Z, 2024-01-01T00:00:00Z]`,
+			Expected: biscuit.Set{
+				biscuit.Date(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+				biscuit.Date(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Input, func(t *testing.T) {
+			term, err := parser.ParseString("test", testCase.Input)
+			require.NoError(t, err)
+			set, err := term.ToBiscuit(nil)
+			require.NoError(t, err)
+			require.Equal(t, testCase.Expected, set)
+		})
+	}
+}
+
+func TestTermToBiscuitErrors(t *testing.T) {
+	parser, err := participle.Build[Term](DefaultParserOptions...)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Input       string
+		ExpectedErr error
+	}{
+		{Input: `{unknown}`, ExpectedErr: ErrUnboundParameter},
+		{Input: `[1, {unknown}]`, ExpectedErr: ErrUnboundParameter},
+		{Input: `2023-13-45T00:00:00Z`, ExpectedErr: ErrInvalidDate},
+		{Input: `[1, $x]`, ExpectedErr: ErrVariableInSet},
+		{Input: `{"a": $x}`, ExpectedErr: ErrVariableInMap},
+		{Input: `{true: 1}`, ExpectedErr: ErrInvalidMapKey},
+		{Input: `{"a": 1, "a": 2}`, ExpectedErr: ErrDuplicateMapKey},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Input, func(t *testing.T) {
+			term, err := parser.ParseString("test", testCase.Input)
+			require.NoError(t, err)
+			_, err = term.ToBiscuit(ParametersMap{"known": biscuit.Integer(1)})
+			require.ErrorIs(t, err, testCase.ExpectedErr)
+		})
+	}
+
+	term, err := parser.ParseString("test", `{unknown}`)
+	require.NoError(t, err)
+	_, err = term.ToBiscuit(nil)
+	var unbound UnboundParameterError
+	require.ErrorAs(t, err, &unbound)
+	require.Equal(t, "unknown", unbound.Name)
+	require.EqualError(t, err, "parser: unbound parameter: unknown")
+
+	invalidHex := HexString("abc")
+	_, err = (&Term{Bytes: &invalidHex}).ToBiscuit(nil)
+	require.ErrorIs(t, err, ErrInvalidHex)
+
+	_, err = (&Term{}).ToBiscuit(nil)
+	require.ErrorIs(t, err, ErrUnsupportedTerm)
+}
+
 func TestGrammarExpression(t *testing.T) {
 	parser, err := participle.Build[Expression](DefaultParserOptions...)
 	require.NoError(t, err)
@@ -182,6 +265,26 @@ func TestGrammarExpression(t *testing.T) {
 				biscuit.BinaryLessOrEqual,
 			},
 		},
+		{
+			Input: `100-200 < 0`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Integer(100)},
+				biscuit.Value{Term: biscuit.Integer(200)},
+				biscuit.BinarySub,
+				biscuit.Value{Term: biscuit.Integer(0)},
+				biscuit.BinaryLessThan,
+			},
+		},
+		{
+			Input: `$0 - -5 == -922`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("0")},
+				biscuit.Value{Term: biscuit.Integer(-5)},
+				biscuit.BinarySub,
+				biscuit.Value{Term: biscuit.Integer(-922)},
+				biscuit.BinaryEqual,
+			},
+		},
 		{
 			Input: `!$0`,
 			Expected: &biscuit.Expression{
@@ -238,6 +341,33 @@ func TestGrammarExpression(t *testing.T) {
 				biscuit.BinaryRegex,
 			},
 		},
+		{
+			Input: `$0.matches_insensitive("abc")`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("0")},
+				biscuit.Value{Term: biscuit.String("abc")},
+				biscuit.BinaryRegexInsensitive,
+			},
+		},
+		{
+			Input: `$0 in [1, 2, 3]`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Set{biscuit.Integer(1), biscuit.Integer(2), biscuit.Integer(3)}},
+				biscuit.Value{Term: biscuit.Variable("0")},
+				biscuit.BinaryContains,
+			},
+		},
+		{
+			Input: `$0 + 1 not in [1, 2, 3]`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Set{biscuit.Integer(1), biscuit.Integer(2), biscuit.Integer(3)}},
+				biscuit.Value{Term: biscuit.Variable("0")},
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinaryAdd,
+				biscuit.BinaryContains,
+				biscuit.UnaryNegate,
+			},
+		},
 		{
 			Input: `["abc", "def"].contains($0)`,
 			Expected: &biscuit.Expression{
@@ -303,6 +433,166 @@ func TestGrammarExpression(t *testing.T) {
 				biscuit.BinaryEqual,
 			},
 		},
+		{
+			Input: `$a + 1 < $b * 2`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("a")},
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinaryAdd,
+				biscuit.Value{Term: biscuit.Variable("b")},
+				biscuit.Value{Term: biscuit.Integer(2)},
+				biscuit.BinaryMul,
+				biscuit.BinaryLessThan,
+			},
+		},
+		{
+			Input: `$a - 1 - 2`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("a")},
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinarySub,
+				biscuit.Value{Term: biscuit.Integer(2)},
+				biscuit.BinarySub,
+			},
+		},
+		{
+			Input: `10 / 5 * 2`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Integer(10)},
+				biscuit.Value{Term: biscuit.Integer(5)},
+				biscuit.BinaryDiv,
+				biscuit.Value{Term: biscuit.Integer(2)},
+				biscuit.BinaryMul,
+			},
+		},
+		{
+			Input: `($a + 1) * 2 >= $b`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("a")},
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinaryAdd,
+				biscuit.UnaryParens,
+				biscuit.Value{Term: biscuit.Integer(2)},
+				biscuit.BinaryMul,
+				biscuit.Value{Term: biscuit.Variable("b")},
+				biscuit.BinaryGreaterOrEqual,
+			},
+		},
+		{
+			Input: `$a * ($b - 1) == 3 || !$c && $d`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("a")},
+				biscuit.Value{Term: biscuit.Variable("b")},
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinarySub,
+				biscuit.UnaryParens,
+				biscuit.BinaryMul,
+				biscuit.Value{Term: biscuit.Integer(3)},
+				biscuit.BinaryEqual,
+				biscuit.Value{Term: biscuit.Variable("c")},
+				biscuit.UnaryNegate,
+				biscuit.Value{Term: biscuit.Variable("d")},
+				biscuit.BinaryAnd,
+				biscuit.BinaryOr,
+			},
+		},
+		{
+			Input: `$a < 1 || $b > 2 && $c != 3`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("a")},
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinaryLessThan,
+				biscuit.Value{Term: biscuit.Variable("b")},
+				biscuit.Value{Term: biscuit.Integer(2)},
+				biscuit.BinaryGreaterThan,
+				biscuit.Value{Term: biscuit.Variable("c")},
+				biscuit.Value{Term: biscuit.Integer(3)},
+				biscuit.BinaryNotEqual,
+				biscuit.BinaryAnd,
+				biscuit.BinaryOr,
+			},
+		},
+		{
+			Input: `!($a + 1 <= 2)`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("a")},
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinaryAdd,
+				biscuit.Value{Term: biscuit.Integer(2)},
+				biscuit.BinaryLessOrEqual,
+				biscuit.UnaryParens,
+				biscuit.UnaryNegate,
+			},
+		},
+		{
+			Input: `$a.to_date() <= 2021-01-01T00:00:00Z`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("a")},
+				biscuit.UnaryToDate,
+				biscuit.Value{Term: biscuit.Date(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))},
+				biscuit.BinaryLessOrEqual,
+			},
+		},
+		{
+			Input: `$d.to_int() + 1`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("d")},
+				biscuit.UnaryToInt,
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinaryAdd,
+			},
+		},
+		{
+			Input: `$a.char_length() < $a.length()`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("a")},
+				biscuit.UnaryCharLength,
+				biscuit.Value{Term: biscuit.Variable("a")},
+				biscuit.UnaryLength,
+				biscuit.BinaryLessThan,
+			},
+		},
+		{
+			Input: `$a.length() + 1 > 2 * $b`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("a")},
+				biscuit.UnaryLength,
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinaryAdd,
+				biscuit.Value{Term: biscuit.Integer(2)},
+				biscuit.Value{Term: biscuit.Variable("b")},
+				biscuit.BinaryMul,
+				biscuit.BinaryGreaterThan,
+			},
+		},
+		{
+			Input: `$0 + 1 & 6 * 2 == 4`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("0")},
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinaryAdd,
+				biscuit.Value{Term: biscuit.Integer(6)},
+				biscuit.Value{Term: biscuit.Integer(2)},
+				biscuit.BinaryMul,
+				biscuit.BinaryBitwiseAnd,
+				biscuit.Value{Term: biscuit.Integer(4)},
+				biscuit.BinaryEqual,
+			},
+		},
+		{
+			Input: `$0 & 3 & 1 == 1 && true`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("0")},
+				biscuit.Value{Term: biscuit.Integer(3)},
+				biscuit.BinaryBitwiseAnd,
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinaryBitwiseAnd,
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinaryEqual,
+				biscuit.Value{Term: biscuit.Bool(true)},
+				biscuit.BinaryAnd,
+			},
+		},
 		{
 			Input: `hex:12ab == hex:ab`,
 			Expected: &biscuit.Expression{
@@ -311,6 +601,40 @@ func TestGrammarExpression(t *testing.T) {
 				biscuit.BinaryEqual,
 			},
 		},
+		{
+			Input: `hex:ab != hex:cd`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Bytes([]byte{0xab})},
+				biscuit.Value{Term: biscuit.Bytes([]byte{0xcd})},
+				biscuit.BinaryNotEqual,
+			},
+		},
+		{
+			Input: `$a != 1`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("a")},
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinaryNotEqual,
+			},
+		},
+		{
+			Input: `"x" != "y"`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.String("x")},
+				biscuit.Value{Term: biscuit.String("y")},
+				biscuit.BinaryNotEqual,
+			},
+		},
+		{
+			Input: `!$a != !$b`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("a")},
+				biscuit.UnaryNegate,
+				biscuit.Value{Term: biscuit.Variable("b")},
+				biscuit.UnaryNegate,
+				biscuit.BinaryNotEqual,
+			},
+		},
 		{
 			Input: `{param1} + {param2} * {param3} == {param4} || {param5}`,
 			Params: map[string]biscuit.Term{
@@ -332,6 +656,54 @@ func TestGrammarExpression(t *testing.T) {
 				biscuit.BinaryOr,
 			},
 		},
+		{
+			Input: `{"a": 1}.get("a") == $0.get(2)`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Map{biscuit.String("a"): biscuit.Integer(1)}},
+				biscuit.Value{Term: biscuit.String("a")},
+				biscuit.BinaryGet,
+				biscuit.Value{Term: biscuit.Variable("0")},
+				biscuit.Value{Term: biscuit.Integer(2)},
+				biscuit.BinaryGet,
+				biscuit.BinaryEqual,
+			},
+		},
+		{
+			Input: `!($a == "abc")`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("a")},
+				biscuit.Value{Term: biscuit.String("abc")},
+				biscuit.BinaryEqual,
+				biscuit.UnaryParens,
+				biscuit.UnaryNegate,
+			},
+		},
+		{
+			Input: `($x + 1) * 2`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("x")},
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinaryAdd,
+				biscuit.UnaryParens,
+				biscuit.Value{Term: biscuit.Integer(2)},
+				biscuit.BinaryMul,
+			},
+		},
+		{
+			Input: `!((($x > 1)) && ($y))`,
+			Expected: &biscuit.Expression{
+				biscuit.Value{Term: biscuit.Variable("x")},
+				biscuit.Value{Term: biscuit.Integer(1)},
+				biscuit.BinaryGreaterThan,
+				biscuit.UnaryParens,
+				biscuit.UnaryParens,
+				biscuit.Value{Term: biscuit.Variable("y")},
+				biscuit.UnaryParens,
+				biscuit.BinaryAnd,
+				biscuit.UnaryParens,
+				biscuit.UnaryNegate,
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -340,13 +712,71 @@ func TestGrammarExpression(t *testing.T) {
 			require.NoError(t, err, testCase.Input)
 
 			var expr biscuit.Expression
-			(*parsed).ToExpr(&expr, testCase.Params)
+			require.NoError(t, (*parsed).ToExpr(&expr, testCase.Params))
 			require.Equal(t, testCase.Expected, &expr, testCase.Input)
 		})
 	}
 
 }
 
+func TestGrammarExpressionIn(t *testing.T) {
+	parser, err := participle.Build[Expression](DefaultParserOptions...)
+	require.NoError(t, err)
+
+	toExpr := func(input string) biscuit.Expression {
+		parsed, err := parser.ParseString("test", input)
+		require.NoError(t, err, input)
+		var expr biscuit.Expression
+		require.NoError(t, parsed.ToExpr(&expr, nil))
+		return expr
+	}
+
+	for infix, method := range map[string]string{
+		`$0 in [1, 2, 3]`:                  `[1, 2, 3].contains($0)`,
+		`$0 not in [1, 2, 3]`:              `![1, 2, 3].contains($0)`,
+		`$0 in ["abc", "def"]`:             `["abc", "def"].contains($0)`,
+		`$0 not in [hex:41, hex:42]`:       `![hex:41, hex:42].contains($0)`,
+		`"a" in $set`:                      `$set.contains("a")`,
+		`$0 in $set && $1 not in $set`:     `$set.contains($0) && !$set.contains($1)`,
+		`$0 in [1, 2] || $0 not in [3, 4]`: `[1, 2].contains($0) || ![3, 4].contains($0)`,
+		`$0 * 2 in [2, 4]`:                 `[2, 4].contains($0 * 2)`,
+		`$0 in [1, 2].union([3])`:          `[1, 2].union([3]).contains($0)`,
+	} {
+		t.Run(infix, func(t *testing.T) {
+			require.Equal(t, toExpr(method), toExpr(infix))
+		})
+	}
+
+	for _, input := range []string{
+		`$0 in`,
+		`$0 not [1, 2]`,
+		`$0 in [1, 2] in [3]`,
+		`$0 in [1, 2] == true`,
+	} {
+		_, err := parser.ParseString("test", input)
+		require.Error(t, err, input)
+	}
+}
+
+func TestGrammarExpressionNil(t *testing.T) {
+	parser, err := participle.Build[Expression](DefaultParserOptions...)
+	require.NoError(t, err)
+
+	// empty parentheses are not a valid expression
+	_, err = parser.ParseString("test", `()`)
+	require.Error(t, err)
+	_, err = parser.ParseString("test", `!() || true`)
+	require.Error(t, err)
+
+	var expr biscuit.Expression
+	require.NotPanics(t, func() {
+		require.NoError(t, (*Expression)(nil).ToExpr(&expr, nil))
+		require.NoError(t, (&Expression{Left: &Expr1{Left: &Expr2{Left: &Expr3{Left: &Expr4{Left: &Expr5{}}}}}}).ToExpr(&expr, nil))
+		require.NoError(t, (&ExprTerm{}).ToExpr(&expr, nil))
+	})
+	require.Empty(t, expr)
+}
+
 func TestGrammarCheck(t *testing.T) {
 	parser, err := participle.Build[Check](DefaultParserOptions...)
 	require.NoError(t, err)
@@ -355,6 +785,48 @@ func TestGrammarCheck(t *testing.T) {
 		Input    string
 		Expected *Check
 	}{
+		{
+			Input: `check all parent("a", $b)`,
+			Expected: &Check{
+				All: true,
+				Queries: []*CheckQuery{
+					{
+						Body: []*RuleElement{
+							{
+								Predicate: &Predicate{
+									Name: sptr("parent"),
+									IDs: []*Term{
+										{String: sptr("a")},
+										{Variable: varptr("b")},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Input: `reject if parent("a", $b)`,
+			Expected: &Check{
+				Reject: true,
+				Queries: []*CheckQuery{
+					{
+						Body: []*RuleElement{
+							{
+								Predicate: &Predicate{
+									Name: sptr("parent"),
+									IDs: []*Term{
+										{String: sptr("a")},
+										{Variable: varptr("b")},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			Input: `check if parent("a", "b"), parent("b", "c")`,
 			Expected: &Check{
@@ -621,6 +1093,44 @@ func TestGrammarBlock(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `/* some
+    multi-line comment */
+    fact(true);
+    // line comment
+    other(1); /* block comment */ last(2);`,
+			Expected: &Block{
+				Comments: []*Comment{commentptr("some\n    multi-line comment")},
+				Body: []*BlockElement{
+					{
+						Predicate: &Predicate{
+							Name: sptr("fact"),
+							IDs: []*Term{
+								{Bool: boolptr(true)},
+							},
+						},
+					},
+					{
+						Comments: []*Comment{commentptr("line comment")},
+						Predicate: &Predicate{
+							Name: sptr("other"),
+							IDs: []*Term{
+								{Integer: i64ptr(1)},
+							},
+						},
+					},
+					{
+						Comments: []*Comment{commentptr("block comment")},
+						Predicate: &Predicate{
+							Name: sptr("last"),
+							IDs: []*Term{
+								{Integer: i64ptr(2)},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
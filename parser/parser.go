@@ -2,6 +2,11 @@ package parser
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/alecthomas/participle/v2"
 	"github.com/alecthomas/participle/v2/lexer"
@@ -11,24 +16,66 @@ import (
 var (
 	ErrVariableInFact = errors.New("parser: a fact cannot contain any variables")
 	ErrVariableInSet  = errors.New("parser: a set cannot contain any variables")
+	ErrScopeInFact    = errors.New("parser: a fact cannot have a trusting annotation")
+	ErrVariableInMap  = errors.New("parser: a map cannot contain any variables")
+	// ErrVariableInArray is returned when an array literal contains a variable
+	ErrVariableInArray = errors.New("parser: an array cannot contain any variables")
+	ErrInvalidMapKey   = errors.New("parser: map keys must be integers or strings")
+	// ErrDuplicateMapKey is returned when a map contains the same key twice
+	ErrDuplicateMapKey = errors.New("parser: duplicate map key")
+	// ErrUnboundParameter is returned, wrapped in an UnboundParameterError,
+	// when a parameter has no value in the ParametersMap
+	ErrUnboundParameter = errors.New("parser: unbound parameter")
+	// ErrInvalidPredicateName is returned when a predicate name parameter
+	// is not bound to a non empty String
+	ErrInvalidPredicateName = errors.New("parser: predicate name parameter must be a non empty string")
+	// ErrInvalidDate is returned when a date is not a valid RFC 3339 date
+	ErrInvalidDate = errors.New("parser: invalid date")
+	// ErrInvalidHex is returned when a byte array is not a valid hex string
+	ErrInvalidHex = errors.New("parser: invalid hex string")
+	// ErrUnsupportedTerm is returned when converting an empty term
+	ErrUnsupportedTerm = errors.New("parser: unsupported term, must be one of integer, string, variable, bytes, date, bool, set, array, map or parameter")
+	// ErrIncludeCycle is returned by FromFile when a file includes itself,
+	// directly or through other files
+	ErrIncludeCycle = errors.New("parser: include cycle")
+	// ErrNotAFact is returned by FromStringFacts when the input contains
+	// a rule or a check
+	ErrNotAFact = errors.New("parser: expected a fact")
 )
 
+// UnboundParameterError gives the name of a parameter missing from the
+// ParametersMap. It matches ErrUnboundParameter with errors.Is.
+type UnboundParameterError struct {
+	Name string
+}
+
+func (e UnboundParameterError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrUnboundParameter, e.Name)
+}
+
+func (e UnboundParameterError) Unwrap() error {
+	return ErrUnboundParameter
+}
+
 var BiscuitLexerRules = []lexer.SimpleRule{
-	{Name: "Keyword", Pattern: `check if|allow if|deny if`},
-	{Name: "Function", Pattern: `prefix|suffix|matches|length|contains`},
+	{Name: "Keyword", Pattern: `check if|check all|reject if|allow if|deny if`},
+	{Name: "Function", Pattern: `(prefix|suffix|matches|length|contains)\b`},
 	{Name: "Hex", Pattern: `hex:([0-9a-fA-F]{2})*`},
 	{Name: "Dot", Pattern: `\.`},
 	{Name: "Arrow", Pattern: `<-`},
 	{Name: "Or", Pattern: `\|\|`},
 	{Name: "And", Pattern: `&&`},
-	{Name: "Operator", Pattern: `==|>=|<=|>|<|\+|-|\*`},
-	{Name: "Comment", Pattern: `//[^\n]*`},
-	{Name: "String", Pattern: `\"[^\"]*\"`},
+	{Name: "Operator", Pattern: `==|!=|>=|<=|>|<|\+|-|\*`},
+	{Name: "Comment", Pattern: `//[^\n]*|/\*([^*]|\*+[^*/])*\*+/`},
+	{Name: "String", Pattern: `"(\\.|[^"\\])*"`},
 	{Name: "Variable", Pattern: `\$[a-zA-Z0-9_:]+`},
-	{Name: "Parameter", Pattern: `\{[a-zA-Z0-9_:]+\}`},
+	// a parameter name cannot start with digits followed by a colon, so that
+	// maps with an integer key, such as `{1:2}`, are not read as a parameter
+	{Name: "Parameter", Pattern: `\{([0-9]+|[0-9]*[a-zA-Z_][a-zA-Z0-9_:]*)\}`},
 	{Name: "DateTime", Pattern: `\d\d\d\d-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d+)?(Z|([-+]\d\d:\d\d))?`},
 	{Name: "Int", Pattern: `[0-9]+`},
-	{Name: "Bool", Pattern: `true|false`},
+	{Name: "Bool", Pattern: `(true|false)\b`},
+	{Name: "PublicKey", Pattern: `ed25519/[0-9a-fA-F]+`},
 	{Name: "Ident", Pattern: `[a-z][a-zA-Z0-9_:]*`},
 	{Name: "Whitespace", Pattern: `[ \t]+`},
 	{Name: "EOL", Pattern: `[\n\r]+`},
@@ -130,19 +177,12 @@ func (p *parser) Check(check string, parameters ParametersMap) (biscuit.Check, e
 		return biscuit.Check{}, err
 	}
 
-	queries := make([]biscuit.Rule, len(parsed.Queries))
-	for i, q := range parsed.Queries {
-		query, err := q.ToBiscuit(parameters)
-		if err != nil {
-			return biscuit.Check{}, err
-		}
-
-		queries[i] = *query
+	c, err := parsed.ToBiscuit(parameters)
+	if err != nil {
+		return biscuit.Check{}, err
 	}
 
-	return biscuit.Check{
-		Queries: queries,
-	}, nil
+	return *c, nil
 }
 
 func (p *parser) Policy(policy string, parameters ParametersMap) (biscuit.Policy, error) {
@@ -268,10 +308,53 @@ func (m *mustParser) Authorizer(authorizer string, parameters ParametersMap) bis
 	return c
 }
 
+// init sets the functions used by the string based APIs of the biscuit
+// package, which cannot import this package.
+func init() {
+	biscuit.ParseFact = FromStringFact
+	biscuit.ParseFacts = FromStringFacts
+	biscuit.ParseRule = FromStringRule
+	biscuit.ParseCheck = FromStringCheck
+	biscuit.ParsePolicy = FromStringPolicy
+}
+
 func FromStringFact(input string) (biscuit.Fact, error) {
 	return FromStringFactWithParams(input, nil)
 }
 
+// FromStringFacts parses a list of `;` separated facts, the last one
+// not requiring a trailing `;`. It returns ErrNotAFact when an element
+// of the list is a rule or a check.
+func FromStringFacts(input string) ([]biscuit.Fact, error) {
+	if trimmed := strings.TrimSpace(input); trimmed != "" && !strings.HasSuffix(trimmed, ";") {
+		input += ";"
+	}
+
+	block, err := FromStringBlock(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(block.Rules) > 0 {
+		return nil, fmt.Errorf("%w, found a rule", ErrNotAFact)
+	}
+	if len(block.Checks) > 0 {
+		return nil, fmt.Errorf("%w, found a check", ErrNotAFact)
+	}
+	for _, f := range block.Facts {
+		for _, a := range f.IDs {
+			if a.Type() == biscuit.TermTypeVariable {
+				return nil, ErrVariableInFact
+			}
+		}
+	}
+
+	return block.Facts, nil
+}
+
+func FromStringPredicate(input string) (biscuit.Predicate, error) {
+	return FromStringPredicateWithParams(input, nil)
+}
+
 func FromStringRule(input string) (biscuit.Rule, error) {
 	return FromStringRuleWithParams(input, nil)
 }
@@ -292,12 +375,88 @@ func FromStringAuthorizer(input string) (biscuit.ParsedAuthorizer, error) {
 	return FromStringAuthorizerWithParams(input, nil)
 }
 
+// includeDirective matches the lines including another file in FromFile.
+var includeDirective = regexp.MustCompile(`^\s*//\s*include\s+"([^"]+)"\s*$`)
+
+// FromFile parses the authorizer in the file at path. A line such as
+// `// include "policies.datalog"` is replaced by the content of the named file,
+// resolved relative to the directory of the including file, so large policy sets
+// can be split in several files. Included files can include other files,
+// and ErrIncludeCycle is returned when a file ends up including itself.
+func FromFile(path string) (biscuit.ParsedAuthorizer, error) {
+	content, err := readWithIncludes(path, nil)
+	if err != nil {
+		return biscuit.ParsedAuthorizer{}, err
+	}
+
+	return FromStringAuthorizer(content)
+}
+
+// readWithIncludes returns the content of the file at path with its include
+// directives replaced, stack holding the files including it.
+func readWithIncludes(path string, stack []string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for _, including := range stack {
+		if including == path {
+			return "", fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(append(stack, path), " -> "))
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		match := includeDirective.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if match == nil {
+			out.WriteString(line)
+			continue
+		}
+
+		included := match[1]
+		if !filepath.IsAbs(included) {
+			included = filepath.Join(filepath.Dir(path), included)
+		}
+		content, err := readWithIncludes(included, append(stack[:len(stack):len(stack)], path))
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(content)
+		if !strings.HasSuffix(content, "\n") {
+			out.WriteString("\n")
+		}
+	}
+
+	return out.String(), nil
+}
+
 func FromStringFactWithParams(input string, parameters ParametersMap) (biscuit.Fact, error) {
 	p := New()
 
 	return p.Fact(input, parameters)
 }
 
+// FromStringPredicateWithParams parses a predicate, which unlike a fact can
+// contain variables, e.g. `right($resource, "read")`.
+func FromStringPredicateWithParams(input string, parameters ParametersMap) (biscuit.Predicate, error) {
+	parsed, err := participle.MustBuild[Predicate](DefaultParserOptions...).ParseString("predicate", input)
+	if err != nil {
+		return biscuit.Predicate{}, err
+	}
+
+	pred, err := parsed.ToBiscuit(parameters)
+	if err != nil {
+		return biscuit.Predicate{}, err
+	}
+
+	return *pred, nil
+}
+
 func FromStringRuleWithParams(input string, parameters ParametersMap) (biscuit.Rule, error) {
 	p := New()
 
@@ -1,7 +1,13 @@
 package parser
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	mrand "math/rand"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -27,13 +33,99 @@ func getFactTestCases() []testCase {
 						biscuit.String("/a/file1.txt"),
 						biscuit.String("read"),
 						biscuit.Set{
-							biscuit.String("read"),
 							biscuit.String("/a/file2.txt"),
+							biscuit.String("read"),
+						},
+					},
+				},
+			},
+		},
+		{
+			Input: `resource(-5)`,
+			Expected: biscuit.Fact{
+				Predicate: biscuit.Predicate{
+					Name: "resource",
+					IDs:  []biscuit.Term{biscuit.Integer(-5)},
+				},
+			},
+		},
+		{
+			Input: `config({"name": "abc", 1: true})`,
+			Expected: biscuit.Fact{
+				Predicate: biscuit.Predicate{
+					Name: "config",
+					IDs: []biscuit.Term{
+						biscuit.Map{
+							biscuit.String("name"): biscuit.String("abc"),
+							biscuit.Integer(1):     biscuit.Bool(true),
+						},
+					},
+				},
+			},
+		},
+		{
+			Input:         `config({"name": $0})`,
+			ExpectFailure: true,
+			ExpectErr:     ErrVariableInMap,
+		},
+		{
+			Input:         `config({true: 1})`,
+			ExpectFailure: true,
+			ExpectErr:     ErrInvalidMapKey,
+		},
+		{
+			Input: `config({1:2, "a":{3:4}})`,
+			Expected: biscuit.Fact{
+				Predicate: biscuit.Predicate{
+					Name: "config",
+					IDs: []biscuit.Term{
+						biscuit.Map{
+							biscuit.Integer(1):  biscuit.Integer(2),
+							biscuit.String("a"): biscuit.Map{biscuit.Integer(3): biscuit.Integer(4)},
 						},
 					},
 				},
 			},
 		},
+		{
+			Input:         `config({a:1})`,
+			ExpectFailure: true,
+		},
+		{
+			Input: `config({1:true})`,
+			Expected: biscuit.Fact{
+				Predicate: biscuit.Predicate{
+					Name: "config",
+					IDs:  []biscuit.Term{biscuit.Map{biscuit.Integer(1): biscuit.Bool(true)}},
+				},
+			},
+		},
+		{
+			Input: `list(array:[3, "a", 3, array:[]], array:[])`,
+			Expected: biscuit.Fact{
+				Predicate: biscuit.Predicate{
+					Name: "list",
+					IDs: []biscuit.Term{
+						biscuit.Array{biscuit.Integer(3), biscuit.String("a"), biscuit.Integer(3), biscuit.Array{}},
+						biscuit.Array{},
+					},
+				},
+			},
+		},
+		{
+			Input:         `list(array:[$0])`,
+			ExpectFailure: true,
+			ExpectErr:     ErrVariableInArray,
+		},
+		{
+			Input: `admin()`,
+			Expected: biscuit.Fact{
+				Predicate: biscuit.Predicate{
+					Name: "admin",
+					IDs:  []biscuit.Term{},
+				},
+			},
+		},
 		{
 			Input:         `right("/a/file1.txt", $0)`,
 			ExpectFailure: true,
@@ -51,6 +143,28 @@ func getFactTestCases() []testCase {
 			Input:         `right("/a/file1.txt", [$0])`,
 			ExpectFailure: true,
 		},
+		{
+			Input: `fact_1(true)`,
+			Expected: biscuit.Fact{
+				Predicate: biscuit.Predicate{
+					Name: "fact_1",
+					IDs:  []biscuit.Term{biscuit.Bool(true)},
+				},
+			},
+		},
+		{
+			Input: `true_value(false)`,
+			Expected: biscuit.Fact{
+				Predicate: biscuit.Predicate{
+					Name: "true_value",
+					IDs:  []biscuit.Term{biscuit.Bool(false)},
+				},
+			},
+		},
+		{
+			Input:         `_hidden(1)`,
+			ExpectFailure: true,
+		},
 	}
 }
 
@@ -436,6 +550,34 @@ func getRuleTestCases() []testCase {
 				},
 			},
 		},
+		{
+			Input: `right($0) <- resource($0) trusting authority, previous`,
+			Expected: biscuit.Rule{
+				Head: biscuit.Predicate{
+					Name: "right",
+					IDs:  []biscuit.Term{biscuit.Variable("0")},
+				},
+				Body: []biscuit.Predicate{
+					{
+						Name: "resource",
+						IDs:  []biscuit.Term{biscuit.Variable("0")},
+					},
+				},
+				Expressions: []biscuit.Expression{},
+				Scope: []biscuit.Scope{
+					{Type: biscuit.ScopeTypeAuthority},
+					{Type: biscuit.ScopeTypePrevious},
+				},
+			},
+		},
+		{
+			Input:         `right($0) <- resource($0) trusting ed25519/acdd`,
+			ExpectFailure: true,
+		},
+		{
+			Input:         `right($0) <- resource($0) trusting`,
+			ExpectFailure: true,
+		},
 	}
 }
 
@@ -497,7 +639,72 @@ func getCheckTestCases() []testCase {
 			Input:         `[ caveat1($0) <- parent(#a, #b), parent(#b, #c) @ $0 in [1,2,3]`,
 			ExpectFailure: true,
 		},
+		{
+			Input: `check if group("admin") trusting ed25519/acdd6d5b53bfee478bf689f8e012fe7988bf755e3d7c5152947abc149bc20189 or right("read")`,
+			Expected: biscuit.Check{
+				Queries: []biscuit.Rule{
+					{
+						Head: biscuit.Predicate{
+							Name: "query",
+							IDs:  []biscuit.Term{},
+						},
+						Body: []biscuit.Predicate{
+							{
+								Name: "group",
+								IDs:  []biscuit.Term{biscuit.String("admin")},
+							},
+						},
+						Expressions: []biscuit.Expression{},
+						Scope: []biscuit.Scope{
+							{
+								Type:      biscuit.ScopeTypePublicKey,
+								PublicKey: mustDecodeHex("acdd6d5b53bfee478bf689f8e012fe7988bf755e3d7c5152947abc149bc20189"),
+							},
+						},
+					},
+					{
+						Head: biscuit.Predicate{
+							Name: "query",
+							IDs:  []biscuit.Term{},
+						},
+						Body: []biscuit.Predicate{
+							{
+								Name: "right",
+								IDs:  []biscuit.Term{biscuit.String("read")},
+							},
+						},
+						Expressions: []biscuit.Expression{},
+					},
+				},
+			},
+		},
+	}
+}
+
+func mustDecodeHex(s string) ed25519.PublicKey {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
 	}
+	return b
+}
+
+func TestSetLiteralSerialization(t *testing.T) {
+	_, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+
+	build := func(fact string) []byte {
+		builder := biscuit.NewBuilder(privateRoot, biscuit.WithRNG(mrand.New(mrand.NewSource(1))))
+		require.NoError(t, builder.AddAuthorityFact(New().Must().Fact(fact, nil)))
+		b, err := builder.Build()
+		require.NoError(t, err)
+		serialized, err := b.Serialize()
+		require.NoError(t, err)
+		return serialized
+	}
+
+	expected := build(`company(["acme", "apollo"], [1, 2, 3])`)
+	require.Equal(t, expected, build(`company(["apollo", "acme"], [3, 1, 2])`))
+	require.Equal(t, expected, build(`company(["acme", "apollo", "acme"], [2, 3, 1, 3, 2])`))
 }
 
 func TestParserFact(t *testing.T) {
@@ -557,6 +764,37 @@ func TestParserCheck(t *testing.T) {
 	}
 }
 
+func TestParserPolicy(t *testing.T) {
+	query := func(name, resource string) biscuit.Rule {
+		return biscuit.Rule{
+			Head:        biscuit.Predicate{Name: "query", IDs: []biscuit.Term{}},
+			Body:        []biscuit.Predicate{{Name: name, IDs: []biscuit.Term{biscuit.String(resource)}}},
+			Expressions: []biscuit.Expression{},
+		}
+	}
+
+	allow := biscuit.Policy{
+		Kind:    biscuit.PolicyKindAllow,
+		Queries: []biscuit.Rule{query("resource", "/a"), query("admin", "/b")},
+	}
+	deny := biscuit.Policy{
+		Kind:    biscuit.PolicyKindDeny,
+		Queries: []biscuit.Rule{query("resource", "/c"), query("admin", "/d")},
+	}
+
+	policy, err := FromStringPolicy(`allow if resource("/a") or admin("/b")`)
+	require.NoError(t, err)
+	require.Equal(t, allow, policy)
+	policy, err = FromStringPolicy(`deny if resource("/c") or admin("/d")`)
+	require.NoError(t, err)
+	require.Equal(t, deny, policy)
+
+	// the authorizer grammar converts its policies separately
+	authorizer, err := FromStringAuthorizer(`allow if resource("/a") or admin("/b"); deny if resource("/c") or admin("/d");`)
+	require.NoError(t, err)
+	require.Equal(t, []biscuit.Policy{allow, deny}, authorizer.Policies)
+}
+
 func TestMustParserFact(t *testing.T) {
 	p := New()
 	for _, testCase := range getFactTestCases() {
@@ -612,3 +850,327 @@ func TestIssue84(t *testing.T) {
 	_ = rule
 	require.NoError(t, err)
 }
+
+func TestFromStringPredicate(t *testing.T) {
+	pred, err := FromStringPredicate(`right($resource, "read")`)
+	require.NoError(t, err)
+	require.Equal(t, biscuit.Predicate{
+		Name: "right",
+		IDs:  []biscuit.Term{biscuit.Variable("resource"), biscuit.String("read")},
+	}, pred)
+
+	pred, err = FromStringPredicateWithParams(`right({resource}, $op)`, ParametersMap{"resource": biscuit.String("/a")})
+	require.NoError(t, err)
+	require.Equal(t, biscuit.Predicate{
+		Name: "right",
+		IDs:  []biscuit.Term{biscuit.String("/a"), biscuit.Variable("op")},
+	}, pred)
+
+	_, err = FromStringPredicate(`right($resource) <- resource($resource)`)
+	require.Error(t, err)
+}
+
+func TestFromStringFacts(t *testing.T) {
+	role := func(name string) biscuit.Fact {
+		return biscuit.Fact{Predicate: biscuit.Predicate{Name: "role", IDs: []biscuit.Term{biscuit.String(name)}}}
+	}
+
+	testCases := []struct {
+		desc     string
+		input    string
+		expected []biscuit.Fact
+		err      error
+	}{
+		{desc: "trailing semicolon", input: `role("admin"); role("dev");`, expected: []biscuit.Fact{role("admin"), role("dev")}},
+		{desc: "no trailing semicolon", input: `role("admin"); role("dev")`, expected: []biscuit.Fact{role("admin"), role("dev")}},
+		{desc: "single fact", input: `role("admin")`, expected: []biscuit.Fact{role("admin")}},
+		{desc: "empty", input: ` `, expected: []biscuit.Fact{}},
+		{desc: "rule", input: `role("admin"); is_admin(true) <- role("admin")`, err: ErrNotAFact},
+		{desc: "check", input: `check if role("admin")`, err: ErrNotAFact},
+		{desc: "variable", input: `role($r)`, err: ErrVariableInFact},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			facts, err := FromStringFacts(tc.input)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, facts)
+		})
+	}
+}
+
+func TestParserPredicateNames(t *testing.T) {
+	for _, name := range []string{
+		"resource_type",
+		"current_time",
+		"fact_1",
+		"ns:resource",
+		// names starting with a function name or a boolean
+		"prefixed",
+		"length_limit",
+		"contains_all",
+		"matches_x",
+		"trusted",
+		"falsy",
+	} {
+		t.Run(name, func(t *testing.T) {
+			pred, err := FromStringPredicate(name + "($x)")
+			require.NoError(t, err)
+			require.Equal(t, biscuit.Predicate{
+				Name: name,
+				IDs:  []biscuit.Term{biscuit.Variable("x")},
+			}, pred)
+		})
+	}
+}
+
+func TestParserPredicateNameParameter(t *testing.T) {
+	params := ParametersMap{
+		"type": biscuit.String("file"),
+		"id":   biscuit.Integer(1),
+	}
+
+	pred, err := FromStringPredicateWithParams(`{type}($id)`, params)
+	require.NoError(t, err)
+	require.Equal(t, biscuit.Predicate{
+		Name: "file",
+		IDs:  []biscuit.Term{biscuit.Variable("id")},
+	}, pred)
+
+	rule, err := FromStringRuleWithParams(`allowed({id}) <- {type}({id}), {id} > 0`, params)
+	require.NoError(t, err)
+	require.Equal(t, "allowed", rule.Head.Name)
+	require.Equal(t, []biscuit.Predicate{{Name: "file", IDs: []biscuit.Term{biscuit.Integer(1)}}}, rule.Body)
+
+	fact, err := New().Fact(`{type}("/a")`, params)
+	require.NoError(t, err)
+	require.Equal(t, biscuit.Fact{Predicate: biscuit.Predicate{Name: "file", IDs: []biscuit.Term{biscuit.String("/a")}}}, fact)
+
+	_, err = FromStringPredicateWithParams(`{missing}($id)`, params)
+	require.ErrorIs(t, err, ErrUnboundParameter)
+
+	_, err = FromStringPredicateWithParams(`{id}($id)`, params)
+	require.ErrorIs(t, err, ErrInvalidPredicateName)
+
+	_, err = FromStringPredicateWithParams(`{type}($id)`, ParametersMap{"type": biscuit.String("")})
+	require.ErrorIs(t, err, ErrInvalidPredicateName)
+}
+
+func TestParserParameterNames(t *testing.T) {
+	params := ParametersMap{
+		"ns:name": biscuit.String("a"),
+		"0":       biscuit.Integer(0),
+		"1a:b":    biscuit.Integer(1),
+	}
+
+	pred, err := FromStringPredicateWithParams(`right({ns:name}, {0}, {1a:b})`, params)
+	require.NoError(t, err)
+	require.Equal(t, biscuit.Predicate{
+		Name: "right",
+		IDs:  []biscuit.Term{biscuit.String("a"), biscuit.Integer(0), biscuit.Integer(1)},
+	}, pred)
+
+	// digits followed by a colon start a map entry
+	pred, err = FromStringPredicateWithParams(`right({0:1})`, params)
+	require.NoError(t, err)
+	require.Equal(t, biscuit.Predicate{
+		Name: "right",
+		IDs:  []biscuit.Term{biscuit.Map{biscuit.Integer(0): biscuit.Integer(1)}},
+	}, pred)
+}
+
+func TestFromStringParens(t *testing.T) {
+	notABC := biscuit.Expression{
+		biscuit.Value{Term: biscuit.Variable("a")},
+		biscuit.Value{Term: biscuit.String("abc")},
+		biscuit.BinaryEqual,
+		biscuit.UnaryParens,
+		biscuit.UnaryNegate,
+	}
+
+	rule, err := FromStringRule(`var($a) <- user($a), !($a == "abc")`)
+	require.NoError(t, err)
+	require.Equal(t, biscuit.Rule{
+		Head:        biscuit.Predicate{Name: "var", IDs: []biscuit.Term{biscuit.Variable("a")}},
+		Body:        []biscuit.Predicate{{Name: "user", IDs: []biscuit.Term{biscuit.Variable("a")}}},
+		Expressions: []biscuit.Expression{notABC},
+	}, rule)
+
+	check, err := FromStringCheck(`check if user($a), !($a == "abc")`)
+	require.NoError(t, err)
+	require.Equal(t, biscuit.Check{Queries: []biscuit.Rule{{
+		Head:        biscuit.Predicate{Name: "query", IDs: []biscuit.Term{}},
+		Body:        []biscuit.Predicate{{Name: "user", IDs: []biscuit.Term{biscuit.Variable("a")}}},
+		Expressions: []biscuit.Expression{notABC},
+	}}}, check)
+}
+
+func TestUnboundParameterInExpression(t *testing.T) {
+	_, err := FromStringCheck(`check if resource($a), $a == {missing}`)
+	require.ErrorIs(t, err, ErrUnboundParameter)
+
+	_, err = FromStringRule(`allowed($a) <- resource($a), [1, {missing}].contains($a)`)
+	require.ErrorIs(t, err, ErrUnboundParameter)
+
+	_, err = FromStringPolicy(`deny if resource($a), !($a.starts_with({missing}))`)
+	require.ErrorIs(t, err, ErrUnboundParameter)
+
+	_, err = FromStringBlockWithParams(`check if resource($a), $a in {missing};`, ParametersMap{"other": biscuit.String("a")})
+	require.ErrorIs(t, err, ErrUnboundParameter)
+
+	check, err := FromStringCheckWithParams(`check if resource($a), $a == {known}`, ParametersMap{"known": biscuit.String("/a")})
+	require.NoError(t, err)
+	require.Equal(t, biscuit.Expression{
+		biscuit.Value{Term: biscuit.Variable("a")},
+		biscuit.Value{Term: biscuit.String("/a")},
+		biscuit.BinaryEqual,
+	}, check.Queries[0].Expressions[0])
+}
+
+func TestParserEmptyPredicate(t *testing.T) {
+	block, err := FromStringBlock(`admin();`)
+	require.NoError(t, err)
+	require.Equal(t, biscuit.FactSet{{Predicate: biscuit.Predicate{Name: "admin", IDs: []biscuit.Term{}}}}, block.Facts)
+
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+	builder := biscuit.NewBuilder(privateRoot)
+	require.NoError(t, builder.AddBlock(block))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = biscuit.Unmarshal(serialized)
+	require.NoError(t, err)
+
+	authorizer, err := b.Authorizer(publicRoot)
+	require.NoError(t, err)
+	authorizerContents, err := FromStringAuthorizer(`check if admin(); allow if true;`)
+	require.NoError(t, err)
+	authorizer.AddAuthorizer(authorizerContents)
+	require.NoError(t, authorizer.Authorize())
+}
+
+func TestParserScopes(t *testing.T) {
+	key := mustDecodeHex("acdd6d5b53bfee478bf689f8e012fe7988bf755e3d7c5152947abc149bc20189")
+
+	rule, err := FromStringRuleWithParams(`right($0) <- resource($0) trusting {key}`, ParametersMap{"key": biscuit.Bytes(key)})
+	require.NoError(t, err)
+	require.Equal(t, []biscuit.Scope{{Type: biscuit.ScopeTypePublicKey, PublicKey: key}}, rule.Scope)
+
+	_, err = FromStringRuleWithParams(`right($0) <- resource($0) trusting {key}`, ParametersMap{"key": biscuit.String("key")})
+	require.Error(t, err)
+
+	_, err = FromStringBlock(`right("file1") trusting authority;`)
+	require.Equal(t, ErrScopeInFact, err)
+
+	block, err := FromStringBlock(`right($0) <- resource($0) trusting previous; check if right("file1") trusting authority;`)
+	require.NoError(t, err)
+	require.Equal(t, []biscuit.Scope{{Type: biscuit.ScopeTypePrevious}}, block.Rules[0].Scope)
+	require.Equal(t, []biscuit.Scope{{Type: biscuit.ScopeTypeAuthority}}, block.Checks[0].Queries[0].Scope)
+
+	policy, err := FromStringPolicy(`allow if right("file1") trusting authority`)
+	require.NoError(t, err)
+	require.Equal(t, []biscuit.Scope{{Type: biscuit.ScopeTypeAuthority}}, policy.Queries[0].Scope)
+}
+
+func TestTermString(t *testing.T) {
+	testCases := []struct {
+		term     biscuit.Term
+		expected string
+		// tagged terms have no datalog syntax
+		noParse bool
+	}{
+		{term: biscuit.Variable("resource"), expected: `$resource`},
+		{term: biscuit.Integer(-42), expected: `-42`},
+		{term: biscuit.String("/a/file.txt"), expected: `"/a/file.txt"`},
+		{term: biscuit.String(`a "quoted" \ string`), expected: `"a \"quoted\" \\ string"`},
+		{term: biscuit.String("line\nbreak\ttab é😁"), expected: "\"line\\nbreak\ttab é😁\""},
+		{term: biscuit.String(""), expected: `""`},
+		{term: biscuit.Date(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)), expected: `2030-01-02T03:04:05Z`},
+		{term: biscuit.Bytes{0xaa, 0xbb, 0x01}, expected: `hex:aabb01`},
+		{term: biscuit.Bool(true), expected: `true`},
+		{term: biscuit.Bool(false), expected: `false`},
+		{term: biscuit.Set{biscuit.String("a"), biscuit.String("b"), biscuit.Integer(1)}, expected: `["a", "b", 1]`},
+		{term: biscuit.Array{biscuit.String("b"), biscuit.Integer(1), biscuit.Array{}}, expected: `array:["b", 1, array:[]]`},
+		{term: biscuit.Tagged{Tag: "uuid", Value: biscuit.Bytes{0xca, 0xfe}}, expected: `tagged("uuid", hex:cafe)`, noParse: true},
+		{term: biscuit.Map{biscuit.String("b"): biscuit.Integer(2), biscuit.String("a"): biscuit.Set{biscuit.Bool(true)}}, expected: `{"a": [true], "b": 2}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			require.Equal(t, tc.expected, biscuit.TermString(tc.term))
+			require.Equal(t, tc.expected, tc.term.String())
+			if tc.noParse {
+				return
+			}
+
+			pred, err := FromStringPredicate("term(" + tc.expected + ")")
+			require.NoError(t, err)
+			require.Equal(t, biscuit.Predicate{Name: "term", IDs: []biscuit.Term{tc.term}}, pred)
+		})
+	}
+
+	// set elements are sorted
+	require.Equal(t, `["a", "b"]`, biscuit.TermString(biscuit.Set{biscuit.String("b"), biscuit.String("a")}))
+	// dates are written in UTC
+	paris := time.FixedZone("CET", 3600)
+	require.Equal(t, `2030-01-02T02:04:05Z`, biscuit.TermString(biscuit.Date(time.Date(2030, 1, 2, 3, 4, 5, 0, paris))))
+}
+
+func TestFromFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	write("policies/rights.datalog", `right("/a", "read");
+right($r, "write") <- owner($r);`)
+	write("policies/checks.datalog", `// include "rights.datalog"
+check if operation("read");`)
+	main := write("main.datalog", `resource("/a");
+  // include "policies/checks.datalog"
+allow if right("/a", "read");`)
+
+	authorizer, err := FromFile(main)
+	require.NoError(t, err)
+	expected, err := FromStringAuthorizer(`resource("/a");
+right("/a", "read");
+right($r, "write") <- owner($r);
+check if operation("read");
+allow if right("/a", "read");`)
+	require.NoError(t, err)
+	require.Equal(t, expected, authorizer)
+	require.Len(t, authorizer.Block.Facts, 2)
+	require.Len(t, authorizer.Block.Rules, 1)
+	require.Len(t, authorizer.Block.Checks, 1)
+	require.Len(t, authorizer.Policies, 1)
+
+	write("a.datalog", `// include "b.datalog"
+allow if true;`)
+	write("b.datalog", `// include "a.datalog"`)
+	_, err = FromFile(filepath.Join(dir, "a.datalog"))
+	require.ErrorIs(t, err, ErrIncludeCycle)
+
+	self := write("self.datalog", `// include "self.datalog"`)
+	_, err = FromFile(self)
+	require.ErrorIs(t, err, ErrIncludeCycle)
+
+	// the same file can be included twice when it is not a cycle
+	write("twice.datalog", `// include "policies/rights.datalog"
+// include "policies/rights.datalog"
+allow if true;`)
+	_, err = FromFile(filepath.Join(dir, "twice.datalog"))
+	require.NoError(t, err)
+
+	missing := write("missing.datalog", `// include "unknown.datalog"`)
+	_, err = FromFile(missing)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -70,28 +70,152 @@ func (x *PublicKey_Algorithm) UnmarshalJSON(b []byte) error {
 
 // Deprecated: Use PublicKey_Algorithm.Descriptor instead.
 func (PublicKey_Algorithm) EnumDescriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{2, 0}
+	return file_biscuit_proto_rawDescGZIP(), []int{3, 0}
+}
+
+type Scope_ScopeType int32
+
+const (
+	Scope_Authority Scope_ScopeType = 0
+	Scope_Previous  Scope_ScopeType = 1
+)
+
+// Enum value maps for Scope_ScopeType.
+var (
+	Scope_ScopeType_name = map[int32]string{
+		0: "Authority",
+		1: "Previous",
+	}
+	Scope_ScopeType_value = map[string]int32{
+		"Authority": 0,
+		"Previous":  1,
+	}
+)
+
+func (x Scope_ScopeType) Enum() *Scope_ScopeType {
+	p := new(Scope_ScopeType)
+	*p = x
+	return p
+}
+
+func (x Scope_ScopeType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Scope_ScopeType) Descriptor() protoreflect.EnumDescriptor {
+	return file_biscuit_proto_enumTypes[1].Descriptor()
+}
+
+func (Scope_ScopeType) Type() protoreflect.EnumType {
+	return &file_biscuit_proto_enumTypes[1]
+}
+
+func (x Scope_ScopeType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *Scope_ScopeType) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = Scope_ScopeType(num)
+	return nil
+}
+
+// Deprecated: Use Scope_ScopeType.Descriptor instead.
+func (Scope_ScopeType) EnumDescriptor() ([]byte, []int) {
+	return file_biscuit_proto_rawDescGZIP(), []int{6, 0}
+}
+
+type CheckV2_Kind int32
+
+const (
+	CheckV2_One    CheckV2_Kind = 0
+	CheckV2_All    CheckV2_Kind = 1
+	CheckV2_Reject CheckV2_Kind = 2
+)
+
+// Enum value maps for CheckV2_Kind.
+var (
+	CheckV2_Kind_name = map[int32]string{
+		0: "One",
+		1: "All",
+		2: "Reject",
+	}
+	CheckV2_Kind_value = map[string]int32{
+		"One":    0,
+		"All":    1,
+		"Reject": 2,
+	}
+)
+
+func (x CheckV2_Kind) Enum() *CheckV2_Kind {
+	p := new(CheckV2_Kind)
+	*p = x
+	return p
+}
+
+func (x CheckV2_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CheckV2_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_biscuit_proto_enumTypes[2].Descriptor()
+}
+
+func (CheckV2_Kind) Type() protoreflect.EnumType {
+	return &file_biscuit_proto_enumTypes[2]
+}
+
+func (x CheckV2_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Do not use.
+func (x *CheckV2_Kind) UnmarshalJSON(b []byte) error {
+	num, err := protoimpl.X.UnmarshalJSONEnum(x.Descriptor(), b)
+	if err != nil {
+		return err
+	}
+	*x = CheckV2_Kind(num)
+	return nil
+}
+
+// Deprecated: Use CheckV2_Kind.Descriptor instead.
+func (CheckV2_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_biscuit_proto_rawDescGZIP(), []int{9, 0}
 }
 
 type OpUnary_Kind int32
 
 const (
-	OpUnary_Negate OpUnary_Kind = 0
-	OpUnary_Parens OpUnary_Kind = 1
-	OpUnary_Length OpUnary_Kind = 2
+	OpUnary_Negate     OpUnary_Kind = 0
+	OpUnary_Parens     OpUnary_Kind = 1
+	OpUnary_Length     OpUnary_Kind = 2
+	OpUnary_CharLength OpUnary_Kind = 64
+	OpUnary_ToDate     OpUnary_Kind = 65
+	OpUnary_ToInt      OpUnary_Kind = 66
 )
 
 // Enum value maps for OpUnary_Kind.
 var (
 	OpUnary_Kind_name = map[int32]string{
-		0: "Negate",
-		1: "Parens",
-		2: "Length",
+		0:  "Negate",
+		1:  "Parens",
+		2:  "Length",
+		64: "CharLength",
+		65: "ToDate",
+		66: "ToInt",
 	}
 	OpUnary_Kind_value = map[string]int32{
-		"Negate": 0,
-		"Parens": 1,
-		"Length": 2,
+		"Negate":     0,
+		"Parens":     1,
+		"Length":     2,
+		"CharLength": 64,
+		"ToDate":     65,
+		"ToInt":      66,
 	}
 )
 
@@ -106,11 +230,11 @@ func (x OpUnary_Kind) String() string {
 }
 
 func (OpUnary_Kind) Descriptor() protoreflect.EnumDescriptor {
-	return file_biscuit_proto_enumTypes[1].Descriptor()
+	return file_biscuit_proto_enumTypes[3].Descriptor()
 }
 
 func (OpUnary_Kind) Type() protoreflect.EnumType {
-	return &file_biscuit_proto_enumTypes[1]
+	return &file_biscuit_proto_enumTypes[3]
 }
 
 func (x OpUnary_Kind) Number() protoreflect.EnumNumber {
@@ -129,29 +253,35 @@ func (x *OpUnary_Kind) UnmarshalJSON(b []byte) error {
 
 // Deprecated: Use OpUnary_Kind.Descriptor instead.
 func (OpUnary_Kind) EnumDescriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{13, 0}
+	return file_biscuit_proto_rawDescGZIP(), []int{20, 0}
 }
 
 type OpBinary_Kind int32
 
 const (
-	OpBinary_LessThan       OpBinary_Kind = 0
-	OpBinary_GreaterThan    OpBinary_Kind = 1
-	OpBinary_LessOrEqual    OpBinary_Kind = 2
-	OpBinary_GreaterOrEqual OpBinary_Kind = 3
-	OpBinary_Equal          OpBinary_Kind = 4
-	OpBinary_Contains       OpBinary_Kind = 5
-	OpBinary_Prefix         OpBinary_Kind = 6
-	OpBinary_Suffix         OpBinary_Kind = 7
-	OpBinary_Regex          OpBinary_Kind = 8
-	OpBinary_Add            OpBinary_Kind = 9
-	OpBinary_Sub            OpBinary_Kind = 10
-	OpBinary_Mul            OpBinary_Kind = 11
-	OpBinary_Div            OpBinary_Kind = 12
-	OpBinary_And            OpBinary_Kind = 13
-	OpBinary_Or             OpBinary_Kind = 14
-	OpBinary_Intersection   OpBinary_Kind = 15
-	OpBinary_Union          OpBinary_Kind = 16
+	OpBinary_LessThan         OpBinary_Kind = 0
+	OpBinary_GreaterThan      OpBinary_Kind = 1
+	OpBinary_LessOrEqual      OpBinary_Kind = 2
+	OpBinary_GreaterOrEqual   OpBinary_Kind = 3
+	OpBinary_Equal            OpBinary_Kind = 4
+	OpBinary_Contains         OpBinary_Kind = 5
+	OpBinary_Prefix           OpBinary_Kind = 6
+	OpBinary_Suffix           OpBinary_Kind = 7
+	OpBinary_Regex            OpBinary_Kind = 8
+	OpBinary_Add              OpBinary_Kind = 9
+	OpBinary_Sub              OpBinary_Kind = 10
+	OpBinary_Mul              OpBinary_Kind = 11
+	OpBinary_Div              OpBinary_Kind = 12
+	OpBinary_And              OpBinary_Kind = 13
+	OpBinary_Or               OpBinary_Kind = 14
+	OpBinary_Intersection     OpBinary_Kind = 15
+	OpBinary_Union            OpBinary_Kind = 16
+	OpBinary_BitwiseAnd       OpBinary_Kind = 17
+	OpBinary_NotEqual         OpBinary_Kind = 20
+	OpBinary_Get              OpBinary_Kind = 27
+	OpBinary_Subset           OpBinary_Kind = 64
+	OpBinary_Superset         OpBinary_Kind = 65
+	OpBinary_RegexInsensitive OpBinary_Kind = 66
 )
 
 // Enum value maps for OpBinary_Kind.
@@ -174,25 +304,37 @@ var (
 		14: "Or",
 		15: "Intersection",
 		16: "Union",
+		17: "BitwiseAnd",
+		20: "NotEqual",
+		27: "Get",
+		64: "Subset",
+		65: "Superset",
+		66: "RegexInsensitive",
 	}
 	OpBinary_Kind_value = map[string]int32{
-		"LessThan":       0,
-		"GreaterThan":    1,
-		"LessOrEqual":    2,
-		"GreaterOrEqual": 3,
-		"Equal":          4,
-		"Contains":       5,
-		"Prefix":         6,
-		"Suffix":         7,
-		"Regex":          8,
-		"Add":            9,
-		"Sub":            10,
-		"Mul":            11,
-		"Div":            12,
-		"And":            13,
-		"Or":             14,
-		"Intersection":   15,
-		"Union":          16,
+		"LessThan":         0,
+		"GreaterThan":      1,
+		"LessOrEqual":      2,
+		"GreaterOrEqual":   3,
+		"Equal":            4,
+		"Contains":         5,
+		"Prefix":           6,
+		"Suffix":           7,
+		"Regex":            8,
+		"Add":              9,
+		"Sub":              10,
+		"Mul":              11,
+		"Div":              12,
+		"And":              13,
+		"Or":               14,
+		"Intersection":     15,
+		"Union":            16,
+		"BitwiseAnd":       17,
+		"NotEqual":         20,
+		"Get":              27,
+		"Subset":           64,
+		"Superset":         65,
+		"RegexInsensitive": 66,
 	}
 )
 
@@ -207,11 +349,11 @@ func (x OpBinary_Kind) String() string {
 }
 
 func (OpBinary_Kind) Descriptor() protoreflect.EnumDescriptor {
-	return file_biscuit_proto_enumTypes[2].Descriptor()
+	return file_biscuit_proto_enumTypes[4].Descriptor()
 }
 
 func (OpBinary_Kind) Type() protoreflect.EnumType {
-	return &file_biscuit_proto_enumTypes[2]
+	return &file_biscuit_proto_enumTypes[4]
 }
 
 func (x OpBinary_Kind) Number() protoreflect.EnumNumber {
@@ -230,7 +372,7 @@ func (x *OpBinary_Kind) UnmarshalJSON(b []byte) error {
 
 // Deprecated: Use OpBinary_Kind.Descriptor instead.
 func (OpBinary_Kind) EnumDescriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{14, 0}
+	return file_biscuit_proto_rawDescGZIP(), []int{21, 0}
 }
 
 type Policy_Kind int32
@@ -263,11 +405,11 @@ func (x Policy_Kind) String() string {
 }
 
 func (Policy_Kind) Descriptor() protoreflect.EnumDescriptor {
-	return file_biscuit_proto_enumTypes[3].Descriptor()
+	return file_biscuit_proto_enumTypes[5].Descriptor()
 }
 
 func (Policy_Kind) Type() protoreflect.EnumType {
-	return &file_biscuit_proto_enumTypes[3]
+	return &file_biscuit_proto_enumTypes[5]
 }
 
 func (x Policy_Kind) Number() protoreflect.EnumNumber {
@@ -286,7 +428,7 @@ func (x *Policy_Kind) UnmarshalJSON(b []byte) error {
 
 // Deprecated: Use Policy_Kind.Descriptor instead.
 func (Policy_Kind) EnumDescriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{15, 0}
+	return file_biscuit_proto_rawDescGZIP(), []int{22, 0}
 }
 
 type Biscuit struct {
@@ -365,9 +507,10 @@ type SignedBlock struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Block     []byte     `protobuf:"bytes,1,req,name=block" json:"block,omitempty"`
-	NextKey   *PublicKey `protobuf:"bytes,2,req,name=nextKey" json:"nextKey,omitempty"`
-	Signature []byte     `protobuf:"bytes,3,req,name=signature" json:"signature,omitempty"`
+	Block             []byte             `protobuf:"bytes,1,req,name=block" json:"block,omitempty"`
+	NextKey           *PublicKey         `protobuf:"bytes,2,req,name=nextKey" json:"nextKey,omitempty"`
+	Signature         []byte             `protobuf:"bytes,3,req,name=signature" json:"signature,omitempty"`
+	ExternalSignature *ExternalSignature `protobuf:"bytes,4,opt,name=externalSignature" json:"externalSignature,omitempty"`
 }
 
 func (x *SignedBlock) Reset() {
@@ -423,6 +566,68 @@ func (x *SignedBlock) GetSignature() []byte {
 	return nil
 }
 
+func (x *SignedBlock) GetExternalSignature() *ExternalSignature {
+	if x != nil {
+		return x.ExternalSignature
+	}
+	return nil
+}
+
+type ExternalSignature struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Signature []byte     `protobuf:"bytes,1,req,name=signature" json:"signature,omitempty"`
+	PublicKey *PublicKey `protobuf:"bytes,2,req,name=publicKey" json:"publicKey,omitempty"`
+}
+
+func (x *ExternalSignature) Reset() {
+	*x = ExternalSignature{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_biscuit_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ExternalSignature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExternalSignature) ProtoMessage() {}
+
+func (x *ExternalSignature) ProtoReflect() protoreflect.Message {
+	mi := &file_biscuit_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExternalSignature.ProtoReflect.Descriptor instead.
+func (*ExternalSignature) Descriptor() ([]byte, []int) {
+	return file_biscuit_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ExternalSignature) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *ExternalSignature) GetPublicKey() *PublicKey {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
 type PublicKey struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -435,7 +640,7 @@ type PublicKey struct {
 func (x *PublicKey) Reset() {
 	*x = PublicKey{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[2]
+		mi := &file_biscuit_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -448,7 +653,7 @@ func (x *PublicKey) String() string {
 func (*PublicKey) ProtoMessage() {}
 
 func (x *PublicKey) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[2]
+	mi := &file_biscuit_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -461,7 +666,7 @@ func (x *PublicKey) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PublicKey.ProtoReflect.Descriptor instead.
 func (*PublicKey) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{2}
+	return file_biscuit_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *PublicKey) GetAlgorithm() PublicKey_Algorithm {
@@ -492,7 +697,7 @@ type Proof struct {
 func (x *Proof) Reset() {
 	*x = Proof{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[3]
+		mi := &file_biscuit_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -505,7 +710,7 @@ func (x *Proof) String() string {
 func (*Proof) ProtoMessage() {}
 
 func (x *Proof) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[3]
+	mi := &file_biscuit_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -518,7 +723,7 @@ func (x *Proof) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Proof.ProtoReflect.Descriptor instead.
 func (*Proof) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{3}
+	return file_biscuit_proto_rawDescGZIP(), []int{4}
 }
 
 func (m *Proof) GetContent() isProof_Content {
@@ -563,18 +768,20 @@ type Block struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Symbols  []string   `protobuf:"bytes,1,rep,name=symbols" json:"symbols,omitempty"`
-	Context  *string    `protobuf:"bytes,2,opt,name=context" json:"context,omitempty"`
-	Version  *uint32    `protobuf:"varint,3,opt,name=version" json:"version,omitempty"`
-	FactsV2  []*FactV2  `protobuf:"bytes,4,rep,name=facts_v2,json=factsV2" json:"facts_v2,omitempty"`
-	RulesV2  []*RuleV2  `protobuf:"bytes,5,rep,name=rules_v2,json=rulesV2" json:"rules_v2,omitempty"`
-	ChecksV2 []*CheckV2 `protobuf:"bytes,6,rep,name=checks_v2,json=checksV2" json:"checks_v2,omitempty"`
+	Symbols    []string     `protobuf:"bytes,1,rep,name=symbols" json:"symbols,omitempty"`
+	Context    *string      `protobuf:"bytes,2,opt,name=context" json:"context,omitempty"`
+	Version    *uint32      `protobuf:"varint,3,opt,name=version" json:"version,omitempty"`
+	FactsV2    []*FactV2    `protobuf:"bytes,4,rep,name=facts_v2,json=factsV2" json:"facts_v2,omitempty"`
+	RulesV2    []*RuleV2    `protobuf:"bytes,5,rep,name=rules_v2,json=rulesV2" json:"rules_v2,omitempty"`
+	ChecksV2   []*CheckV2   `protobuf:"bytes,6,rep,name=checks_v2,json=checksV2" json:"checks_v2,omitempty"`
+	Scope      []*Scope     `protobuf:"bytes,7,rep,name=scope" json:"scope,omitempty"`
+	PublicKeys []*PublicKey `protobuf:"bytes,8,rep,name=publicKeys" json:"publicKeys,omitempty"`
 }
 
 func (x *Block) Reset() {
 	*x = Block{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[4]
+		mi := &file_biscuit_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -587,7 +794,7 @@ func (x *Block) String() string {
 func (*Block) ProtoMessage() {}
 
 func (x *Block) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[4]
+	mi := &file_biscuit_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -600,7 +807,7 @@ func (x *Block) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Block.ProtoReflect.Descriptor instead.
 func (*Block) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{4}
+	return file_biscuit_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *Block) GetSymbols() []string {
@@ -645,6 +852,100 @@ func (x *Block) GetChecksV2() []*CheckV2 {
 	return nil
 }
 
+func (x *Block) GetScope() []*Scope {
+	if x != nil {
+		return x.Scope
+	}
+	return nil
+}
+
+func (x *Block) GetPublicKeys() []*PublicKey {
+	if x != nil {
+		return x.PublicKeys
+	}
+	return nil
+}
+
+type Scope struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Content:
+	//	*Scope_ScopeType_
+	//	*Scope_PublicKey
+	Content isScope_Content `protobuf_oneof:"Content"`
+}
+
+func (x *Scope) Reset() {
+	*x = Scope{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_biscuit_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Scope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Scope) ProtoMessage() {}
+
+func (x *Scope) ProtoReflect() protoreflect.Message {
+	mi := &file_biscuit_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Scope.ProtoReflect.Descriptor instead.
+func (*Scope) Descriptor() ([]byte, []int) {
+	return file_biscuit_proto_rawDescGZIP(), []int{6}
+}
+
+func (m *Scope) GetContent() isScope_Content {
+	if m != nil {
+		return m.Content
+	}
+	return nil
+}
+
+func (x *Scope) GetScopeType() Scope_ScopeType {
+	if x, ok := x.GetContent().(*Scope_ScopeType_); ok {
+		return x.ScopeType
+	}
+	return Scope_Authority
+}
+
+func (x *Scope) GetPublicKey() int64 {
+	if x, ok := x.GetContent().(*Scope_PublicKey); ok {
+		return x.PublicKey
+	}
+	return 0
+}
+
+type isScope_Content interface {
+	isScope_Content()
+}
+
+type Scope_ScopeType_ struct {
+	ScopeType Scope_ScopeType `protobuf:"varint,1,opt,name=scopeType,enum=Scope_ScopeType,oneof"`
+}
+
+type Scope_PublicKey struct {
+	PublicKey int64 `protobuf:"varint,2,opt,name=publicKey,oneof"`
+}
+
+func (*Scope_ScopeType_) isScope_Content() {}
+
+func (*Scope_PublicKey) isScope_Content() {}
+
 type FactV2 struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -656,7 +957,7 @@ type FactV2 struct {
 func (x *FactV2) Reset() {
 	*x = FactV2{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[5]
+		mi := &file_biscuit_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -669,7 +970,7 @@ func (x *FactV2) String() string {
 func (*FactV2) ProtoMessage() {}
 
 func (x *FactV2) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[5]
+	mi := &file_biscuit_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -682,7 +983,7 @@ func (x *FactV2) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FactV2.ProtoReflect.Descriptor instead.
 func (*FactV2) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{5}
+	return file_biscuit_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *FactV2) GetPredicate() *PredicateV2 {
@@ -700,12 +1001,13 @@ type RuleV2 struct {
 	Head        *PredicateV2    `protobuf:"bytes,1,req,name=head" json:"head,omitempty"`
 	Body        []*PredicateV2  `protobuf:"bytes,2,rep,name=body" json:"body,omitempty"`
 	Expressions []*ExpressionV2 `protobuf:"bytes,3,rep,name=expressions" json:"expressions,omitempty"`
+	Scope       []*Scope        `protobuf:"bytes,4,rep,name=scope" json:"scope,omitempty"`
 }
 
 func (x *RuleV2) Reset() {
 	*x = RuleV2{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[6]
+		mi := &file_biscuit_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -718,7 +1020,7 @@ func (x *RuleV2) String() string {
 func (*RuleV2) ProtoMessage() {}
 
 func (x *RuleV2) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[6]
+	mi := &file_biscuit_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -731,7 +1033,7 @@ func (x *RuleV2) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RuleV2.ProtoReflect.Descriptor instead.
 func (*RuleV2) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{6}
+	return file_biscuit_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *RuleV2) GetHead() *PredicateV2 {
@@ -755,18 +1057,26 @@ func (x *RuleV2) GetExpressions() []*ExpressionV2 {
 	return nil
 }
 
+func (x *RuleV2) GetScope() []*Scope {
+	if x != nil {
+		return x.Scope
+	}
+	return nil
+}
+
 type CheckV2 struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Queries []*RuleV2 `protobuf:"bytes,1,rep,name=queries" json:"queries,omitempty"`
+	Queries []*RuleV2     `protobuf:"bytes,1,rep,name=queries" json:"queries,omitempty"`
+	Kind    *CheckV2_Kind `protobuf:"varint,2,opt,name=kind,enum=CheckV2_Kind" json:"kind,omitempty"`
 }
 
 func (x *CheckV2) Reset() {
 	*x = CheckV2{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[7]
+		mi := &file_biscuit_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -779,7 +1089,7 @@ func (x *CheckV2) String() string {
 func (*CheckV2) ProtoMessage() {}
 
 func (x *CheckV2) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[7]
+	mi := &file_biscuit_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -792,7 +1102,7 @@ func (x *CheckV2) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CheckV2.ProtoReflect.Descriptor instead.
 func (*CheckV2) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{7}
+	return file_biscuit_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *CheckV2) GetQueries() []*RuleV2 {
@@ -802,6 +1112,13 @@ func (x *CheckV2) GetQueries() []*RuleV2 {
 	return nil
 }
 
+func (x *CheckV2) GetKind() CheckV2_Kind {
+	if x != nil && x.Kind != nil {
+		return *x.Kind
+	}
+	return CheckV2_One
+}
+
 type PredicateV2 struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -814,7 +1131,7 @@ type PredicateV2 struct {
 func (x *PredicateV2) Reset() {
 	*x = PredicateV2{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[8]
+		mi := &file_biscuit_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -827,7 +1144,7 @@ func (x *PredicateV2) String() string {
 func (*PredicateV2) ProtoMessage() {}
 
 func (x *PredicateV2) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[8]
+	mi := &file_biscuit_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -840,7 +1157,7 @@ func (x *PredicateV2) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PredicateV2.ProtoReflect.Descriptor instead.
 func (*PredicateV2) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{8}
+	return file_biscuit_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *PredicateV2) GetName() uint64 {
@@ -870,13 +1187,16 @@ type TermV2 struct {
 	//	*TermV2_Bytes
 	//	*TermV2_Bool
 	//	*TermV2_Set
+	//	*TermV2_Array
+	//	*TermV2_Map
+	//	*TermV2_Tagged
 	Content isTermV2_Content `protobuf_oneof:"Content"`
 }
 
 func (x *TermV2) Reset() {
 	*x = TermV2{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[9]
+		mi := &file_biscuit_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -889,7 +1209,7 @@ func (x *TermV2) String() string {
 func (*TermV2) ProtoMessage() {}
 
 func (x *TermV2) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[9]
+	mi := &file_biscuit_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -902,7 +1222,7 @@ func (x *TermV2) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TermV2.ProtoReflect.Descriptor instead.
 func (*TermV2) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{9}
+	return file_biscuit_proto_rawDescGZIP(), []int{11}
 }
 
 func (m *TermV2) GetContent() isTermV2_Content {
@@ -961,15 +1281,36 @@ func (x *TermV2) GetSet() *TermSet {
 	return nil
 }
 
-type isTermV2_Content interface {
-	isTermV2_Content()
+func (x *TermV2) GetArray() *Array {
+	if x, ok := x.GetContent().(*TermV2_Array); ok {
+		return x.Array
+	}
+	return nil
 }
 
-type TermV2_Variable struct {
-	Variable uint32 `protobuf:"varint,1,opt,name=variable,oneof"`
+func (x *TermV2) GetMap() *Map {
+	if x, ok := x.GetContent().(*TermV2_Map); ok {
+		return x.Map
+	}
+	return nil
 }
 
-type TermV2_Integer struct {
+func (x *TermV2) GetTagged() *Tagged {
+	if x, ok := x.GetContent().(*TermV2_Tagged); ok {
+		return x.Tagged
+	}
+	return nil
+}
+
+type isTermV2_Content interface {
+	isTermV2_Content()
+}
+
+type TermV2_Variable struct {
+	Variable uint32 `protobuf:"varint,1,opt,name=variable,oneof"`
+}
+
+type TermV2_Integer struct {
 	Integer int64 `protobuf:"varint,2,opt,name=integer,oneof"`
 }
 
@@ -993,6 +1334,18 @@ type TermV2_Set struct {
 	Set *TermSet `protobuf:"bytes,7,opt,name=set,oneof"`
 }
 
+type TermV2_Array struct {
+	Array *Array `protobuf:"bytes,9,opt,name=array,oneof"`
+}
+
+type TermV2_Map struct {
+	Map *Map `protobuf:"bytes,10,opt,name=map,oneof"`
+}
+
+type TermV2_Tagged struct {
+	Tagged *Tagged `protobuf:"bytes,64,opt,name=tagged,oneof"`
+}
+
 func (*TermV2_Variable) isTermV2_Content() {}
 
 func (*TermV2_Integer) isTermV2_Content() {}
@@ -1007,6 +1360,67 @@ func (*TermV2_Bool) isTermV2_Content() {}
 
 func (*TermV2_Set) isTermV2_Content() {}
 
+func (*TermV2_Array) isTermV2_Content() {}
+
+func (*TermV2_Map) isTermV2_Content() {}
+
+func (*TermV2_Tagged) isTermV2_Content() {}
+
+type Tagged struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Tag   *uint64 `protobuf:"varint,1,req,name=tag" json:"tag,omitempty"`
+	Value []byte  `protobuf:"bytes,2,req,name=value" json:"value,omitempty"`
+}
+
+func (x *Tagged) Reset() {
+	*x = Tagged{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_biscuit_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Tagged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tagged) ProtoMessage() {}
+
+func (x *Tagged) ProtoReflect() protoreflect.Message {
+	mi := &file_biscuit_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tagged.ProtoReflect.Descriptor instead.
+func (*Tagged) Descriptor() ([]byte, []int) {
+	return file_biscuit_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Tagged) GetTag() uint64 {
+	if x != nil && x.Tag != nil {
+		return *x.Tag
+	}
+	return 0
+}
+
+func (x *Tagged) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
 type TermSet struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -1018,7 +1432,7 @@ type TermSet struct {
 func (x *TermSet) Reset() {
 	*x = TermSet{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[10]
+		mi := &file_biscuit_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1031,7 +1445,7 @@ func (x *TermSet) String() string {
 func (*TermSet) ProtoMessage() {}
 
 func (x *TermSet) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[10]
+	mi := &file_biscuit_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1044,7 +1458,7 @@ func (x *TermSet) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TermSet.ProtoReflect.Descriptor instead.
 func (*TermSet) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{10}
+	return file_biscuit_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *TermSet) GetSet() []*TermV2 {
@@ -1054,6 +1468,235 @@ func (x *TermSet) GetSet() []*TermV2 {
 	return nil
 }
 
+type Array struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Array []*TermV2 `protobuf:"bytes,1,rep,name=array" json:"array,omitempty"`
+}
+
+func (x *Array) Reset() {
+	*x = Array{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_biscuit_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Array) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Array) ProtoMessage() {}
+
+func (x *Array) ProtoReflect() protoreflect.Message {
+	mi := &file_biscuit_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Array.ProtoReflect.Descriptor instead.
+func (*Array) Descriptor() ([]byte, []int) {
+	return file_biscuit_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Array) GetArray() []*TermV2 {
+	if x != nil {
+		return x.Array
+	}
+	return nil
+}
+
+type Map struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Entries []*MapEntry `protobuf:"bytes,1,rep,name=entries" json:"entries,omitempty"`
+}
+
+func (x *Map) Reset() {
+	*x = Map{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_biscuit_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Map) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Map) ProtoMessage() {}
+
+func (x *Map) ProtoReflect() protoreflect.Message {
+	mi := &file_biscuit_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Map.ProtoReflect.Descriptor instead.
+func (*Map) Descriptor() ([]byte, []int) {
+	return file_biscuit_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Map) GetEntries() []*MapEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type MapEntry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key   *MapKey `protobuf:"bytes,1,req,name=key" json:"key,omitempty"`
+	Value *TermV2 `protobuf:"bytes,2,req,name=value" json:"value,omitempty"`
+}
+
+func (x *MapEntry) Reset() {
+	*x = MapEntry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_biscuit_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MapEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MapEntry) ProtoMessage() {}
+
+func (x *MapEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_biscuit_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MapEntry.ProtoReflect.Descriptor instead.
+func (*MapEntry) Descriptor() ([]byte, []int) {
+	return file_biscuit_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *MapEntry) GetKey() *MapKey {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *MapEntry) GetValue() *TermV2 {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+type MapKey struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Content:
+	//	*MapKey_Integer
+	//	*MapKey_String_
+	Content isMapKey_Content `protobuf_oneof:"Content"`
+}
+
+func (x *MapKey) Reset() {
+	*x = MapKey{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_biscuit_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MapKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MapKey) ProtoMessage() {}
+
+func (x *MapKey) ProtoReflect() protoreflect.Message {
+	mi := &file_biscuit_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MapKey.ProtoReflect.Descriptor instead.
+func (*MapKey) Descriptor() ([]byte, []int) {
+	return file_biscuit_proto_rawDescGZIP(), []int{17}
+}
+
+func (m *MapKey) GetContent() isMapKey_Content {
+	if m != nil {
+		return m.Content
+	}
+	return nil
+}
+
+func (x *MapKey) GetInteger() int64 {
+	if x, ok := x.GetContent().(*MapKey_Integer); ok {
+		return x.Integer
+	}
+	return 0
+}
+
+func (x *MapKey) GetString_() uint64 {
+	if x, ok := x.GetContent().(*MapKey_String_); ok {
+		return x.String_
+	}
+	return 0
+}
+
+type isMapKey_Content interface {
+	isMapKey_Content()
+}
+
+type MapKey_Integer struct {
+	Integer int64 `protobuf:"varint,1,opt,name=integer,oneof"`
+}
+
+type MapKey_String_ struct {
+	String_ uint64 `protobuf:"varint,2,opt,name=string,oneof"`
+}
+
+func (*MapKey_Integer) isMapKey_Content() {}
+
+func (*MapKey_String_) isMapKey_Content() {}
+
 type ExpressionV2 struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -1065,7 +1708,7 @@ type ExpressionV2 struct {
 func (x *ExpressionV2) Reset() {
 	*x = ExpressionV2{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[11]
+		mi := &file_biscuit_proto_msgTypes[18]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1078,7 +1721,7 @@ func (x *ExpressionV2) String() string {
 func (*ExpressionV2) ProtoMessage() {}
 
 func (x *ExpressionV2) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[11]
+	mi := &file_biscuit_proto_msgTypes[18]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1091,7 +1734,7 @@ func (x *ExpressionV2) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExpressionV2.ProtoReflect.Descriptor instead.
 func (*ExpressionV2) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{11}
+	return file_biscuit_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *ExpressionV2) GetOps() []*Op {
@@ -1116,7 +1759,7 @@ type Op struct {
 func (x *Op) Reset() {
 	*x = Op{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[12]
+		mi := &file_biscuit_proto_msgTypes[19]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1129,7 +1772,7 @@ func (x *Op) String() string {
 func (*Op) ProtoMessage() {}
 
 func (x *Op) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[12]
+	mi := &file_biscuit_proto_msgTypes[19]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1142,7 +1785,7 @@ func (x *Op) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Op.ProtoReflect.Descriptor instead.
 func (*Op) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{12}
+	return file_biscuit_proto_rawDescGZIP(), []int{19}
 }
 
 func (m *Op) GetContent() isOp_Content {
@@ -1206,7 +1849,7 @@ type OpUnary struct {
 func (x *OpUnary) Reset() {
 	*x = OpUnary{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[13]
+		mi := &file_biscuit_proto_msgTypes[20]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1219,7 +1862,7 @@ func (x *OpUnary) String() string {
 func (*OpUnary) ProtoMessage() {}
 
 func (x *OpUnary) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[13]
+	mi := &file_biscuit_proto_msgTypes[20]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1232,7 +1875,7 @@ func (x *OpUnary) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use OpUnary.ProtoReflect.Descriptor instead.
 func (*OpUnary) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{13}
+	return file_biscuit_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *OpUnary) GetKind() OpUnary_Kind {
@@ -1253,7 +1896,7 @@ type OpBinary struct {
 func (x *OpBinary) Reset() {
 	*x = OpBinary{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[14]
+		mi := &file_biscuit_proto_msgTypes[21]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1266,7 +1909,7 @@ func (x *OpBinary) String() string {
 func (*OpBinary) ProtoMessage() {}
 
 func (x *OpBinary) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[14]
+	mi := &file_biscuit_proto_msgTypes[21]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1279,7 +1922,7 @@ func (x *OpBinary) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use OpBinary.ProtoReflect.Descriptor instead.
 func (*OpBinary) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{14}
+	return file_biscuit_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *OpBinary) GetKind() OpBinary_Kind {
@@ -1301,7 +1944,7 @@ type Policy struct {
 func (x *Policy) Reset() {
 	*x = Policy{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[15]
+		mi := &file_biscuit_proto_msgTypes[22]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1314,7 +1957,7 @@ func (x *Policy) String() string {
 func (*Policy) ProtoMessage() {}
 
 func (x *Policy) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[15]
+	mi := &file_biscuit_proto_msgTypes[22]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1327,7 +1970,7 @@ func (x *Policy) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Policy.ProtoReflect.Descriptor instead.
 func (*Policy) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{15}
+	return file_biscuit_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *Policy) GetQueries() []*RuleV2 {
@@ -1360,7 +2003,7 @@ type AuthorizerPolicies struct {
 func (x *AuthorizerPolicies) Reset() {
 	*x = AuthorizerPolicies{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_biscuit_proto_msgTypes[16]
+		mi := &file_biscuit_proto_msgTypes[23]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1373,7 +2016,7 @@ func (x *AuthorizerPolicies) String() string {
 func (*AuthorizerPolicies) ProtoMessage() {}
 
 func (x *AuthorizerPolicies) ProtoReflect() protoreflect.Message {
-	mi := &file_biscuit_proto_msgTypes[16]
+	mi := &file_biscuit_proto_msgTypes[23]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1386,7 +2029,7 @@ func (x *AuthorizerPolicies) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AuthorizerPolicies.ProtoReflect.Descriptor instead.
 func (*AuthorizerPolicies) Descriptor() ([]byte, []int) {
-	return file_biscuit_proto_rawDescGZIP(), []int{16}
+	return file_biscuit_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *AuthorizerPolicies) GetSymbols() []string {
@@ -1431,6 +2074,116 @@ func (x *AuthorizerPolicies) GetPolicies() []*Policy {
 	return nil
 }
 
+type ThirdPartyBlockRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PreviousKey *PublicKey   `protobuf:"bytes,1,req,name=previousKey" json:"previousKey,omitempty"`
+	PublicKeys  []*PublicKey `protobuf:"bytes,2,rep,name=publicKeys" json:"publicKeys,omitempty"`
+}
+
+func (x *ThirdPartyBlockRequest) Reset() {
+	*x = ThirdPartyBlockRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_biscuit_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ThirdPartyBlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ThirdPartyBlockRequest) ProtoMessage() {}
+
+func (x *ThirdPartyBlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_biscuit_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ThirdPartyBlockRequest.ProtoReflect.Descriptor instead.
+func (*ThirdPartyBlockRequest) Descriptor() ([]byte, []int) {
+	return file_biscuit_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ThirdPartyBlockRequest) GetPreviousKey() *PublicKey {
+	if x != nil {
+		return x.PreviousKey
+	}
+	return nil
+}
+
+func (x *ThirdPartyBlockRequest) GetPublicKeys() []*PublicKey {
+	if x != nil {
+		return x.PublicKeys
+	}
+	return nil
+}
+
+type ThirdPartyBlockContents struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Payload           []byte             `protobuf:"bytes,1,req,name=payload" json:"payload,omitempty"`
+	ExternalSignature *ExternalSignature `protobuf:"bytes,2,req,name=externalSignature" json:"externalSignature,omitempty"`
+}
+
+func (x *ThirdPartyBlockContents) Reset() {
+	*x = ThirdPartyBlockContents{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_biscuit_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ThirdPartyBlockContents) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ThirdPartyBlockContents) ProtoMessage() {}
+
+func (x *ThirdPartyBlockContents) ProtoReflect() protoreflect.Message {
+	mi := &file_biscuit_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ThirdPartyBlockContents.ProtoReflect.Descriptor instead.
+func (*ThirdPartyBlockContents) Descriptor() ([]byte, []int) {
+	return file_biscuit_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ThirdPartyBlockContents) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *ThirdPartyBlockContents) GetExternalSignature() *ExternalSignature {
+	if x != nil {
+		return x.ExternalSignature
+	}
+	return nil
+}
+
 var File_biscuit_proto protoreflect.FileDescriptor
 
 var file_biscuit_proto_rawDesc = []byte{
@@ -1444,14 +2197,24 @@ var file_biscuit_proto_rawDesc = []byte{
 	0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x53, 0x69, 0x67, 0x6e, 0x65, 0x64, 0x42, 0x6c,
 	0x6f, 0x63, 0x6b, 0x52, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12, 0x1c, 0x0a, 0x05, 0x70,
 	0x72, 0x6f, 0x6f, 0x66, 0x18, 0x04, 0x20, 0x02, 0x28, 0x0b, 0x32, 0x06, 0x2e, 0x50, 0x72, 0x6f,
-	0x6f, 0x66, 0x52, 0x05, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x22, 0x67, 0x0a, 0x0b, 0x53, 0x69, 0x67,
-	0x6e, 0x65, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x6c, 0x6f, 0x63,
-	0x6b, 0x18, 0x01, 0x20, 0x02, 0x28, 0x0c, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x24,
-	0x0a, 0x07, 0x6e, 0x65, 0x78, 0x74, 0x4b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x02, 0x28, 0x0b, 0x32,
-	0x0a, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x52, 0x07, 0x6e, 0x65, 0x78,
-	0x74, 0x4b, 0x65, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72,
-	0x65, 0x18, 0x03, 0x20, 0x02, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75,
-	0x72, 0x65, 0x22, 0x6b, 0x0a, 0x09, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12,
+	0x6f, 0x66, 0x52, 0x05, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x22, 0xa9, 0x01, 0x0a, 0x0b, 0x53, 0x69,
+	0x67, 0x6e, 0x65, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x6c, 0x6f,
+	0x63, 0x6b, 0x18, 0x01, 0x20, 0x02, 0x28, 0x0c, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x12,
+	0x24, 0x0a, 0x07, 0x6e, 0x65, 0x78, 0x74, 0x4b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x02, 0x28, 0x0b,
+	0x32, 0x0a, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x52, 0x07, 0x6e, 0x65,
+	0x78, 0x74, 0x4b, 0x65, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75,
+	0x72, 0x65, 0x18, 0x03, 0x20, 0x02, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74,
+	0x75, 0x72, 0x65, 0x12, 0x40, 0x0a, 0x11, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x53,
+	0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12,
+	0x2e, 0x45, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75,
+	0x72, 0x65, 0x52, 0x11, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x53, 0x69, 0x67, 0x6e,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x22, 0x5b, 0x0a, 0x11, 0x45, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61,
+	0x6c, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69,
+	0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x01, 0x20, 0x02, 0x28, 0x0c, 0x52, 0x09, 0x73,
+	0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x28, 0x0a, 0x09, 0x70, 0x75, 0x62, 0x6c,
+	0x69, 0x63, 0x4b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x02, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x50, 0x75,
+	0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x52, 0x09, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b,
+	0x65, 0x79, 0x22, 0x6b, 0x0a, 0x09, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x12,
 	0x32, 0x0a, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x18, 0x01, 0x20, 0x02,
 	0x28, 0x0e, 0x32, 0x14, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x2e, 0x41,
 	0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x52, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69,
@@ -1464,7 +2227,7 @@ var file_biscuit_proto_rawDesc = []byte{
 	0x6e, 0x61, 0x6c, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01,
 	0x28, 0x0c, 0x48, 0x00, 0x52, 0x0e, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x53, 0x69, 0x67, 0x6e, 0x61,
 	0x74, 0x75, 0x72, 0x65, 0x42, 0x09, 0x0a, 0x07, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x22,
-	0xc4, 0x01, 0x0a, 0x05, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x79, 0x6d,
+	0x8e, 0x02, 0x0a, 0x05, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x79, 0x6d,
 	0x62, 0x6f, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x73, 0x79, 0x6d, 0x62,
 	0x6f, 0x6c, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x18, 0x02,
 	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x12, 0x18, 0x0a,
@@ -1476,92 +2239,158 @@ var file_biscuit_proto_rawDesc = []byte{
 	0x52, 0x75, 0x6c, 0x65, 0x56, 0x32, 0x52, 0x07, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x56, 0x32, 0x12,
 	0x25, 0x0a, 0x09, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x5f, 0x76, 0x32, 0x18, 0x06, 0x20, 0x03,
 	0x28, 0x0b, 0x32, 0x08, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x56, 0x32, 0x52, 0x08, 0x63, 0x68,
-	0x65, 0x63, 0x6b, 0x73, 0x56, 0x32, 0x22, 0x34, 0x0a, 0x06, 0x46, 0x61, 0x63, 0x74, 0x56, 0x32,
-	0x12, 0x2a, 0x0a, 0x09, 0x70, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20,
-	0x02, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x56,
-	0x32, 0x52, 0x09, 0x70, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x22, 0x7d, 0x0a, 0x06,
-	0x52, 0x75, 0x6c, 0x65, 0x56, 0x32, 0x12, 0x20, 0x0a, 0x04, 0x68, 0x65, 0x61, 0x64, 0x18, 0x01,
-	0x20, 0x02, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65,
-	0x56, 0x32, 0x52, 0x04, 0x68, 0x65, 0x61, 0x64, 0x12, 0x20, 0x0a, 0x04, 0x62, 0x6f, 0x64, 0x79,
-	0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61,
-	0x74, 0x65, 0x56, 0x32, 0x52, 0x04, 0x62, 0x6f, 0x64, 0x79, 0x12, 0x2f, 0x0a, 0x0b, 0x65, 0x78,
-	0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x0d, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x56, 0x32, 0x52, 0x0b,
-	0x65, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x2c, 0x0a, 0x07, 0x43,
-	0x68, 0x65, 0x63, 0x6b, 0x56, 0x32, 0x12, 0x21, 0x0a, 0x07, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65,
-	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x52, 0x75, 0x6c, 0x65, 0x56, 0x32,
-	0x52, 0x07, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73, 0x22, 0x40, 0x0a, 0x0b, 0x50, 0x72, 0x65,
-	0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x56, 0x32, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65,
-	0x18, 0x01, 0x20, 0x02, 0x28, 0x04, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1d, 0x0a, 0x05,
-	0x74, 0x65, 0x72, 0x6d, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x54, 0x65,
-	0x72, 0x6d, 0x56, 0x32, 0x52, 0x05, 0x74, 0x65, 0x72, 0x6d, 0x73, 0x22, 0xc9, 0x01, 0x0a, 0x06,
-	0x54, 0x65, 0x72, 0x6d, 0x56, 0x32, 0x12, 0x1c, 0x0a, 0x08, 0x76, 0x61, 0x72, 0x69, 0x61, 0x62,
-	0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x00, 0x52, 0x08, 0x76, 0x61, 0x72, 0x69,
-	0x61, 0x62, 0x6c, 0x65, 0x12, 0x1a, 0x0a, 0x07, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x65, 0x72, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x07, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x65, 0x72,
-	0x12, 0x18, 0x0a, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04,
-	0x48, 0x00, 0x52, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x12, 0x14, 0x0a, 0x04, 0x64, 0x61,
-	0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x04, 0x64, 0x61, 0x74, 0x65,
-	0x12, 0x16, 0x0a, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x48,
-	0x00, 0x52, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x04, 0x62, 0x6f, 0x6f, 0x6c,
-	0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x48, 0x00, 0x52, 0x04, 0x62, 0x6f, 0x6f, 0x6c, 0x12, 0x1c,
-	0x0a, 0x03, 0x73, 0x65, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x54, 0x65,
-	0x72, 0x6d, 0x53, 0x65, 0x74, 0x48, 0x00, 0x52, 0x03, 0x73, 0x65, 0x74, 0x42, 0x09, 0x0a, 0x07,
-	0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x22, 0x24, 0x0a, 0x07, 0x54, 0x65, 0x72, 0x6d, 0x53,
-	0x65, 0x74, 0x12, 0x19, 0x0a, 0x03, 0x73, 0x65, 0x74, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x07, 0x2e, 0x54, 0x65, 0x72, 0x6d, 0x56, 0x32, 0x52, 0x03, 0x73, 0x65, 0x74, 0x22, 0x25, 0x0a,
-	0x0c, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x56, 0x32, 0x12, 0x15, 0x0a,
-	0x03, 0x6f, 0x70, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x03, 0x2e, 0x4f, 0x70, 0x52,
-	0x03, 0x6f, 0x70, 0x73, 0x22, 0x77, 0x0a, 0x02, 0x4f, 0x70, 0x12, 0x1f, 0x0a, 0x05, 0x76, 0x61,
-	0x6c, 0x75, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x54, 0x65, 0x72, 0x6d,
-	0x56, 0x32, 0x48, 0x00, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x20, 0x0a, 0x05, 0x75,
-	0x6e, 0x61, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x4f, 0x70, 0x55,
-	0x6e, 0x61, 0x72, 0x79, 0x48, 0x00, 0x52, 0x05, 0x75, 0x6e, 0x61, 0x72, 0x79, 0x12, 0x23, 0x0a,
-	0x06, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x09, 0x2e,
-	0x4f, 0x70, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x48, 0x00, 0x52, 0x06, 0x42, 0x69, 0x6e, 0x61,
-	0x72, 0x79, 0x42, 0x09, 0x0a, 0x07, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x22, 0x58, 0x0a,
-	0x07, 0x4f, 0x70, 0x55, 0x6e, 0x61, 0x72, 0x79, 0x12, 0x21, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64,
-	0x18, 0x01, 0x20, 0x02, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x4f, 0x70, 0x55, 0x6e, 0x61, 0x72, 0x79,
-	0x2e, 0x4b, 0x69, 0x6e, 0x64, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x22, 0x2a, 0x0a, 0x04, 0x4b,
-	0x69, 0x6e, 0x64, 0x12, 0x0a, 0x0a, 0x06, 0x4e, 0x65, 0x67, 0x61, 0x74, 0x65, 0x10, 0x00, 0x12,
-	0x0a, 0x0a, 0x06, 0x50, 0x61, 0x72, 0x65, 0x6e, 0x73, 0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06, 0x4c,
-	0x65, 0x6e, 0x67, 0x74, 0x68, 0x10, 0x02, 0x22, 0x89, 0x02, 0x0a, 0x08, 0x4f, 0x70, 0x42, 0x69,
-	0x6e, 0x61, 0x72, 0x79, 0x12, 0x22, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x02,
-	0x28, 0x0e, 0x32, 0x0e, 0x2e, 0x4f, 0x70, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x2e, 0x4b, 0x69,
-	0x6e, 0x64, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x22, 0xd8, 0x01, 0x0a, 0x04, 0x4b, 0x69, 0x6e,
-	0x64, 0x12, 0x0c, 0x0a, 0x08, 0x4c, 0x65, 0x73, 0x73, 0x54, 0x68, 0x61, 0x6e, 0x10, 0x00, 0x12,
-	0x0f, 0x0a, 0x0b, 0x47, 0x72, 0x65, 0x61, 0x74, 0x65, 0x72, 0x54, 0x68, 0x61, 0x6e, 0x10, 0x01,
-	0x12, 0x0f, 0x0a, 0x0b, 0x4c, 0x65, 0x73, 0x73, 0x4f, 0x72, 0x45, 0x71, 0x75, 0x61, 0x6c, 0x10,
-	0x02, 0x12, 0x12, 0x0a, 0x0e, 0x47, 0x72, 0x65, 0x61, 0x74, 0x65, 0x72, 0x4f, 0x72, 0x45, 0x71,
-	0x75, 0x61, 0x6c, 0x10, 0x03, 0x12, 0x09, 0x0a, 0x05, 0x45, 0x71, 0x75, 0x61, 0x6c, 0x10, 0x04,
-	0x12, 0x0c, 0x0a, 0x08, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x73, 0x10, 0x05, 0x12, 0x0a,
-	0x0a, 0x06, 0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x10, 0x06, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x75,
-	0x66, 0x66, 0x69, 0x78, 0x10, 0x07, 0x12, 0x09, 0x0a, 0x05, 0x52, 0x65, 0x67, 0x65, 0x78, 0x10,
-	0x08, 0x12, 0x07, 0x0a, 0x03, 0x41, 0x64, 0x64, 0x10, 0x09, 0x12, 0x07, 0x0a, 0x03, 0x53, 0x75,
-	0x62, 0x10, 0x0a, 0x12, 0x07, 0x0a, 0x03, 0x4d, 0x75, 0x6c, 0x10, 0x0b, 0x12, 0x07, 0x0a, 0x03,
-	0x44, 0x69, 0x76, 0x10, 0x0c, 0x12, 0x07, 0x0a, 0x03, 0x41, 0x6e, 0x64, 0x10, 0x0d, 0x12, 0x06,
-	0x0a, 0x02, 0x4f, 0x72, 0x10, 0x0e, 0x12, 0x10, 0x0a, 0x0c, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x73,
-	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x10, 0x0f, 0x12, 0x09, 0x0a, 0x05, 0x55, 0x6e, 0x69, 0x6f,
-	0x6e, 0x10, 0x10, 0x22, 0x6a, 0x0a, 0x06, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x21, 0x0a,
-	0x07, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x07,
-	0x2e, 0x52, 0x75, 0x6c, 0x65, 0x56, 0x32, 0x52, 0x07, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73,
-	0x12, 0x20, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x02, 0x28, 0x0e, 0x32, 0x0c,
-	0x2e, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x2e, 0x4b, 0x69, 0x6e, 0x64, 0x52, 0x04, 0x6b, 0x69,
-	0x6e, 0x64, 0x22, 0x1b, 0x0a, 0x04, 0x4b, 0x69, 0x6e, 0x64, 0x12, 0x09, 0x0a, 0x05, 0x41, 0x6c,
-	0x6c, 0x6f, 0x77, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x44, 0x65, 0x6e, 0x79, 0x10, 0x01, 0x22,
-	0xcd, 0x01, 0x0a, 0x12, 0x41, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x72, 0x50, 0x6f,
-	0x6c, 0x69, 0x63, 0x69, 0x65, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x79, 0x6d, 0x62, 0x6f, 0x6c,
-	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x73, 0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x73,
-	0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x0d, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1d, 0x0a, 0x05, 0x66, 0x61,
-	0x63, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x46, 0x61, 0x63, 0x74,
-	0x56, 0x32, 0x52, 0x05, 0x66, 0x61, 0x63, 0x74, 0x73, 0x12, 0x1d, 0x0a, 0x05, 0x72, 0x75, 0x6c,
-	0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x52, 0x75, 0x6c, 0x65, 0x56,
-	0x32, 0x52, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x12, 0x20, 0x0a, 0x06, 0x63, 0x68, 0x65, 0x63,
-	0x6b, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b,
-	0x56, 0x32, 0x52, 0x06, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x12, 0x23, 0x0a, 0x08, 0x70, 0x6f,
-	0x6c, 0x69, 0x63, 0x69, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x50,
-	0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x08, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65, 0x73, 0x42,
+	0x65, 0x63, 0x6b, 0x73, 0x56, 0x32, 0x12, 0x1c, 0x0a, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x18,
+	0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x06, 0x2e, 0x53, 0x63, 0x6f, 0x70, 0x65, 0x52, 0x05, 0x73,
+	0x63, 0x6f, 0x70, 0x65, 0x12, 0x2a, 0x0a, 0x0a, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65,
+	0x79, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69,
+	0x63, 0x4b, 0x65, 0x79, 0x52, 0x0a, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x73,
+	0x22, 0x8e, 0x01, 0x0a, 0x05, 0x53, 0x63, 0x6f, 0x70, 0x65, 0x12, 0x30, 0x0a, 0x09, 0x73, 0x63,
+	0x6f, 0x70, 0x65, 0x54, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x10, 0x2e,
+	0x53, 0x63, 0x6f, 0x70, 0x65, 0x2e, 0x53, 0x63, 0x6f, 0x70, 0x65, 0x54, 0x79, 0x70, 0x65, 0x48,
+	0x00, 0x52, 0x09, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1e, 0x0a, 0x09,
+	0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x48,
+	0x00, 0x52, 0x09, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x22, 0x28, 0x0a, 0x09,
+	0x53, 0x63, 0x6f, 0x70, 0x65, 0x54, 0x79, 0x70, 0x65, 0x12, 0x0d, 0x0a, 0x09, 0x41, 0x75, 0x74,
+	0x68, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x10, 0x00, 0x12, 0x0c, 0x0a, 0x08, 0x50, 0x72, 0x65, 0x76,
+	0x69, 0x6f, 0x75, 0x73, 0x10, 0x01, 0x42, 0x09, 0x0a, 0x07, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e,
+	0x74, 0x22, 0x34, 0x0a, 0x06, 0x46, 0x61, 0x63, 0x74, 0x56, 0x32, 0x12, 0x2a, 0x0a, 0x09, 0x70,
+	0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x02, 0x28, 0x0b, 0x32, 0x0c,
+	0x2e, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x56, 0x32, 0x52, 0x09, 0x70, 0x72,
+	0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x22, 0x9b, 0x01, 0x0a, 0x06, 0x52, 0x75, 0x6c, 0x65,
+	0x56, 0x32, 0x12, 0x20, 0x0a, 0x04, 0x68, 0x65, 0x61, 0x64, 0x18, 0x01, 0x20, 0x02, 0x28, 0x0b,
+	0x32, 0x0c, 0x2e, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x56, 0x32, 0x52, 0x04,
+	0x68, 0x65, 0x61, 0x64, 0x12, 0x20, 0x0a, 0x04, 0x62, 0x6f, 0x64, 0x79, 0x18, 0x02, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x56, 0x32,
+	0x52, 0x04, 0x62, 0x6f, 0x64, 0x79, 0x12, 0x2f, 0x0a, 0x0b, 0x65, 0x78, 0x70, 0x72, 0x65, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x45, 0x78,
+	0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x56, 0x32, 0x52, 0x0b, 0x65, 0x78, 0x70, 0x72,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1c, 0x0a, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65,
+	0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x06, 0x2e, 0x53, 0x63, 0x6f, 0x70, 0x65, 0x52, 0x05,
+	0x73, 0x63, 0x6f, 0x70, 0x65, 0x22, 0x75, 0x0a, 0x07, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x56, 0x32,
+	0x12, 0x21, 0x0a, 0x07, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x07, 0x2e, 0x52, 0x75, 0x6c, 0x65, 0x56, 0x32, 0x52, 0x07, 0x71, 0x75, 0x65, 0x72,
+	0x69, 0x65, 0x73, 0x12, 0x21, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0e, 0x32, 0x0d, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x56, 0x32, 0x2e, 0x4b, 0x69, 0x6e, 0x64,
+	0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x22, 0x24, 0x0a, 0x04, 0x4b, 0x69, 0x6e, 0x64, 0x12, 0x07,
+	0x0a, 0x03, 0x4f, 0x6e, 0x65, 0x10, 0x00, 0x12, 0x07, 0x0a, 0x03, 0x41, 0x6c, 0x6c, 0x10, 0x01,
+	0x12, 0x0a, 0x0a, 0x06, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x10, 0x02, 0x22, 0x40, 0x0a, 0x0b,
+	0x50, 0x72, 0x65, 0x64, 0x69, 0x63, 0x61, 0x74, 0x65, 0x56, 0x32, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x02, 0x28, 0x04, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
+	0x1d, 0x0a, 0x05, 0x74, 0x65, 0x72, 0x6d, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x07,
+	0x2e, 0x54, 0x65, 0x72, 0x6d, 0x56, 0x32, 0x52, 0x05, 0x74, 0x65, 0x72, 0x6d, 0x73, 0x22, 0xa6,
+	0x02, 0x0a, 0x06, 0x54, 0x65, 0x72, 0x6d, 0x56, 0x32, 0x12, 0x1c, 0x0a, 0x08, 0x76, 0x61, 0x72,
+	0x69, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x00, 0x52, 0x08, 0x76,
+	0x61, 0x72, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x1a, 0x0a, 0x07, 0x69, 0x6e, 0x74, 0x65, 0x67,
+	0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x07, 0x69, 0x6e, 0x74, 0x65,
+	0x67, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x12, 0x14, 0x0a,
+	0x04, 0x64, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x04, 0x64,
+	0x61, 0x74, 0x65, 0x12, 0x16, 0x0a, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x0c, 0x48, 0x00, 0x52, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x04, 0x62,
+	0x6f, 0x6f, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x48, 0x00, 0x52, 0x04, 0x62, 0x6f, 0x6f,
+	0x6c, 0x12, 0x1c, 0x0a, 0x03, 0x73, 0x65, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08,
+	0x2e, 0x54, 0x65, 0x72, 0x6d, 0x53, 0x65, 0x74, 0x48, 0x00, 0x52, 0x03, 0x73, 0x65, 0x74, 0x12,
+	0x1e, 0x0a, 0x05, 0x61, 0x72, 0x72, 0x61, 0x79, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x06,
+	0x2e, 0x41, 0x72, 0x72, 0x61, 0x79, 0x48, 0x00, 0x52, 0x05, 0x61, 0x72, 0x72, 0x61, 0x79, 0x12,
+	0x18, 0x0a, 0x03, 0x6d, 0x61, 0x70, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x04, 0x2e, 0x4d,
+	0x61, 0x70, 0x48, 0x00, 0x52, 0x03, 0x6d, 0x61, 0x70, 0x12, 0x21, 0x0a, 0x06, 0x74, 0x61, 0x67,
+	0x67, 0x65, 0x64, 0x18, 0x40, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x54, 0x61, 0x67, 0x67,
+	0x65, 0x64, 0x48, 0x00, 0x52, 0x06, 0x74, 0x61, 0x67, 0x67, 0x65, 0x64, 0x42, 0x09, 0x0a, 0x07,
+	0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x22, 0x30, 0x0a, 0x06, 0x54, 0x61, 0x67, 0x67, 0x65,
+	0x64, 0x12, 0x10, 0x0a, 0x03, 0x74, 0x61, 0x67, 0x18, 0x01, 0x20, 0x02, 0x28, 0x04, 0x52, 0x03,
+	0x74, 0x61, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x02,
+	0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x24, 0x0a, 0x07, 0x54, 0x65, 0x72,
+	0x6d, 0x53, 0x65, 0x74, 0x12, 0x19, 0x0a, 0x03, 0x73, 0x65, 0x74, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x07, 0x2e, 0x54, 0x65, 0x72, 0x6d, 0x56, 0x32, 0x52, 0x03, 0x73, 0x65, 0x74, 0x22,
+	0x26, 0x0a, 0x05, 0x41, 0x72, 0x72, 0x61, 0x79, 0x12, 0x1d, 0x0a, 0x05, 0x61, 0x72, 0x72, 0x61,
+	0x79, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x54, 0x65, 0x72, 0x6d, 0x56, 0x32,
+	0x52, 0x05, 0x61, 0x72, 0x72, 0x61, 0x79, 0x22, 0x2a, 0x0a, 0x03, 0x4d, 0x61, 0x70, 0x12, 0x23,
+	0x0a, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x09, 0x2e, 0x4d, 0x61, 0x70, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x65, 0x6e, 0x74, 0x72,
+	0x69, 0x65, 0x73, 0x22, 0x44, 0x0a, 0x08, 0x4d, 0x61, 0x70, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x19, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x02, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x4d,
+	0x61, 0x70, 0x4b, 0x65, 0x79, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x1d, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x02, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x54, 0x65, 0x72, 0x6d,
+	0x56, 0x32, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x49, 0x0a, 0x06, 0x4d, 0x61, 0x70,
+	0x4b, 0x65, 0x79, 0x12, 0x1a, 0x0a, 0x07, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x65, 0x72, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x07, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x65, 0x72, 0x12,
+	0x18, 0x0a, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x48,
+	0x00, 0x52, 0x06, 0x73, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x42, 0x09, 0x0a, 0x07, 0x43, 0x6f, 0x6e,
+	0x74, 0x65, 0x6e, 0x74, 0x22, 0x25, 0x0a, 0x0c, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x56, 0x32, 0x12, 0x15, 0x0a, 0x03, 0x6f, 0x70, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x03, 0x2e, 0x4f, 0x70, 0x52, 0x03, 0x6f, 0x70, 0x73, 0x22, 0x77, 0x0a, 0x02, 0x4f,
+	0x70, 0x12, 0x1f, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x07, 0x2e, 0x54, 0x65, 0x72, 0x6d, 0x56, 0x32, 0x48, 0x00, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x12, 0x20, 0x0a, 0x05, 0x75, 0x6e, 0x61, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x08, 0x2e, 0x4f, 0x70, 0x55, 0x6e, 0x61, 0x72, 0x79, 0x48, 0x00, 0x52, 0x05, 0x75,
+	0x6e, 0x61, 0x72, 0x79, 0x12, 0x23, 0x0a, 0x06, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x4f, 0x70, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x48,
+	0x00, 0x52, 0x06, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x42, 0x09, 0x0a, 0x07, 0x43, 0x6f, 0x6e,
+	0x74, 0x65, 0x6e, 0x74, 0x22, 0x7f, 0x0a, 0x07, 0x4f, 0x70, 0x55, 0x6e, 0x61, 0x72, 0x79, 0x12,
+	0x21, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x02, 0x28, 0x0e, 0x32, 0x0d, 0x2e,
+	0x4f, 0x70, 0x55, 0x6e, 0x61, 0x72, 0x79, 0x2e, 0x4b, 0x69, 0x6e, 0x64, 0x52, 0x04, 0x6b, 0x69,
+	0x6e, 0x64, 0x22, 0x51, 0x0a, 0x04, 0x4b, 0x69, 0x6e, 0x64, 0x12, 0x0a, 0x0a, 0x06, 0x4e, 0x65,
+	0x67, 0x61, 0x74, 0x65, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x50, 0x61, 0x72, 0x65, 0x6e, 0x73,
+	0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06, 0x4c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x10, 0x02, 0x12, 0x0e,
+	0x0a, 0x0a, 0x43, 0x68, 0x61, 0x72, 0x4c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x10, 0x40, 0x12, 0x0a,
+	0x0a, 0x06, 0x54, 0x6f, 0x44, 0x61, 0x74, 0x65, 0x10, 0x41, 0x12, 0x09, 0x0a, 0x05, 0x54, 0x6f,
+	0x49, 0x6e, 0x74, 0x10, 0x42, 0x22, 0xe0, 0x02, 0x0a, 0x08, 0x4f, 0x70, 0x42, 0x69, 0x6e, 0x61,
+	0x72, 0x79, 0x12, 0x22, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x02, 0x28, 0x0e,
+	0x32, 0x0e, 0x2e, 0x4f, 0x70, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x2e, 0x4b, 0x69, 0x6e, 0x64,
+	0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x22, 0xaf, 0x02, 0x0a, 0x04, 0x4b, 0x69, 0x6e, 0x64, 0x12,
+	0x0c, 0x0a, 0x08, 0x4c, 0x65, 0x73, 0x73, 0x54, 0x68, 0x61, 0x6e, 0x10, 0x00, 0x12, 0x0f, 0x0a,
+	0x0b, 0x47, 0x72, 0x65, 0x61, 0x74, 0x65, 0x72, 0x54, 0x68, 0x61, 0x6e, 0x10, 0x01, 0x12, 0x0f,
+	0x0a, 0x0b, 0x4c, 0x65, 0x73, 0x73, 0x4f, 0x72, 0x45, 0x71, 0x75, 0x61, 0x6c, 0x10, 0x02, 0x12,
+	0x12, 0x0a, 0x0e, 0x47, 0x72, 0x65, 0x61, 0x74, 0x65, 0x72, 0x4f, 0x72, 0x45, 0x71, 0x75, 0x61,
+	0x6c, 0x10, 0x03, 0x12, 0x09, 0x0a, 0x05, 0x45, 0x71, 0x75, 0x61, 0x6c, 0x10, 0x04, 0x12, 0x0c,
+	0x0a, 0x08, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x73, 0x10, 0x05, 0x12, 0x0a, 0x0a, 0x06,
+	0x50, 0x72, 0x65, 0x66, 0x69, 0x78, 0x10, 0x06, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x75, 0x66, 0x66,
+	0x69, 0x78, 0x10, 0x07, 0x12, 0x09, 0x0a, 0x05, 0x52, 0x65, 0x67, 0x65, 0x78, 0x10, 0x08, 0x12,
+	0x07, 0x0a, 0x03, 0x41, 0x64, 0x64, 0x10, 0x09, 0x12, 0x07, 0x0a, 0x03, 0x53, 0x75, 0x62, 0x10,
+	0x0a, 0x12, 0x07, 0x0a, 0x03, 0x4d, 0x75, 0x6c, 0x10, 0x0b, 0x12, 0x07, 0x0a, 0x03, 0x44, 0x69,
+	0x76, 0x10, 0x0c, 0x12, 0x07, 0x0a, 0x03, 0x41, 0x6e, 0x64, 0x10, 0x0d, 0x12, 0x06, 0x0a, 0x02,
+	0x4f, 0x72, 0x10, 0x0e, 0x12, 0x10, 0x0a, 0x0c, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x10, 0x0f, 0x12, 0x09, 0x0a, 0x05, 0x55, 0x6e, 0x69, 0x6f, 0x6e, 0x10,
+	0x10, 0x12, 0x0e, 0x0a, 0x0a, 0x42, 0x69, 0x74, 0x77, 0x69, 0x73, 0x65, 0x41, 0x6e, 0x64, 0x10,
+	0x11, 0x12, 0x0c, 0x0a, 0x08, 0x4e, 0x6f, 0x74, 0x45, 0x71, 0x75, 0x61, 0x6c, 0x10, 0x14, 0x12,
+	0x07, 0x0a, 0x03, 0x47, 0x65, 0x74, 0x10, 0x1b, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x75, 0x62, 0x73,
+	0x65, 0x74, 0x10, 0x40, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x75, 0x70, 0x65, 0x72, 0x73, 0x65, 0x74,
+	0x10, 0x41, 0x12, 0x14, 0x0a, 0x10, 0x52, 0x65, 0x67, 0x65, 0x78, 0x49, 0x6e, 0x73, 0x65, 0x6e,
+	0x73, 0x69, 0x74, 0x69, 0x76, 0x65, 0x10, 0x42, 0x22, 0x6a, 0x0a, 0x06, 0x50, 0x6f, 0x6c, 0x69,
+	0x63, 0x79, 0x12, 0x21, 0x0a, 0x07, 0x71, 0x75, 0x65, 0x72, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x07, 0x2e, 0x52, 0x75, 0x6c, 0x65, 0x56, 0x32, 0x52, 0x07, 0x71, 0x75,
+	0x65, 0x72, 0x69, 0x65, 0x73, 0x12, 0x20, 0x0a, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x18, 0x02, 0x20,
+	0x02, 0x28, 0x0e, 0x32, 0x0c, 0x2e, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x2e, 0x4b, 0x69, 0x6e,
+	0x64, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x22, 0x1b, 0x0a, 0x04, 0x4b, 0x69, 0x6e, 0x64, 0x12,
+	0x09, 0x0a, 0x05, 0x41, 0x6c, 0x6c, 0x6f, 0x77, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x44, 0x65,
+	0x6e, 0x79, 0x10, 0x01, 0x22, 0xcd, 0x01, 0x0a, 0x12, 0x41, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69,
+	0x7a, 0x65, 0x72, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x73,
+	0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x73, 0x79,
+	0x6d, 0x62, 0x6f, 0x6c, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12,
+	0x1d, 0x0a, 0x05, 0x66, 0x61, 0x63, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x07,
+	0x2e, 0x46, 0x61, 0x63, 0x74, 0x56, 0x32, 0x52, 0x05, 0x66, 0x61, 0x63, 0x74, 0x73, 0x12, 0x1d,
+	0x0a, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x07, 0x2e,
+	0x52, 0x75, 0x6c, 0x65, 0x56, 0x32, 0x52, 0x05, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x12, 0x20, 0x0a,
+	0x06, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x08, 0x2e,
+	0x43, 0x68, 0x65, 0x63, 0x6b, 0x56, 0x32, 0x52, 0x06, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x12,
+	0x23, 0x0a, 0x08, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x07, 0x2e, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x08, 0x70, 0x6f, 0x6c, 0x69,
+	0x63, 0x69, 0x65, 0x73, 0x22, 0x72, 0x0a, 0x16, 0x54, 0x68, 0x69, 0x72, 0x64, 0x50, 0x61, 0x72,
+	0x74, 0x79, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2c,
+	0x0a, 0x0b, 0x70, 0x72, 0x65, 0x76, 0x69, 0x6f, 0x75, 0x73, 0x4b, 0x65, 0x79, 0x18, 0x01, 0x20,
+	0x02, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x52,
+	0x0b, 0x70, 0x72, 0x65, 0x76, 0x69, 0x6f, 0x75, 0x73, 0x4b, 0x65, 0x79, 0x12, 0x2a, 0x0a, 0x0a,
+	0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x0a, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x52, 0x0a, 0x70, 0x75,
+	0x62, 0x6c, 0x69, 0x63, 0x4b, 0x65, 0x79, 0x73, 0x22, 0x75, 0x0a, 0x17, 0x54, 0x68, 0x69, 0x72,
+	0x64, 0x50, 0x61, 0x72, 0x74, 0x79, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x43, 0x6f, 0x6e, 0x74, 0x65,
+	0x6e, 0x74, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x01,
+	0x20, 0x02, 0x28, 0x0c, 0x52, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x12, 0x40, 0x0a,
+	0x11, 0x65, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75,
+	0x72, 0x65, 0x18, 0x02, 0x20, 0x02, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x45, 0x78, 0x74, 0x65, 0x72,
+	0x6e, 0x61, 0x6c, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x11, 0x65, 0x78,
+	0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x42,
 	0x06, 0x5a, 0x04, 0x2e, 0x3b, 0x70, 0x62,
 }
 
@@ -1577,65 +2406,93 @@ func file_biscuit_proto_rawDescGZIP() []byte {
 	return file_biscuit_proto_rawDescData
 }
 
-var file_biscuit_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
-var file_biscuit_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_biscuit_proto_enumTypes = make([]protoimpl.EnumInfo, 6)
+var file_biscuit_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
 var file_biscuit_proto_goTypes = []interface{}{
-	(PublicKey_Algorithm)(0),   // 0: PublicKey.Algorithm
-	(OpUnary_Kind)(0),          // 1: OpUnary.Kind
-	(OpBinary_Kind)(0),         // 2: OpBinary.Kind
-	(Policy_Kind)(0),           // 3: Policy.Kind
-	(*Biscuit)(nil),            // 4: Biscuit
-	(*SignedBlock)(nil),        // 5: SignedBlock
-	(*PublicKey)(nil),          // 6: PublicKey
-	(*Proof)(nil),              // 7: Proof
-	(*Block)(nil),              // 8: Block
-	(*FactV2)(nil),             // 9: FactV2
-	(*RuleV2)(nil),             // 10: RuleV2
-	(*CheckV2)(nil),            // 11: CheckV2
-	(*PredicateV2)(nil),        // 12: PredicateV2
-	(*TermV2)(nil),             // 13: TermV2
-	(*TermSet)(nil),            // 14: TermSet
-	(*ExpressionV2)(nil),       // 15: ExpressionV2
-	(*Op)(nil),                 // 16: Op
-	(*OpUnary)(nil),            // 17: OpUnary
-	(*OpBinary)(nil),           // 18: OpBinary
-	(*Policy)(nil),             // 19: Policy
-	(*AuthorizerPolicies)(nil), // 20: AuthorizerPolicies
+	(PublicKey_Algorithm)(0),        // 0: PublicKey.Algorithm
+	(Scope_ScopeType)(0),            // 1: Scope.ScopeType
+	(CheckV2_Kind)(0),               // 2: CheckV2.Kind
+	(OpUnary_Kind)(0),               // 3: OpUnary.Kind
+	(OpBinary_Kind)(0),              // 4: OpBinary.Kind
+	(Policy_Kind)(0),                // 5: Policy.Kind
+	(*Biscuit)(nil),                 // 6: Biscuit
+	(*SignedBlock)(nil),             // 7: SignedBlock
+	(*ExternalSignature)(nil),       // 8: ExternalSignature
+	(*PublicKey)(nil),               // 9: PublicKey
+	(*Proof)(nil),                   // 10: Proof
+	(*Block)(nil),                   // 11: Block
+	(*Scope)(nil),                   // 12: Scope
+	(*FactV2)(nil),                  // 13: FactV2
+	(*RuleV2)(nil),                  // 14: RuleV2
+	(*CheckV2)(nil),                 // 15: CheckV2
+	(*PredicateV2)(nil),             // 16: PredicateV2
+	(*TermV2)(nil),                  // 17: TermV2
+	(*Tagged)(nil),                  // 18: Tagged
+	(*TermSet)(nil),                 // 19: TermSet
+	(*Array)(nil),                   // 20: Array
+	(*Map)(nil),                     // 21: Map
+	(*MapEntry)(nil),                // 22: MapEntry
+	(*MapKey)(nil),                  // 23: MapKey
+	(*ExpressionV2)(nil),            // 24: ExpressionV2
+	(*Op)(nil),                      // 25: Op
+	(*OpUnary)(nil),                 // 26: OpUnary
+	(*OpBinary)(nil),                // 27: OpBinary
+	(*Policy)(nil),                  // 28: Policy
+	(*AuthorizerPolicies)(nil),      // 29: AuthorizerPolicies
+	(*ThirdPartyBlockRequest)(nil),  // 30: ThirdPartyBlockRequest
+	(*ThirdPartyBlockContents)(nil), // 31: ThirdPartyBlockContents
 }
 var file_biscuit_proto_depIdxs = []int32{
-	5,  // 0: Biscuit.authority:type_name -> SignedBlock
-	5,  // 1: Biscuit.blocks:type_name -> SignedBlock
-	7,  // 2: Biscuit.proof:type_name -> Proof
-	6,  // 3: SignedBlock.nextKey:type_name -> PublicKey
-	0,  // 4: PublicKey.algorithm:type_name -> PublicKey.Algorithm
-	9,  // 5: Block.facts_v2:type_name -> FactV2
-	10, // 6: Block.rules_v2:type_name -> RuleV2
-	11, // 7: Block.checks_v2:type_name -> CheckV2
-	12, // 8: FactV2.predicate:type_name -> PredicateV2
-	12, // 9: RuleV2.head:type_name -> PredicateV2
-	12, // 10: RuleV2.body:type_name -> PredicateV2
-	15, // 11: RuleV2.expressions:type_name -> ExpressionV2
-	10, // 12: CheckV2.queries:type_name -> RuleV2
-	13, // 13: PredicateV2.terms:type_name -> TermV2
-	14, // 14: TermV2.set:type_name -> TermSet
-	13, // 15: TermSet.set:type_name -> TermV2
-	16, // 16: ExpressionV2.ops:type_name -> Op
-	13, // 17: Op.value:type_name -> TermV2
-	17, // 18: Op.unary:type_name -> OpUnary
-	18, // 19: Op.Binary:type_name -> OpBinary
-	1,  // 20: OpUnary.kind:type_name -> OpUnary.Kind
-	2,  // 21: OpBinary.kind:type_name -> OpBinary.Kind
-	10, // 22: Policy.queries:type_name -> RuleV2
-	3,  // 23: Policy.kind:type_name -> Policy.Kind
-	9,  // 24: AuthorizerPolicies.facts:type_name -> FactV2
-	10, // 25: AuthorizerPolicies.rules:type_name -> RuleV2
-	11, // 26: AuthorizerPolicies.checks:type_name -> CheckV2
-	19, // 27: AuthorizerPolicies.policies:type_name -> Policy
-	28, // [28:28] is the sub-list for method output_type
-	28, // [28:28] is the sub-list for method input_type
-	28, // [28:28] is the sub-list for extension type_name
-	28, // [28:28] is the sub-list for extension extendee
-	0,  // [0:28] is the sub-list for field type_name
+	7,  // 0: Biscuit.authority:type_name -> SignedBlock
+	7,  // 1: Biscuit.blocks:type_name -> SignedBlock
+	10, // 2: Biscuit.proof:type_name -> Proof
+	9,  // 3: SignedBlock.nextKey:type_name -> PublicKey
+	8,  // 4: SignedBlock.externalSignature:type_name -> ExternalSignature
+	9,  // 5: ExternalSignature.publicKey:type_name -> PublicKey
+	0,  // 6: PublicKey.algorithm:type_name -> PublicKey.Algorithm
+	13, // 7: Block.facts_v2:type_name -> FactV2
+	14, // 8: Block.rules_v2:type_name -> RuleV2
+	15, // 9: Block.checks_v2:type_name -> CheckV2
+	12, // 10: Block.scope:type_name -> Scope
+	9,  // 11: Block.publicKeys:type_name -> PublicKey
+	1,  // 12: Scope.scopeType:type_name -> Scope.ScopeType
+	16, // 13: FactV2.predicate:type_name -> PredicateV2
+	16, // 14: RuleV2.head:type_name -> PredicateV2
+	16, // 15: RuleV2.body:type_name -> PredicateV2
+	24, // 16: RuleV2.expressions:type_name -> ExpressionV2
+	12, // 17: RuleV2.scope:type_name -> Scope
+	14, // 18: CheckV2.queries:type_name -> RuleV2
+	2,  // 19: CheckV2.kind:type_name -> CheckV2.Kind
+	17, // 20: PredicateV2.terms:type_name -> TermV2
+	19, // 21: TermV2.set:type_name -> TermSet
+	20, // 22: TermV2.array:type_name -> Array
+	21, // 23: TermV2.map:type_name -> Map
+	18, // 24: TermV2.tagged:type_name -> Tagged
+	17, // 25: TermSet.set:type_name -> TermV2
+	17, // 26: Array.array:type_name -> TermV2
+	22, // 27: Map.entries:type_name -> MapEntry
+	23, // 28: MapEntry.key:type_name -> MapKey
+	17, // 29: MapEntry.value:type_name -> TermV2
+	25, // 30: ExpressionV2.ops:type_name -> Op
+	17, // 31: Op.value:type_name -> TermV2
+	26, // 32: Op.unary:type_name -> OpUnary
+	27, // 33: Op.Binary:type_name -> OpBinary
+	3,  // 34: OpUnary.kind:type_name -> OpUnary.Kind
+	4,  // 35: OpBinary.kind:type_name -> OpBinary.Kind
+	14, // 36: Policy.queries:type_name -> RuleV2
+	5,  // 37: Policy.kind:type_name -> Policy.Kind
+	13, // 38: AuthorizerPolicies.facts:type_name -> FactV2
+	14, // 39: AuthorizerPolicies.rules:type_name -> RuleV2
+	15, // 40: AuthorizerPolicies.checks:type_name -> CheckV2
+	28, // 41: AuthorizerPolicies.policies:type_name -> Policy
+	9,  // 42: ThirdPartyBlockRequest.previousKey:type_name -> PublicKey
+	9,  // 43: ThirdPartyBlockRequest.publicKeys:type_name -> PublicKey
+	8,  // 44: ThirdPartyBlockContents.externalSignature:type_name -> ExternalSignature
+	45, // [45:45] is the sub-list for method output_type
+	45, // [45:45] is the sub-list for method input_type
+	45, // [45:45] is the sub-list for extension type_name
+	45, // [45:45] is the sub-list for extension extendee
+	0,  // [0:45] is the sub-list for field type_name
 }
 
 func init() { file_biscuit_proto_init() }
@@ -1669,7 +2526,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PublicKey); i {
+			switch v := v.(*ExternalSignature); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1681,7 +2538,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Proof); i {
+			switch v := v.(*PublicKey); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1693,7 +2550,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Block); i {
+			switch v := v.(*Proof); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1705,7 +2562,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*FactV2); i {
+			switch v := v.(*Block); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1717,7 +2574,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RuleV2); i {
+			switch v := v.(*Scope); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1729,7 +2586,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CheckV2); i {
+			switch v := v.(*FactV2); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1741,7 +2598,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PredicateV2); i {
+			switch v := v.(*RuleV2); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1753,7 +2610,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*TermV2); i {
+			switch v := v.(*CheckV2); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1765,7 +2622,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*TermSet); i {
+			switch v := v.(*PredicateV2); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1777,7 +2634,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ExpressionV2); i {
+			switch v := v.(*TermV2); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1789,7 +2646,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Op); i {
+			switch v := v.(*Tagged); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1801,7 +2658,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*OpUnary); i {
+			switch v := v.(*TermSet); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1813,7 +2670,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*OpBinary); i {
+			switch v := v.(*Array); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1825,7 +2682,7 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Policy); i {
+			switch v := v.(*Map); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1837,6 +2694,90 @@ func file_biscuit_proto_init() {
 			}
 		}
 		file_biscuit_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MapEntry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_biscuit_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MapKey); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_biscuit_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ExpressionV2); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_biscuit_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Op); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_biscuit_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OpUnary); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_biscuit_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OpBinary); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_biscuit_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Policy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_biscuit_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*AuthorizerPolicies); i {
 			case 0:
 				return &v.state
@@ -1848,12 +2789,40 @@ func file_biscuit_proto_init() {
 				return nil
 			}
 		}
+		file_biscuit_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ThirdPartyBlockRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_biscuit_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ThirdPartyBlockContents); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
-	file_biscuit_proto_msgTypes[3].OneofWrappers = []interface{}{
+	file_biscuit_proto_msgTypes[4].OneofWrappers = []interface{}{
 		(*Proof_NextSecret)(nil),
 		(*Proof_FinalSignature)(nil),
 	}
-	file_biscuit_proto_msgTypes[9].OneofWrappers = []interface{}{
+	file_biscuit_proto_msgTypes[6].OneofWrappers = []interface{}{
+		(*Scope_ScopeType_)(nil),
+		(*Scope_PublicKey)(nil),
+	}
+	file_biscuit_proto_msgTypes[11].OneofWrappers = []interface{}{
 		(*TermV2_Variable)(nil),
 		(*TermV2_Integer)(nil),
 		(*TermV2_String_)(nil),
@@ -1861,8 +2830,15 @@ func file_biscuit_proto_init() {
 		(*TermV2_Bytes)(nil),
 		(*TermV2_Bool)(nil),
 		(*TermV2_Set)(nil),
+		(*TermV2_Array)(nil),
+		(*TermV2_Map)(nil),
+		(*TermV2_Tagged)(nil),
+	}
+	file_biscuit_proto_msgTypes[17].OneofWrappers = []interface{}{
+		(*MapKey_Integer)(nil),
+		(*MapKey_String_)(nil),
 	}
-	file_biscuit_proto_msgTypes[12].OneofWrappers = []interface{}{
+	file_biscuit_proto_msgTypes[19].OneofWrappers = []interface{}{
 		(*Op_Value)(nil),
 		(*Op_Unary)(nil),
 		(*Op_Binary)(nil),
@@ -1872,8 +2848,8 @@ func file_biscuit_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_biscuit_proto_rawDesc,
-			NumEnums:      4,
-			NumMessages:   17,
+			NumEnums:      6,
+			NumMessages:   26,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,86 @@
+package biscuit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrSchemaMismatch is returned by NewFact when a fact does not match
+// the schema registered for its predicate.
+var ErrSchemaMismatch = errors.New("biscuit: fact does not match its predicate schema")
+
+type predicateSchema struct {
+	arity int
+	types []TermType
+}
+
+var predicateSchemas = struct {
+	sync.RWMutex
+	schemas map[string]predicateSchema
+}{schemas: make(map[string]predicateSchema)}
+
+var termTypeNames = map[TermType]string{
+	TermTypeSymbol:   "symbol",
+	TermTypeVariable: "variable",
+	TermTypeInteger:  "integer",
+	TermTypeString:   "string",
+	TermTypeDate:     "date",
+	TermTypeBytes:    "bytes",
+	TermTypeBool:     "bool",
+	TermTypeSet:      "set",
+	TermTypeArray:    "array",
+	TermTypeMap:      "map",
+	TermTypeTagged:   "tagged",
+}
+
+// RegisterPredicateSchema registers the number of terms of the facts
+// named name, and the type of each term. When types is nil, only the number
+// of terms is checked. The schemas are used by NewFact, predicates without
+// a schema accept any term. Registering a predicate again replaces its schema.
+// It panics when types is not nil and does not have arity elements.
+func RegisterPredicateSchema(name string, arity int, types []TermType) {
+	if types != nil && len(types) != arity {
+		panic(fmt.Sprintf("biscuit: schema of %s has %d types for %d terms", name, len(types), arity))
+	}
+
+	predicateSchemas.Lock()
+	defer predicateSchemas.Unlock()
+	predicateSchemas.schemas[name] = predicateSchema{
+		arity: arity,
+		types: append([]TermType{}, types...),
+	}
+}
+
+// UnregisterPredicateSchema removes the schema registered for name, if any.
+func UnregisterPredicateSchema(name string) {
+	predicateSchemas.Lock()
+	defer predicateSchemas.Unlock()
+	delete(predicateSchemas.schemas, name)
+}
+
+// NewFact returns the fact name(terms...), after checking it against the schema
+// registered for name with RegisterPredicateSchema. It returns ErrSchemaMismatch
+// when the fact does not have the registered number of terms, or when a term
+// does not have the registered type.
+func NewFact(name string, terms ...Term) (Fact, error) {
+	fact := Fact{Predicate: Predicate{Name: name, IDs: terms}}
+
+	predicateSchemas.RLock()
+	schema, ok := predicateSchemas.schemas[name]
+	predicateSchemas.RUnlock()
+	if !ok {
+		return fact, nil
+	}
+
+	if len(terms) != schema.arity {
+		return Fact{}, fmt.Errorf("%w: %s has %d terms, expected %d", ErrSchemaMismatch, fact, len(terms), schema.arity)
+	}
+	for i, expected := range schema.types {
+		if got := terms[i].Type(); got != expected {
+			return Fact{}, fmt.Errorf("%w: term %d of %s has type %s, expected %s", ErrSchemaMismatch, i, fact, termTypeNames[got], termTypeNames[expected])
+		}
+	}
+
+	return fact, nil
+}
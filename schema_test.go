@@ -0,0 +1,56 @@
+package biscuit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredicateSchema(t *testing.T) {
+	RegisterPredicateSchema("right", 2, []TermType{TermTypeString, TermTypeString})
+	RegisterPredicateSchema("expiration", 1, nil)
+	t.Cleanup(func() {
+		UnregisterPredicateSchema("right")
+		UnregisterPredicateSchema("expiration")
+	})
+
+	testCases := []struct {
+		desc  string
+		name  string
+		terms []Term
+		err   bool
+	}{
+		{desc: "valid", name: "right", terms: []Term{String("/a"), String("read")}},
+		{desc: "wrong type", name: "right", terms: []Term{String("/a"), Integer(5)}, err: true},
+		{desc: "too few terms", name: "right", terms: []Term{String("/a")}, err: true},
+		{desc: "too many terms", name: "right", terms: []Term{String("/a"), String("read"), String("write")}, err: true},
+		{desc: "variable", name: "right", terms: []Term{String("/a"), Variable("op")}, err: true},
+		{desc: "arity only", name: "expiration", terms: []Term{Date(time.Now())}},
+		{desc: "arity only mismatch", name: "expiration", terms: []Term{}, err: true},
+		{desc: "unregistered", name: "owner", terms: []Term{Integer(1), Bool(true), String("x")}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fact, err := NewFact(tc.name, tc.terms...)
+			if tc.err {
+				require.ErrorIs(t, err, ErrSchemaMismatch)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, Fact{Predicate: Predicate{Name: tc.name, IDs: tc.terms}}, fact)
+		})
+	}
+
+	_, err := NewFact("right", String("/a"), Integer(5))
+	require.EqualError(t, err, `biscuit: fact does not match its predicate schema: term 1 of right("/a", 5) has type integer, expected string`)
+
+	require.Panics(t, func() {
+		RegisterPredicateSchema("invalid", 2, []TermType{TermTypeString})
+	})
+
+	UnregisterPredicateSchema("expiration")
+	_, err = NewFact("expiration")
+	require.NoError(t, err)
+}
@@ -0,0 +1,208 @@
+package biscuit
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"io"
+
+	"github.com/biscuit-auth/biscuit-go/v2/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// ThirdPartyRequest is sent by a token holder to an external party, so it
+// can sign a block that will be appended to the token. It carries the public
+// key of the token's last block, which the external signature is bound to,
+// and the token's public key table, which the block extends.
+type ThirdPartyRequest struct {
+	previousKey ed25519.PublicKey
+	publicKeys  publicKeyTable
+}
+
+// ThirdPartyBlock is a block signed by an external party, ready to be
+// appended to the token it was requested for.
+type ThirdPartyBlock struct {
+	contents *pb.ThirdPartyBlockContents
+}
+
+// ThirdPartyRequest creates a request for a third party block to be appended to the token.
+func (b *Biscuit) ThirdPartyRequest() (*ThirdPartyRequest, error) {
+	if _, err := b.nextPrivateKey(); err != nil {
+		return nil, err
+	}
+
+	return &ThirdPartyRequest{
+		previousKey: b.lastSignedBlock().NextKey.Key,
+		publicKeys:  b.publicKeys.Clone(),
+	}, nil
+}
+
+// AppendThirdParty appends a block signed by an external party. The block
+// must have been created from a request on this token.
+func (b *Biscuit) AppendThirdParty(rng io.Reader, block *ThirdPartyBlock) (*Biscuit, error) {
+	privateKey, err := b.nextPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	externalSignature := block.contents.ExternalSignature
+	if err := verifyExternalSignature(block.contents.Payload, externalSignature, b.lastSignedBlock().NextKey.Key); err != nil {
+		return nil, err
+	}
+
+	pbBlock := new(pb.Block)
+	if err := proto.Unmarshal(block.contents.Payload, pbBlock); err != nil {
+		return nil, err
+	}
+	publicKeys := b.publicKeys.Clone()
+	tokenBlock, err := protoBlockToTokenBlock(pbBlock, &publicKeys)
+	if err != nil {
+		return nil, err
+	}
+	tokenBlock.externalKey = externalSignature.PublicKey.Key
+	publicKeys.Insert(tokenBlock.externalKey)
+
+	return b.appendBlock(rng, privateKey, tokenBlock, block.contents.Payload, externalSignature, b.symbols.Clone(), publicKeys), nil
+}
+
+func (b *Biscuit) lastSignedBlock() *pb.SignedBlock {
+	if len(b.container.Blocks) == 0 {
+		return b.container.Authority
+	}
+	return b.container.Blocks[len(b.container.Blocks)-1]
+}
+
+// UnmarshalThirdPartyRequest decodes a serialized request.
+func UnmarshalThirdPartyRequest(serialized []byte) (*ThirdPartyRequest, error) {
+	request := new(pb.ThirdPartyBlockRequest)
+	if err := proto.Unmarshal(serialized, request); err != nil {
+		return nil, err
+	}
+
+	if request.PreviousKey.GetAlgorithm() != pb.PublicKey_Ed25519 {
+		return nil, UnsupportedAlgorithm
+	}
+	if len(request.PreviousKey.GetKey()) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKeySize
+	}
+
+	publicKeys := publicKeyTable{}
+	for _, pbKey := range request.PublicKeys {
+		if pbKey.GetAlgorithm() != pb.PublicKey_Ed25519 {
+			return nil, UnsupportedAlgorithm
+		}
+		if len(pbKey.Key) != ed25519.PublicKeySize {
+			return nil, ErrInvalidKeySize
+		}
+		publicKeys.Insert(pbKey.Key)
+	}
+
+	return &ThirdPartyRequest{
+		previousKey: request.PreviousKey.Key,
+		publicKeys:  publicKeys,
+	}, nil
+}
+
+func (r *ThirdPartyRequest) Serialize() ([]byte, error) {
+	algorithm := pb.PublicKey_Ed25519
+	publicKeys := make([]*pb.PublicKey, len(r.publicKeys))
+	for i, key := range r.publicKeys {
+		publicKeys[i] = &pb.PublicKey{
+			Algorithm: &algorithm,
+			Key:       key,
+		}
+	}
+
+	return proto.Marshal(&pb.ThirdPartyBlockRequest{
+		PreviousKey: &pb.PublicKey{
+			Algorithm: &algorithm,
+			Key:       r.previousKey,
+		},
+		PublicKeys: publicKeys,
+	})
+}
+
+// CreateBlock returns a builder for the third party block. Third party
+// blocks do not share the token's symbol table, so the builder starts
+// from the default symbols.
+func (r *ThirdPartyRequest) CreateBlock() BlockBuilder {
+	return NewBlockBuilder(defaultSymbolTable.Clone())
+}
+
+// Sign signs the block with the external party's private key.
+func (r *ThirdPartyRequest) Sign(privateKey ed25519.PrivateKey, block *Block) (*ThirdPartyBlock, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidKeySize
+	}
+
+	signed := *block
+	if signed.version < thirdPartySchemaVersion {
+		signed.version = thirdPartySchemaVersion
+	}
+
+	publicKeys := r.publicKeys.Clone()
+	protoBlock, err := tokenBlockToProtoBlock(&signed, &publicKeys)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := proto.Marshal(protoBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm := pb.PublicKey_Ed25519
+	return &ThirdPartyBlock{
+		contents: &pb.ThirdPartyBlockContents{
+			Payload: payload,
+			ExternalSignature: &pb.ExternalSignature{
+				Signature: ed25519.Sign(privateKey, externalSignaturePayload(payload, r.previousKey)),
+				PublicKey: &pb.PublicKey{
+					Algorithm: &algorithm,
+					Key:       privateKey.Public().(ed25519.PublicKey),
+				},
+			},
+		},
+	}, nil
+}
+
+// UnmarshalThirdPartyBlock decodes a serialized third party block.
+func UnmarshalThirdPartyBlock(serialized []byte) (*ThirdPartyBlock, error) {
+	contents := new(pb.ThirdPartyBlockContents)
+	if err := proto.Unmarshal(serialized, contents); err != nil {
+		return nil, err
+	}
+
+	if _, err := protoExternalSignatureKey(contents.ExternalSignature); err != nil {
+		return nil, err
+	}
+
+	return &ThirdPartyBlock{
+		contents: contents,
+	}, nil
+}
+
+func (b *ThirdPartyBlock) Serialize() ([]byte, error) {
+	return proto.Marshal(b.contents)
+}
+
+// externalSignaturePayload returns the data signed by an external key: the
+// block followed by the public key of the previous block, which prevents
+// the block from being appended to another token.
+func externalSignaturePayload(payload []byte, previousKey ed25519.PublicKey) []byte {
+	algorithm := make([]byte, 4)
+	binary.LittleEndian.PutUint32(algorithm[0:], uint32(pb.PublicKey_Ed25519))
+	toSign := append([]byte{}, payload...)
+	toSign = append(toSign, algorithm...)
+	return append(toSign, previousKey...)
+}
+
+func verifyExternalSignature(payload []byte, signature *pb.ExternalSignature, previousKey ed25519.PublicKey) error {
+	publicKey, err := protoExternalSignatureKey(signature)
+	if err != nil {
+		return err
+	}
+
+	if ok := ed25519.Verify(publicKey, externalSignaturePayload(payload, previousKey), signature.Signature); !ok {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package biscuit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/biscuit-auth/biscuit-go/v2/pb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestThirdPartyBlock(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+	externalPublic, externalPrivate, _ := ed25519.GenerateKey(rng)
+
+	builder := NewBuilder(privateRoot)
+	builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "right", IDs: []Term{String("file1")}}})
+	builder.AddAuthorityCheck(Check{Queries: []Rule{{
+		Head:  Predicate{Name: "query"},
+		Body:  []Predicate{{Name: "group", IDs: []Term{String("operators")}}},
+		Scope: []Scope{{Type: ScopeTypePublicKey, PublicKey: externalPublic}},
+	}}})
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	// the request and the signed block go through the external party
+	request, err := b.ThirdPartyRequest()
+	require.NoError(t, err)
+	serializedRequest, err := request.Serialize()
+	require.NoError(t, err)
+	request, err = UnmarshalThirdPartyRequest(serializedRequest)
+	require.NoError(t, err)
+
+	block := request.CreateBlock()
+	require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "group", IDs: []Term{String("operators")}}}))
+	require.NoError(t, block.AddCheck(Check{Queries: []Rule{{
+		Head: Predicate{Name: "query"},
+		Body: []Predicate{{Name: "right", IDs: []Term{String("file1")}}},
+	}}}))
+	thirdPartyBlock, err := request.Sign(externalPrivate, block.Build())
+	require.NoError(t, err)
+	serializedBlock, err := thirdPartyBlock.Serialize()
+	require.NoError(t, err)
+	thirdPartyBlock, err = UnmarshalThirdPartyBlock(serializedBlock)
+	require.NoError(t, err)
+
+	b2, err := b.AppendThirdParty(rng, thirdPartyBlock)
+	require.NoError(t, err)
+
+	serialized, err := b2.Serialize()
+	require.NoError(t, err)
+	b2, err = Unmarshal(serialized)
+	require.NoError(t, err)
+	require.Contains(t, b2.Code()[0], `group("operators")`)
+	require.Contains(t, b2.Code()[0], `check if right("file1")`)
+
+	v, err := b2.AuthorizerFor(WithSingularRootPublicKey(publicRoot))
+	require.NoError(t, err)
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+
+	t.Run("other token", func(t *testing.T) {
+		other, err := NewBuilder(privateRoot).Build()
+		require.NoError(t, err)
+
+		_, err = other.AppendThirdParty(rng, thirdPartyBlock)
+		require.Equal(t, ErrInvalidSignature, err)
+	})
+
+	t.Run("tampered external signature", func(t *testing.T) {
+		container := new(pb.Biscuit)
+		require.NoError(t, proto.Unmarshal(serialized, container))
+		container.Blocks[0].ExternalSignature.Signature[0] ^= 1
+		tampered, err := proto.Marshal(container)
+		require.NoError(t, err)
+
+		b, err := Unmarshal(tampered)
+		require.NoError(t, err)
+		_, err = b.AuthorizerFor(WithSingularRootPublicKey(publicRoot))
+		require.Equal(t, ErrInvalidSignature, err)
+	})
+}
+
+func TestWithTrustedExternalKeys(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+	externalPublic, externalPrivate, _ := ed25519.GenerateKey(rng)
+	otherPublic, _, _ := ed25519.GenerateKey(rng)
+
+	b, err := NewBuilder(privateRoot).Build()
+	require.NoError(t, err)
+	request, err := b.ThirdPartyRequest()
+	require.NoError(t, err)
+	block := request.CreateBlock()
+	require.NoError(t, block.AddFact(Fact{Predicate: Predicate{Name: "group", IDs: []Term{String("operators")}}}))
+	thirdPartyBlock, err := request.Sign(externalPrivate, block.Build())
+	require.NoError(t, err)
+	b, err = b.AppendThirdParty(rng, thirdPartyBlock)
+	require.NoError(t, err)
+
+	// by default, any external key is accepted
+	_, err = b.AuthorizerFor(WithSingularRootPublicKey(publicRoot))
+	require.NoError(t, err)
+
+	v, err := b.AuthorizerFor(WithSingularRootPublicKey(publicRoot), WithTrustedExternalKeys([]ed25519.PublicKey{otherPublic, externalPublic}))
+	require.NoError(t, err)
+	v.AddPolicy(DefaultAllowPolicy)
+	require.NoError(t, v.Authorize())
+
+	_, err = b.AuthorizerFor(WithSingularRootPublicKey(publicRoot), WithTrustedExternalKeys([]ed25519.PublicKey{otherPublic}))
+	require.ErrorIs(t, err, ErrUntrustedExternalKey)
+
+	_, err = b.AuthorizerFor(WithSingularRootPublicKey(publicRoot), WithTrustedExternalKeys(nil))
+	require.ErrorIs(t, err, ErrUntrustedExternalKey)
+
+	// tokens without third party blocks are not affected
+	b, err = NewBuilder(privateRoot).Build()
+	require.NoError(t, err)
+	_, err = b.AuthorizerFor(WithSingularRootPublicKey(publicRoot), WithTrustedExternalKeys(nil))
+	require.NoError(t, err)
+}
@@ -1,9 +1,12 @@
 package biscuit
 
 import (
+	"crypto/ed25519"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,7 +14,13 @@ import (
 )
 
 const MinSchemaVersion uint32 = 3
-const MaxSchemaVersion uint32 = 3
+const MaxSchemaVersion uint32 = 6
+
+// scopesSchemaVersion is the first block version supporting scopes
+const scopesSchemaVersion uint32 = 4
+
+// thirdPartySchemaVersion is the first block version supporting external signatures
+const thirdPartySchemaVersion uint32 = 4
 
 // defaultSymbolTable predefines some symbols available in every implementation, to avoid
 // transmitting them with every token
@@ -22,8 +31,219 @@ type Block struct {
 	facts   *datalog.FactSet
 	rules   []datalog.Rule
 	checks  []datalog.Check
+	scopes  []datalog.Scope
 	context string
 	version uint32
+
+	// externalKey is the public key of the external signature of
+	// a third party block, or nil for blocks signed by the token holder.
+	// Third party blocks do not share the token's symbol table.
+	externalKey ed25519.PublicKey
+}
+
+// withDefaults returns the block, or a copy of it completed with an empty
+// symbol table and fact set, and the lowest supported version, when those
+// are missing, such as for a zero Block.
+func (b *Block) withDefaults() *Block {
+	if b.symbols != nil && b.facts != nil && b.version != 0 {
+		return b
+	}
+
+	block := *b
+	if block.symbols == nil {
+		block.symbols = &datalog.SymbolTable{}
+	}
+	if block.facts == nil {
+		block.facts = &datalog.FactSet{}
+	}
+	if block.version == 0 {
+		block.version = blockSchemaVersion(block.facts, block.rules, block.checks)
+	}
+	return &block
+}
+
+// collectionsSchemaVersion is the first block version supporting arrays, maps and get
+const collectionsSchemaVersion uint32 = 6
+
+// notEqualSchemaVersion is the first block version supporting the != operation
+const notEqualSchemaVersion uint32 = 4
+
+// checkAllSchemaVersion is the first block version supporting `check all`
+const checkAllSchemaVersion uint32 = 4
+
+// rejectSchemaVersion is the first block version supporting `reject if`
+const rejectSchemaVersion uint32 = 6
+
+// bitwiseSchemaVersion is the first block version supporting the bitwise operations
+const bitwiseSchemaVersion uint32 = 4
+
+// extensionsSchemaVersion is the block version of the terms and operations
+// which are not part of the specification, such as tagged terms: it is the
+// highest supported version, so that earlier readers reject them
+const extensionsSchemaVersion = MaxSchemaVersion
+
+// blockSchemaVersion returns the lowest block version able to
+// represent the given facts, rules and checks.
+func blockSchemaVersion(facts *datalog.FactSet, rules []datalog.Rule, checks []datalog.Check) uint32 {
+	version := MinSchemaVersion
+	useVersion := func(v uint32) {
+		if v > version {
+			version = v
+		}
+	}
+
+	queries := append([]datalog.Rule{}, rules...)
+	for _, c := range checks {
+		switch c.Kind {
+		case datalog.CheckKindAll:
+			useVersion(checkAllSchemaVersion)
+		case datalog.CheckKindReject:
+			useVersion(rejectSchemaVersion)
+		}
+		queries = append(queries, c.Queries...)
+	}
+	for _, r := range queries {
+		if len(r.Scope) > 0 {
+			useVersion(scopesSchemaVersion)
+		}
+		if ruleUsesCollections(r) {
+			useVersion(collectionsSchemaVersion)
+		}
+		if ruleUsesTerm(r, isTagged) {
+			useVersion(extensionsSchemaVersion)
+		}
+		if ruleUsesBinaryOp(r, datalog.BinaryNotEqual) {
+			useVersion(notEqualSchemaVersion)
+		}
+		if ruleUsesBinaryOp(r, datalog.BinaryBitwiseAnd) {
+			useVersion(bitwiseSchemaVersion)
+		}
+		if ruleUsesExtensionOp(r) {
+			useVersion(extensionsSchemaVersion)
+		}
+	}
+	if facts != nil {
+		for _, f := range *facts {
+			if predicateUsesTerm(f.Predicate, isCollection) {
+				useVersion(collectionsSchemaVersion)
+			}
+			if predicateUsesTerm(f.Predicate, isTagged) {
+				useVersion(extensionsSchemaVersion)
+			}
+		}
+	}
+	return version
+}
+
+func ruleUsesCollections(r datalog.Rule) bool {
+	return ruleUsesTerm(r, isCollection) || ruleUsesBinaryOp(r, datalog.BinaryGet)
+}
+
+// ruleUsesTerm returns true when a predicate or an expression of the rule
+// contains a term matching the function.
+func ruleUsesTerm(r datalog.Rule, match func(datalog.Term) bool) bool {
+	if predicateUsesTerm(r.Head, match) {
+		return true
+	}
+	for _, p := range r.Body {
+		if predicateUsesTerm(p, match) {
+			return true
+		}
+	}
+	for _, e := range r.Expressions {
+		for _, op := range e {
+			if op.Type() == datalog.OpTypeValue && containsTerm(op.(datalog.Value).ID, match) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ruleUsesBinaryOp returns true when an expression of the rule uses the operation.
+func ruleUsesBinaryOp(r datalog.Rule, opType datalog.BinaryOpType) bool {
+	for _, e := range r.Expressions {
+		for _, op := range e {
+			if op.Type() == datalog.OpTypeBinary && op.(datalog.BinaryOp).BinaryOpFunc.Type() == opType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ruleUsesExtensionOp returns true when an expression of the rule uses
+// an operation which is not part of the specification.
+func ruleUsesExtensionOp(r datalog.Rule) bool {
+	for _, e := range r.Expressions {
+		for _, op := range e {
+			switch op.Type() {
+			case datalog.OpTypeUnary:
+				switch op.(datalog.UnaryOp).UnaryOpFunc.Type() {
+				case datalog.UnaryCharLength, datalog.UnaryToDate, datalog.UnaryToInt:
+					return true
+				}
+			case datalog.OpTypeBinary:
+				switch op.(datalog.BinaryOp).BinaryOpFunc.Type() {
+				case datalog.BinarySubset, datalog.BinarySuperset, datalog.BinaryRegexInsensitive:
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func predicateUsesTerm(p datalog.Predicate, match func(datalog.Term) bool) bool {
+	for _, t := range p.Terms {
+		if containsTerm(t, match) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsTerm returns true when the term, or one of the terms it contains,
+// matches the function.
+func containsTerm(t datalog.Term, match func(datalog.Term) bool) bool {
+	if match(t) {
+		return true
+	}
+	switch t := t.(type) {
+	case datalog.Set:
+		for _, e := range t {
+			if containsTerm(e, match) {
+				return true
+			}
+		}
+	case datalog.Array:
+		for _, e := range t {
+			if containsTerm(e, match) {
+				return true
+			}
+		}
+	case datalog.Map:
+		for k, v := range t {
+			if containsTerm(k, match) || containsTerm(v, match) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isCollection(t datalog.Term) bool {
+	return t.Type() == datalog.TermTypeArray || t.Type() == datalog.TermTypeMap
+}
+
+func isTagged(t datalog.Term) bool {
+	return t.Type() == datalog.TermTypeTagged
+}
+
+// Context returns the context set with BlockBuilder.SetContext,
+// or an empty string.
+func (b *Block) Context() string {
+	return b.context
 }
 
 func (b *Block) Code(symbols *datalog.SymbolTable) string {
@@ -108,6 +328,89 @@ type ParsedBlock struct {
 	Checks []Check
 }
 
+// ReservedPredicates lists the predicate names only the authority block
+// can declare, as checked by ParsedBlock.Validate.
+var ReservedPredicates = []string{"authority", "ambient", "revocation_id"}
+
+// ReservedPredicateError lists the facts, and the heads of the rules,
+// using a predicate from ReservedPredicates outside of the authority block.
+// It matches ErrInvalidBlockFact with errors.Is when Facts is not empty,
+// and ErrInvalidBlockRule when Rules is not empty.
+type ReservedPredicateError struct {
+	Facts []string
+	Rules []string
+}
+
+func (e ReservedPredicateError) Error() string {
+	misuses := make([]string, 0, len(e.Facts)+len(e.Rules))
+	for _, f := range e.Facts {
+		misuses = append(misuses, "fact "+f)
+	}
+	for _, r := range e.Rules {
+		misuses = append(misuses, "rule "+r)
+	}
+	return "biscuit: reserved predicates outside of the authority block: " + strings.Join(misuses, ", ")
+}
+
+func (e ReservedPredicateError) Is(target error) bool {
+	return (target == ErrInvalidBlockFact && len(e.Facts) > 0) ||
+		(target == ErrInvalidBlockRule && len(e.Rules) > 0)
+}
+
+// Validate returns a ReservedPredicateError listing the facts, and the rules
+// generating a fact, with a predicate from ReservedPredicates when the block
+// is not the authority block.
+func (pb ParsedBlock) Validate(isAuthority bool) error {
+	if isAuthority {
+		return nil
+	}
+
+	var e ReservedPredicateError
+	for _, f := range pb.Facts {
+		if isReservedPredicate(f.Name) {
+			e.Facts = append(e.Facts, f.String())
+		}
+	}
+	for _, r := range pb.Rules {
+		if isReservedPredicate(r.Head.Name) {
+			e.Rules = append(e.Rules, r.Head.String())
+		}
+	}
+	if len(e.Facts) > 0 || len(e.Rules) > 0 {
+		return e
+	}
+
+	return nil
+}
+
+// SymbolTable returns the symbols the block adds to a token's symbol table,
+// in the order a BlockBuilder inserts them: the predicate names, strings and
+// variable names of its facts, then its rules, then its checks. The default
+// symbols are not included. The table can be compared with another one using
+// SymbolTable.IsDisjoint, to detect an overlap before appending the block.
+func (pb ParsedBlock) SymbolTable() *datalog.SymbolTable {
+	symbols := &datalog.SymbolTable{}
+	for _, f := range pb.Facts {
+		f.convert(symbols)
+	}
+	for _, r := range pb.Rules {
+		r.convert(symbols)
+	}
+	for _, c := range pb.Checks {
+		c.convert(symbols)
+	}
+	return symbols
+}
+
+func isReservedPredicate(name string) bool {
+	for _, reserved := range ReservedPredicates {
+		if name == reserved {
+			return true
+		}
+	}
+	return false
+}
+
 type ParsedAuthorizer struct {
 	Policies []Policy
 	Block    ParsedBlock
@@ -168,6 +471,9 @@ func fromDatalogID(symbols *datalog.SymbolTable, id datalog.Term) (Term, error)
 		a = Bytes(id.(datalog.Bytes))
 	case datalog.TermTypeBool:
 		a = Bool(id.(datalog.Bool))
+	case datalog.TermTypeTagged:
+		tagged := id.(datalog.Tagged)
+		a = Tagged{Tag: symbols.Str(tagged.Tag), Value: Bytes(tagged.Value)}
 	case datalog.TermTypeSet:
 		setIDs := id.(datalog.Set)
 		set := make(Set, 0, len(setIDs))
@@ -179,6 +485,32 @@ func fromDatalogID(symbols *datalog.SymbolTable, id datalog.Term) (Term, error)
 			set = append(set, setTerm)
 		}
 		a = set
+	case datalog.TermTypeArray:
+		arrayIDs := id.(datalog.Array)
+		array := make(Array, 0, len(arrayIDs))
+		for _, i := range arrayIDs {
+			arrayTerm, err := fromDatalogID(symbols, i)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, arrayTerm)
+		}
+		a = array
+	case datalog.TermTypeMap:
+		mapIDs := id.(datalog.Map)
+		m := make(Map, len(mapIDs))
+		for k, v := range mapIDs {
+			key, err := fromDatalogID(symbols, k)
+			if err != nil {
+				return nil, err
+			}
+			value, err := fromDatalogID(symbols, v)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		a = m
 	default:
 		return nil, fmt.Errorf("unsupported term type: %v", id.Type())
 	}
@@ -190,6 +522,9 @@ type Rule struct {
 	Head        Predicate
 	Body        []Predicate
 	Expressions []Expression
+	// Scope lists the blocks the rule accepts facts from,
+	// the default scope is used when it is empty.
+	Scope []Scope
 }
 
 func (r Rule) convert(symbols *datalog.SymbolTable) datalog.Rule {
@@ -202,10 +537,15 @@ func (r Rule) convert(symbols *datalog.SymbolTable) datalog.Rule {
 	for i, e := range r.Expressions {
 		dlExpressions[i] = e.convert(symbols)
 	}
+	var dlScopes []datalog.Scope
+	for _, s := range r.Scope {
+		dlScopes = append(dlScopes, s.convert())
+	}
 	return datalog.Rule{
 		Head:        r.Head.convert(symbols),
 		Body:        dlBody,
 		Expressions: dlExpressions,
+		Scope:       dlScopes,
 	}
 }
 
@@ -233,13 +573,80 @@ func fromDatalogRule(symbols *datalog.SymbolTable, dlRule datalog.Rule) (*Rule,
 		expressions[i] = expr
 	}
 
+	var scopes []Scope
+	for _, dlScope := range dlRule.Scope {
+		scope, err := fromDatalogScope(dlScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert datalog rule scope: %v", err)
+		}
+		scopes = append(scopes, *scope)
+	}
+
 	return &Rule{
 		Head:        *head,
 		Body:        body,
 		Expressions: expressions,
+		Scope:       scopes,
 	}, nil
 }
 
+type ScopeType byte
+
+const (
+	// ScopeTypeAuthority trusts the facts from the authority block.
+	ScopeTypeAuthority ScopeType = iota
+	// ScopeTypePrevious trusts the facts from all the blocks before the current one.
+	ScopeTypePrevious
+	// ScopeTypePublicKey trusts the facts from the blocks signed with PublicKey.
+	ScopeTypePublicKey
+)
+
+// Scope is an element of a `trusting` annotation, restricting the
+// blocks a rule, check or policy can use facts from.
+type Scope struct {
+	Type      ScopeType
+	PublicKey ed25519.PublicKey
+}
+
+func (s Scope) convert() datalog.Scope {
+	switch s.Type {
+	case ScopeTypeAuthority:
+		return datalog.Scope{Type: datalog.ScopeTypeAuthority}
+	case ScopeTypePrevious:
+		return datalog.Scope{Type: datalog.ScopeTypePrevious}
+	case ScopeTypePublicKey:
+		return datalog.Scope{Type: datalog.ScopeTypePublicKey, PublicKey: s.PublicKey}
+	default:
+		panic(fmt.Sprintf("biscuit: cannot convert invalid scope type: %v", s.Type))
+	}
+}
+
+func (s Scope) String() string {
+	switch s.Type {
+	case ScopeTypeAuthority:
+		return "authority"
+	case ScopeTypePrevious:
+		return "previous"
+	case ScopeTypePublicKey:
+		return "ed25519/" + hex.EncodeToString(s.PublicKey)
+	default:
+		return fmt.Sprintf("<invalid scope %d>", s.Type)
+	}
+}
+
+func fromDatalogScope(dlScope datalog.Scope) (*Scope, error) {
+	switch dlScope.Type {
+	case datalog.ScopeTypeAuthority:
+		return &Scope{Type: ScopeTypeAuthority}, nil
+	case datalog.ScopeTypePrevious:
+		return &Scope{Type: ScopeTypePrevious}, nil
+	case datalog.ScopeTypePublicKey:
+		return &Scope{Type: ScopeTypePublicKey, PublicKey: ed25519.PublicKey(dlScope.PublicKey)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported datalog scope type: %v", dlScope.Type)
+	}
+}
+
 type Expression []Op
 
 func (e Expression) convert(symbols *datalog.SymbolTable) datalog.Expression {
@@ -250,6 +657,54 @@ func (e Expression) convert(symbols *datalog.SymbolTable) datalog.Expression {
 	return expr
 }
 
+// String returns the datalog source of the expression, such as `$r.starts_with("/a")`,
+// which can be parsed back. The symbols are not modified, and the default table is used when nil.
+func (e Expression) String(symbols *datalog.SymbolTable) string {
+	if symbols == nil {
+		symbols = defaultSymbolTable
+	}
+	symbols = symbols.Clone()
+	expr := e.convert(symbols)
+	return expr.Print(symbols)
+}
+
+// ErrUnboundVariable is returned by EvaluateExpression when a variable of
+// the expression has no value in the bindings.
+var ErrUnboundVariable = errors.New("biscuit: unbound variable")
+
+// EvaluateExpression evaluates the expression outside of an authorizer, such as
+// to test a policy expression. The variables take their value in bindings,
+// keyed by their name without the `$` prefix, which cannot be variables.
+// It returns ErrUnboundVariable when a variable has no value.
+func EvaluateExpression(e Expression, bindings map[string]Term) (Term, error) {
+	for _, op := range e {
+		if v, ok := op.(Value); ok {
+			if name, ok := v.Term.(Variable); ok {
+				if _, bound := bindings[string(name)]; !bound {
+					return nil, fmt.Errorf("%w: %s", ErrUnboundVariable, name)
+				}
+			}
+		}
+	}
+
+	symbols := defaultSymbolTable.Clone()
+	values := make(map[datalog.Variable]*datalog.Term, len(bindings))
+	for name, term := range bindings {
+		if term == nil || term.Type() == TermTypeVariable {
+			return nil, fmt.Errorf("biscuit: invalid value for $%s: %v", name, term)
+		}
+		value := term.convert(symbols)
+		values[Variable(name).convert(symbols).(datalog.Variable)] = &value
+	}
+
+	expr := e.convert(symbols)
+	res, err := expr.Evaluate(values, symbols)
+	if err != nil {
+		return nil, err
+	}
+	return fromDatalogID(symbols, res)
+}
+
 func fromDatalogExpression(symbols *datalog.SymbolTable, dlExpr datalog.Expression) (Expression, error) {
 	expr := make(Expression, len(dlExpr))
 	for i, dlOP := range dlExpr {
@@ -319,6 +774,9 @@ const (
 	UnaryNegate
 	UnaryParens
 	UnaryLength
+	UnaryCharLength
+	UnaryToDate
+	UnaryToInt
 )
 
 func (UnaryOp) Type() OpType {
@@ -332,6 +790,12 @@ func (op UnaryOp) convert(symbols *datalog.SymbolTable) datalog.Op {
 		return datalog.UnaryOp{UnaryOpFunc: datalog.Parens{}}
 	case UnaryLength:
 		return datalog.UnaryOp{UnaryOpFunc: datalog.Length{}}
+	case UnaryCharLength:
+		return datalog.UnaryOp{UnaryOpFunc: datalog.CharLength{}}
+	case UnaryToDate:
+		return datalog.UnaryOp{UnaryOpFunc: datalog.ToDate{}}
+	case UnaryToInt:
+		return datalog.UnaryOp{UnaryOpFunc: datalog.ToInt{}}
 	default:
 		panic(fmt.Sprintf("biscuit: cannot convert invalid unary op type: %v", op))
 	}
@@ -345,6 +809,12 @@ func fromDatalogUnaryOp(symbols *datalog.SymbolTable, dlUnary datalog.UnaryOp) (
 		return UnaryParens, nil
 	case datalog.UnaryLength:
 		return UnaryLength, nil
+	case datalog.UnaryCharLength:
+		return UnaryCharLength, nil
+	case datalog.UnaryToDate:
+		return UnaryToDate, nil
+	case datalog.UnaryToInt:
+		return UnaryToInt, nil
 	default:
 		return UnaryUndefined, fmt.Errorf("unsupported datalog unary op: %v", dlUnary.UnaryOpFunc.Type())
 	}
@@ -373,6 +843,12 @@ const (
 	BinaryOr
 	BinaryIntersection
 	BinaryUnion
+	BinaryGet
+	BinaryNotEqual
+	BinarySubset
+	BinarySuperset
+	BinaryBitwiseAnd
+	BinaryRegexInsensitive
 )
 
 func (BinaryOp) Type() OpType {
@@ -414,6 +890,18 @@ func (op BinaryOp) convert(symbols *datalog.SymbolTable) datalog.Op {
 		return datalog.BinaryOp{BinaryOpFunc: datalog.Intersection{}}
 	case BinaryUnion:
 		return datalog.BinaryOp{BinaryOpFunc: datalog.Union{}}
+	case BinaryGet:
+		return datalog.BinaryOp{BinaryOpFunc: datalog.Get{}}
+	case BinaryNotEqual:
+		return datalog.BinaryOp{BinaryOpFunc: datalog.NotEqual{}}
+	case BinarySubset:
+		return datalog.BinaryOp{BinaryOpFunc: datalog.Subset{}}
+	case BinarySuperset:
+		return datalog.BinaryOp{BinaryOpFunc: datalog.Superset{}}
+	case BinaryBitwiseAnd:
+		return datalog.BinaryOp{BinaryOpFunc: datalog.BitwiseAnd{}}
+	case BinaryRegexInsensitive:
+		return datalog.BinaryOp{BinaryOpFunc: datalog.RegexInsensitive{}}
 	default:
 		panic(fmt.Sprintf("biscuit: cannot convert invalid binary op type: %v", op))
 	}
@@ -455,13 +943,39 @@ func fromDatalogBinaryOp(symbols *datalog.SymbolTable, dbBinary datalog.BinaryOp
 		return BinaryIntersection, nil
 	case datalog.BinaryUnion:
 		return BinaryUnion, nil
+	case datalog.BinaryGet:
+		return BinaryGet, nil
+	case datalog.BinaryNotEqual:
+		return BinaryNotEqual, nil
+	case datalog.BinarySubset:
+		return BinarySubset, nil
+	case datalog.BinarySuperset:
+		return BinarySuperset, nil
+	case datalog.BinaryBitwiseAnd:
+		return BinaryBitwiseAnd, nil
+	case datalog.BinaryRegexInsensitive:
+		return BinaryRegexInsensitive, nil
 	default:
 		return BinaryUndefined, fmt.Errorf("unsupported datalog binary op: %v", dbBinary.BinaryOpFunc.Type())
 	}
 }
 
+// CheckKind defines how the queries of a check are matched.
+type CheckKind byte
+
+const (
+	// CheckKindOne, written `check if`, succeeds when one query matches.
+	CheckKindOne CheckKind = iota
+	// CheckKindAll, written `check all`, succeeds when a query matches and
+	// all the facts it matches satisfy its expressions.
+	CheckKindAll
+	// CheckKindReject, written `reject if`, fails when one query matches.
+	CheckKindReject
+)
+
 type Check struct {
 	Queries []Rule
+	Kind    CheckKind
 }
 
 func (c Check) convert(symbols *datalog.SymbolTable) datalog.Check {
@@ -470,11 +984,32 @@ func (c Check) convert(symbols *datalog.SymbolTable) datalog.Check {
 		queries[i] = q.convert(symbols)
 	}
 
+	kind := datalog.CheckKindOne
+	switch c.Kind {
+	case CheckKindAll:
+		kind = datalog.CheckKindAll
+	case CheckKindReject:
+		kind = datalog.CheckKindReject
+	}
+
 	return datalog.Check{
 		Queries: queries,
+		Kind:    kind,
 	}
 }
 
+// String returns the datalog source of the check, such as
+// `check if resource($r), $r.starts_with("/a")`, which can be parsed back.
+// The symbols are not modified, and the default table is used when nil.
+func (c Check) String(symbols *datalog.SymbolTable) string {
+	if symbols == nil {
+		symbols = defaultSymbolTable
+	}
+	symbols = symbols.Clone()
+	debug := datalog.SymbolDebugger{SymbolTable: symbols}
+	return debug.Check(c.convert(symbols))
+}
+
 func fromDatalogCheck(symbols *datalog.SymbolTable, dlCheck datalog.Check) (*Check, error) {
 	queries := make([]Rule, len(dlCheck.Queries))
 	for i, q := range dlCheck.Queries {
@@ -485,8 +1020,21 @@ func fromDatalogCheck(symbols *datalog.SymbolTable, dlCheck datalog.Check) (*Che
 		queries[i] = *query
 	}
 
+	var kind CheckKind
+	switch dlCheck.Kind {
+	case datalog.CheckKindOne:
+		kind = CheckKindOne
+	case datalog.CheckKindAll:
+		kind = CheckKindAll
+	case datalog.CheckKindReject:
+		kind = CheckKindReject
+	default:
+		return nil, fmt.Errorf("unsupported datalog check kind: %v", dlCheck.Kind)
+	}
+
 	return &Check{
 		Queries: queries,
+		Kind:    kind,
 	}, nil
 }
 
@@ -509,7 +1057,7 @@ func (p Predicate) convert(symbols *datalog.SymbolTable) datalog.Predicate {
 func (p Predicate) String() string {
 	terms := make([]string, 0, len(p.IDs))
 	for _, a := range p.IDs {
-		terms = append(terms, a.String())
+		terms = append(terms, TermString(a))
 	}
 	return fmt.Sprintf("%s(%s)", p.Name, strings.Join(terms, ", "))
 }
@@ -517,6 +1065,9 @@ func (p Predicate) String() string {
 type TermType byte
 
 const (
+	// TermTypeSymbol is a legacy value, no term has this type: the symbols of
+	// previous versions of the format are strings, stored in the symbol table
+	// like the String terms. It is kept so the other values do not change.
 	TermTypeSymbol TermType = iota
 	TermTypeVariable
 	TermTypeInteger
@@ -525,6 +1076,9 @@ const (
 	TermTypeBytes
 	TermTypeBool
 	TermTypeSet
+	TermTypeArray
+	TermTypeMap
+	TermTypeTagged
 )
 
 type Term interface {
@@ -533,13 +1087,66 @@ type Term interface {
 	convert(symbols *datalog.SymbolTable) datalog.Term
 }
 
+// stringEscaper escapes the characters which cannot appear as is in a datalog
+// string, other characters such as tabulations or emojis are kept.
+var stringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+// TermString returns the datalog source of a term, which the parser reads
+// back as the same term: strings are quoted and escaped, dates are written
+// in RFC 3339 format in UTC, bytes are hex encoded with the `hex:` prefix,
+// arrays with the `array:` prefix, and the elements of sets and the entries
+// of maps are sorted. Tagged terms have no datalog syntax: they are written
+// as `tagged("tag", hex:...)`, which the parser does not read.
+func TermString(t Term) string {
+	switch t := t.(type) {
+	case Variable:
+		return "$" + string(t)
+	case Integer:
+		return strconv.FormatInt(int64(t), 10)
+	case String:
+		return `"` + stringEscaper.Replace(string(t)) + `"`
+	case Date:
+		return time.Time(t).UTC().Format(time.RFC3339)
+	case Bytes:
+		return "hex:" + hex.EncodeToString(t)
+	case Bool:
+		return strconv.FormatBool(bool(t))
+	case Set:
+		elts := make([]string, 0, len(t))
+		for _, e := range t {
+			elts = append(elts, TermString(e))
+		}
+		sort.Strings(elts)
+		return fmt.Sprintf("[%s]", strings.Join(elts, ", "))
+	case Array:
+		elts := make([]string, 0, len(t))
+		for _, e := range t {
+			elts = append(elts, TermString(e))
+		}
+		return fmt.Sprintf("array:[%s]", strings.Join(elts, ", "))
+	case Map:
+		elts := make([]string, 0, len(t))
+		for k, v := range t {
+			elts = append(elts, fmt.Sprintf("%s: %s", TermString(k), TermString(v)))
+		}
+		sort.Strings(elts)
+		return fmt.Sprintf("{%s}", strings.Join(elts, ", "))
+	case Tagged:
+		return fmt.Sprintf("tagged(%s, %s)", TermString(String(t.Tag)), TermString(t.Value))
+	case nil:
+		return "<nil>"
+	default:
+		return t.String()
+	}
+}
+
 type Variable string
 
 func (a Variable) Type() TermType { return TermTypeVariable }
 func (a Variable) convert(symbols *datalog.SymbolTable) datalog.Term {
 	return datalog.Variable(symbols.Insert(string(a)))
 }
-func (a Variable) String() string { return fmt.Sprintf("$%s", string(a)) }
+func (a Variable) String() string { return TermString(a) }
 
 type Integer int64
 
@@ -547,7 +1154,7 @@ func (a Integer) Type() TermType { return TermTypeInteger }
 func (a Integer) convert(symbols *datalog.SymbolTable) datalog.Term {
 	return datalog.Integer(a)
 }
-func (a Integer) String() string { return fmt.Sprintf("%d", a) }
+func (a Integer) String() string { return TermString(a) }
 
 type String string
 
@@ -555,7 +1162,7 @@ func (a String) Type() TermType { return TermTypeString }
 func (a String) convert(symbols *datalog.SymbolTable) datalog.Term {
 	return datalog.String(symbols.Insert(string(a)))
 }
-func (a String) String() string { return fmt.Sprintf("%q", string(a)) }
+func (a String) String() string { return TermString(a) }
 
 type Date time.Time
 
@@ -563,7 +1170,7 @@ func (a Date) Type() TermType { return TermTypeDate }
 func (a Date) convert(symbols *datalog.SymbolTable) datalog.Term {
 	return datalog.Date(time.Time(a).Unix())
 }
-func (a Date) String() string { return time.Time(a).Format(time.RFC3339) }
+func (a Date) String() string { return TermString(a) }
 
 type Bytes []byte
 
@@ -571,7 +1178,22 @@ func (a Bytes) Type() TermType { return TermTypeBytes }
 func (a Bytes) convert(symbols *datalog.SymbolTable) datalog.Term {
 	return datalog.Bytes(a)
 }
-func (a Bytes) String() string { return fmt.Sprintf("hex:%s", hex.EncodeToString(a)) }
+func (a Bytes) String() string { return TermString(a) }
+
+// Tagged carries an opaque value of a domain specific type, as bytes
+// interpreted according to its tag. It is not part of the biscuit
+// specification, so other implementations cannot read tokens using it,
+// and the parser does not read it back.
+type Tagged struct {
+	Tag   string
+	Value Bytes
+}
+
+func (a Tagged) Type() TermType { return TermTypeTagged }
+func (a Tagged) convert(symbols *datalog.SymbolTable) datalog.Term {
+	return datalog.Tagged{Tag: datalog.String(symbols.Insert(a.Tag)), Value: datalog.Bytes(a.Value)}
+}
+func (a Tagged) String() string { return TermString(a) }
 
 type Bool bool
 
@@ -579,7 +1201,7 @@ func (b Bool) Type() TermType { return TermTypeBool }
 func (b Bool) convert(symbols *datalog.SymbolTable) datalog.Term {
 	return datalog.Bool(b)
 }
-func (b Bool) String() string { return fmt.Sprintf("%t", b) }
+func (b Bool) String() string { return TermString(b) }
 
 type Set []Term
 
@@ -591,13 +1213,97 @@ func (a Set) convert(symbols *datalog.SymbolTable) datalog.Term {
 	}
 	return datalogSet
 }
-func (a Set) String() string {
-	elts := make([]string, 0, len(a))
+func (a Set) String() string { return TermString(a) }
+
+// Array is an ordered list of terms.
+type Array []Term
+
+func (a Array) Type() TermType { return TermTypeArray }
+func (a Array) convert(symbols *datalog.SymbolTable) datalog.Term {
+	datalogArray := make(datalog.Array, 0, len(a))
 	for _, e := range a {
-		elts = append(elts, e.String())
+		datalogArray = append(datalogArray, e.convert(symbols))
+	}
+	return datalogArray
+}
+func (a Array) String() string { return TermString(a) }
+
+// Map associates terms to keys, which must be Integer or String.
+type Map map[Term]Term
+
+func (a Map) Type() TermType { return TermTypeMap }
+func (a Map) convert(symbols *datalog.SymbolTable) datalog.Term {
+	datalogMap := make(datalog.Map, len(a))
+	for k, v := range a {
+		datalogMap[k.convert(symbols)] = v.convert(symbols)
+	}
+	return datalogMap
+}
+func (a Map) String() string { return TermString(a) }
+
+// ErrInvalidMapKey is returned when adding to a block a Map with a key
+// which is not an Integer or a String, the only key types of the token format.
+var ErrInvalidMapKey = errors.New("biscuit: map keys must be integers or strings")
+
+// checkMapKeys returns ErrInvalidMapKey when one of the terms, or a term
+// they contain, is a Map with an unsupported key.
+func checkMapKeys(terms ...Term) error {
+	for _, t := range terms {
+		switch t := t.(type) {
+		case Set:
+			if err := checkMapKeys(t...); err != nil {
+				return err
+			}
+		case Array:
+			if err := checkMapKeys(t...); err != nil {
+				return err
+			}
+		case Map:
+			for k, v := range t {
+				if k == nil || (k.Type() != TermTypeInteger && k.Type() != TermTypeString) {
+					return fmt.Errorf("%w: %s", ErrInvalidMapKey, TermString(k))
+				}
+				if err := checkMapKeys(v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p Predicate) checkMapKeys() error {
+	return checkMapKeys(p.IDs...)
+}
+
+func (r Rule) checkMapKeys() error {
+	if err := r.Head.checkMapKeys(); err != nil {
+		return err
+	}
+	for _, p := range r.Body {
+		if err := p.checkMapKeys(); err != nil {
+			return err
+		}
+	}
+	for _, e := range r.Expressions {
+		for _, op := range e {
+			if v, ok := op.(Value); ok {
+				if err := checkMapKeys(v.Term); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c Check) checkMapKeys() error {
+	for _, q := range c.Queries {
+		if err := q.checkMapKeys(); err != nil {
+			return err
+		}
 	}
-	sort.Strings(elts)
-	return fmt.Sprintf("[%s]", strings.Join(elts, ", "))
+	return nil
 }
 
 type PolicyKind byte
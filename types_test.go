@@ -1,6 +1,14 @@
 package biscuit
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"strings"
 	"testing"
 	"time"
 
@@ -54,3 +62,255 @@ func TestFromDatalogFact(t *testing.T) {
 	}
 	require.Equal(t, expectedFact, fact)
 }
+
+func TestParsedBlockValidate(t *testing.T) {
+	reservedFact := Fact{Predicate: Predicate{Name: "authority", IDs: []Term{String("file1")}}}
+	reservedRule := Rule{
+		Head: Predicate{Name: "ambient", IDs: []Term{Variable("file")}},
+		Body: []Predicate{{Name: "resource", IDs: []Term{Variable("file")}}},
+	}
+	legalFact := Fact{Predicate: Predicate{Name: "resource", IDs: []Term{String("file1")}}}
+
+	testCases := []struct {
+		desc        string
+		block       ParsedBlock
+		isAuthority bool
+		expectedErr error
+	}{
+		{
+			desc:  "legal block",
+			block: ParsedBlock{Facts: FactSet{legalFact}},
+		},
+		{
+			desc:        "reserved fact",
+			block:       ParsedBlock{Facts: FactSet{legalFact, reservedFact}},
+			expectedErr: ErrInvalidBlockFact,
+		},
+		{
+			desc:        "reserved rule",
+			block:       ParsedBlock{Rules: []Rule{reservedRule}},
+			expectedErr: ErrInvalidBlockRule,
+		},
+		{
+			desc:        "revocation id",
+			block:       ParsedBlock{Facts: FactSet{{Predicate: Predicate{Name: "revocation_id", IDs: []Term{Integer(0), Bytes("id")}}}}},
+			expectedErr: ErrInvalidBlockFact,
+		},
+		{
+			desc:        "authority block",
+			block:       ParsedBlock{Facts: FactSet{reservedFact}, Rules: []Rule{reservedRule}},
+			isAuthority: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.block.Validate(tc.isAuthority)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("all misuses", func(t *testing.T) {
+		ambientFact := Fact{Predicate: Predicate{Name: "ambient", IDs: []Term{Integer(1)}}}
+		err := ParsedBlock{Facts: FactSet{reservedFact, legalFact, ambientFact}, Rules: []Rule{reservedRule}}.Validate(false)
+		require.ErrorIs(t, err, ErrInvalidBlockFact)
+		require.ErrorIs(t, err, ErrInvalidBlockRule)
+		require.Equal(t, ReservedPredicateError{
+			Facts: []string{`authority("file1")`, `ambient(1)`},
+			Rules: []string{`ambient($file)`},
+		}, err)
+		require.EqualError(t, err, `biscuit: reserved predicates outside of the authority block: fact authority("file1"), fact ambient(1), rule ambient($file)`)
+
+		err = ParsedBlock{Facts: FactSet{reservedFact}}.Validate(false)
+		require.False(t, errors.Is(err, ErrInvalidBlockRule))
+	})
+
+	t.Run("block builder", func(t *testing.T) {
+		_, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+		b, err := NewBuilder(privateRoot).Build()
+		require.NoError(t, err)
+
+		block := b.CreateBlock()
+		require.NoError(t, block.AddFact(legalFact))
+		require.NoError(t, block.Validate())
+
+		require.NoError(t, block.AddFact(reservedFact))
+		require.NoError(t, block.AddRule(reservedRule))
+		err = block.Validate()
+		require.Equal(t, ReservedPredicateError{
+			Facts: []string{`authority("file1")`},
+			Rules: []string{`ambient($file)`},
+		}, err)
+	})
+
+	t.Run("append", func(t *testing.T) {
+		rng := rand.Reader
+		_, privateRoot, _ := ed25519.GenerateKey(rng)
+		b, err := NewBuilder(privateRoot).Build()
+		require.NoError(t, err)
+
+		_, err = b.AppendParsedBlock(rng, ParsedBlock{Facts: FactSet{reservedFact}})
+		require.ErrorIs(t, err, ErrInvalidBlockFact)
+
+		b2, err := b.AppendParsedBlock(rng, ParsedBlock{Facts: FactSet{legalFact}})
+		require.NoError(t, err)
+		require.Equal(t, 1, b2.BlockCount())
+	})
+}
+
+func TestSymbolFreeAPI(t *testing.T) {
+	// facts are built from the public terms only, strings being
+	// converted to symbol table indexes internally
+	now := time.Unix(time.Now().Unix(), 0)
+	facts := []Fact{
+		{Predicate: Predicate{Name: "company", IDs: []Term{String("acme"), Integer(42), Bool(true)}}},
+		{Predicate: Predicate{Name: "project", IDs: []Term{Date(now), Bytes([]byte{1, 2}), Set{String("a")}}}},
+		{Predicate: Predicate{Name: "label", IDs: []Term{Array{String("a")}, Map{String("k"): Integer(1)}}}},
+	}
+
+	_, privateRoot, _ := ed25519.GenerateKey(rand.Reader)
+	builder := NewBuilder(privateRoot)
+	for _, f := range facts {
+		require.NoError(t, builder.AddAuthorityFact(f))
+	}
+	b, err := builder.Build()
+	require.NoError(t, err)
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = Unmarshal(serialized)
+	require.NoError(t, err)
+
+	for i, dlFact := range *b.authority.facts {
+		fact, err := fromDatalogFact(b.symbols, dlFact)
+		require.NoError(t, err)
+		require.Equal(t, facts[i], *fact)
+		for _, term := range fact.IDs {
+			require.NotEqual(t, TermTypeSymbol, term.Type())
+		}
+	}
+
+	// no exported declaration reintroduces a Symbol term
+	pkgs, err := parser.ParseDir(token.NewFileSet(), ".", func(info fs.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	require.NoError(t, err)
+	for _, pkg := range pkgs {
+		for name, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				ast.Inspect(decl, func(n ast.Node) bool {
+					switch n := n.(type) {
+					case *ast.TypeSpec:
+						require.NotEqual(t, "Symbol", n.Name.Name, name)
+					case *ast.ValueSpec:
+						for _, id := range n.Names {
+							require.False(t, id.IsExported() && strings.HasPrefix(id.Name, "Symbol"), "%s declares %s", name, id.Name)
+						}
+					}
+					return true
+				})
+			}
+		}
+	}
+}
+
+func TestTaggedTerm(t *testing.T) {
+	rng := rand.Reader
+	publicRoot, privateRoot, _ := ed25519.GenerateKey(rng)
+
+	id := Tagged{Tag: "uuid", Value: Bytes{0xca, 0xfe}}
+	require.Equal(t, `tagged("uuid", hex:cafe)`, id.String())
+
+	builder := NewBuilder(privateRoot)
+	require.NoError(t, builder.AddAuthorityFact(Fact{Predicate: Predicate{Name: "company", IDs: []Term{id}}}))
+	require.NoError(t, builder.AddAuthorityCheck(Check{Queries: []Rule{{
+		Head: Predicate{Name: "query"},
+		Body: []Predicate{{Name: "label", IDs: []Term{Variable("l")}}},
+		Expressions: []Expression{{
+			Value{Term: Set{id, Tagged{Tag: "uuid", Value: Bytes{0xbe, 0xef}}}},
+			Value{Term: Variable("l")},
+			BinaryContains,
+		}},
+	}}}))
+	b, err := builder.Build()
+	require.NoError(t, err)
+
+	serialized, err := b.Serialize()
+	require.NoError(t, err)
+	b, err = Unmarshal(serialized)
+	require.NoError(t, err)
+
+	fact, err := fromDatalogFact(b.symbols, (*b.authority.facts)[0])
+	require.NoError(t, err)
+	require.Equal(t, Fact{Predicate: Predicate{Name: "company", IDs: []Term{id}}}, *fact)
+	require.Contains(t, b.String(), `check if label($l), [tagged("uuid", hex:beef), tagged("uuid", hex:cafe)].contains($l)`)
+
+	for _, tc := range []struct {
+		desc  string
+		label Term
+		valid bool
+	}{
+		{desc: "same tagged value", label: Tagged{Tag: "uuid", Value: Bytes{0xbe, 0xef}}, valid: true},
+		{desc: "other tag", label: Tagged{Tag: "ip", Value: Bytes{0xbe, 0xef}}, valid: false},
+		{desc: "untagged bytes", label: Bytes{0xbe, 0xef}, valid: false},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			v, err := b.Authorizer(publicRoot)
+			require.NoError(t, err)
+			v.AddFact(Fact{Predicate: Predicate{Name: "label", IDs: []Term{tc.label}}})
+			v.AddPolicy(DefaultAllowPolicy)
+			if tc.valid {
+				require.NoError(t, v.Authorize())
+			} else {
+				require.Error(t, v.Authorize())
+			}
+		})
+	}
+}
+
+func TestEvaluateExpression(t *testing.T) {
+	greaterThan5 := Expression{
+		Value{Term: Variable("a")},
+		Value{Term: Integer(5)},
+		BinaryGreaterThan,
+	}
+
+	res, err := EvaluateExpression(greaterThan5, map[string]Term{"a": Integer(10)})
+	require.NoError(t, err)
+	require.Equal(t, Bool(true), res)
+
+	res, err = EvaluateExpression(greaterThan5, map[string]Term{"a": Integer(3)})
+	require.NoError(t, err)
+	require.Equal(t, Bool(false), res)
+
+	_, err = EvaluateExpression(greaterThan5, nil)
+	require.ErrorIs(t, err, ErrUnboundVariable)
+	require.EqualError(t, err, "biscuit: unbound variable: $a")
+
+	_, err = EvaluateExpression(greaterThan5, map[string]Term{"a": Variable("b")})
+	require.Error(t, err)
+
+	// type errors of the evaluation are returned
+	_, err = EvaluateExpression(greaterThan5, map[string]Term{"a": String("10")})
+	require.Error(t, err)
+
+	// the result is converted to a public term
+	res, err = EvaluateExpression(Expression{
+		Value{Term: Variable("project")},
+		Value{Term: String("-prod")},
+		BinaryAdd,
+	}, map[string]Term{"project": String("apollo")})
+	require.NoError(t, err)
+	require.Equal(t, String("apollo-prod"), res)
+
+	res, err = EvaluateExpression(Expression{
+		Value{Term: Set{String("read"), String("write")}},
+		Value{Term: Variable("op")},
+		BinaryContains,
+	}, map[string]Term{"op": String("read"), "unused": Integer(1)})
+	require.NoError(t, err)
+	require.Equal(t, Bool(true), res)
+}